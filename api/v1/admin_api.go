@@ -0,0 +1,29 @@
+package apiv1
+
+import "github.com/gin-gonic/gin"
+
+type AdminHandler interface {
+	ListAuditEvents(c *gin.Context)
+	CreateWebhookSubscription(c *gin.Context)
+	ListWebhookSubscriptions(c *gin.Context)
+	GetWebhookSubscription(c *gin.Context)
+	UpdateWebhookSubscription(c *gin.Context)
+	DeleteWebhookSubscription(c *gin.Context)
+	ListWebhookDeliveries(c *gin.Context)
+	ReplayWebhookDelivery(c *gin.Context)
+}
+
+func SetupAdminRoutes(router *gin.RouterGroup, adminHandler AdminHandler, middlewares ...gin.HandlerFunc) {
+	adminGroup := router.Group("/admin", middlewares...)
+	{
+		adminGroup.GET("/audit-events", adminHandler.ListAuditEvents)
+
+		adminGroup.POST("/webhooks", adminHandler.CreateWebhookSubscription)
+		adminGroup.GET("/webhooks", adminHandler.ListWebhookSubscriptions)
+		adminGroup.GET("/webhooks/:id", adminHandler.GetWebhookSubscription)
+		adminGroup.PUT("/webhooks/:id", adminHandler.UpdateWebhookSubscription)
+		adminGroup.DELETE("/webhooks/:id", adminHandler.DeleteWebhookSubscription)
+		adminGroup.GET("/webhooks/:id/deliveries", adminHandler.ListWebhookDeliveries)
+		adminGroup.POST("/webhooks/deliveries/:id/replay", adminHandler.ReplayWebhookDelivery)
+	}
+}
@@ -2,10 +2,35 @@ package apiv1
 
 import "github.com/gin-gonic/gin"
 
-func SetupAPIRoutes(router *gin.Engine, authHandler AuthHandler, middlewares ...gin.HandlerFunc) {
+func SetupAPIRoutes(
+	router *gin.Engine,
+	authHandler AuthHandler,
+	userHandler UserHandler,
+	adminHandler AdminHandler,
+	oauthHandler OAuthHandler,
+	authMiddleware gin.HandlerFunc,
+	adminMiddleware gin.HandlerFunc,
+	clientAuthMiddleware gin.HandlerFunc,
+	middlewares ...gin.HandlerFunc,
+) {
 	// public routes
 	apiV1 := router.Group("/api/v1")
 	{
 		SetupAuthRoutes(apiV1, authHandler, middlewares...)
 	}
+
+	// authenticated routes
+	{
+		SetupUserRoutes(apiV1, userHandler, authMiddleware)
+	}
+
+	// admin routes
+	{
+		SetupAdminRoutes(apiV1, adminHandler, authMiddleware, adminMiddleware)
+	}
+
+	// routes for other services
+	{
+		SetupOAuthRoutes(router, oauthHandler, clientAuthMiddleware, middlewares...)
+	}
 }
@@ -7,10 +7,9 @@ import (
 )
 
 type AuthHandler interface {
-	Login(c *gin.Context)
-	Signup(c *gin.Context)
+	RequestPhoneLogin(c *gin.Context)
+	PhoneLogin(c *gin.Context)
 	Logout(c *gin.Context)
-	RefreshToken(c *gin.Context)
 
 	ForgotPassword(c *gin.Context)
 	ResetPassword(c *gin.Context)
@@ -22,10 +21,10 @@ type AuthHandler interface {
 func SetupAuthRoutes(router *gin.RouterGroup, authHandler AuthHandler, middlewares ...gin.HandlerFunc) {
 	authGroup := router.Group("/auth")
 	{
-		authGroup.POST("/signup", authHandler.Signup)
-		authGroup.POST("/login", authHandler.Login)
+		// signup, login, refresh and token validation are served by the gateway from the AuthService protos
+		authGroup.POST("/login/phone/code", authHandler.RequestPhoneLogin)
+		authGroup.POST("/login/phone", authHandler.PhoneLogin)
 		authGroup.POST("/logout", authHandler.Logout)
-		authGroup.POST("/refresh", authHandler.RefreshToken)
 
 		authGroup.POST("/password/forgot", authHandler.ForgotPassword)
 		authGroup.POST("/password/reset", authHandler.ResetPassword)
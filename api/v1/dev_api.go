@@ -0,0 +1,17 @@
+package apiv1
+
+import "github.com/gin-gonic/gin"
+
+type DevHandler interface {
+	ListEmailTemplates(c *gin.Context)
+	PreviewEmail(c *gin.Context)
+}
+
+// SetupDevRoutes mounts development tooling, it must not be called in production
+func SetupDevRoutes(router *gin.RouterGroup, devHandler DevHandler, middlewares ...gin.HandlerFunc) {
+	devGroup := router.Group("/dev", middlewares...)
+	{
+		devGroup.GET("/emails", devHandler.ListEmailTemplates)
+		devGroup.GET("/emails/:template", devHandler.PreviewEmail)
+	}
+}
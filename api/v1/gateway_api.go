@@ -0,0 +1,24 @@
+package apiv1
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiV1Prefix = "/api/v1/"
+
+// SetupGatewayRoutes hands the /api/v1 requests no gin route matched to the gateway generated from the
+// protobufs, gin routes keep precedence. Requests outside /api/v1 still get the default 404.
+func SetupGatewayRoutes(router *gin.Engine, gateway http.Handler) {
+	router.NoRoute(func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, apiV1Prefix) {
+			return
+		}
+
+		// gin answers 404 unless the handler sets another status
+		c.Status(http.StatusOK)
+		gateway.ServeHTTP(c.Writer, c.Request)
+	})
+}
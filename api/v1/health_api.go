@@ -0,0 +1,14 @@
+package apiv1
+
+import "github.com/gin-gonic/gin"
+
+type HealthHandler interface {
+	Liveness(c *gin.Context)
+	Readiness(c *gin.Context)
+}
+
+// SetupHealthRoutes mounts the liveness and readiness probes at the root, outside the versioned api
+func SetupHealthRoutes(router gin.IRouter, healthHandler HealthHandler) {
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+}
@@ -0,0 +1,21 @@
+package apiv1
+
+import "github.com/gin-gonic/gin"
+
+type OAuthHandler interface {
+	IntrospectToken(c *gin.Context)
+}
+
+// SetupOAuthRoutes mounts the endpoints other services call with their client credentials,
+// at the paths OAuth clients expect rather than under the versioned api
+func SetupOAuthRoutes(
+	router gin.IRouter,
+	oauthHandler OAuthHandler,
+	clientAuthMiddleware gin.HandlerFunc,
+	middlewares ...gin.HandlerFunc,
+) {
+	oauthGroup := router.Group("/oauth", clientAuthMiddleware)
+	{
+		oauthGroup.POST("/introspect", oauthHandler.IntrospectToken)
+	}
+}
@@ -0,0 +1,37 @@
+package apiv1
+
+import "github.com/gin-gonic/gin"
+
+type UserHandler interface {
+	DeleteMe(c *gin.Context)
+
+	RequestPhoneVerification(c *gin.Context)
+	VerifyPhone(c *gin.Context)
+
+	RequestDataExport(c *gin.Context)
+	GetDataExport(c *gin.Context)
+	DownloadDataExport(c *gin.Context)
+
+	ListSecurityEvents(c *gin.Context)
+}
+
+func SetupUserRoutes(router *gin.RouterGroup, userHandler UserHandler, authMiddleware gin.HandlerFunc) {
+	userGroup := router.Group("/users", authMiddleware)
+	{
+		userGroup.DELETE("/me", userHandler.DeleteMe)
+
+		userGroup.POST("/me/phone", userHandler.RequestPhoneVerification)
+		userGroup.POST("/me/phone/verify", userHandler.VerifyPhone)
+
+		userGroup.POST("/me/export", userHandler.RequestDataExport)
+		userGroup.GET("/me/export/:id", userHandler.GetDataExport)
+
+		userGroup.GET("/me/security-events", userHandler.ListSecurityEvents)
+	}
+
+	// authorized by the signature of the download url instead of an access token
+	exportGroup := router.Group("/exports")
+	{
+		exportGroup.GET("/:id/download", userHandler.DownloadDataExport)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/datpham/user-service-ms/internal/app"
+	"github.com/datpham/user-service-ms/internal/infra/database"
+	auditRepo "github.com/datpham/user-service-ms/internal/repository/audit"
+	auditSvc "github.com/datpham/user-service-ms/internal/service/audit"
+	"github.com/spf13/cobra"
+)
+
+func (c *cli) newAuditVerifyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "audit-verify",
+		Short: "Walk the audit log hash chain and report the first broken link",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := database.NewDatabase(c.config)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			auditRepo := auditRepo.New(db.GetDB(), auditRepo.ChainConfig{
+				SigningKey:         c.config.Audit.SigningKey,
+				CheckpointInterval: c.config.Audit.CheckpointInterval,
+			})
+			auditChainSvc := auditSvc.New(app.NewLogger(), auditRepo, c.config.Audit.SigningKey)
+
+			report, err := auditChainSvc.VerifyChain(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "verified %d audit events (%d redacted) and %d checkpoints\n",
+				report.VerifiedEvents, report.RedactedEvents, report.VerifiedCheckpoints)
+			if report.FirstBreak != nil {
+				return fmt.Errorf(
+					"audit chain is broken at sequence %d (event %q): %s",
+					report.FirstBreak.Sequence, report.FirstBreak.EventID, report.FirstBreak.Reason,
+				)
+			}
+
+			fmt.Fprintln(out, "audit chain is intact")
+			return nil
+		},
+	}
+}
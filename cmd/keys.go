@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/spf13/cobra"
+)
+
+const jwtSecretSize = 32
+
+func (c *cli) newKeysCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage the signing keys",
+	}
+
+	var keep int
+	rotate := &cobra.Command{
+		Use:   "rotate",
+		Short: "Generate a new JWT secret and print the config to deploy it with",
+		Long: "Generate a new JWT secret. New tokens are signed with it once the printed config is deployed, " +
+			"while the previous secrets keep verifying the tokens issued before. Tokens signed with a secret " +
+			"that is no longer kept stop working; sessions purge-expired clears their refresh tokens.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			secret, err := newJwtSecret()
+			if err != nil {
+				return err
+			}
+
+			printJwtConfig(cmd.OutOrStdout(), rotateJwtSecret(c.config.Jwt, secret, keep))
+			return nil
+		},
+	}
+	rotate.Flags().IntVar(&keep, "keep", 1, "number of previous secrets that keep verifying tokens")
+	cmd.AddCommand(rotate)
+
+	return cmd
+}
+
+func newJwtSecret() (string, error) {
+	secret := make([]byte, jwtSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// rotateJwtSecret makes secret the signing secret, the current one becomes the first of at most keep previous secrets
+func rotateJwtSecret(jwtConfig config.JwtConfig, secret string, keep int) config.JwtConfig {
+	previousSecrets := []string{}
+	if jwtConfig.Secret != "" {
+		previousSecrets = append(previousSecrets, jwtConfig.Secret)
+	}
+	previousSecrets = append(previousSecrets, jwtConfig.PreviousSecrets...)
+	if len(previousSecrets) > max(keep, 0) {
+		previousSecrets = previousSecrets[:max(keep, 0)]
+	}
+
+	return config.JwtConfig{Secret: secret, PreviousSecrets: previousSecrets}
+}
+
+func printJwtConfig(out io.Writer, jwtConfig config.JwtConfig) {
+	fmt.Fprintln(out, "# Deploy this config to every instance, then restart them")
+	fmt.Fprintln(out, "jwt:")
+	fmt.Fprintf(out, "    secret: %q\n", jwtConfig.Secret)
+	if len(jwtConfig.PreviousSecrets) == 0 {
+		fmt.Fprintln(out, "    previous_secrets: []")
+		return
+	}
+
+	fmt.Fprintln(out, "    previous_secrets:")
+	for _, previousSecret := range jwtConfig.PreviousSecrets {
+		fmt.Fprintf(out, "        - %q\n", previousSecret)
+	}
+}
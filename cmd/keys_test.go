@@ -0,0 +1,25 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/datpham/user-service-ms/config"
+)
+
+func TestRotateJwtSecret(t *testing.T) {
+	current := config.JwtConfig{Secret: "current", PreviousSecrets: []string{"older", "oldest"}}
+
+	rotated := rotateJwtSecret(current, "new", 2)
+
+	if rotated.Secret != "new" {
+		t.Fatalf("expected the new secret to sign, got %q", rotated.Secret)
+	}
+	if !slices.Equal(rotated.PreviousSecrets, []string{"current", "older"}) {
+		t.Fatalf("unexpected previous secrets %v", rotated.PreviousSecrets)
+	}
+
+	if rotated := rotateJwtSecret(current, "new", 0); len(rotated.PreviousSecrets) != 0 {
+		t.Fatalf("expected no previous secrets, got %v", rotated.PreviousSecrets)
+	}
+}
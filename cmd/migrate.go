@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/datpham/user-service-ms/internal/infra/database"
+	"github.com/spf13/cobra"
+)
+
+func (c *cli) newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema",
+	}
+
+	cmd.AddCommand(c.newMigrateUpCommand(), c.newMigrateDownCommand(), c.newMigrateStatusCommand())
+
+	return cmd
+}
+
+func (c *cli) newMigrateUpCommand() *cobra.Command {
+	var opts database.MigrateOptions
+
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply the pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.withMigrator(func(migrator *database.Migrator) error {
+				migrations, err := migrator.Up(cmd.Context(), opts)
+				printMigrations(cmd.OutOrStdout(), "applied", migrations, opts.DryRun, func(m database.Migration) string {
+					return m.Up
+				})
+				if err != nil {
+					return err
+				}
+
+				if len(migrations) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "schema is up to date")
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "print the migrations that would be applied without applying them")
+	cmd.Flags().IntVar(&opts.Steps, "steps", 0, "number of migrations to apply, every pending one when 0")
+
+	return cmd
+}
+
+func (c *cli) newMigrateDownCommand() *cobra.Command {
+	var (
+		opts      database.MigrateOptions
+		confirmed bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the last applied migrations, and drop the data they hold",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !confirmed && !opts.DryRun {
+				return errors.New("migrate down may drop tables and their data, pass --yes to confirm")
+			}
+
+			return c.withMigrator(func(migrator *database.Migrator) error {
+				migrations, err := migrator.Down(cmd.Context(), opts)
+				printMigrations(cmd.OutOrStdout(), "reverted", migrations, opts.DryRun, func(m database.Migration) string {
+					return m.Down
+				})
+				if err != nil {
+					return err
+				}
+
+				if len(migrations) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "no migration to revert")
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "print the migrations that would be reverted without reverting them")
+	cmd.Flags().IntVar(&opts.Steps, "steps", 1, "number of migrations to revert")
+	cmd.Flags().BoolVar(&confirmed, "yes", false, "confirm reverting the migrations")
+
+	return cmd
+}
+
+func (c *cli) newMigrateStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "List the migrations and whether they were applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.withMigrator(func(migrator *database.Migrator) error {
+				statuses, err := migrator.Status(cmd.Context())
+				if err != nil {
+					return err
+				}
+
+				for _, status := range statuses {
+					appliedAt := ""
+					if status.AppliedAt != nil {
+						appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "%04d %-32s %-8s %s\n", status.Version, status.Name, status.State, appliedAt)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+// printMigrations lists the migrations that ran, or their scripts on a dry run
+func printMigrations(
+	out io.Writer,
+	verb string,
+	migrations []database.Migration,
+	dryRun bool,
+	script func(m database.Migration) string,
+) {
+	for _, migration := range migrations {
+		if !dryRun {
+			fmt.Fprintf(out, "%s %s\n", verb, migration)
+			continue
+		}
+
+		fmt.Fprintf(out, "-- %s\n%s\n", migration, script(migration))
+	}
+}
+
+func (c *cli) withMigrator(fn func(migrator *database.Migrator) error) error {
+	db, err := database.NewDatabase(c.config)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db.GetDB())
+	if err != nil {
+		return err
+	}
+
+	return fn(migrator)
+}
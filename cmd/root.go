@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// cli holds what the subcommands share: the config is loaded once before any of them runs
+type cli struct {
+	configDir string
+	config    *config.Config
+}
+
+func newRootCommand() *cobra.Command {
+	c := &cli{config: &config.Config{}}
+
+	root := &cobra.Command{
+		Use:          "user-service",
+		Short:        "User service: authentication, accounts and their events",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return config.LoadConfig(c.configDir, c.config)
+		},
+		// without a subcommand the service serves everything, as before the subcommands existed
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.serve(cmd, serveOptions{})
+		},
+	}
+	root.PersistentFlags().StringVar(
+		&c.configDir, "config-dir", "config", "directory of config.yaml, environment variables override it",
+	)
+
+	root.AddCommand(
+		c.newServeCommand(),
+		c.newMigrateCommand(),
+		c.newUserCommand(),
+		c.newKeysCommand(),
+		c.newSessionsCommand(),
+		c.newConfigCommand(),
+		c.newAuditVerifyCommand(),
+	)
+
+	return root
+}
+
+// withApp builds the service without any server for a one-off command
+func (c *cli) withApp(fn func(application *app.App) error) error {
+	application, err := app.New(c.config, app.WithServers(app.Servers{}))
+	if err != nil {
+		return fmt.Errorf("failed to initialize service: %w", err)
+	}
+	defer application.Close()
+
+	return fn(application)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/datpham/user-service-ms/internal/app"
+	"github.com/spf13/cobra"
+)
+
+type serveOptions struct {
+	http      bool
+	grpc      bool
+	consumers bool
+	// migrate applies the pending migrations before serving, otherwise the service refuses to start on them
+	migrate bool
+}
+
+// servers selects every server when none was picked
+func (o serveOptions) servers() app.Servers {
+	if !o.http && !o.grpc && !o.consumers {
+		return app.AllServers
+	}
+
+	return app.Servers{HTTP: o.http, GRPC: o.grpc, Consumers: o.consumers}
+}
+
+func (c *cli) newServeCommand() *cobra.Command {
+	var opts serveOptions
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the HTTP and gRPC APIs and consume events, or only the selected ones",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.serve(cmd, opts)
+		},
+	}
+	cmd.Flags().BoolVar(&opts.http, "http", false, "serve the HTTP API and the REST gateway, requires --grpc")
+	cmd.Flags().BoolVar(&opts.grpc, "grpc", false, "serve the gRPC API")
+	cmd.Flags().BoolVar(&opts.consumers, "consumers", false, "consume events from the event bus")
+	cmd.Flags().BoolVar(&opts.migrate, "migrate", false, "apply the pending database migrations first")
+
+	return cmd
+}
+
+func (c *cli) serve(cmd *cobra.Command, opts serveOptions) error {
+	if err := c.config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	application, err := app.New(c.config, app.WithServers(opts.servers()))
+	if err != nil {
+		return fmt.Errorf("failed to initialize service: %w", err)
+	}
+
+	if opts.migrate {
+		if err := application.Migrate(cmd.Context()); err != nil {
+			application.Close()
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	return application.Run(cmd.Context())
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/datpham/user-service-ms/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func (c *cli) newSessionsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Manage the sessions of the users",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "purge-expired",
+		Short: "Clear the refresh tokens that expired or were signed with a secret that was rotated out",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.withApp(func(application *app.App) error {
+				purged, err := application.AuthService().PurgeExpiredSessions(cmd.Context())
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "purged %d expired sessions\n", purged)
+				return nil
+			})
+		},
+	})
+
+	return cmd
+}
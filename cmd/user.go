@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/datpham/user-service-ms/internal/app"
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	"github.com/spf13/cobra"
+)
+
+func (c *cli) newUserCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage users",
+	}
+
+	cmd.AddCommand(c.newCreateAdminCommand(), c.newResetPasswordCommand())
+
+	return cmd
+}
+
+func (c *cli) newCreateAdminCommand() *cobra.Command {
+	req := &reqDto.CreateAdminRequest{}
+
+	cmd := &cobra.Command{
+		Use:   "create-admin",
+		Short: "Create a user with the admin role",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			password, err := passwordFlagOrStdin(cmd, req.Password)
+			if err != nil {
+				return err
+			}
+			req.Password = password
+
+			return c.withApp(func(application *app.App) error {
+				user, err := application.AuthService().CreateAdmin(cmd.Context(), req)
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "created admin %s (%s)\n", user.Email, user.ID)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&req.Email, "email", "", "email of the admin")
+	cmd.Flags().StringVar(&req.Username, "username", "", "username of the admin")
+	cmd.Flags().StringVar(&req.Password, "password", "", "password of the admin, read from stdin when unset")
+	cmd.MarkFlagRequired("email")
+
+	return cmd
+}
+
+func (c *cli) newResetPasswordCommand() *cobra.Command {
+	req := &reqDto.SetPasswordRequest{}
+
+	cmd := &cobra.Command{
+		Use:   "reset-password",
+		Short: "Set a new password for a user and sign them out",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			password, err := passwordFlagOrStdin(cmd, req.Password)
+			if err != nil {
+				return err
+			}
+			req.Password = password
+
+			return c.withApp(func(application *app.App) error {
+				if err := application.AuthService().SetPassword(cmd.Context(), req); err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "password of %s reset\n", req.Email)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&req.Email, "email", "", "email of the user")
+	cmd.Flags().StringVar(&req.Password, "password", "", "new password, read from stdin when unset")
+	cmd.MarkFlagRequired("email")
+
+	return cmd
+}
+
+// passwordFlagOrStdin reads the password from the first line of stdin when the flag is unset,
+// so it does not end up in the shell history
+func passwordFlagOrStdin(cmd *cobra.Command, password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(cmd.ErrOrStderr(), "password: ")
+	line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	password = strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password is required")
+	}
+
+	return password, nil
+}
@@ -1,25 +1,61 @@
 package config
 
+import "time"
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
 type Config struct {
-	Env      string            `yaml:"env" mapstructure:"env"`
-	Server   ServerConfig      `yaml:"server" mapstructure:"server"`
-	Database DatabaseConfig    `yaml:"database" mapstructure:"database"`
-	Cache    CacheConfig       `yaml:"cache" mapstructure:"cache"`
-	Jwt      JwtConfig         `yaml:"jwt" mapstructure:"jwt"`
-	OAuth    GoogleOAuthConfig `yaml:"oauth" mapstructure:"oauth"`
-	RabbitMQ RabbitMQConfig    `yaml:"rabbitmq" mapstructure:"rabbitmq"`
+	Env           string              `yaml:"env" mapstructure:"env"`
+	Server        ServerConfig        `yaml:"server" mapstructure:"server"`
+	Database      DatabaseConfig      `yaml:"database" mapstructure:"database"`
+	Cache         CacheConfig         `yaml:"cache" mapstructure:"cache"`
+	Jwt           JwtConfig           `yaml:"jwt" mapstructure:"jwt"`
+	OAuth         GoogleOAuthConfig   `yaml:"oauth" mapstructure:"oauth"`
+	EventBus      EventBusConfig      `yaml:"event_bus" mapstructure:"event_bus"`
+	RabbitMQ      RabbitMQConfig      `yaml:"rabbitmq" mapstructure:"rabbitmq"`
+	Kafka         KafkaConfig         `yaml:"kafka" mapstructure:"kafka"`
+	Nats          NatsConfig          `yaml:"nats" mapstructure:"nats"`
+	Account       AccountConfig       `yaml:"account" mapstructure:"account"`
+	DataExport    DataExportConfig    `yaml:"data_export" mapstructure:"data_export"`
+	Audit         AuditConfig         `yaml:"audit" mapstructure:"audit"`
+	LoginSecurity LoginSecurityConfig `yaml:"login_security" mapstructure:"login_security"`
+	Outbox        OutboxConfig        `yaml:"outbox" mapstructure:"outbox"`
+	Consumer      ConsumerConfig      `yaml:"consumer" mapstructure:"consumer"`
+	Webhook       WebhookConfig       `yaml:"webhook" mapstructure:"webhook"`
+	Notifier      NotifierConfig      `yaml:"notifier" mapstructure:"notifier"`
+	SMTP          SMTPConfig          `yaml:"smtp" mapstructure:"smtp"`
+	Phone         PhoneConfig         `yaml:"phone" mapstructure:"phone"`
+	SMS           SMSConfig           `yaml:"sms" mapstructure:"sms"`
+	Introspection IntrospectionConfig `yaml:"introspection" mapstructure:"introspection"`
+	Health        HealthConfig        `yaml:"health" mapstructure:"health"`
+}
+
+// IsDevelopment reports whether development only features, e.g. the email previews, are enabled
+func (c *Config) IsDevelopment() bool {
+	return c.Env == EnvDevelopment
 }
 
 type ServerConfig struct {
 	Grpc struct {
 		Host string `yaml:"host" mapstructure:"host"`
 		Port string `yaml:"port" mapstructure:"port"`
+		// DefaultTimeout bounds unary calls whose client did not set a deadline
+		DefaultTimeout time.Duration `yaml:"default_timeout" mapstructure:"default_timeout"`
+		// Reflection registers the server reflection service for tools like grpcurl
+		Reflection bool `yaml:"reflection" mapstructure:"reflection"`
 	} `yaml:"grpc" mapstructure:"grpc"`
 	Http struct {
 		Host string `yaml:"host" mapstructure:"host"`
 		Port string `yaml:"port" mapstructure:"port"`
 	} `yaml:"http" mapstructure:"http"`
-	TLS struct {
+	// DrainTimeout bounds the graceful shutdown of every component together
+	DrainTimeout time.Duration `yaml:"drain_timeout" mapstructure:"drain_timeout"`
+	// DrainDelay is how long readiness reports false before the servers stop accepting requests
+	DrainDelay time.Duration `yaml:"drain_delay" mapstructure:"drain_delay"`
+	TLS        struct {
 		Enable   bool   `yaml:"enable" mapstructure:"enable"`
 		CertFile string `yaml:"cert_file" mapstructure:"cert_file"`
 		KeyFile  string `yaml:"key_file" mapstructure:"key_file"`
@@ -50,6 +86,8 @@ type CacheConfig struct {
 
 type JwtConfig struct {
 	Secret string `yaml:"secret" mapstructure:"secret"`
+	// PreviousSecrets verify the tokens signed before the secret was rotated until they expire
+	PreviousSecrets []string `yaml:"previous_secrets" mapstructure:"previous_secrets"`
 }
 
 type GoogleOAuthConfig struct {
@@ -64,4 +102,172 @@ type RabbitMQConfig struct {
 	User         string `yaml:"user" mapstructure:"user"`
 	Password     string `yaml:"password" mapstructure:"password"`
 	ExchangeName string `yaml:"exchange_name" mapstructure:"exchange_name"`
+
+	ChannelPoolSize         int           `yaml:"channel_pool_size" mapstructure:"channel_pool_size"`
+	ReconnectInitialBackoff time.Duration `yaml:"reconnect_initial_backoff" mapstructure:"reconnect_initial_backoff"`
+	ReconnectMaxBackoff     time.Duration `yaml:"reconnect_max_backoff" mapstructure:"reconnect_max_backoff"`
+	PublishTimeout          time.Duration `yaml:"publish_timeout" mapstructure:"publish_timeout"`
+}
+
+// EventBusConfig selects the broker events are published to and consumed from: rabbitmq, kafka, nats or memory
+type EventBusConfig struct {
+	Driver string `yaml:"driver" mapstructure:"driver"`
+}
+
+type KafkaConfig struct {
+	Brokers []string `yaml:"brokers" mapstructure:"brokers"`
+	// Topic receives the events published by this service, ConsumerTopic holds the events it consumes
+	Topic          string        `yaml:"topic" mapstructure:"topic"`
+	ConsumerTopic  string        `yaml:"consumer_topic" mapstructure:"consumer_topic"`
+	GroupID        string        `yaml:"group_id" mapstructure:"group_id"`
+	PublishTimeout time.Duration `yaml:"publish_timeout" mapstructure:"publish_timeout"`
+}
+
+type NatsConfig struct {
+	URL string `yaml:"url" mapstructure:"url"`
+	// Stream stores the events published by this service, ConsumerStream holds the events it consumes
+	Stream         string        `yaml:"stream" mapstructure:"stream"`
+	Subjects       []string      `yaml:"subjects" mapstructure:"subjects"`
+	ConsumerStream string        `yaml:"consumer_stream" mapstructure:"consumer_stream"`
+	DurableName    string        `yaml:"durable_name" mapstructure:"durable_name"`
+	PublishTimeout time.Duration `yaml:"publish_timeout" mapstructure:"publish_timeout"`
+}
+
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" mapstructure:"deletion_grace_period"`
+	PurgeInterval       time.Duration `yaml:"purge_interval" mapstructure:"purge_interval"`
+	PurgeBatchSize      int           `yaml:"purge_batch_size" mapstructure:"purge_batch_size"`
+}
+
+type DataExportConfig struct {
+	StorageDir    string `yaml:"storage_dir" mapstructure:"storage_dir"`
+	PublicBaseURL string `yaml:"public_base_url" mapstructure:"public_base_url"`
+	SigningSecret string `yaml:"signing_secret" mapstructure:"signing_secret"`
+	// URLTTL is how long a signed download URL is valid, the URL of the ready event is signed when the archive
+	// is written so it must outlast the outbox relay retries before the email is sent
+	URLTTL time.Duration `yaml:"url_ttl" mapstructure:"url_ttl"`
+
+	WorkerInterval time.Duration `yaml:"worker_interval" mapstructure:"worker_interval"`
+	BatchSize      int           `yaml:"batch_size" mapstructure:"batch_size"`
+	MaxAttempts    int           `yaml:"max_attempts" mapstructure:"max_attempts"`
+	// ProcessTimeout bounds one export attempt, an export still processing after it is claimed again
+	ProcessTimeout time.Duration `yaml:"process_timeout" mapstructure:"process_timeout"`
+	// Retention is how long a finished archive is kept before it is deleted
+	Retention time.Duration `yaml:"retention" mapstructure:"retention"`
+}
+
+type AuditConfig struct {
+	SigningKey         string `yaml:"signing_key" mapstructure:"signing_key"`
+	CheckpointInterval int64  `yaml:"checkpoint_interval" mapstructure:"checkpoint_interval"`
+}
+
+type LoginSecurityConfig struct {
+	GeoIPDatabasePath string  `yaml:"geoip_database_path" mapstructure:"geoip_database_path"`
+	MaxTravelSpeedKmh float64 `yaml:"max_travel_speed_kmh" mapstructure:"max_travel_speed_kmh"`
+}
+
+type OutboxConfig struct {
+	RelayInterval time.Duration `yaml:"relay_interval" mapstructure:"relay_interval"`
+	BatchSize     int           `yaml:"batch_size" mapstructure:"batch_size"`
+	MaxAttempts   int           `yaml:"max_attempts" mapstructure:"max_attempts"`
+	RetryBackoff  time.Duration `yaml:"retry_backoff" mapstructure:"retry_backoff"`
+	// ClaimLease is how long claimed messages are hidden from the other relays, they are published again
+	// after it when the relay that claimed them stopped
+	ClaimLease time.Duration `yaml:"claim_lease" mapstructure:"claim_lease"`
+	// EncryptionKey encrypts the stored payloads, which carry reset tokens and download URLs
+	EncryptionKey string `yaml:"encryption_key" mapstructure:"encryption_key"`
+}
+
+type ConsumerConfig struct {
+	QueueName       string        `yaml:"queue_name" mapstructure:"queue_name"`
+	Concurrency     int           `yaml:"concurrency" mapstructure:"concurrency"`
+	Prefetch        int           `yaml:"prefetch" mapstructure:"prefetch"`
+	MaxAttempts     int           `yaml:"max_attempts" mapstructure:"max_attempts"`
+	RetryDelay      time.Duration `yaml:"retry_delay" mapstructure:"retry_delay"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" mapstructure:"shutdown_timeout"`
+}
+
+type WebhookConfig struct {
+	DeliveryInterval time.Duration `yaml:"delivery_interval" mapstructure:"delivery_interval"`
+	BatchSize        int           `yaml:"batch_size" mapstructure:"batch_size"`
+	MaxAttempts      int           `yaml:"max_attempts" mapstructure:"max_attempts"`
+	RetryBackoff     time.Duration `yaml:"retry_backoff" mapstructure:"retry_backoff"`
+	RequestTimeout   time.Duration `yaml:"request_timeout" mapstructure:"request_timeout"`
+	// ClaimLease is how long claimed deliveries are hidden from the other workers, it must cover sending a
+	// whole batch or deliveries are sent twice
+	ClaimLease time.Duration `yaml:"claim_lease" mapstructure:"claim_lease"`
+	// DisableAfterFailures disables a subscription after this many consecutive failed delivery attempts
+	DisableAfterFailures int `yaml:"disable_after_failures" mapstructure:"disable_after_failures"`
+}
+
+type NotifierConfig struct {
+	Enabled bool   `yaml:"enabled" mapstructure:"enabled"`
+	From    string `yaml:"from" mapstructure:"from"`
+	AppName string `yaml:"app_name" mapstructure:"app_name"`
+	// DefaultLocale is used for users without a locale or with one that has no templates
+	DefaultLocale string `yaml:"default_locale" mapstructure:"default_locale"`
+	// TemplateDir overrides the embedded templates, it holds one directory per locale
+	TemplateDir string                            `yaml:"template_dir" mapstructure:"template_dir"`
+	Templates   map[string]NotifierTemplateConfig `yaml:"templates" mapstructure:"templates"`
+}
+
+type NotifierTemplateConfig struct {
+	Disabled bool   `yaml:"disabled" mapstructure:"disabled"`
+	From     string `yaml:"from" mapstructure:"from"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" mapstructure:"host"`
+	Port     int    `yaml:"port" mapstructure:"port"`
+	Username string `yaml:"username" mapstructure:"username"`
+	Password string `yaml:"password" mapstructure:"password"`
+	// TLSMode is "starttls" (default when the server offers it), "tls" for implicit TLS or "none"
+	TLSMode string        `yaml:"tls_mode" mapstructure:"tls_mode"`
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+type PhoneConfig struct {
+	OTPTTL time.Duration `yaml:"otp_ttl" mapstructure:"otp_ttl"`
+	// OTPMaxAttempts is how many wrong codes are accepted before the code is revoked
+	OTPMaxAttempts int `yaml:"otp_max_attempts" mapstructure:"otp_max_attempts"`
+	// OTPResendInterval is the minimum time between two codes sent to the same number
+	OTPResendInterval time.Duration `yaml:"otp_resend_interval" mapstructure:"otp_resend_interval"`
+	// OTPDailyMaxAttempts is how many codes can be tried in a day for a login number or a verifying user,
+	// new codes do not reset it
+	OTPDailyMaxAttempts int `yaml:"otp_daily_max_attempts" mapstructure:"otp_daily_max_attempts"`
+}
+
+type SMSConfig struct {
+	// Driver is "log" (default) to log messages and optionally append them to FilePath, or "http" for the gateway
+	Driver   string           `yaml:"driver" mapstructure:"driver"`
+	FilePath string           `yaml:"file_path" mapstructure:"file_path"`
+	Gateway  SMSGatewayConfig `yaml:"gateway" mapstructure:"gateway"`
+}
+
+type SMSGatewayConfig struct {
+	URL     string        `yaml:"url" mapstructure:"url"`
+	APIKey  string        `yaml:"api_key" mapstructure:"api_key"`
+	From    string        `yaml:"from" mapstructure:"from"`
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+}
+
+type IntrospectionConfig struct {
+	// CacheTTL is how long an introspection result is cached, never longer than the token is valid
+	CacheTTL time.Duration `yaml:"cache_ttl" mapstructure:"cache_ttl"`
+	// Clients are the services allowed to introspect tokens and look up users, at least one is required
+	Clients []IntrospectionClientConfig `yaml:"clients" mapstructure:"clients"`
+}
+
+type IntrospectionClientConfig struct {
+	ID     string `yaml:"id" mapstructure:"id"`
+	Secret string `yaml:"secret" mapstructure:"secret"`
+}
+
+type HealthConfig struct {
+	// CheckTimeout bounds every dependency check of the readiness probe
+	CheckTimeout time.Duration `yaml:"check_timeout" mapstructure:"check_timeout"`
+	// Timeouts overrides CheckTimeout per dependency: database, cache or rabbitmq
+	Timeouts map[string]time.Duration `yaml:"timeouts" mapstructure:"timeouts"`
+	// CheckInterval is how often the grpc.health.v1 serving status is refreshed from the readiness checks
+	CheckInterval time.Duration `yaml:"check_interval" mapstructure:"check_interval"`
 }
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Validate reports the settings the service cannot start without, all of them at once
+func (c *Config) Validate() error {
+	var errs []error
+	required := func(name string, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+
+	required("jwt.secret", c.Jwt.Secret)
+	if slices.Contains(c.Jwt.PreviousSecrets, c.Jwt.Secret) {
+		errs = append(errs, errors.New("jwt.previous_secrets must not contain jwt.secret"))
+	}
+
+	// every key protects one kind of data, so rotating or leaking one does not affect the others
+	required("data_export.signing_secret", c.DataExport.SigningSecret)
+	required("audit.signing_key", c.Audit.SigningKey)
+	required("outbox.encryption_key", c.Outbox.EncryptionKey)
+	keys := []string{c.Jwt.Secret, c.DataExport.SigningSecret, c.Audit.SigningKey, c.Outbox.EncryptionKey}
+	for i, key := range keys {
+		if key != "" && slices.Contains(keys[:i], key) {
+			errs = append(errs, errors.New(
+				"jwt.secret, data_export.signing_secret, audit.signing_key and outbox.encryption_key must be different",
+			))
+			break
+		}
+	}
+
+	required("server.http.port", c.Server.Http.Port)
+	required("server.grpc.host", c.Server.Grpc.Host)
+	required("server.grpc.port", c.Server.Grpc.Port)
+	if c.Server.DrainTimeout < 0 || c.Server.DrainDelay < 0 {
+		errs = append(errs, errors.New("server.drain_timeout and server.drain_delay must not be negative"))
+	}
+
+	// client services authenticate token introspection and user lookups with these credentials
+	if len(c.Introspection.Clients) == 0 {
+		errs = append(errs, errors.New("introspection.clients requires at least one client"))
+	}
+	for i, client := range c.Introspection.Clients {
+		required(fmt.Sprintf("introspection.clients[%d].id", i), client.ID)
+		required(fmt.Sprintf("introspection.clients[%d].secret", i), client.Secret)
+	}
+
+	required("database.postgres.host", c.Database.Postgres.Host)
+	required("database.postgres.user", c.Database.Postgres.User)
+	required("database.postgres.db_name", c.Database.Postgres.DBName)
+	required("cache.host", c.Cache.Host)
+
+	if c.Notifier.Enabled {
+		required("smtp.host", c.SMTP.Host)
+	}
+	if c.Server.TLS.Enable {
+		required("server.tls.cert_file", c.Server.TLS.CertFile)
+		required("server.tls.key_file", c.Server.TLS.KeyFile)
+	}
+
+	return errors.Join(errs...)
+}
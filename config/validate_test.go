@@ -0,0 +1,85 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func validConfig() *Config {
+	cfg := &Config{}
+	cfg.Jwt.Secret = "secret"
+	cfg.DataExport.SigningSecret = "export-secret"
+	cfg.Audit.SigningKey = "audit-key"
+	cfg.Outbox.EncryptionKey = "outbox-key"
+	cfg.Server.Http.Port = "8080"
+	cfg.Server.Grpc.Host = "0.0.0.0"
+	cfg.Server.Grpc.Port = "9090"
+	cfg.Database.Postgres.Host = "localhost"
+	cfg.Database.Postgres.User = "postgres"
+	cfg.Database.Postgres.DBName = "users"
+	cfg.Cache.Host = "localhost"
+	cfg.Introspection.Clients = []IntrospectionClientConfig{{ID: "api-gateway", Secret: "gateway-secret"}}
+
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("expected a valid config, got %v", err)
+	}
+
+	cfg := validConfig()
+	cfg.Jwt.Secret = ""
+	cfg.Cache.Host = ""
+	cfg.Notifier.Enabled = true
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an invalid config")
+	}
+	for _, setting := range []string{"jwt.secret", "cache.host", "smtp.host"} {
+		if !strings.Contains(err.Error(), setting) {
+			t.Errorf("expected %s to be reported, got %v", setting, err)
+		}
+	}
+}
+
+func TestValidateSigningKeys(t *testing.T) {
+	cfg := validConfig()
+	cfg.DataExport.SigningSecret = ""
+	cfg.Audit.SigningKey = ""
+	cfg.Outbox.EncryptionKey = ""
+
+	err := cfg.Validate()
+	for _, setting := range []string{"data_export.signing_secret", "audit.signing_key", "outbox.encryption_key"} {
+		if err == nil || !strings.Contains(err.Error(), setting+" is required") {
+			t.Errorf("expected %s to be required, got %v", setting, err)
+		}
+	}
+
+	cfg = validConfig()
+	cfg.Audit.SigningKey = cfg.Jwt.Secret
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "must be different") {
+		t.Errorf("expected the jwt secret to be rejected as the audit signing key, got %v", err)
+	}
+
+	cfg = validConfig()
+	cfg.Outbox.EncryptionKey = cfg.DataExport.SigningSecret
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "must be different") {
+		t.Errorf("expected the export signing secret to be rejected as the outbox encryption key, got %v", err)
+	}
+}
+
+func TestValidateIntrospectionClients(t *testing.T) {
+	cfg := validConfig()
+	cfg.Introspection.Clients = nil
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "introspection.clients") {
+		t.Fatalf("expected introspection.clients to be required, got %v", err)
+	}
+
+	cfg = validConfig()
+	cfg.Introspection.Clients = []IntrospectionClientConfig{{ID: "api-gateway"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "introspection.clients[0].secret is required") {
+		t.Fatalf("expected the client secret to be required, got %v", err)
+	}
+}
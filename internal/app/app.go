@@ -0,0 +1,348 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	userv1 "github.com/datpham/user-service-ms/api/proto/user/v1"
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/client/oauth"
+	authServer "github.com/datpham/user-service-ms/internal/delivery/grpc/auth"
+	healthServer "github.com/datpham/user-service-ms/internal/delivery/grpc/health"
+	userServer "github.com/datpham/user-service-ms/internal/delivery/grpc/user"
+	adminHandler "github.com/datpham/user-service-ms/internal/delivery/http/admin"
+	authHandler "github.com/datpham/user-service-ms/internal/delivery/http/auth"
+	devHandler "github.com/datpham/user-service-ms/internal/delivery/http/dev"
+	healthHandler "github.com/datpham/user-service-ms/internal/delivery/http/health"
+	userHandler "github.com/datpham/user-service-ms/internal/delivery/http/user"
+	"github.com/datpham/user-service-ms/internal/infra/cache"
+	"github.com/datpham/user-service-ms/internal/infra/database"
+	"github.com/datpham/user-service-ms/internal/infra/geoip"
+	"github.com/datpham/user-service-ms/internal/pkg/httpclient"
+	"github.com/datpham/user-service-ms/internal/pkg/lifecycle"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	auditRepo "github.com/datpham/user-service-ms/internal/repository/audit"
+	authRepo "github.com/datpham/user-service-ms/internal/repository/auth"
+	"github.com/datpham/user-service-ms/internal/repository/common"
+	dataExportRepo "github.com/datpham/user-service-ms/internal/repository/dataexport"
+	deviceRepo "github.com/datpham/user-service-ms/internal/repository/device"
+	inboxRepo "github.com/datpham/user-service-ms/internal/repository/inbox"
+	outboxRepo "github.com/datpham/user-service-ms/internal/repository/outbox"
+	webhookRepo "github.com/datpham/user-service-ms/internal/repository/webhook"
+	authSvc "github.com/datpham/user-service-ms/internal/service/auth"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	"github.com/datpham/user-service-ms/internal/service/notifier"
+	"github.com/datpham/user-service-ms/internal/service/sms"
+	tokensvc "github.com/datpham/user-service-ms/internal/service/token"
+	webhookSvc "github.com/datpham/user-service-ms/internal/service/webhook"
+	"github.com/datpham/user-service-ms/internal/worker"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// Servers selects the servers of the process, the background workers run along the HTTP or gRPC server
+type Servers struct {
+	HTTP      bool
+	GRPC      bool
+	Consumers bool
+}
+
+var AllServers = Servers{HTTP: true, GRPC: true, Consumers: true}
+
+// App is the object graph of the service built from a config, a process may hold several of them
+type App struct {
+	config  *config.Config
+	servers Servers
+
+	// dependencies, built from the config unless given as options
+	logger    *logger.Logger
+	cache     ICache
+	database  IDatabase
+	eventBus  eventbus.EventBus
+	geoIP     IGeoIP
+	smsSender sms.SMSSender
+	mailer    notifier.IMailer
+
+	lifecycle    *lifecycle.Manager
+	authService  *authSvc.AuthService
+	router       http.Handler
+	gatewayConn  *grpc.ClientConn
+	grpcServer   *grpc.Server
+	grpcListener net.Listener
+	httpServer   *http.Server
+	httpListener net.Listener
+}
+
+// NewLogger returns the process wide logger
+func NewLogger() *logger.Logger {
+	return logger.SetupLogger(logger.LoggerConfig{
+		Env:         "development",
+		Level:       logrus.InfoLevel,
+		ServiceName: "user-ms-service",
+		EnableJSON:  false,
+		Fields: map[string]any{
+			"version": "1.0.0",
+		},
+	})
+}
+
+// New connects to the dependencies that were not given as options and builds the services, handlers and
+// servers. Nothing is served until Run or Start.
+func New(cfg *config.Config, opts ...Option) (*App, error) {
+	a := &App{
+		config:  cfg,
+		servers: AllServers,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	// signup, login and token refresh are only served by the REST gateway, which relays them to the gRPC server
+	if a.servers.HTTP && !a.servers.GRPC {
+		return nil, errors.New("the HTTP server requires the gRPC server")
+	}
+
+	if err := a.initInfra(); err != nil {
+		a.closeInfra()
+		return nil, err
+	}
+
+	if err := a.build(); err != nil {
+		a.closeInfra()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *App) initInfra() error {
+	if a.logger == nil {
+		a.logger = NewLogger()
+	}
+
+	if a.cache == nil {
+		cacheClient := cache.NewCacheClient(a.logger, a.config)
+		a.cache = cacheClient
+		if err := cacheClient.Ping(context.Background()); err != nil {
+			return fmt.Errorf("failed to ping cache: %w", err)
+		}
+	}
+
+	if a.database == nil {
+		db, err := database.NewDatabase(a.config)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		a.database = db
+	}
+
+	if a.eventBus == nil {
+		eventBus, err := a.newEventBus()
+		if err != nil {
+			return fmt.Errorf("failed to initialize event bus: %w", err)
+		}
+		a.eventBus = eventBus
+	}
+
+	if a.geoIP == nil {
+		geoIP, err := geoip.NewGeoIP(a.config.LoginSecurity.GeoIPDatabasePath)
+		if err != nil {
+			return fmt.Errorf("failed to initialize GeoIP: %w", err)
+		}
+		a.geoIP = geoIP
+	}
+
+	if a.smsSender == nil {
+		smsSender, err := a.newSMSSender()
+		if err != nil {
+			return fmt.Errorf("failed to initialize SMS sender: %w", err)
+		}
+		a.smsSender = smsSender
+	}
+
+	return nil
+}
+
+// closeInfra closes the connections of a partially built app
+func (a *App) closeInfra() {
+	closers := []interface{ Close() error }{a.cache, a.database, a.eventBus, a.geoIP}
+	for _, closer := range closers {
+		if closer == nil {
+			continue
+		}
+		if err := closer.Close(); err != nil && a.logger != nil {
+			a.logger.Errorf("failed to close dependency: %s", err.Error())
+		}
+	}
+}
+
+func (a *App) build() error {
+	dbConn := a.database.GetDB()
+	a.lifecycle = lifecycle.New(a.logger, a.config.Server.DrainTimeout, a.config.Server.DrainDelay)
+
+	// init client
+	httpClient := httpclient.NewClient(10 * time.Second)
+	oauthClient := oauth.NewOauthClient(httpClient)
+	webhookTimeout := a.config.Webhook.RequestTimeout
+	if webhookTimeout <= 0 {
+		webhookTimeout = webhookSvc.DefaultRequestTimeout
+	}
+	webhookClient := httpclient.NewClient(webhookTimeout)
+
+	// init repositories
+	authRepo := authRepo.New(dbConn)
+	dataExportRepo := dataExportRepo.New(dbConn)
+	auditRepo := auditRepo.New(dbConn, auditRepo.ChainConfig{
+		SigningKey:         a.config.Audit.SigningKey,
+		CheckpointInterval: a.config.Audit.CheckpointInterval,
+	})
+	deviceRepo := deviceRepo.New(dbConn)
+	outboxRepo := outboxRepo.New(dbConn, a.config.Outbox.EncryptionKey)
+	inboxRepo := inboxRepo.New(dbConn)
+	webhookSubscriptionRepo := webhookRepo.NewSubscriptionRepository(dbConn)
+	webhookDeliveryRepo := webhookRepo.NewDeliveryRepository(dbConn)
+	txManager := common.NewTransactionManager(dbConn)
+
+	// init services
+	tokenSvc := tokensvc.NewJwtToken(a.config.Jwt.Secret, a.config.Jwt.PreviousSecrets...)
+	oauthSvc := tokensvc.NewOAuthService(a.config, oauthClient)
+	webhookSvc := webhookSvc.New(
+		a.logger, webhookSubscriptionRepo, webhookDeliveryRepo, webhookClient, a.config.Webhook,
+	)
+	authSvc := authSvc.New(
+		a.logger, authRepo, tokenSvc, oauthSvc, a.cache, outboxRepo, inboxRepo, webhookSvc, txManager, a.config.Account,
+		dataExportRepo, a.config.DataExport, auditRepo,
+		deviceRepo, a.geoIP, a.config.LoginSecurity,
+		a.smsSender, a.config.Phone,
+		a.config.Introspection,
+	)
+	a.authService = authSvc
+	healthSvc := a.newHealthService()
+
+	notifierSvc, err := a.newNotifier(authRepo)
+	if err != nil {
+		return fmt.Errorf("failed to initialize notifier: %w", err)
+	}
+
+	// init handlers
+	authHandler := authHandler.New(authSvc)
+	userHandler := userHandler.New(authSvc)
+	adminHandler := adminHandler.New(authSvc, webhookSvc)
+	devHandler := devHandler.New(notifierSvc)
+	healthHandler := healthHandler.New(healthSvc)
+
+	// init grpc servers
+	authServer := authServer.New(authSvc)
+	userServer := userServer.New(authSvc)
+	healthServer := healthServer.New(
+		healthSvc, userv1.AuthService_ServiceDesc.ServiceName, userv1.UserService_ServiceDesc.ServiceName,
+	)
+	a.grpcServer = a.newGrpcServer(authSvc, authServer, userServer, healthServer)
+
+	gateway, err := a.newGateway()
+	if err != nil {
+		return fmt.Errorf("failed to create gRPC gateway: %w", err)
+	}
+	a.router = a.newRouter(
+		authHandler, userHandler, adminHandler, devHandler, healthHandler, authSvc, authSvc, gateway,
+	)
+
+	// init consumers
+	a.registerConsumerHandlers(a.eventBus, authSvc, notifierSvc)
+
+	// init workers
+	accountPurgeWorker := worker.NewAccountPurgeWorker(a.logger, authSvc, a.config.Account.PurgeInterval)
+	outboxRelayWorker := worker.NewOutboxRelayWorker(a.logger, outboxRepo, a.eventBus, a.config.Outbox)
+	webhookDeliveryWorker := worker.NewWebhookDeliveryWorker(a.logger, webhookSvc, a.config.Webhook)
+	dataExportWorker := worker.NewDataExportWorker(a.logger, authSvc, a.config.DataExport.WorkerInterval)
+
+	// components start in this order and stop in reverse: the servers stop first, the connections last
+	a.lifecycle.Add(
+		lifecycle.Closer("postgres", a.database.Close),
+		lifecycle.Component{Name: "database schema", Start: a.database.CheckSchema},
+		lifecycle.Closer("redis", a.cache.Close),
+		lifecycle.Closer("geoip", a.geoIP.Close),
+		lifecycle.Closer("event bus", a.eventBus.Close),
+		lifecycle.Closer("grpc gateway connection", a.gatewayConn.Close),
+	)
+
+	if a.servers.HTTP || a.servers.GRPC {
+		a.lifecycle.Add(
+			lifecycle.Background("outbox relay", outboxRelayWorker.Start),
+			lifecycle.Background("account purge worker", accountPurgeWorker.Start),
+			lifecycle.Background("webhook delivery worker", webhookDeliveryWorker.Start),
+			lifecycle.Background("data export worker", dataExportWorker.Start),
+		)
+	}
+	if a.servers.Consumers {
+		a.lifecycle.Add(lifecycle.Background("consumers", a.runConsumers(a.eventBus)))
+	}
+	if a.servers.GRPC {
+		a.lifecycle.Add(lifecycle.Component{Name: "grpc server", Start: a.startGrpcServer, Stop: a.shutdownGrpcServer})
+	}
+	if a.servers.HTTP {
+		a.lifecycle.Add(lifecycle.Component{Name: "http server", Start: a.startHttpServer, Stop: a.shutdownHttpServer})
+	}
+	if a.servers.GRPC {
+		// stopped first so the grpc health service reports not serving while the servers drain
+		a.lifecycle.Add(lifecycle.Background("grpc health", healthServer.Start))
+	}
+
+	return nil
+}
+
+// Migrate applies the pending schema migrations, Start refuses to run while one is pending
+func (a *App) Migrate(ctx context.Context) error {
+	return a.database.Migrate(ctx)
+}
+
+// Run starts the selected servers and stops them on SIGINT, SIGTERM or the end of ctx
+func (a *App) Run(ctx context.Context) error {
+	return a.lifecycle.Run(ctx)
+}
+
+// Start starts the selected servers and returns once they listen
+func (a *App) Start(ctx context.Context) error {
+	return a.lifecycle.Start(ctx)
+}
+
+// Stop drains the servers and closes the dependencies
+func (a *App) Stop(ctx context.Context) error {
+	return a.lifecycle.Stop(ctx)
+}
+
+// Close closes the dependencies of an app that was never started, e.g. one built for a one-off command
+func (a *App) Close() error {
+	a.closeInfra()
+	return a.gatewayConn.Close()
+}
+
+// AuthService serves the one-off commands that manage users
+func (a *App) AuthService() *authSvc.AuthService {
+	return a.authService
+}
+
+// Handler is the HTTP router, it can serve requests without Start, e.g. with httptest
+func (a *App) Handler() http.Handler {
+	return a.router
+}
+
+// HTTPAddr is the address the HTTP server listens on once started
+func (a *App) HTTPAddr() string {
+	if a.httpListener == nil {
+		return ""
+	}
+	return a.httpListener.Addr().String()
+}
+
+// GRPCAddr is the address the gRPC server listens on once started
+func (a *App) GRPCAddr() string {
+	if a.grpcListener == nil {
+		return ""
+	}
+	return a.grpcListener.Addr().String()
+}
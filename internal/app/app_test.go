@@ -0,0 +1,323 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/infra/database"
+	"github.com/datpham/user-service-ms/internal/pkg/cacheutil"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	tokensvc "github.com/datpham/user-service-ms/internal/service/token"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	testJwtSecret    = "test-secret"
+	testClientID     = "api-gateway"
+	testClientSecret = "gateway-secret"
+)
+
+// fakeCache stores values the way redis does: strings as is, anything else in its text form
+type fakeCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{values: map[string]string{}}
+}
+
+func (c *fakeCache) Get(ctx context.Context, key string, obj any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, ok := c.values[key]
+	if !ok {
+		return redis.Nil
+	}
+	return json.Unmarshal([]byte(value), obj)
+}
+
+func (c *fakeCache) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values[key] = fmt.Sprint(value)
+	return nil
+}
+
+func (c *fakeCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.values, key)
+	return nil
+}
+
+func (c *fakeCache) IncrWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var count int64
+	fmt.Sscan(c.values[key], &count)
+	count++
+	c.values[key] = fmt.Sprint(count)
+	return count, nil
+}
+
+func (c *fakeCache) Has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.values[key]
+	return ok
+}
+
+func (c *fakeCache) Ping(ctx context.Context) error { return nil }
+func (c *fakeCache) Close() error                   { return nil }
+
+// fakeDatabase is never connected, the routes under test must not reach it
+type fakeDatabase struct {
+	db        *gorm.DB
+	schemaErr error
+}
+
+func newFakeDatabase(t *testing.T) *fakeDatabase {
+	t.Helper()
+
+	db, err := gorm.Open(
+		postgres.Open("host=127.0.0.1 port=1 user=test dbname=test sslmode=disable connect_timeout=1"),
+		&gorm.Config{DisableAutomaticPing: true},
+	)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	return &fakeDatabase{db: db}
+}
+
+func (d *fakeDatabase) GetDB() *gorm.DB                       { return d.db }
+func (d *fakeDatabase) Ping(ctx context.Context) error        { return nil }
+func (d *fakeDatabase) Close() error                          { return nil }
+func (d *fakeDatabase) Migrate(ctx context.Context) error     { return nil }
+func (d *fakeDatabase) CheckSchema(ctx context.Context) error { return d.schemaErr }
+
+func newTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Jwt.Secret = testJwtSecret
+	cfg.Server.Grpc.Host = "127.0.0.1"
+	cfg.Server.Grpc.Port = "0"
+	cfg.Server.Http.Port = "0"
+	cfg.Server.DrainTimeout = time.Second * 5
+	cfg.EventBus.Driver = eventbus.DriverMemory
+	cfg.Introspection.Clients = []config.IntrospectionClientConfig{{ID: testClientID, Secret: testClientSecret}}
+
+	return cfg
+}
+
+func newTestApp(t *testing.T, cache *fakeCache) *App {
+	t.Helper()
+
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+	a, err := New(newTestConfig(), WithLogger(testLogger), WithCache(cache), WithDatabase(newFakeDatabase(t)))
+	if err != nil {
+		t.Fatalf("failed to build app: %v", err)
+	}
+
+	return a
+}
+
+func startTestApp(t *testing.T, a *App) {
+	t.Helper()
+
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start app: %v", err)
+	}
+	t.Cleanup(func() { a.Stop(context.Background()) })
+}
+
+// httpURL is the url of path on the HTTP server of a over loopback
+func httpURL(t *testing.T, a *App, path string) string {
+	t.Helper()
+
+	_, port, err := net.SplitHostPort(a.HTTPAddr())
+	if err != nil {
+		t.Fatalf("unexpected http address %q: %v", a.HTTPAddr(), err)
+	}
+
+	return "http://" + net.JoinHostPort("127.0.0.1", port) + path
+}
+
+func serve(a *App, method string, path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	a.Handler().ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+	return recorder
+}
+
+func TestReadinessFollowsLifecycle(t *testing.T) {
+	a := newTestApp(t, newFakeCache())
+
+	if code := serve(a, http.MethodGet, "/readyz").Code; code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 before start, got %d", code)
+	}
+
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start app: %v", err)
+	}
+
+	if code := serve(a, http.MethodGet, "/readyz").Code; code != http.StatusOK {
+		t.Fatalf("expected 200 once started, got %d", code)
+	}
+	if code := serve(a, http.MethodGet, "/healthz").Code; code != http.StatusOK {
+		t.Fatalf("expected 200 from liveness, got %d", code)
+	}
+
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatalf("failed to stop app: %v", err)
+	}
+
+	if code := serve(a, http.MethodGet, "/readyz").Code; code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 once stopped, got %d", code)
+	}
+}
+
+func TestHTTPServerRequiresGRPC(t *testing.T) {
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+	_, err := New(
+		newTestConfig(),
+		WithLogger(testLogger), WithCache(newFakeCache()), WithDatabase(newFakeDatabase(t)),
+		WithServers(Servers{HTTP: true}),
+	)
+	if err == nil {
+		t.Fatal("expected the HTTP server without the gRPC server to be refused")
+	}
+}
+
+func TestStartRefusesBehindSchema(t *testing.T) {
+	db := newFakeDatabase(t)
+	db.schemaErr = fmt.Errorf("%w: 1 pending migrations", database.ErrSchemaBehind)
+
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+	a, err := New(newTestConfig(), WithLogger(testLogger), WithCache(newFakeCache()), WithDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to build app: %v", err)
+	}
+
+	if err := a.Start(context.Background()); !errors.Is(err, database.ErrSchemaBehind) {
+		t.Fatalf("expected the start to fail on the schema, got %v", err)
+	}
+	if a.HTTPAddr() != "" {
+		t.Fatalf("expected no server to listen, got %s", a.HTTPAddr())
+	}
+}
+
+func TestProtectedRoutesRequireToken(t *testing.T) {
+	a := newTestApp(t, newFakeCache())
+
+	if code := serve(a, http.MethodGet, "/api/v1/users/me/security-events").Code; code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", code)
+	}
+}
+
+func TestGatewayValidatesTokenThroughGRPC(t *testing.T) {
+	cache := newFakeCache()
+	a := newTestApp(t, cache)
+	startTestApp(t, a)
+
+	accessToken, _, err := tokensvc.NewJwtToken(testJwtSecret).GenerateTokenPair("user-1")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	claims, err := tokensvc.NewJwtToken(testJwtSecret).ParseToken(accessToken)
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+
+	// a revoked token is rejected from the cache, without a database lookup
+	cache.Set(context.Background(), cacheutil.ConstructTokenDenylistKey(claims.TokenID), time.Now().Unix(), time.Hour)
+
+	validate := func(withClient bool) *http.Response {
+		req, err := http.NewRequest(
+			http.MethodPost,
+			httpURL(t, a, "/api/v1/auth/token/validate"),
+			strings.NewReader(fmt.Sprintf(`{"accessToken": %q}`, accessToken)),
+		)
+		if err != nil {
+			t.Fatalf("failed to build request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if withClient {
+			req.SetBasicAuth(testClientID, testClientSecret)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("validate token: %v", err)
+		}
+		return resp
+	}
+
+	resp := validate(false)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without client credentials, got %d", resp.StatusCode)
+	}
+
+	resp = validate(true)
+	defer resp.Body.Close()
+
+	var body struct {
+		StatusCode int `json:"status_code"`
+		Data       struct {
+			Active bool `json:"active"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || body.StatusCode != http.StatusOK || body.Data.Active {
+		t.Fatalf("expected an inactive token, got %d %+v", resp.StatusCode, body)
+	}
+	if !cache.Has(cacheutil.ConstructTokenIntrospectionKey(accessToken)) {
+		t.Fatal("expected the introspection to be cached in the given cache")
+	}
+}
+
+func TestAppsStartSideBySide(t *testing.T) {
+	first := newTestApp(t, newFakeCache())
+	second := newTestApp(t, newFakeCache())
+	startTestApp(t, first)
+	startTestApp(t, second)
+
+	if first.HTTPAddr() == second.HTTPAddr() || first.GRPCAddr() == second.GRPCAddr() {
+		t.Fatalf("expected distinct listeners, got %s and %s", first.HTTPAddr(), second.HTTPAddr())
+	}
+
+	for _, a := range []*App{first, second} {
+		resp, err := http.Get(httpURL(t, a, "/healthz"))
+		if err != nil {
+			t.Fatalf("liveness: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200 from %s, got %d", a.HTTPAddr(), resp.StatusCode)
+		}
+	}
+}
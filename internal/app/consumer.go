@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+
+	"github.com/datpham/user-service-ms/internal/delivery/messaging"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+)
+
+// registerConsumerHandlers registers the handlers of the events this service subscribes to,
+// the notifier also consumes the events this service publishes when it is enabled
+func (a *App) registerConsumerHandlers(
+	subscriber eventbus.EventSubscriber,
+	eventService messaging.IEventService,
+	notifier messaging.INotifier,
+) {
+	messaging.New(eventService).Register(subscriber)
+
+	if a.config.Notifier.Enabled {
+		messaging.NewNotificationHandler(notifier).Register(subscriber)
+	}
+}
+
+// runConsumers consumes messages until the context is cancelled, in-flight messages are drained before it returns
+func (a *App) runConsumers(subscriber eventbus.EventSubscriber) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		if err := subscriber.Start(ctx); err != nil {
+			a.logger.Errorf("failed to run consumers: %s", err.Error())
+		}
+	}
+}
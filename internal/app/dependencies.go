@@ -0,0 +1,27 @@
+package app
+
+import (
+	"context"
+
+	authSvc "github.com/datpham/user-service-ms/internal/service/auth"
+	"gorm.io/gorm"
+)
+
+type ICache interface {
+	authSvc.ICacheService
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+type IDatabase interface {
+	GetDB() *gorm.DB
+	Ping(ctx context.Context) error
+	Close() error
+	Migrate(ctx context.Context) error
+	CheckSchema(ctx context.Context) error
+}
+
+type IGeoIP interface {
+	authSvc.IGeoIPService
+	Close() error
+}
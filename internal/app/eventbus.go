@@ -0,0 +1,38 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/datpham/user-service-ms/internal/infra/kafka"
+	"github.com/datpham/user-service-ms/internal/infra/memory"
+	"github.com/datpham/user-service-ms/internal/infra/nats"
+	"github.com/datpham/user-service-ms/internal/infra/rabbitmq"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+)
+
+// newEventBus connects to the broker selected by event_bus.driver, rabbitmq when unset
+func (a *App) newEventBus() (eventbus.EventBus, error) {
+	switch a.config.EventBus.Driver {
+	case "", eventbus.DriverRabbitMQ:
+		client, err := rabbitmq.NewRabbitMQClient(a.logger, a.config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize RabbitMQ: %w", err)
+		}
+
+		if err := client.Setup("user-events", "user.#"); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to setup RabbitMQ: %w", err)
+		}
+
+		consumer := rabbitmq.NewConsumer(a.logger, client, a.config.Consumer)
+		return rabbitmq.NewEventBus(client, consumer), nil
+	case eventbus.DriverKafka:
+		return kafka.NewEventBus(a.logger, a.config.Kafka, a.config.Consumer)
+	case eventbus.DriverNats:
+		return nats.NewEventBus(a.logger, a.config.Nats, a.config.Consumer)
+	case eventbus.DriverMemory:
+		return memory.NewEventBus(a.config.Consumer.MaxAttempts), nil
+	default:
+		return nil, fmt.Errorf("unknown event bus driver %q", a.config.EventBus.Driver)
+	}
+}
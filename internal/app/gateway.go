@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/datpham/user-service-ms/internal/delivery/gateway"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// newGateway connects the REST gateway to the gRPC server. The address is resolved on every dial,
+// so the gateway follows the listener of this process, e.g. a random port in tests.
+func (a *App) newGateway() (http.Handler, error) {
+	conn, err := grpc.NewClient(
+		"passthrough:///grpc-server",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "tcp", a.gatewayTarget())
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	a.gatewayConn = conn
+
+	return gateway.New(context.Background(), conn)
+}
+
+// gatewayTarget is the gRPC address over loopback when the server listens on every interface, the gRPC
+// server only trusts the client IP forwarded by the gateway from loopback peers
+func (a *App) gatewayTarget() string {
+	host, port := a.config.Server.Grpc.Host, a.config.Server.Grpc.Port
+	if a.grpcListener != nil {
+		host, port, _ = net.SplitHostPort(a.grpcListener.Addr().String())
+	}
+
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	userv1 "github.com/datpham/user-service-ms/api/proto/user/v1"
+	"github.com/datpham/user-service-ms/internal/delivery/grpc/interceptor"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionalphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+type GrpcServerRegistar interface {
+	RegisterGRPCHandlers(server *grpc.Server)
+}
+
+// grpcPublicMethods can be called without an access token, they authenticate the caller themselves
+var grpcPublicMethods = []string{
+	userv1.AuthService_Signup_FullMethodName,
+	userv1.AuthService_Login_FullMethodName,
+	userv1.AuthService_RefreshToken_FullMethodName,
+	healthpb.Health_Check_FullMethodName,
+	healthpb.Health_Watch_FullMethodName,
+	reflectionpb.ServerReflection_ServerReflectionInfo_FullMethodName,
+	reflectionalphapb.ServerReflection_ServerReflectionInfo_FullMethodName,
+}
+
+func (a *App) newGrpcServer(tokenValidator interceptor.ITokenValidator, registrars ...GrpcServerRegistar) *grpc.Server {
+	// the recovery interceptor is the outermost so it also catches panics of the other interceptors
+	interceptors := []interceptor.Interceptor{
+		interceptor.NewRecoveryInterceptor(a.logger),
+		interceptor.NewLoggerInterceptor(a.logger),
+		interceptor.NewMetricsInterceptor(),
+		interceptor.NewDeadlineInterceptor(a.config.Server.Grpc.DefaultTimeout),
+		interceptor.NewAuthInterceptor(tokenValidator, a.introspectionClientSecrets(), grpcPublicMethods...),
+	}
+
+	serverOptions := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(16 * 1024 * 1024), // 16MB
+		grpc.MaxSendMsgSize(16 * 1024 * 1024), // 16MB
+	}
+	server := grpc.NewServer(append(serverOptions, interceptor.ServerOptions(interceptors...)...)...)
+	for _, registrar := range registrars {
+		registrar.RegisterGRPCHandlers(server)
+	}
+
+	if a.config.Server.Grpc.Reflection {
+		reflection.Register(server)
+	}
+
+	return server
+}
+
+// startGrpcServer returns once the server listens, it serves in the background until shutdownGrpcServer
+func (a *App) startGrpcServer(ctx context.Context) error {
+	if !a.checkGrpcConfig() {
+		return errors.New("grpc server is not configured")
+	}
+
+	grpcAddress := net.JoinHostPort(a.config.Server.Grpc.Host, a.config.Server.Grpc.Port)
+	lis, err := net.Listen("tcp", grpcAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen to tcp address: %w", err)
+	}
+	a.grpcListener = lis
+
+	go func() {
+		a.logger.Infof("Starting GRPC server on %s", lis.Addr())
+		if err := a.grpcServer.Serve(lis); err != nil {
+			a.logger.Fatalf("Failed to serve gRPC: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// shutdownGrpcServer stops accepting calls and waits for the running ones until ctx is done,
+// then closes the remaining connections
+func (a *App) shutdownGrpcServer(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		a.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		a.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
+
+func (a *App) checkGrpcConfig() bool {
+	return a.config.Server.Grpc.Port != "" && a.config.Server.Grpc.Host != ""
+}
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"github.com/datpham/user-service-ms/internal/infra/rabbitmq"
+	healthSvc "github.com/datpham/user-service-ms/internal/service/health"
+)
+
+// newHealthService checks the database, the cache and, with the rabbitmq driver, the broker connection.
+// The lifecycle check keeps the service unready until every component started and while it drains.
+func (a *App) newHealthService() *healthSvc.HealthService {
+	dependencies := map[string]healthSvc.IPinger{
+		"database":  a.database,
+		"cache":     a.cache,
+		"lifecycle": a.lifecycle,
+	}
+	if rabbitMQBus, ok := a.eventBus.(*rabbitmq.EventBus); ok {
+		dependencies["rabbitmq"] = rabbitMQBus
+	}
+
+	return healthSvc.New(a.logger, a.config.Health, dependencies)
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	apiv1 "github.com/datpham/user-service-ms/api/v1"
+	"github.com/datpham/user-service-ms/internal/delivery/http/admin"
+	"github.com/datpham/user-service-ms/internal/delivery/http/auth"
+	"github.com/datpham/user-service-ms/internal/delivery/http/dev"
+	"github.com/datpham/user-service-ms/internal/delivery/http/health"
+	"github.com/datpham/user-service-ms/internal/delivery/http/user"
+	"github.com/datpham/user-service-ms/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func (a *App) newRouter(
+	authHandler *auth.AuthHandler,
+	userHandler *user.UserHandler,
+	adminHandler *admin.AdminHandler,
+	devHandler *dev.DevHandler,
+	healthHandler *health.HealthHandler,
+	tokenValidator middleware.ITokenValidator,
+	roleChecker middleware.IRoleChecker,
+	gateway http.Handler,
+) *gin.Engine {
+	router := gin.New()
+
+	// init middlewares
+	loggerMiddleware := middleware.NewLoggerMiddleware(a.logger)
+	authMiddleware := middleware.NewAuthMiddleware(tokenValidator)
+	adminMiddleware := middleware.NewAdminMiddleware(roleChecker)
+	clientAuthMiddleware := middleware.NewClientAuthMiddleware(a.introspectionClientSecrets())
+	commonMiddlewares := []middleware.CommonMiddleware{loggerMiddleware}
+	middlewareManager := middleware.NewMiddlewareManager(commonMiddlewares...)
+
+	router.Use(
+		gin.Recovery(),
+		middlewareManager.CommonHandle(),
+	)
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	apiv1.SetupHealthRoutes(router, healthHandler)
+
+	a.setupRoutes(
+		router, authHandler, userHandler, adminHandler, devHandler,
+		authMiddleware.Handle(), adminMiddleware.Handle(), clientAuthMiddleware.Handle(), loggerMiddleware.Handle(),
+	)
+	apiv1.SetupGatewayRoutes(router, gateway)
+
+	return router
+}
+
+// startHttpServer returns once the server listens, it serves in the background until shutdownHttpServer
+func (a *App) startHttpServer(ctx context.Context) error {
+	a.httpServer = &http.Server{
+		Addr:    fmt.Sprintf(":%s", a.config.Server.Http.Port),
+		Handler: a.router,
+	}
+
+	// listening before returning reports a port in use as a failed start
+	lis, err := net.Listen("tcp", a.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen to tcp address: %w", err)
+	}
+	a.httpListener = lis
+
+	go func() {
+		a.logger.Infof("Starting HTTP server on %s", lis.Addr())
+		if err := a.httpServer.Serve(lis); err != nil && err != http.ErrServerClosed {
+			a.logger.Fatalf("Failed to serve HTTP: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func (a *App) shutdownHttpServer(ctx context.Context) error {
+	return a.httpServer.Shutdown(ctx)
+}
+
+func (a *App) setupRoutes(
+	router *gin.Engine,
+	authHandler *auth.AuthHandler,
+	userHandler *user.UserHandler,
+	adminHandler *admin.AdminHandler,
+	devHandler *dev.DevHandler,
+	authMiddleware gin.HandlerFunc,
+	adminMiddleware gin.HandlerFunc,
+	clientAuthMiddleware gin.HandlerFunc,
+	middlewares ...gin.HandlerFunc,
+) {
+	apiv1.SetupAPIRoutes(
+		router, authHandler, userHandler, adminHandler, authHandler,
+		authMiddleware, adminMiddleware, clientAuthMiddleware, middlewares...,
+	)
+
+	// email previews and other tooling are only served in development mode
+	if a.config.IsDevelopment() {
+		apiv1.SetupDevRoutes(router.Group("/api/v1"), devHandler, middlewares...)
+	}
+}
+
+// introspectionClientSecrets returns the credentials of the client services, clients without a
+// secret are ignored so they can never authenticate
+func (a *App) introspectionClientSecrets() map[string]string {
+	clientSecrets := map[string]string{}
+	for _, client := range a.config.Introspection.Clients {
+		if client.ID == "" || client.Secret == "" {
+			continue
+		}
+		clientSecrets[client.ID] = client.Secret
+	}
+
+	return clientSecrets
+}
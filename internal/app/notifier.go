@@ -0,0 +1,31 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/datpham/user-service-ms/internal/infra/smtp"
+	"github.com/datpham/user-service-ms/internal/service/notifier"
+)
+
+// newNotifier parses the email templates and sets up the SMTP mailer unless one was given,
+// nothing is sent unless notifier.enabled is set
+func (a *App) newNotifier(userRepository notifier.IUserRepository) (*notifier.NotifierService, error) {
+	defaultLocale := a.config.Notifier.DefaultLocale
+	if defaultLocale == "" {
+		defaultLocale = notifier.DefaultLocale
+	}
+
+	renderer, err := notifier.NewRenderer(notifier.TemplateFS(a.config.Notifier.TemplateDir), defaultLocale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email templates: %w", err)
+	}
+
+	if a.mailer == nil {
+		if a.config.Notifier.Enabled && a.config.SMTP.Host == "" {
+			return nil, fmt.Errorf("smtp.host is required when the notifier is enabled")
+		}
+		a.mailer = smtp.NewMailer(a.config.SMTP)
+	}
+
+	return notifier.New(a.logger, renderer, a.mailer, userRepository, a.config.Notifier), nil
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	"github.com/datpham/user-service-ms/internal/service/notifier"
+	"github.com/datpham/user-service-ms/internal/service/sms"
+)
+
+// Option replaces a dependency the app would otherwise build from the config, e.g. with a fake in tests.
+// The app owns the dependencies it is given and closes them when it stops.
+type Option func(a *App)
+
+func WithLogger(logger *logger.Logger) Option {
+	return func(a *App) {
+		a.logger = logger
+	}
+}
+
+func WithCache(cache ICache) Option {
+	return func(a *App) {
+		a.cache = cache
+	}
+}
+
+func WithDatabase(database IDatabase) Option {
+	return func(a *App) {
+		a.database = database
+	}
+}
+
+func WithEventBus(eventBus eventbus.EventBus) Option {
+	return func(a *App) {
+		a.eventBus = eventBus
+	}
+}
+
+func WithGeoIP(geoIP IGeoIP) Option {
+	return func(a *App) {
+		a.geoIP = geoIP
+	}
+}
+
+func WithSMSSender(smsSender sms.SMSSender) Option {
+	return func(a *App) {
+		a.smsSender = smsSender
+	}
+}
+
+func WithMailer(mailer notifier.IMailer) Option {
+	return func(a *App) {
+		a.mailer = mailer
+	}
+}
+
+// WithServers selects what Run starts, every server by default
+func WithServers(servers Servers) Option {
+	return func(a *App) {
+		a.servers = servers
+	}
+}
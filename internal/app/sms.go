@@ -0,0 +1,20 @@
+package app
+
+import (
+	"fmt"
+
+	smsSender "github.com/datpham/user-service-ms/internal/infra/sms"
+	"github.com/datpham/user-service-ms/internal/service/sms"
+)
+
+// newSMSSender returns the sender selected by sms.driver, the log sender when unset
+func (a *App) newSMSSender() (sms.SMSSender, error) {
+	switch a.config.SMS.Driver {
+	case "", sms.DriverLog:
+		return smsSender.NewLogSender(a.logger, a.config.SMS.FilePath), nil
+	case sms.DriverHTTP:
+		return smsSender.NewGatewaySender(a.config.SMS.Gateway)
+	default:
+		return nil, fmt.Errorf("unknown sms driver %q", a.config.SMS.Driver)
+	}
+}
@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	userv1 "github.com/datpham/user-service-ms/api/proto/user/v1"
+	"github.com/datpham/user-service-ms/internal/delivery/grpc/interceptor"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/pkg/response"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+)
+
+// successStatusCodes are the statuses of the RPCs that do not answer 200 OK
+var successStatusCodes = map[string]int{
+	userv1.AuthService_Signup_FullMethodName: http.StatusCreated,
+}
+
+// New returns the REST gateway of the RPCs annotated with HTTP rules. Requests are relayed to the gRPC
+// server over conn, so they go through the same interceptors as gRPC calls: authentication, logging,
+// metrics and deadlines. The request ID, client IP and user agent the gin middlewares put in the request
+// context are forwarded as metadata, the Authorization header is the only header forwarded.
+func New(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, newEnvelopeMarshaler()),
+		runtime.WithIncomingHeaderMatcher(skipHeader),
+		runtime.WithOutgoingHeaderMatcher(skipHeader),
+		runtime.WithOutgoingTrailerMatcher(skipHeader),
+		runtime.WithMetadata(forwardRequestMetadata),
+		runtime.WithForwardResponseOption(writeSuccessStatus),
+		runtime.WithForwardResponseRewriter(wrapResponse),
+		runtime.WithErrorHandler(writeError),
+	)
+
+	if err := userv1.RegisterAuthServiceHandler(ctx, mux, conn); err != nil {
+		return nil, fmt.Errorf("failed to register auth service gateway: %w", err)
+	}
+
+	return mux, nil
+}
+
+// skipHeader forwards no header between HTTP and gRPC, the gateway always forwards Authorization itself.
+// The default matchers would forward Grpc-Metadata-* headers a client could use to set the forwarded
+// client metadata, and the gRPC response metadata while the gin middlewares already set the request ID header.
+func skipHeader(string) (string, bool) {
+	return "", false
+}
+
+func forwardRequestMetadata(ctx context.Context, r *http.Request) metadata.MD {
+	requestMetadata := contextutil.GetRequestMetadata(ctx)
+	if requestMetadata.RequestID == "" {
+		requestMetadata.RequestID = r.Header.Get(requestIDHeader)
+	}
+	if requestMetadata.UserAgent == "" {
+		requestMetadata.UserAgent = r.UserAgent()
+	}
+
+	md := metadata.MD{}
+	if requestMetadata.RequestID != "" {
+		md.Set(interceptor.RequestIDMetadataKey, requestMetadata.RequestID)
+	}
+	if requestMetadata.IP != "" {
+		md.Set(interceptor.ForwardedClientIPMetadataKey, requestMetadata.IP)
+	}
+	if requestMetadata.UserAgent != "" {
+		md.Set(interceptor.ForwardedUserAgentMetadataKey, requestMetadata.UserAgent)
+	}
+
+	return md
+}
+
+func successStatusCode(ctx context.Context) int {
+	method, _ := runtime.RPCMethod(ctx)
+	if statusCode, ok := successStatusCodes[method]; ok {
+		return statusCode
+	}
+
+	return http.StatusOK
+}
+
+func writeSuccessStatus(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	if statusCode := successStatusCode(ctx); statusCode != http.StatusOK {
+		w.WriteHeader(statusCode)
+	}
+
+	return nil
+}
+
+func wrapResponse(ctx context.Context, resp proto.Message) (any, error) {
+	return response.NewResponse(successStatusCode(ctx), "", resp), nil
+}
+
+// writeError answers like response.ErrorService, the status codes map to HTTP statuses the same way
+// custom error codes do
+func writeError(
+	ctx context.Context,
+	_ *runtime.ServeMux,
+	_ runtime.Marshaler,
+	w http.ResponseWriter,
+	_ *http.Request,
+	err error,
+) {
+	var httpStatusErr *runtime.HTTPStatusError
+	statusCode := 0
+	if errors.As(err, &httpStatusErr) {
+		statusCode = httpStatusErr.HTTPStatus
+		err = httpStatusErr.Err
+	}
+
+	s := status.Convert(err)
+	if statusCode == 0 {
+		statusCode = runtime.HTTPStatusFromCode(s.Code())
+	}
+
+	w.Header().Del("Trailer")
+	w.Header().Del("Transfer-Encoding")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response.NewResponse(statusCode, s.Message(), nil))
+}
@@ -0,0 +1,162 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	userv1 "github.com/datpham/user-service-ms/api/proto/user/v1"
+	"github.com/datpham/user-service-ms/internal/delivery/grpc/interceptor"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeAuthServer struct {
+	userv1.UnimplementedAuthServiceServer
+}
+
+func (s *fakeAuthServer) Signup(ctx context.Context, in *userv1.SignupRequest) (*userv1.SignupResponse, error) {
+	if in.GetEmail() == "taken@example.com" {
+		return nil, status.Error(codes.AlreadyExists, "User already exists")
+	}
+	return &userv1.SignupResponse{}, nil
+}
+
+// Login reports the request metadata the interceptors put in the context through the tokens
+func (s *fakeAuthServer) Login(ctx context.Context, in *userv1.LoginRequest) (*userv1.LoginResponse, error) {
+	requestMetadata := contextutil.GetRequestMetadata(ctx)
+	md, _ := metadata.FromIncomingContext(ctx)
+	return &userv1.LoginResponse{
+		AccessToken:  requestMetadata.RequestID + " " + strings.Join(md.Get("authorization"), ","),
+		RefreshToken: requestMetadata.IP + " " + requestMetadata.UserAgent,
+	}, nil
+}
+
+func newTestGateway(t *testing.T) http.Handler {
+	t.Helper()
+
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+	server := grpc.NewServer(interceptor.ServerOptions(interceptor.NewLoggerInterceptor(testLogger))...)
+	userv1.RegisterAuthServiceServer(server, &fakeAuthServer{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	gateway, err := New(context.Background(), conn)
+	if err != nil {
+		t.Fatalf("failed to create gateway: %v", err)
+	}
+
+	return gateway
+}
+
+type testResponse struct {
+	StatusCode int             `json:"status_code"`
+	Error      string          `json:"error"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func serve(t *testing.T, gateway http.Handler, req *http.Request) (*httptest.ResponseRecorder, testResponse) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	gateway.ServeHTTP(recorder, req)
+
+	var resp testResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response %q is not an envelope: %v", recorder.Body.String(), err)
+	}
+	if resp.StatusCode != recorder.Code {
+		t.Fatalf("envelope status %d differs from response status %d", resp.StatusCode, recorder.Code)
+	}
+
+	return recorder, resp
+}
+
+func TestSignupAnswersCreatedEnvelope(t *testing.T) {
+	gateway := newTestGateway(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/signup",
+		strings.NewReader(`{"email":"new@example.com","password":"Password123","unknown":true}`))
+	recorder, resp := serve(t, gateway, req)
+
+	if recorder.Code != http.StatusCreated || resp.Error != "" || string(resp.Data) != "{}" {
+		t.Fatalf("unexpected response %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestErrorsUseEnvelope(t *testing.T) {
+	gateway := newTestGateway(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/signup",
+		strings.NewReader(`{"email":"taken@example.com","password":"Password123"}`))
+	recorder, resp := serve(t, gateway, req)
+	if recorder.Code != http.StatusConflict || resp.Error != "User already exists" || resp.Data != nil {
+		t.Fatalf("unexpected response %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/auth/signup", strings.NewReader(`{not json`))
+	if recorder, _ := serve(t, gateway, req); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for a malformed body, got %d", recorder.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/auth/unknown", nil)
+	if recorder, _ := serve(t, gateway, req); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected not found for an unknown path, got %d", recorder.Code)
+	}
+}
+
+func TestRequestMetadataIsForwarded(t *testing.T) {
+	gateway := newTestGateway(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login",
+		strings.NewReader(`{"email":"user@example.com","password":"Password123"}`))
+	req.Header.Set("Authorization", "Bearer token")
+	// clients cannot set the forwarded client themselves
+	req.Header.Set("Grpc-Metadata-"+interceptor.ForwardedClientIPMetadataKey, "198.51.100.1")
+	req = req.WithContext(contextutil.WithRequestMetadata(req.Context(), contextutil.RequestMetadata{
+		RequestID: "request-1",
+		IP:        "203.0.113.7",
+		UserAgent: "test-browser",
+	}))
+
+	recorder, resp := serve(t, gateway, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	var login struct {
+		AccessToken  string `json:"accessToken"`
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.Unmarshal(resp.Data, &login); err != nil {
+		t.Fatalf("unexpected data %s: %v", resp.Data, err)
+	}
+	if login.AccessToken != "request-1 Bearer token" {
+		t.Fatalf("expected the request ID and authorization to be forwarded, got %q", login.AccessToken)
+	}
+	if login.RefreshToken != "203.0.113.7 test-browser" {
+		t.Fatalf("expected the client IP and user agent to be forwarded, got %q", login.RefreshToken)
+	}
+}
@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"encoding/json"
+
+	"github.com/datpham/user-service-ms/internal/pkg/response"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// envelopeMarshaler writes the responses of the gateway in the response.Response envelope of the gin
+// handlers. The data is marshaled with protojson: camelCase field names like the REST DTOs, and every
+// field, like the DTOs without omitempty. Request bodies are decoded by the embedded JSONPb.
+type envelopeMarshaler struct {
+	*runtime.JSONPb
+}
+
+func newEnvelopeMarshaler() *envelopeMarshaler {
+	return &envelopeMarshaler{
+		JSONPb: &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				// gin ignores unknown fields too
+				DiscardUnknown: true,
+			},
+		},
+	}
+}
+
+func (m *envelopeMarshaler) Marshal(v any) ([]byte, error) {
+	envelope, ok := v.(*response.Response)
+	if !ok {
+		return m.JSONPb.Marshal(v)
+	}
+
+	data := envelope.Data
+	if message, ok := data.(proto.Message); ok {
+		raw, err := m.JSONPb.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		data = json.RawMessage(raw)
+	}
+
+	return json.Marshal(response.NewResponse(envelope.StatusCode, envelope.Error, data))
+}
+
+func (m *envelopeMarshaler) ContentType(_ any) string {
+	return "application/json; charset=utf-8"
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	userv1 "github.com/datpham/user-service-ms/api/proto/user/v1"
+	dto "github.com/datpham/user-service-ms/internal/dto/request"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/pkg/grpcutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AuthServer serves user.v1.AuthService over the auth service, mirroring the /auth REST endpoints
+type AuthServer struct {
+	userv1.UnimplementedAuthServiceServer
+	authService IAuthService
+}
+
+func New(authService IAuthService) *AuthServer {
+	return &AuthServer{authService: authService}
+}
+
+func (s *AuthServer) RegisterGRPCHandlers(server *grpc.Server) {
+	userv1.RegisterAuthServiceServer(server, s)
+}
+
+func (s *AuthServer) Signup(ctx context.Context, in *userv1.SignupRequest) (*userv1.SignupResponse, error) {
+	req := dto.UserSignupRequest{
+		Email:    in.GetEmail(),
+		Password: in.GetPassword(),
+		Locale:   in.GetLocale(),
+	}
+	if err := grpcutil.Validate(&req); err != nil {
+		return nil, err
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, grpcutil.InvalidArgument(err)
+	}
+
+	if err := s.authService.Signup(ctx, &req); err != nil {
+		return nil, grpcutil.ErrorService(err)
+	}
+
+	return &userv1.SignupResponse{}, nil
+}
+
+func (s *AuthServer) Login(ctx context.Context, in *userv1.LoginRequest) (*userv1.LoginResponse, error) {
+	req := dto.UserLoginRequest{
+		Email:    in.GetEmail(),
+		Password: in.GetPassword(),
+	}
+	if err := grpcutil.Validate(&req); err != nil {
+		return nil, err
+	}
+
+	loginResponse, err := s.authService.Login(ctx, &req)
+	if err != nil {
+		return nil, grpcutil.ErrorService(err)
+	}
+
+	return &userv1.LoginResponse{
+		AccessToken:  loginResponse.AccessToken,
+		RefreshToken: loginResponse.RefreshToken,
+	}, nil
+}
+
+func (s *AuthServer) RefreshToken(
+	ctx context.Context,
+	in *userv1.RefreshTokenRequest,
+) (*userv1.RefreshTokenResponse, error) {
+	req := dto.RefreshTokenRequest{RefreshToken: in.GetRefreshToken()}
+	if err := grpcutil.Validate(&req); err != nil {
+		return nil, err
+	}
+
+	refreshResponse, err := s.authService.RefreshToken(ctx, &req)
+	if err != nil {
+		return nil, grpcutil.ErrorService(err)
+	}
+
+	return &userv1.RefreshTokenResponse{
+		AccessToken:  refreshResponse.AccessToken,
+		RefreshToken: refreshResponse.RefreshToken,
+	}, nil
+}
+
+func (s *AuthServer) ValidateToken(
+	ctx context.Context,
+	in *userv1.ValidateTokenRequest,
+) (*userv1.ValidateTokenResponse, error) {
+	// like /oauth/introspect, only client services may learn who a token was issued to
+	if contextutil.GetClientID(ctx) == "" {
+		return nil, status.Error(codes.PermissionDenied, "client credentials are required")
+	}
+	if in.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token is required")
+	}
+
+	validation, err := s.authService.ValidateAccessToken(ctx, in.GetAccessToken())
+	if err != nil {
+		return nil, grpcutil.ErrorService(err)
+	}
+
+	if !validation.Active {
+		return &userv1.ValidateTokenResponse{Active: false}, nil
+	}
+
+	return &userv1.ValidateTokenResponse{
+		Active:    true,
+		UserId:    validation.Subject,
+		TokenId:   validation.TokenID,
+		IssuedAt:  timestamppb.New(time.Unix(validation.IssuedAt, 0)),
+		ExpiresAt: timestamppb.New(time.Unix(validation.ExpiresAt, 0)),
+		Email:     validation.Email,
+		Username:  validation.Username,
+		Role:      validation.Role,
+	}, nil
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	userv1 "github.com/datpham/user-service-ms/api/proto/user/v1"
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeAuthService struct {
+	signups []*reqDto.UserSignupRequest
+}
+
+func (s *fakeAuthService) Signup(ctx context.Context, req *reqDto.UserSignupRequest) error {
+	if req.Email == "taken@example.com" {
+		return customErr.NewCustomError(customErr.ErrConflict, "User already exists")
+	}
+	s.signups = append(s.signups, req)
+	return nil
+}
+
+func (s *fakeAuthService) Login(ctx context.Context, req *reqDto.UserLoginRequest) (*respDto.UserLoginResponse, error) {
+	if req.Password != "Password123" {
+		return nil, customErr.NewCustomError(customErr.ErrInvalidRequest, "Incorrect password")
+	}
+	return &respDto.UserLoginResponse{AccessToken: "access", RefreshToken: "refresh"}, nil
+}
+
+func (s *fakeAuthService) RefreshToken(
+	ctx context.Context,
+	req *reqDto.RefreshTokenRequest,
+) (*respDto.UserLoginResponse, error) {
+	return &respDto.UserLoginResponse{AccessToken: "access-2", RefreshToken: "refresh-2"}, nil
+}
+
+func (s *fakeAuthService) ValidateAccessToken(
+	ctx context.Context,
+	accessToken string,
+) (*respDto.TokenIntrospectionResponse, error) {
+	if accessToken != "access" {
+		return &respDto.TokenIntrospectionResponse{Active: false}, nil
+	}
+	return &respDto.TokenIntrospectionResponse{
+		Active:    true,
+		Subject:   "user-1",
+		TokenID:   "token-1",
+		IssuedAt:  1735603200,
+		ExpiresAt: 1735689600,
+		Email:     "user@example.com",
+		Role:      "admin",
+	}, nil
+}
+
+func newTestClient(t *testing.T, authService IAuthService) userv1.AuthServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.UnaryInterceptor(testCallerInterceptor))
+	New(authService).RegisterGRPCHandlers(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return userv1.NewAuthServiceClient(conn)
+}
+
+// testCallerInterceptor stands in for the auth interceptor, taking the client from the test metadata
+func testCallerInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-test-client-id"); len(values) > 0 {
+		ctx = contextutil.WithClientID(ctx, values[0])
+	}
+	return handler(ctx, req)
+}
+
+func TestSignup(t *testing.T) {
+	authService := &fakeAuthService{}
+	client := newTestClient(t, authService)
+	ctx := context.Background()
+
+	if _, err := client.Signup(ctx, &userv1.SignupRequest{Email: "new@example.com", Password: "Password123"}); err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	if len(authService.signups) != 1 || authService.signups[0].Email != "new@example.com" {
+		t.Fatalf("unexpected signups %+v", authService.signups)
+	}
+
+	tests := []struct {
+		name string
+		req  *userv1.SignupRequest
+		code codes.Code
+	}{
+		{"invalid email", &userv1.SignupRequest{Email: "not-an-email", Password: "Password123"}, codes.InvalidArgument},
+		{"weak password", &userv1.SignupRequest{Email: "new@example.com", Password: "password"}, codes.InvalidArgument},
+		{"existing user", &userv1.SignupRequest{Email: "taken@example.com", Password: "Password123"}, codes.AlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Signup(ctx, tt.req)
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	client := newTestClient(t, &fakeAuthService{})
+	ctx := context.Background()
+
+	resp, err := client.Login(ctx, &userv1.LoginRequest{Email: "user@example.com", Password: "Password123"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.GetAccessToken() != "access" || resp.GetRefreshToken() != "refresh" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	_, err = client.Login(ctx, &userv1.LoginRequest{Email: "user@example.com", Password: "Wrong12345"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	client := newTestClient(t, &fakeAuthService{})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-test-client-id", "api-gateway")
+
+	resp, err := client.ValidateToken(ctx, &userv1.ValidateTokenRequest{AccessToken: "access"})
+	if err != nil {
+		t.Fatalf("validate token: %v", err)
+	}
+	if !resp.GetActive() || resp.GetUserId() != "user-1" || resp.GetRole() != "admin" || resp.GetTokenId() != "token-1" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if resp.GetExpiresAt().GetSeconds() != 1735689600 || resp.GetIssuedAt().GetSeconds() != 1735603200 {
+		t.Fatalf("unexpected token times %+v", resp)
+	}
+
+	resp, err = client.ValidateToken(ctx, &userv1.ValidateTokenRequest{AccessToken: "expired"})
+	if err != nil {
+		t.Fatalf("validate token: %v", err)
+	}
+	if resp.GetActive() || resp.GetUserId() != "" || resp.GetExpiresAt() != nil {
+		t.Fatalf("expected inactive token, got %+v", resp)
+	}
+
+	_, err = client.ValidateToken(ctx, &userv1.ValidateTokenRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestValidateTokenRequiresClient(t *testing.T) {
+	client := newTestClient(t, &fakeAuthService{})
+
+	_, err := client.ValidateToken(context.Background(), &userv1.ValidateTokenRequest{AccessToken: "access"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied without client credentials, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"context"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+)
+
+type IAuthService interface {
+	Signup(ctx context.Context, req *reqDto.UserSignupRequest) error
+	Login(ctx context.Context, req *reqDto.UserLoginRequest) (*respDto.UserLoginResponse, error)
+	RefreshToken(ctx context.Context, req *reqDto.RefreshTokenRequest) (*respDto.UserLoginResponse, error)
+	ValidateAccessToken(ctx context.Context, accessToken string) (*respDto.TokenIntrospectionResponse, error)
+}
@@ -0,0 +1,13 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+)
+
+type IHealthService interface {
+	Readiness(ctx context.Context) *respDto.HealthResponse
+	CheckInterval() time.Duration
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	healthSvc "github.com/datpham/user-service-ms/internal/service/health"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// HealthServer serves grpc.health.v1.Health. The serving status of the server ("") and of every
+// service follows the readiness checks, refreshed every CheckInterval while Start runs.
+type HealthServer struct {
+	server        *health.Server
+	healthService IHealthService
+	services      []string
+}
+
+func New(healthService IHealthService, services ...string) *HealthServer {
+	s := &HealthServer{
+		server:        health.NewServer(),
+		healthService: healthService,
+		services:      services,
+	}
+	// not serving until the first check passed
+	s.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	return s
+}
+
+func (s *HealthServer) RegisterGRPCHandlers(server *grpc.Server) {
+	healthpb.RegisterHealthServer(server, s.server)
+}
+
+// Start refreshes the serving status until the context is cancelled, then reports not serving for good
+// so clients watching the status stop sending calls before the server stops
+func (s *HealthServer) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.healthService.CheckInterval())
+	defer ticker.Stop()
+
+	for {
+		s.update(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.server.Shutdown()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *HealthServer) update(ctx context.Context) {
+	servingStatus := healthpb.HealthCheckResponse_SERVING
+	if s.healthService.Readiness(ctx).Status != healthSvc.StatusUp {
+		servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	s.setServingStatus(servingStatus)
+}
+
+func (s *HealthServer) setServingStatus(servingStatus healthpb.HealthCheckResponse_ServingStatus) {
+	s.server.SetServingStatus("", servingStatus)
+	for _, service := range s.services {
+		s.server.SetServingStatus(service, servingStatus)
+	}
+}
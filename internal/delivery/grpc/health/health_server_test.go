@@ -0,0 +1,111 @@
+package health
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	healthSvc "github.com/datpham/user-service-ms/internal/service/health"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testService = "user.v1.UserService"
+
+type fakeHealthService struct {
+	mu     sync.Mutex
+	status string
+}
+
+func (s *fakeHealthService) Readiness(ctx context.Context) *respDto.HealthResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &respDto.HealthResponse{Status: s.status}
+}
+
+func (s *fakeHealthService) CheckInterval() time.Duration {
+	return time.Millisecond * 10
+}
+
+func (s *fakeHealthService) setStatus(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func newTestClient(t *testing.T, healthServer *HealthServer) healthpb.HealthClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	healthServer.RegisterGRPCHandlers(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func waitForStatus(t *testing.T, client healthpb.HealthClient, service string, want healthpb.HealthCheckResponse_ServingStatus) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err == nil && resp.GetStatus() == want {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	t.Fatalf("service %q did not become %s", service, want)
+}
+
+func TestServingStatusFollowsReadiness(t *testing.T) {
+	healthService := &fakeHealthService{status: healthSvc.StatusUp}
+	healthServer := New(healthService, testService)
+	client := newTestClient(t, healthServer)
+
+	// not serving before the first check
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("expected not serving before start, got %v, %v", resp, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		healthServer.Start(ctx)
+		close(done)
+	}()
+
+	waitForStatus(t, client, "", healthpb.HealthCheckResponse_SERVING)
+	waitForStatus(t, client, testService, healthpb.HealthCheckResponse_SERVING)
+
+	healthService.setStatus(healthSvc.StatusDown)
+	waitForStatus(t, client, testService, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	healthService.setStatus(healthSvc.StatusUp)
+	waitForStatus(t, client, testService, healthpb.HealthCheckResponse_SERVING)
+
+	// stopping reports not serving even though the dependencies are up
+	cancel()
+	<-done
+	waitForStatus(t, client, "", healthpb.HealthCheckResponse_NOT_SERVING)
+}
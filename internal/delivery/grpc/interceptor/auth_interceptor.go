@@ -0,0 +1,118 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationMetadataKey = "authorization"
+	bearerPrefix             = "Bearer "
+	basicPrefix              = "Basic "
+)
+
+type ITokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (string, error)
+}
+
+type AuthInterceptor struct {
+	tokenValidator ITokenValidator
+	// clientSecrets maps the ID of a client service to its secret
+	clientSecrets map[string]string
+	// publicMethods are the full method names callable without an access token
+	publicMethods map[string]bool
+}
+
+func NewAuthInterceptor(
+	tokenValidator ITokenValidator,
+	clientSecrets map[string]string,
+	publicMethods ...string,
+) *AuthInterceptor {
+	methods := make(map[string]bool, len(publicMethods))
+	for _, method := range publicMethods {
+		methods[method] = true
+	}
+
+	return &AuthInterceptor{
+		tokenValidator: tokenValidator,
+		clientSecrets:  clientSecrets,
+		publicMethods:  methods,
+	}
+}
+
+// Unary rejects calls to non-public methods without a valid bearer access token or the basic credentials
+// of a known client in the authorization metadata, and stores the user or client ID in the context
+func (ai *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := ai.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (ai *AuthInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := ai.authenticate(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, wrapServerStream(stream, ctx))
+	}
+}
+
+func (ai *AuthInterceptor) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if ai.publicMethods[method] {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorization := firstMetadataValue(md, authorizationMetadataKey)
+	if strings.HasPrefix(authorization, basicPrefix) {
+		clientID, ok := ai.authenticateClient(strings.TrimPrefix(authorization, basicPrefix))
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid client credentials")
+		}
+		return contextutil.WithClientID(ctx, clientID), nil
+	}
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	userID, err := ai.tokenValidator.ValidateToken(ctx, strings.TrimPrefix(authorization, bearerPrefix))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid access token")
+	}
+
+	return contextutil.WithUserID(ctx, userID), nil
+}
+
+// authenticateClient checks base64 encoded "id:secret" credentials against the known clients
+func (ai *AuthInterceptor) authenticateClient(credentials string) (string, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return "", false
+	}
+	clientID, clientSecret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", false
+	}
+
+	secret, known := ai.clientSecrets[clientID]
+	if !known || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(secret)) != 1 {
+		return "", false
+	}
+
+	return clientID, true
+}
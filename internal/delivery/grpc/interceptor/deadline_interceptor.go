@@ -0,0 +1,45 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	DefaultDeadline = time.Second * 10
+)
+
+type DeadlineInterceptor struct {
+	defaultDeadline time.Duration
+}
+
+func NewDeadlineInterceptor(defaultDeadline time.Duration) *DeadlineInterceptor {
+	if defaultDeadline <= 0 {
+		defaultDeadline = DefaultDeadline
+	}
+
+	return &DeadlineInterceptor{defaultDeadline: defaultDeadline}
+}
+
+// Unary bounds calls whose client set no deadline, so a stuck dependency cannot hold a handler forever
+func (di *DeadlineInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := ctx.Deadline(); ok {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, di.defaultDeadline)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
+
+// Stream leaves streams alone, they are expected to stay open, e.g. health watches
+func (di *DeadlineInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, stream)
+	}
+}
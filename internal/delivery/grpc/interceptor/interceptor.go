@@ -0,0 +1,42 @@
+package interceptor
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Interceptor is implemented by every interceptor of the package, like CommonMiddleware for HTTP
+type Interceptor interface {
+	Unary() grpc.UnaryServerInterceptor
+	Stream() grpc.StreamServerInterceptor
+}
+
+// ServerOptions chains the unary and stream interceptors in the given order, the first one is the outermost
+func ServerOptions(interceptors ...Interceptor) []grpc.ServerOption {
+	unaryInterceptors := make([]grpc.UnaryServerInterceptor, 0, len(interceptors))
+	streamInterceptors := make([]grpc.StreamServerInterceptor, 0, len(interceptors))
+	for _, interceptor := range interceptors {
+		unaryInterceptors = append(unaryInterceptors, interceptor.Unary())
+		streamInterceptors = append(streamInterceptors, interceptor.Stream())
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+}
+
+// wrappedServerStream replaces the context of a stream so stream interceptors can pass values down
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func wrapServerStream(stream grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &wrappedServerStream{ServerStream: stream, ctx: ctx}
+}
@@ -0,0 +1,272 @@
+package interceptor
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	userv1 "github.com/datpham/user-service-ms/api/proto/user/v1"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+type testAuthServer struct {
+	userv1.UnimplementedAuthServiceServer
+}
+
+func (s *testAuthServer) Login(ctx context.Context, in *userv1.LoginRequest) (*userv1.LoginResponse, error) {
+	if in.GetEmail() == "panic@example.com" {
+		panic("boom")
+	}
+	requestMetadata := contextutil.GetRequestMetadata(ctx)
+	return &userv1.LoginResponse{
+		AccessToken:  requestMetadata.RequestID,
+		RefreshToken: requestMetadata.IP + " " + requestMetadata.UserAgent,
+	}, nil
+}
+
+// testUserServer reports what the interceptors put in the context through the user fields
+type testUserServer struct {
+	userv1.UnimplementedUserServiceServer
+}
+
+func (s *testUserServer) GetUser(ctx context.Context, in *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	user := &userv1.User{Id: contextutil.GetUserID(ctx), Username: contextutil.GetClientID(ctx)}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= time.Minute {
+		user.Role = "deadline"
+	}
+	return &userv1.GetUserResponse{User: user}, nil
+}
+
+type fakeTokenValidator struct{}
+
+func (v *fakeTokenValidator) ValidateToken(ctx context.Context, token string) (string, error) {
+	if token != "valid" {
+		return "", errors.New("invalid token")
+	}
+	return "user-1", nil
+}
+
+func newTestServer(t *testing.T) *grpc.Server {
+	t.Helper()
+
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+	server := grpc.NewServer(ServerOptions(
+		NewRecoveryInterceptor(testLogger),
+		NewLoggerInterceptor(testLogger),
+		NewMetricsInterceptor(),
+		NewDeadlineInterceptor(time.Second*5),
+		NewAuthInterceptor(
+			&fakeTokenValidator{},
+			map[string]string{"api-gateway": "gateway-secret"},
+			userv1.AuthService_Login_FullMethodName,
+		),
+	)...)
+	userv1.RegisterAuthServiceServer(server, &testAuthServer{})
+	userv1.RegisterUserServiceServer(server, &testUserServer{})
+	t.Cleanup(server.Stop)
+
+	return server
+}
+
+func newTestConn(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	go newTestServer(t).Serve(listener)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestRecoveryInterceptor(t *testing.T) {
+	client := userv1.NewAuthServiceClient(newTestConn(t))
+
+	_, err := client.Login(context.Background(), &userv1.LoginRequest{Email: "panic@example.com"})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal, got %v", err)
+	}
+
+	// the server keeps serving after a panic
+	if _, err := client.Login(context.Background(), &userv1.LoginRequest{Email: "user@example.com"}); err != nil {
+		t.Fatalf("login after panic: %v", err)
+	}
+}
+
+func TestLoggerInterceptorPropagatesRequestID(t *testing.T) {
+	client := userv1.NewAuthServiceClient(newTestConn(t))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDMetadataKey, "request-1")
+	var header metadata.MD
+	resp, err := client.Login(ctx, &userv1.LoginRequest{Email: "user@example.com"}, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.GetAccessToken() != "request-1" {
+		t.Fatalf("expected request id in the service context, got %q", resp.GetAccessToken())
+	}
+	if values := header.Get(RequestIDMetadataKey); len(values) != 1 || values[0] != "request-1" {
+		t.Fatalf("expected request id header, got %v", header)
+	}
+
+	// a request ID is generated when the client sends none
+	resp, err = client.Login(context.Background(), &userv1.LoginRequest{Email: "user@example.com"}, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.GetAccessToken() == "" || header.Get(RequestIDMetadataKey)[0] != resp.GetAccessToken() {
+		t.Fatalf("expected generated request id, got %q and header %v", resp.GetAccessToken(), header)
+	}
+}
+
+func TestLoggerInterceptorForwardedClient(t *testing.T) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		ForwardedClientIPMetadataKey, "203.0.113.7",
+		ForwardedUserAgentMetadataKey, "test-browser",
+	)
+
+	// bufconn peers are not loopback, the forwarded client is ignored
+	resp, err := userv1.NewAuthServiceClient(newTestConn(t)).Login(ctx, &userv1.LoginRequest{Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.GetRefreshToken() == "203.0.113.7 test-browser" {
+		t.Fatal("forwarded client trusted from a non loopback peer")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go newTestServer(t).Serve(listener)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	resp, err = userv1.NewAuthServiceClient(conn).Login(ctx, &userv1.LoginRequest{Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.GetRefreshToken() != "203.0.113.7 test-browser" {
+		t.Fatalf("expected the forwarded client from a loopback peer, got %q", resp.GetRefreshToken())
+	}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	client := userv1.NewUserServiceClient(newTestConn(t))
+
+	_, err := client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-2"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without token, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer invalid")
+	_, err = client.GetUser(ctx, &userv1.GetUserRequest{UserId: "user-2"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated with invalid token, got %v", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer valid")
+	resp, err := client.GetUser(ctx, &userv1.GetUserRequest{UserId: "user-2"})
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if resp.GetUser().GetId() != "user-1" {
+		t.Fatalf("expected authenticated user id in context, got %q", resp.GetUser().GetId())
+	}
+
+	wrongSecret := base64.StdEncoding.EncodeToString([]byte("api-gateway:wrong"))
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic "+wrongSecret)
+	_, err = client.GetUser(ctx, &userv1.GetUserRequest{UserId: "user-2"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated with a wrong client secret, got %v", err)
+	}
+
+	credentials := base64.StdEncoding.EncodeToString([]byte("api-gateway:gateway-secret"))
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic "+credentials)
+	resp, err = client.GetUser(ctx, &userv1.GetUserRequest{UserId: "user-2"})
+	if err != nil {
+		t.Fatalf("get user as client: %v", err)
+	}
+	if resp.GetUser().GetUsername() != "api-gateway" || resp.GetUser().GetId() != "" {
+		t.Fatalf("expected authenticated client id in context, got %+v", resp.GetUser())
+	}
+}
+
+func TestDeadlineInterceptor(t *testing.T) {
+	client := userv1.NewUserServiceClient(newTestConn(t))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer valid")
+
+	resp, err := client.GetUser(ctx, &userv1.GetUserRequest{UserId: "user-1"})
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if resp.GetUser().GetRole() != "deadline" {
+		t.Fatal("expected the default deadline on a call without one")
+	}
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	client := userv1.NewUserServiceClient(newTestConn(t))
+	counter := metrics.GRPCServerHandledTotal.WithLabelValues("user.v1.UserService", "GetUser", codes.Unauthenticated.String())
+	before := testutil.ToFloat64(counter)
+
+	if _, err := client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-1"}); err == nil {
+		t.Fatal("expected unauthenticated call to fail")
+	}
+
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Fatalf("expected 1 unauthenticated call counted, got %v", got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	login := &userv1.LoginRequest{Email: "user@example.com", Password: "Password123"}
+
+	redacted := Redact(login).(*userv1.LoginRequest)
+	if redacted.GetPassword() != redactedValue || redacted.GetEmail() != "user@example.com" {
+		t.Fatalf("unexpected redacted request %+v", redacted)
+	}
+	if login.GetPassword() != "Password123" {
+		t.Fatal("redacting must not change the original message")
+	}
+
+	tokens := Redact(&userv1.RefreshTokenResponse{AccessToken: "a", RefreshToken: "r"}).(*userv1.RefreshTokenResponse)
+	if tokens.GetAccessToken() != redactedValue || tokens.GetRefreshToken() != redactedValue {
+		t.Fatalf("unexpected redacted response %+v", tokens)
+	}
+
+	// unset fields stay unset
+	empty := Redact(&userv1.LoginRequest{Email: "user@example.com"})
+	if !proto.Equal(empty, &userv1.LoginRequest{Email: "user@example.com"}) {
+		t.Fatalf("unexpected redacted request %+v", empty)
+	}
+}
@@ -0,0 +1,153 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// RequestIDMetadataKey carries the request ID like the X-Request-ID header of the HTTP API,
+	// metadata keys are lower case
+	RequestIDMetadataKey = "x-request-id"
+	// ForwardedClientIPMetadataKey and ForwardedUserAgentMetadataKey describe the HTTP client of the calls
+	// relayed by the REST gateway. They are only trusted from loopback peers, any other client could set them.
+	ForwardedClientIPMetadataKey  = "x-forwarded-client-ip"
+	ForwardedUserAgentMetadataKey = "x-forwarded-user-agent"
+	userAgentMetadataKey          = "user-agent"
+)
+
+type LoggerInterceptor struct {
+	logger *logger.Logger
+}
+
+func NewLoggerInterceptor(logger *logger.Logger) *LoggerInterceptor {
+	return &LoggerInterceptor{logger: logger}
+}
+
+// Unary propagates the request ID, exposes the request metadata to the service layer and logs the call.
+// Requests are only logged for failed calls, with their sensitive fields redacted, responses never are.
+func (li *LoggerInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, logEntry := li.startCall(ctx, info.FullMethod)
+
+		resp, err := handler(ctx, req)
+
+		if err != nil {
+			if message, ok := req.(proto.Message); ok {
+				logEntry = logEntry.WithField("request", redactedJSON(message))
+			}
+		}
+		li.endCall(logEntry, err, time.Since(start))
+
+		return resp, err
+	}
+}
+
+func (li *LoggerInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, logEntry := li.startCall(stream.Context(), info.FullMethod)
+
+		err := handler(srv, wrapServerStream(stream, ctx))
+
+		li.endCall(logEntry, err, time.Since(start))
+
+		return err
+	}
+}
+
+func (li *LoggerInterceptor) startCall(ctx context.Context, method string) (context.Context, *logrus.Entry) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := firstMetadataValue(md, RequestIDMetadataKey)
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+
+	var ip string
+	userAgent := firstMetadataValue(md, userAgentMetadataKey)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+
+		if isLoopback(p.Addr) {
+			if forwardedIP := firstMetadataValue(md, ForwardedClientIPMetadataKey); forwardedIP != "" {
+				ip = forwardedIP
+			}
+			if forwardedUserAgent := firstMetadataValue(md, ForwardedUserAgentMetadataKey); forwardedUserAgent != "" {
+				userAgent = forwardedUserAgent
+			}
+		}
+	}
+
+	ctx = contextutil.WithRequestMetadata(ctx, contextutil.RequestMetadata{
+		RequestID: requestID,
+		IP:        ip,
+		UserAgent: userAgent,
+	})
+
+	// echo the request ID so clients can correlate their logs with ours
+	if err := grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID)); err != nil {
+		li.logger.Errorf("method: %s, failed to set request id header: %s", method, err.Error())
+	}
+
+	logEntry := li.logger.WithRequestID(requestID).WithFields(map[string]any{
+		logger.FieldMethod: method,
+		logger.FieldIP:     ip,
+	})
+	logEntry.Info("Request started")
+
+	return ctx, logEntry
+}
+
+func (li *LoggerInterceptor) endCall(logEntry *logrus.Entry, err error, duration time.Duration) {
+	code := status.Code(err)
+	logEntry = logEntry.WithFields(map[string]any{
+		logger.FieldStatusCode: code.String(),
+		logger.FieldDuration:   duration.String(),
+	})
+
+	switch code {
+	case codes.OK:
+		logEntry.Info("Request completed")
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		logEntry.WithField(logger.FieldError, err.Error()).Error("Server error")
+	default:
+		logEntry.WithField(logger.FieldError, err.Error()).Warn("Client error")
+	}
+}
+
+func firstMetadataValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func redactedJSON(message proto.Message) string {
+	encoded, err := protojson.Marshal(Redact(message))
+	if err != nil {
+		return ""
+	}
+
+	return string(encoded)
+}
+
+func isLoopback(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	return ok && tcpAddr.IP.IsLoopback()
+}
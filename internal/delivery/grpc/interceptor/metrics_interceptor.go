@@ -0,0 +1,56 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/pkg/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+type MetricsInterceptor struct{}
+
+func NewMetricsInterceptor() *MetricsInterceptor {
+	return &MetricsInterceptor{}
+}
+
+// Unary counts the calls by method and status code and observes how long they took
+func (mi *MetricsInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(info.FullMethod, err, time.Since(start))
+
+		return resp, err
+	}
+}
+
+func (mi *MetricsInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		observe(info.FullMethod, err, time.Since(start))
+
+		return err
+	}
+}
+
+func observe(fullMethod string, err error, duration time.Duration) {
+	service, method := splitMethod(fullMethod)
+	code := status.Code(err).String()
+
+	metrics.GRPCServerHandledTotal.WithLabelValues(service, method, code).Inc()
+	metrics.GRPCServerHandlingSeconds.WithLabelValues(service, method).Observe(duration.Seconds())
+}
+
+// splitMethod splits "/user.v1.AuthService/Login" into the service and method names
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", "unknown"
+	}
+
+	return service, method
+}
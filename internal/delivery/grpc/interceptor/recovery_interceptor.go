@@ -0,0 +1,49 @@
+package interceptor
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type RecoveryInterceptor struct {
+	logger *logger.Logger
+}
+
+func NewRecoveryInterceptor(logger *logger.Logger) *RecoveryInterceptor {
+	return &RecoveryInterceptor{logger: logger}
+}
+
+// Unary turns a panic in a handler into an INTERNAL error instead of crashing the server
+func (ri *RecoveryInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = ri.recovered(info.FullMethod, r)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+func (ri *RecoveryInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = ri.recovered(info.FullMethod, r)
+			}
+		}()
+
+		return handler(srv, stream)
+	}
+}
+
+func (ri *RecoveryInterceptor) recovered(method string, r any) error {
+	ri.logger.Errorf("method: %s, panic: %v\n%s", method, r, debug.Stack())
+	return status.Error(codes.Internal, "internal server error")
+}
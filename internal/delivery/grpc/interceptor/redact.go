@@ -0,0 +1,64 @@
+package interceptor
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const redactedValue = "[REDACTED]"
+
+// sensitiveFields are the names of string fields that are never logged
+var sensitiveFields = map[protoreflect.Name]bool{
+	"password":      true,
+	"access_token":  true,
+	"refresh_token": true,
+	"token":         true,
+	"code":          true,
+	"secret":        true,
+}
+
+// Redact returns a copy of the message with its sensitive fields replaced, including in nested messages
+func Redact(message proto.Message) proto.Message {
+	clone := proto.Clone(message)
+	redactMessage(clone.ProtoReflect())
+
+	return clone
+}
+
+func redactMessage(message protoreflect.Message) {
+	var redacted []protoreflect.FieldDescriptor
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case field.IsMap():
+			// maps are left as is, none of our messages keep secrets in them
+		case field.IsList():
+			if field.Message() != nil {
+				list := value.List()
+				for i := 0; i < list.Len(); i++ {
+					redactMessage(list.Get(i).Message())
+				}
+			} else if field.Kind() == protoreflect.StringKind && sensitiveFields[field.Name()] {
+				redacted = append(redacted, field)
+			}
+		case field.Message() != nil:
+			redactMessage(value.Message())
+		case field.Kind() == protoreflect.StringKind && sensitiveFields[field.Name()]:
+			redacted = append(redacted, field)
+		}
+
+		return true
+	})
+
+	// fields are replaced after ranging since the message must not be changed while ranging over it
+	for _, field := range redacted {
+		if field.IsList() {
+			list := message.Mutable(field).List()
+			for i := 0; i < list.Len(); i++ {
+				list.Set(i, protoreflect.ValueOfString(redactedValue))
+			}
+			continue
+		}
+
+		message.Set(field, protoreflect.ValueOfString(redactedValue))
+	}
+}
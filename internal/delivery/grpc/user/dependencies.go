@@ -0,0 +1,13 @@
+package user
+
+import (
+	"context"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+)
+
+type IUserService interface {
+	GetUser(ctx context.Context, userID string) (*respDto.UserResponse, error)
+	BatchGetUsers(ctx context.Context, req *reqDto.BatchGetUsersRequest) (*respDto.BatchUsersResponse, error)
+}
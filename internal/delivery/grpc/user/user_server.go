@@ -0,0 +1,107 @@
+package user
+
+import (
+	"context"
+
+	userv1 "github.com/datpham/user-service-ms/api/proto/user/v1"
+	dto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/pkg/grpcutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UserServer serves user.v1.UserService, the profile lookups other services make by user ID
+type UserServer struct {
+	userv1.UnimplementedUserServiceServer
+	userService IUserService
+}
+
+func New(userService IUserService) *UserServer {
+	return &UserServer{userService: userService}
+}
+
+func (s *UserServer) RegisterGRPCHandlers(server *grpc.Server) {
+	userv1.RegisterUserServiceServer(server, s)
+}
+
+func (s *UserServer) GetUser(ctx context.Context, in *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	if in.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if err := authorizeLookup(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.GetUser(ctx, in.GetUserId())
+	if err != nil {
+		return nil, grpcutil.ErrorService(err)
+	}
+
+	return &userv1.GetUserResponse{User: toUserMessage(user)}, nil
+}
+
+func (s *UserServer) BatchGetUsers(
+	ctx context.Context,
+	in *userv1.BatchGetUsersRequest,
+) (*userv1.BatchGetUsersResponse, error) {
+	req := dto.BatchGetUsersRequest{UserIDs: in.GetUserIds()}
+	if err := grpcutil.Validate(&req); err != nil {
+		return nil, err
+	}
+	if err := authorizeLookup(ctx, req.UserIDs...); err != nil {
+		return nil, err
+	}
+
+	batchResponse, err := s.userService.BatchGetUsers(ctx, &req)
+	if err != nil {
+		return nil, grpcutil.ErrorService(err)
+	}
+
+	users := make([]*userv1.User, 0, len(batchResponse.Users))
+	for _, user := range batchResponse.Users {
+		users = append(users, toUserMessage(user))
+	}
+
+	return &userv1.BatchGetUsersResponse{
+		Users:       users,
+		NotFoundIds: batchResponse.NotFoundIDs,
+	}, nil
+}
+
+// authorizeLookup lets client services look up any user, end users may only look up themselves
+func authorizeLookup(ctx context.Context, userIDs ...string) error {
+	if contextutil.GetClientID(ctx) != "" {
+		return nil
+	}
+
+	callerID := contextutil.GetUserID(ctx)
+	for _, userID := range userIDs {
+		if userID != callerID {
+			return status.Error(codes.PermissionDenied, "users can only look up their own profile")
+		}
+	}
+
+	return nil
+}
+
+func toUserMessage(user *respDto.UserResponse) *userv1.User {
+	message := &userv1.User{
+		Id:          user.ID,
+		Email:       user.Email,
+		Username:    user.Username,
+		Role:        user.Role,
+		Locale:      user.Locale,
+		PhoneNumber: user.PhoneNumber,
+		Suspended:   user.Suspended,
+		CreatedAt:   timestamppb.New(user.CreatedAt),
+	}
+	if user.PhoneVerifiedAt != nil {
+		message.PhoneVerifiedAt = timestamppb.New(*user.PhoneVerifiedAt)
+	}
+
+	return message
+}
@@ -0,0 +1,201 @@
+package user
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	userv1 "github.com/datpham/user-service-ms/api/proto/user/v1"
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeUserService struct {
+	users map[string]*respDto.UserResponse
+}
+
+func (s *fakeUserService) GetUser(ctx context.Context, userID string) (*respDto.UserResponse, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, customErr.NewCustomError(customErr.ErrNotFound, "User not found")
+	}
+	return user, nil
+}
+
+func (s *fakeUserService) BatchGetUsers(
+	ctx context.Context,
+	req *reqDto.BatchGetUsersRequest,
+) (*respDto.BatchUsersResponse, error) {
+	resp := &respDto.BatchUsersResponse{}
+	for _, id := range req.UserIDs {
+		if user, ok := s.users[id]; ok {
+			resp.Users = append(resp.Users, user)
+		} else {
+			resp.NotFoundIDs = append(resp.NotFoundIDs, id)
+		}
+	}
+	return resp, nil
+}
+
+func newTestClient(t *testing.T, userService IUserService) userv1.UserServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.UnaryInterceptor(testCallerInterceptor))
+	New(userService).RegisterGRPCHandlers(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return userv1.NewUserServiceClient(conn)
+}
+
+// testCallerInterceptor stands in for the auth interceptor, taking the caller from the test metadata
+func testCallerInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-test-user-id"); len(values) > 0 {
+		ctx = contextutil.WithUserID(ctx, values[0])
+	}
+	if values := md.Get("x-test-client-id"); len(values) > 0 {
+		ctx = contextutil.WithClientID(ctx, values[0])
+	}
+	return handler(ctx, req)
+}
+
+func asUser(userID string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-test-user-id", userID)
+}
+
+func asClient(clientID string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-test-client-id", clientID)
+}
+
+func testUserService() *fakeUserService {
+	verifiedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	return &fakeUserService{users: map[string]*respDto.UserResponse{
+		"user-1": {
+			ID:              "user-1",
+			Email:           "one@example.com",
+			Role:            "user",
+			PhoneNumber:     "+84901234567",
+			PhoneVerifiedAt: &verifiedAt,
+			CreatedAt:       verifiedAt.Add(-time.Hour),
+		},
+		"user-2": {ID: "user-2", Email: "two@example.com", Role: "admin", Suspended: true},
+	}}
+}
+
+func TestGetUser(t *testing.T) {
+	client := newTestClient(t, testUserService())
+	ctx := asClient("api-gateway")
+
+	resp, err := client.GetUser(ctx, &userv1.GetUserRequest{UserId: "user-1"})
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	user := resp.GetUser()
+	if user.GetEmail() != "one@example.com" || user.GetPhoneNumber() != "+84901234567" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+	if !user.GetPhoneVerifiedAt().AsTime().Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected phone verified at %v", user.GetPhoneVerifiedAt())
+	}
+
+	_, err = client.GetUser(ctx, &userv1.GetUserRequest{UserId: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	_, err = client.GetUser(ctx, &userv1.GetUserRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestBatchGetUsers(t *testing.T) {
+	client := newTestClient(t, testUserService())
+	ctx := asClient("api-gateway")
+
+	resp, err := client.BatchGetUsers(ctx, &userv1.BatchGetUsersRequest{UserIds: []string{"user-2", "missing", "user-1"}})
+	if err != nil {
+		t.Fatalf("batch get users: %v", err)
+	}
+	var ids []string
+	for _, user := range resp.GetUsers() {
+		ids = append(ids, user.GetId())
+	}
+	if !slices.Equal(ids, []string{"user-2", "user-1"}) || !slices.Equal(resp.GetNotFoundIds(), []string{"missing"}) {
+		t.Fatalf("unexpected response users %v, not found %v", ids, resp.GetNotFoundIds())
+	}
+	if !resp.GetUsers()[0].GetSuspended() {
+		t.Fatal("expected suspended user")
+	}
+
+	_, err = client.BatchGetUsers(ctx, &userv1.BatchGetUsersRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for empty batch, got %v", err)
+	}
+
+	tooMany := make([]string, 101)
+	for i := range tooMany {
+		tooMany[i] = "user-1"
+	}
+	_, err = client.BatchGetUsers(ctx, &userv1.BatchGetUsersRequest{UserIds: tooMany})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for oversized batch, got %v", err)
+	}
+}
+
+func TestUsersCanOnlyLookUpThemselves(t *testing.T) {
+	client := newTestClient(t, testUserService())
+	ctx := asUser("user-1")
+
+	resp, err := client.GetUser(ctx, &userv1.GetUserRequest{UserId: "user-1"})
+	if err != nil {
+		t.Fatalf("get own user: %v", err)
+	}
+	if resp.GetUser().GetId() != "user-1" {
+		t.Fatalf("unexpected user %+v", resp.GetUser())
+	}
+
+	_, err = client.GetUser(ctx, &userv1.GetUserRequest{UserId: "user-2"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied for another user, got %v", err)
+	}
+
+	_, err = client.BatchGetUsers(ctx, &userv1.BatchGetUsersRequest{UserIds: []string{"user-1", "user-2"}})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied for a batch with another user, got %v", err)
+	}
+
+	_, err = client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-1"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied without a caller, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package admin
+
+import (
+	"net/http"
+
+	dto "github.com/datpham/user-service-ms/internal/dto/request"
+	"github.com/datpham/user-service-ms/internal/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	adminService   IAdminService
+	webhookService IWebhookService
+}
+
+func New(adminService IAdminService, webhookService IWebhookService) *AdminHandler {
+	return &AdminHandler{adminService, webhookService}
+}
+
+func (h *AdminHandler) ListAuditEvents(c *gin.Context) {
+	var req dto.AuditEventFilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	events, err := h.adminService.ListAuditEvents(c.Request.Context(), &req)
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Success(c, events)
+}
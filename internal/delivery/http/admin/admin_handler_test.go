@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	"github.com/datpham/user-service-ms/internal/middleware"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type fakeAdminService struct {
+	requests []*reqDto.AuditEventFilterRequest
+	admins   map[string]bool
+}
+
+func (s *fakeAdminService) ListAuditEvents(ctx context.Context, req *reqDto.AuditEventFilterRequest) ([]*respDto.AuditEventResponse, error) {
+	s.requests = append(s.requests, req)
+	return []*respDto.AuditEventResponse{{ID: "event-1", SubjectUserID: req.SubjectUserID}}, nil
+}
+
+func (s *fakeAdminService) IsAdmin(ctx context.Context, userID string) (bool, error) {
+	return s.admins[userID], nil
+}
+
+func newTestRouter(service *fakeAdminService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	authenticate := func(c *gin.Context) {
+		c.Set(logger.FieldUserID, c.GetHeader("X-Test-User"))
+	}
+	router.GET(
+		"/admin/audit-events",
+		authenticate, middleware.NewAdminMiddleware(service).Handle(), New(service, nil).ListAuditEvents,
+	)
+
+	return router
+}
+
+func listAuditEvents(router *gin.Engine, userID string, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/admin/audit-events?"+query, nil)
+	req.Header.Set("X-Test-User", userID)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestListAuditEventsBindsFilters(t *testing.T) {
+	service := &fakeAdminService{admins: map[string]bool{"admin-1": true}}
+	router := newTestRouter(service)
+
+	rec := listAuditEvents(router, "admin-1",
+		"event_type=user_login&outcome=failure&from=2026-01-01T00:00:00Z&to=2026-01-02T00:00:00Z"+
+			"&limit=10&offset=20&actor_id=admin-2&user_id=user-1&ip=203.0.113.7",
+	)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	req := service.requests[0]
+	if req.EventType != "user_login" || req.Outcome != "failure" || req.Limit != 10 || req.Offset != 20 {
+		t.Errorf("unexpected event filters: %+v", req.SecurityEventFilterRequest)
+	}
+	if !req.From.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) || !req.To.Equal(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time range: %s - %s", req.From, req.To)
+	}
+	if req.ActorID != "admin-2" || req.SubjectUserID != "user-1" || req.IP != "203.0.113.7" {
+		t.Errorf("unexpected admin filters: %+v", req)
+	}
+
+	var body struct {
+		Data []respDto.AuditEventResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Data) != 1 || body.Data[0].SubjectUserID != "user-1" {
+		t.Errorf("unexpected response: %s", rec.Body)
+	}
+}
+
+func TestListAuditEventsRejectsInvalidFilters(t *testing.T) {
+	service := &fakeAdminService{admins: map[string]bool{"admin-1": true}}
+	router := newTestRouter(service)
+
+	for _, query := range []string{"outcome=unknown", "limit=501", "offset=-1", "from=yesterday"} {
+		if rec := listAuditEvents(router, "admin-1", query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+	if len(service.requests) != 0 {
+		t.Errorf("expected invalid filters not to reach the service, got %d requests", len(service.requests))
+	}
+}
+
+func TestListAuditEventsRequiresAdmin(t *testing.T) {
+	service := &fakeAdminService{admins: map[string]bool{"admin-1": true}}
+	router := newTestRouter(service)
+
+	if rec := listAuditEvents(router, "user-1", "user_id=user-2"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a user without the admin role, got %d", rec.Code)
+	}
+	if len(service.requests) != 0 {
+		t.Error("expected the audit events not to be listed for a user without the admin role")
+	}
+}
@@ -0,0 +1,22 @@
+package admin
+
+import (
+	"context"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+)
+
+type IAdminService interface {
+	ListAuditEvents(ctx context.Context, req *reqDto.AuditEventFilterRequest) ([]*respDto.AuditEventResponse, error)
+}
+
+type IWebhookService interface {
+	CreateSubscription(ctx context.Context, req *reqDto.CreateWebhookSubscriptionRequest) (*respDto.WebhookSubscriptionResponse, error)
+	ListSubscriptions(ctx context.Context) ([]*respDto.WebhookSubscriptionResponse, error)
+	GetSubscription(ctx context.Context, id string) (*respDto.WebhookSubscriptionResponse, error)
+	UpdateSubscription(ctx context.Context, id string, req *reqDto.UpdateWebhookSubscriptionRequest) (*respDto.WebhookSubscriptionResponse, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, subscriptionID string, req *reqDto.WebhookDeliveryFilterRequest) ([]*respDto.WebhookDeliveryResponse, error)
+	ReplayDelivery(ctx context.Context, id string) (*respDto.WebhookDeliveryResponse, error)
+}
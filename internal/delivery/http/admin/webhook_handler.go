@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"net/http"
+
+	dto "github.com/datpham/user-service-ms/internal/dto/request"
+	"github.com/datpham/user-service-ms/internal/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *AdminHandler) CreateWebhookSubscription(c *gin.Context) {
+	var req dto.CreateWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	subscription, err := h.webhookService.CreateSubscription(c.Request.Context(), &req)
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Created(c, subscription)
+}
+
+func (h *AdminHandler) ListWebhookSubscriptions(c *gin.Context) {
+	subscriptions, err := h.webhookService.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Success(c, subscriptions)
+}
+
+func (h *AdminHandler) GetWebhookSubscription(c *gin.Context) {
+	subscription, err := h.webhookService.GetSubscription(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Success(c, subscription)
+}
+
+func (h *AdminHandler) UpdateWebhookSubscription(c *gin.Context) {
+	var req dto.UpdateWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	subscription, err := h.webhookService.UpdateSubscription(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Success(c, subscription)
+}
+
+func (h *AdminHandler) DeleteWebhookSubscription(c *gin.Context) {
+	if err := h.webhookService.DeleteSubscription(c.Request.Context(), c.Param("id")); err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+func (h *AdminHandler) ListWebhookDeliveries(c *gin.Context) {
+	var req dto.WebhookDeliveryFilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Success(c, deliveries)
+}
+
+func (h *AdminHandler) ReplayWebhookDelivery(c *gin.Context) {
+	delivery, err := h.webhookService.ReplayDelivery(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Accepted(c, delivery)
+}
@@ -4,8 +4,10 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	dto "github.com/datpham/user-service-ms/internal/dto/request"
+	"github.com/datpham/user-service-ms/internal/middleware"
 	"github.com/datpham/user-service-ms/internal/pkg/response"
 	"github.com/gin-gonic/gin"
 )
@@ -18,34 +20,30 @@ func New(authService IAuthService) *AuthHandler {
 	return &AuthHandler{authService}
 }
 
-func (h *AuthHandler) Signup(c *gin.Context) {
-	var req dto.UserSignupRequest
+func (h *AuthHandler) RequestPhoneLogin(c *gin.Context) {
+	var req dto.PhoneNumberRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, err)
 		return
 	}
 
-	if err := req.Validate(); err != nil {
-		response.Error(c, http.StatusBadRequest, err)
-		return
-	}
-
-	if err := h.authService.Signup(c.Request.Context(), &req); err != nil {
+	codeResponse, err := h.authService.RequestPhoneLogin(c.Request.Context(), &req)
+	if err != nil {
 		response.ErrorService(c, err)
 		return
 	}
 
-	response.Created(c, response.CREATED)
+	response.Accepted(c, codeResponse)
 }
 
-func (h *AuthHandler) Login(c *gin.Context) {
-	var req dto.UserLoginRequest
+func (h *AuthHandler) PhoneLogin(c *gin.Context) {
+	var req dto.PhoneLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, err)
 		return
 	}
 
-	loginResponse, err := h.authService.Login(c.Request.Context(), &req)
+	loginResponse, err := h.authService.LoginWithPhone(c.Request.Context(), &req)
 	if err != nil {
 		response.ErrorService(c, err)
 		return
@@ -76,23 +74,37 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
+	authHeader := c.GetHeader(middleware.AUTHORIZATION_HEADER)
+	if !strings.HasPrefix(authHeader, middleware.BEARER_PREFIX) {
+		response.Error(c, http.StatusUnauthorized, errors.New("missing bearer token"))
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), strings.TrimPrefix(authHeader, middleware.BEARER_PREFIX)); err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
 	response.Success(c, response.OK)
 }
 
-func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	var req dto.RefreshTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+// IntrospectToken answers with the bare RFC 7662 response instead of the response envelope
+// so standard OAuth clients can read it
+func (h *AuthHandler) IntrospectToken(c *gin.Context) {
+	var req dto.IntrospectTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, err)
 		return
 	}
 
-	loginResponse, err := h.authService.RefreshToken(c.Request.Context(), &req)
+	introspection, err := h.authService.IntrospectToken(c.Request.Context(), &req)
 	if err != nil {
 		response.ErrorService(c, err)
 		return
 	}
 
-	response.Success(c, loginResponse)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, introspection)
 }
 
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
@@ -8,12 +8,14 @@ import (
 )
 
 type IAuthService interface {
-	Signup(ctx context.Context, req *reqDto.UserSignupRequest) error
-	Login(ctx context.Context, req *reqDto.UserLoginRequest) (*respDto.UserLoginResponse, error)
-	RefreshToken(ctx context.Context, req *reqDto.RefreshTokenRequest) (*respDto.UserLoginResponse, error)
+	Logout(ctx context.Context, accessToken string) error
+	IntrospectToken(ctx context.Context, req *reqDto.IntrospectTokenRequest) (*respDto.TokenIntrospectionResponse, error)
 
 	GetGoogleAuthUrl() string
 	ProcessGoogleCallback(ctx context.Context, req *reqDto.GoogleCallbackRequest) (*respDto.UserLoginResponse, error)
 	ForgotPassword(ctx context.Context, req *reqDto.ForgotPasswordRequest) error
 	ResetPassword(ctx context.Context, token int, req *reqDto.ResetPasswordRequest) error
+
+	RequestPhoneLogin(ctx context.Context, req *reqDto.PhoneNumberRequest) (*respDto.PhoneCodeSentResponse, error)
+	LoginWithPhone(ctx context.Context, req *reqDto.PhoneLoginRequest) (*respDto.UserLoginResponse, error)
 }
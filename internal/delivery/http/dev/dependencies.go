@@ -0,0 +1,10 @@
+package dev
+
+import (
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+)
+
+type INotifierService interface {
+	ListTemplates() []*respDto.EmailTemplateResponse
+	PreviewTemplate(name string, locale string) (*respDto.EmailPreviewResponse, error)
+}
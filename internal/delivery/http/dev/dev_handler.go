@@ -0,0 +1,45 @@
+package dev
+
+import (
+	"net/http"
+
+	dto "github.com/datpham/user-service-ms/internal/dto/request"
+	"github.com/datpham/user-service-ms/internal/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// DevHandler serves tooling that is only mounted in development mode
+type DevHandler struct {
+	notifierService INotifierService
+}
+
+func New(notifierService INotifierService) *DevHandler {
+	return &DevHandler{notifierService}
+}
+
+func (h *DevHandler) ListEmailTemplates(c *gin.Context) {
+	response.Success(c, h.notifierService.ListTemplates())
+}
+
+func (h *DevHandler) PreviewEmail(c *gin.Context) {
+	var req dto.EmailPreviewRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	preview, err := h.notifierService.PreviewTemplate(c.Param("template"), req.Locale)
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	switch req.Format {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(preview.HTML))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(preview.Subject+"\n\n"+preview.Text))
+	default:
+		response.Success(c, preview)
+	}
+}
@@ -0,0 +1,12 @@
+package health
+
+import (
+	"context"
+
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+)
+
+type IHealthService interface {
+	Liveness() *respDto.HealthResponse
+	Readiness(ctx context.Context) *respDto.HealthResponse
+}
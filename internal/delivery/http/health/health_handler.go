@@ -0,0 +1,35 @@
+package health
+
+import (
+	"net/http"
+
+	healthSvc "github.com/datpham/user-service-ms/internal/service/health"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler serves the probes of the orchestrator. The responses are bare JSON rather than the
+// response envelope, the status code alone tells whether the probe passed.
+type HealthHandler struct {
+	healthService IHealthService
+}
+
+func New(healthService IHealthService) *HealthHandler {
+	return &HealthHandler{healthService}
+}
+
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, h.healthService.Liveness())
+}
+
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	resp := h.healthService.Readiness(c.Request.Context())
+
+	statusCode := http.StatusOK
+	if resp.Status != healthSvc.StatusUp {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(statusCode, resp)
+}
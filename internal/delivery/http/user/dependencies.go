@@ -0,0 +1,22 @@
+package user
+
+import (
+	"context"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+)
+
+type IUserService interface {
+	ScheduleAccountDeletion(ctx context.Context, userID string) (*respDto.AccountDeletionResponse, error)
+
+	RequestPhoneVerification(ctx context.Context, userID string, req *reqDto.PhoneNumberRequest) (*respDto.PhoneCodeSentResponse, error)
+	VerifyPhone(ctx context.Context, userID string, req *reqDto.VerifyPhoneRequest) (*respDto.UserPhoneResponse, error)
+
+	RequestDataExport(ctx context.Context, userID string, req *reqDto.DataExportRequest) (*respDto.DataExportResponse, error)
+	GetDataExport(ctx context.Context, userID string, exportID string) (*respDto.DataExportResponse, error)
+	GetDataExportFile(ctx context.Context, exportID string, req *reqDto.DataExportDownloadRequest) (*entity.DataExport, error)
+
+	ListSecurityEvents(ctx context.Context, userID string, req *reqDto.SecurityEventFilterRequest) ([]*respDto.AuditEventResponse, error)
+}
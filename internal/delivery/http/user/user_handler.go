@@ -0,0 +1,119 @@
+package user
+
+import (
+	"net/http"
+	"path/filepath"
+
+	dto "github.com/datpham/user-service-ms/internal/dto/request"
+	"github.com/datpham/user-service-ms/internal/middleware"
+	"github.com/datpham/user-service-ms/internal/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	userService IUserService
+}
+
+func New(userService IUserService) *UserHandler {
+	return &UserHandler{userService}
+}
+
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	deletionResponse, err := h.userService.ScheduleAccountDeletion(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Success(c, deletionResponse)
+}
+
+func (h *UserHandler) RequestPhoneVerification(c *gin.Context) {
+	var req dto.PhoneNumberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	codeResponse, err := h.userService.RequestPhoneVerification(c.Request.Context(), middleware.GetUserID(c), &req)
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Accepted(c, codeResponse)
+}
+
+func (h *UserHandler) VerifyPhone(c *gin.Context) {
+	var req dto.VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	phoneResponse, err := h.userService.VerifyPhone(c.Request.Context(), middleware.GetUserID(c), &req)
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Success(c, phoneResponse)
+}
+
+func (h *UserHandler) RequestDataExport(c *gin.Context) {
+	var req dto.DataExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	exportResponse, err := h.userService.RequestDataExport(c.Request.Context(), middleware.GetUserID(c), &req)
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Accepted(c, exportResponse)
+}
+
+func (h *UserHandler) GetDataExport(c *gin.Context) {
+	exportResponse, err := h.userService.GetDataExport(c.Request.Context(), middleware.GetUserID(c), c.Param("id"))
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Success(c, exportResponse)
+}
+
+func (h *UserHandler) DownloadDataExport(c *gin.Context) {
+	var req dto.DataExportDownloadRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	dataExport, err := h.userService.GetDataExportFile(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	c.FileAttachment(dataExport.FilePath, filepath.Base(dataExport.FilePath))
+}
+
+func (h *UserHandler) ListSecurityEvents(c *gin.Context) {
+	var req dto.SecurityEventFilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	events, err := h.userService.ListSecurityEvents(c.Request.Context(), middleware.GetUserID(c), &req)
+	if err != nil {
+		response.ErrorService(c, err)
+		return
+	}
+
+	response.Success(c, events)
+}
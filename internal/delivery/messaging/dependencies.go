@@ -0,0 +1,26 @@
+package messaging
+
+import (
+	"context"
+
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/service/notifier"
+)
+
+type IEventService interface {
+	HandleMailBounced(ctx context.Context, envelope *event.InboundEnvelope, payload *event.MailBouncedPayload) error
+	HandleBillingAccountClosed(
+		ctx context.Context,
+		envelope *event.InboundEnvelope,
+		payload *event.BillingAccountClosedPayload,
+	) error
+	HandleAdminUserRoleGranted(
+		ctx context.Context,
+		envelope *event.InboundEnvelope,
+		payload *event.AdminUserRoleGrantedPayload,
+	) error
+}
+
+type INotifier interface {
+	Notify(ctx context.Context, notification *notifier.Notification) error
+}
@@ -0,0 +1,84 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+)
+
+// EventHandler applies events published by other services to the users of this service
+type EventHandler struct {
+	eventService IEventService
+}
+
+func New(eventService IEventService) *EventHandler {
+	return &EventHandler{eventService}
+}
+
+// Register subscribes the handlers to their routing keys
+func (h *EventHandler) Register(subscriber eventbus.EventSubscriber) {
+	subscriber.Subscribe(event.MailBouncedRoutingKey, h.MailBounced)
+	subscriber.Subscribe(event.BillingAccountClosedRoutingKey, h.BillingAccountClosed)
+	subscriber.Subscribe(event.AdminUserRoleGrantedRoutingKey, h.AdminUserRoleGranted)
+}
+
+func (h *EventHandler) MailBounced(ctx context.Context, message eventbus.Message) error {
+	var payload event.MailBouncedPayload
+	envelope, err := event.DecodeInbound(message.Body, event.MailBounced, &payload)
+	if err != nil {
+		return poison(err)
+	}
+
+	return toDeliveryError(h.eventService.HandleMailBounced(withEventContext(ctx, envelope), envelope, &payload))
+}
+
+func (h *EventHandler) BillingAccountClosed(ctx context.Context, message eventbus.Message) error {
+	var payload event.BillingAccountClosedPayload
+	envelope, err := event.DecodeInbound(message.Body, event.BillingAccountClosed, &payload)
+	if err != nil {
+		return poison(err)
+	}
+
+	return toDeliveryError(h.eventService.HandleBillingAccountClosed(withEventContext(ctx, envelope), envelope, &payload))
+}
+
+func (h *EventHandler) AdminUserRoleGranted(ctx context.Context, message eventbus.Message) error {
+	var payload event.AdminUserRoleGrantedPayload
+	envelope, err := event.DecodeInbound(message.Body, event.AdminUserRoleGranted, &payload)
+	if err != nil {
+		return poison(err)
+	}
+
+	ctx = withEventContext(ctx, envelope)
+	if payload.GrantedBy != "" {
+		ctx = contextutil.WithUserID(ctx, payload.GrantedBy)
+	}
+
+	return toDeliveryError(h.eventService.HandleAdminUserRoleGranted(ctx, envelope, &payload))
+}
+
+// withEventContext carries the correlation ID of the event as the request ID of the audit entries it causes
+func withEventContext(ctx context.Context, envelope *event.InboundEnvelope) context.Context {
+	return contextutil.WithRequestMetadata(ctx, contextutil.RequestMetadata{
+		RequestID: envelope.CorrelationID,
+	})
+}
+
+// toDeliveryError sends events the service rejected as invalid to the dead letter queue, other errors are retried
+func toDeliveryError(err error) error {
+	var serviceErr *customErr.CustomError
+	if errors.As(err, &serviceErr) && serviceErr.Code == customErr.ErrInvalidRequest {
+		return poison(err)
+	}
+
+	return err
+}
+
+func poison(err error) error {
+	return fmt.Errorf("%w: %s", eventbus.ErrPoisonMessage, err.Error())
+}
@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"context"
+
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	"github.com/datpham/user-service-ms/internal/service/notifier"
+)
+
+// NotificationHandler emails users about the events this service publishes for them
+type NotificationHandler struct {
+	notifier INotifier
+}
+
+func NewNotificationHandler(notifier INotifier) *NotificationHandler {
+	return &NotificationHandler{notifier}
+}
+
+// Register subscribes the handlers to the routing keys of the events that send an email
+func (h *NotificationHandler) Register(subscriber eventbus.EventSubscriber) {
+	handlers := map[event.Type]eventbus.Handler{
+		event.UserResetPassword:   h.PasswordResetRequested,
+		event.UserNewDeviceLogin:  h.NewDeviceLogin,
+		event.UserSuspiciousLogin: h.SuspiciousLogin,
+		event.UserDataExportReady: h.DataExportReady,
+	}
+	for eventType, handler := range handlers {
+		definition, err := event.Lookup(eventType)
+		if err != nil {
+			panic(err)
+		}
+		subscriber.Subscribe(definition.RoutingKey, handler)
+	}
+}
+
+func (h *NotificationHandler) PasswordResetRequested(ctx context.Context, message eventbus.Message) error {
+	var payload event.PasswordResetRequestedPayload
+	envelope, err := event.DecodeInbound(message.Body, event.UserResetPassword, &payload)
+	if err != nil {
+		return poison(err)
+	}
+
+	return h.notify(ctx, envelope, notifier.TemplatePasswordReset, payload.Email, &payload)
+}
+
+func (h *NotificationHandler) NewDeviceLogin(ctx context.Context, message eventbus.Message) error {
+	var payload event.LoginDevicePayload
+	envelope, err := event.DecodeInbound(message.Body, event.UserNewDeviceLogin, &payload)
+	if err != nil {
+		return poison(err)
+	}
+
+	return h.notify(ctx, envelope, notifier.TemplateNewDeviceLogin, payload.Email, &payload)
+}
+
+func (h *NotificationHandler) SuspiciousLogin(ctx context.Context, message eventbus.Message) error {
+	var payload event.SuspiciousLoginPayload
+	envelope, err := event.DecodeInbound(message.Body, event.UserSuspiciousLogin, &payload)
+	if err != nil {
+		return poison(err)
+	}
+
+	return h.notify(ctx, envelope, notifier.TemplateSuspiciousLogin, payload.Email, &payload)
+}
+
+func (h *NotificationHandler) DataExportReady(ctx context.Context, message eventbus.Message) error {
+	var payload event.DataExportReadyPayload
+	envelope, err := event.DecodeInbound(message.Body, event.UserDataExportReady, &payload)
+	if err != nil {
+		return poison(err)
+	}
+
+	return h.notify(ctx, envelope, notifier.TemplateDataExportReady, payload.Email, &payload)
+}
+
+func (h *NotificationHandler) notify(
+	ctx context.Context,
+	envelope *event.InboundEnvelope,
+	template string,
+	recipient string,
+	payload any,
+) error {
+	return toDeliveryError(h.notifier.Notify(withEventContext(ctx, envelope), &notifier.Notification{
+		Template:  template,
+		UserID:    envelope.UserID,
+		Recipient: recipient,
+		Payload:   payload,
+	}))
+}
@@ -5,6 +5,7 @@ import "github.com/datpham/user-service-ms/internal/pkg/validatorutil"
 type UserSignupRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
+	Locale   string `json:"locale" binding:"omitempty,bcp47_language_tag"`
 }
 
 func (r *UserSignupRequest) Validate() error {
@@ -33,6 +34,12 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+// IntrospectTokenRequest is the form body of an RFC 7662 introspection request
+type IntrospectTokenRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint" binding:"omitempty,oneof=access_token refresh_token"`
+}
+
 type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
 }
@@ -44,3 +51,41 @@ type VerifyResetPasswordTokenRequest struct {
 type ResetPasswordRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
+
+type PhoneNumberRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required,e164"`
+}
+
+type VerifyPhoneRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type PhoneLoginRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required,e164"`
+	Code        string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// CreateAdminRequest is given on the command line to create an admin, e.g. the first one of a deployment
+type CreateAdminRequest struct {
+	Email    string
+	Username string
+	Password string
+}
+
+func (r *CreateAdminRequest) Validate() error {
+	if err := validatorutil.ValidateEmail(r.Email); err != nil {
+		return err
+	}
+
+	return validatorutil.ValidatePassword(r.Password)
+}
+
+// SetPasswordRequest is given on the command line to replace the password of a user without a reset token
+type SetPasswordRequest struct {
+	Email    string
+	Password string
+}
+
+func (r *SetPasswordRequest) Validate() error {
+	return validatorutil.ValidatePassword(r.Password)
+}
@@ -0,0 +1,7 @@
+package dto
+
+type EmailPreviewRequest struct {
+	Locale string `form:"locale" binding:"omitempty,bcp47_language_tag"`
+	// Format renders the html or text body on its own so it can be opened in a browser, json returns every part
+	Format string `form:"format" binding:"omitempty,oneof=json html text"`
+}
@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+type BatchGetUsersRequest struct {
+	UserIDs []string `json:"user_ids" binding:"required,min=1,max=100,dive,required"`
+}
+
+type DataExportRequest struct {
+	Format string `form:"format" binding:"omitempty,oneof=json zip"`
+}
+
+type DataExportDownloadRequest struct {
+	Expires   string `form:"expires" binding:"required"`
+	Signature string `form:"signature" binding:"required"`
+}
+
+type SecurityEventFilterRequest struct {
+	EventType string    `form:"event_type"`
+	Outcome   string    `form:"outcome" binding:"omitempty,oneof=success failure"`
+	From      time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit     int       `form:"limit" binding:"omitempty,min=1,max=500"`
+	Offset    int       `form:"offset" binding:"omitempty,min=0"`
+}
+
+type AuditEventFilterRequest struct {
+	SecurityEventFilterRequest
+	ActorID       string `form:"actor_id"`
+	SubjectUserID string `form:"user_id"`
+	IP            string `form:"ip"`
+}
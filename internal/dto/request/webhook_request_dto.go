@@ -0,0 +1,21 @@
+package dto
+
+type CreateWebhookSubscriptionRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,required"`
+	// Secret signs the deliveries, a random one is generated when it is empty
+	Secret string `json:"secret" binding:"omitempty,min=16"`
+}
+
+type UpdateWebhookSubscriptionRequest struct {
+	URL        *string  `json:"url" binding:"omitempty,url"`
+	EventTypes []string `json:"event_types" binding:"omitempty,min=1,dive,required"`
+	// Enabled re-enables a subscription that was disabled after failing too often, or disables it
+	Enabled *bool `json:"enabled"`
+}
+
+type WebhookDeliveryFilterRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded failed"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=500"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+}
@@ -1,11 +1,37 @@
 package dto
 
+import "time"
+
 type UserLoginResponse struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
 }
 
+// TokenIntrospectionResponse uses the member names of RFC 7662 instead of camelCase,
+// only Active is set for inactive tokens
+type TokenIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Subject   string `json:"sub,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Role      string `json:"role,omitempty"`
+}
+
 type UserGoogleLoginResponse struct {
 	AccessToken string `json:"accessToken"`
 	Email       string `json:"email"`
 }
+
+type PhoneCodeSentResponse struct {
+	PhoneNumber string    `json:"phoneNumber"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+type UserPhoneResponse struct {
+	PhoneNumber string    `json:"phoneNumber"`
+	VerifiedAt  time.Time `json:"verifiedAt"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+type HealthResponse struct {
+	Status string                               `json:"status"`
+	Checks map[string]*DependencyHealthResponse `json:"checks,omitempty"`
+}
+
+type DependencyHealthResponse struct {
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Reason     string `json:"reason,omitempty"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+type EmailTemplateResponse struct {
+	Name     string   `json:"name"`
+	Locales  []string `json:"locales"`
+	Disabled bool     `json:"disabled"`
+}
+
+type EmailPreviewResponse struct {
+	Template string `json:"template"`
+	Locale   string `json:"locale"`
+	Subject  string `json:"subject"`
+	Text     string `json:"text"`
+	HTML     string `json:"html"`
+}
@@ -0,0 +1,48 @@
+package dto
+
+import "time"
+
+type UserResponse struct {
+	ID              string     `json:"id"`
+	Email           string     `json:"email"`
+	Username        string     `json:"username"`
+	Role            string     `json:"role"`
+	Locale          string     `json:"locale,omitempty"`
+	PhoneNumber     string     `json:"phoneNumber,omitempty"`
+	PhoneVerifiedAt *time.Time `json:"phoneVerifiedAt,omitempty"`
+	Suspended       bool       `json:"suspended"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+type BatchUsersResponse struct {
+	Users       []*UserResponse `json:"users"`
+	NotFoundIDs []string        `json:"notFoundIds"`
+}
+
+type AccountDeletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletionScheduledAt"`
+}
+
+type DataExportResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	// ExpiresAt is when the archive of a ready export is deleted
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	DownloadURL string     `json:"downloadUrl,omitempty"`
+}
+
+type AuditEventResponse struct {
+	ID            string         `json:"id"`
+	ActorID       string         `json:"actorId,omitempty"`
+	SubjectUserID string         `json:"userId,omitempty"`
+	EventType     string         `json:"eventType"`
+	IP            string         `json:"ip,omitempty"`
+	UserAgent     string         `json:"userAgent,omitempty"`
+	RequestID     string         `json:"requestId,omitempty"`
+	Outcome       string         `json:"outcome"`
+	Metadata      map[string]any `json:"metadata,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+}
@@ -3,12 +3,13 @@ package errors
 type ErrorCode string
 
 const (
-	ErrInvalidRequest ErrorCode = "invalid_request"
-	ErrInternalServer ErrorCode = "internal_server_error"
-	ErrNotFound       ErrorCode = "not_found"
-	ErrUnauthorized   ErrorCode = "unauthorized"
-	ErrForbidden      ErrorCode = "forbidden"
-	ErrConflict       ErrorCode = "conflict"
+	ErrInvalidRequest  ErrorCode = "invalid_request"
+	ErrInternalServer  ErrorCode = "internal_server_error"
+	ErrNotFound        ErrorCode = "not_found"
+	ErrUnauthorized    ErrorCode = "unauthorized"
+	ErrForbidden       ErrorCode = "forbidden"
+	ErrConflict        ErrorCode = "conflict"
+	ErrTooManyRequests ErrorCode = "too_many_requests"
 )
 
 type CustomError struct {
@@ -0,0 +1,99 @@
+package event
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+//go:generate go run ./schemagen -out ../../docs/events/schemas
+
+type Type string
+
+const (
+	UserResetPassword   Type = "user_reset_password"
+	UserDeleted         Type = "user_deleted"
+	UserDataExportReady Type = "user_data_export_ready"
+	UserNewDeviceLogin  Type = "user_new_device_login"
+	UserSuspiciousLogin Type = "user_suspicious_login"
+)
+
+// Definition describes a published event: where it is routed and the shape of its payload
+type Definition struct {
+	Type          Type
+	RoutingKey    string
+	SchemaVersion int
+	Description   string
+	Payload       Payload
+}
+
+var catalog = map[Type]Definition{
+	UserResetPassword: {
+		Type:          UserResetPassword,
+		RoutingKey:    "user.password_reset.requested",
+		SchemaVersion: 1,
+		Description:   "A user asked for a password reset token to be sent to them.",
+		Payload:       &PasswordResetRequestedPayload{},
+	},
+	UserDeleted: {
+		Type:          UserDeleted,
+		RoutingKey:    "user.account.deleted",
+		SchemaVersion: 1,
+		Description:   "A user account was anonymized after its deletion grace period, downstream copies must be purged.",
+		Payload:       &UserDeletedPayload{},
+	},
+	UserDataExportReady: {
+		Type:          UserDataExportReady,
+		RoutingKey:    "user.data_export.ready",
+		SchemaVersion: 1,
+		Description:   "A requested data export archive is ready for download.",
+		Payload:       &DataExportReadyPayload{},
+	},
+	UserNewDeviceLogin: {
+		Type:          UserNewDeviceLogin,
+		RoutingKey:    "user.login.new_device",
+		SchemaVersion: 1,
+		Description:   "A user logged in from a device that was not seen before.",
+		Payload:       &LoginDevicePayload{},
+	},
+	UserSuspiciousLogin: {
+		Type:          UserSuspiciousLogin,
+		RoutingKey:    "user.login.suspicious",
+		SchemaVersion: 1,
+		Description:   "A login looks suspicious, e.g. it implies travelling faster than possible since the previous login.",
+		Payload:       &SuspiciousLoginPayload{},
+	},
+}
+
+// Lookup returns the catalog definition of an event type
+func Lookup(eventType Type) (Definition, error) {
+	definition, ok := catalog[eventType]
+	if !ok {
+		return Definition{}, fmt.Errorf("event type %q is not in the event catalog", eventType)
+	}
+
+	return definition, nil
+}
+
+// Definitions returns every catalog definition ordered by event type
+func Definitions() []Definition {
+	definitions := make([]Definition, 0, len(catalog))
+	for _, definition := range catalog {
+		definitions = append(definitions, definition)
+	}
+
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Type < definitions[j].Type
+	})
+
+	return definitions
+}
+
+// SchemaFileName is the name of the JSON Schema file generated for the definition
+func (d Definition) SchemaFileName() string {
+	return fmt.Sprintf("%s.v%d.json", d.Type, d.SchemaVersion)
+}
+
+func (d Definition) payloadType() reflect.Type {
+	return reflect.TypeOf(d.Payload)
+}
@@ -0,0 +1,118 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const schemaDir = "../../docs/events/schemas"
+
+func TestCatalogDefinitions(t *testing.T) {
+	routingKeys := map[string]Type{}
+	for _, definition := range Definitions() {
+		if got := definition.Payload.EventType(); got != definition.Type {
+			t.Errorf("%s: payload belongs to event type %s", definition.Type, got)
+		}
+
+		if definition.SchemaVersion < 1 {
+			t.Errorf("%s: schema version must be at least 1, got %d", definition.Type, definition.SchemaVersion)
+		}
+
+		if !strings.HasPrefix(definition.RoutingKey, "user.") {
+			t.Errorf("%s: routing key %q must start with \"user.\"", definition.Type, definition.RoutingKey)
+		}
+
+		if other, ok := routingKeys[definition.RoutingKey]; ok {
+			t.Errorf("%s: routing key %q is already used by %s", definition.Type, definition.RoutingKey, other)
+		}
+		routingKeys[definition.RoutingKey] = definition.Type
+	}
+}
+
+// TestSchemasAreUpToDate fails when a payload struct changed without regenerating the schemas,
+// run `go generate ./internal/event` and bump the schema version for breaking changes.
+func TestSchemasAreUpToDate(t *testing.T) {
+	for _, definition := range Definitions() {
+		want, err := GenerateSchema(definition)
+		if err != nil {
+			t.Fatalf("%s: failed to generate schema: %v", definition.Type, err)
+		}
+
+		got, err := os.ReadFile(filepath.Join(schemaDir, definition.SchemaFileName()))
+		if err != nil {
+			t.Errorf("%s: failed to read schema file: %v", definition.Type, err)
+			continue
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: %s is out of date, run go generate ./internal/event", definition.Type, definition.SchemaFileName())
+		}
+	}
+}
+
+func TestEnvelopeMatchesSchema(t *testing.T) {
+	for _, definition := range Definitions() {
+		envelope, _, err := NewEnvelope("user-id", definition.Payload)
+		if err != nil {
+			t.Fatalf("%s: failed to create envelope: %v", definition.Type, err)
+		}
+
+		encoded, err := json.Marshal(envelope)
+		if err != nil {
+			t.Fatalf("%s: failed to marshal envelope: %v", definition.Type, err)
+		}
+
+		var message map[string]any
+		if err := json.Unmarshal(encoded, &message); err != nil {
+			t.Fatalf("%s: failed to unmarshal envelope: %v", definition.Type, err)
+		}
+
+		schemaFile, err := os.ReadFile(filepath.Join(schemaDir, definition.SchemaFileName()))
+		if err != nil {
+			t.Fatalf("%s: failed to read schema file: %v", definition.Type, err)
+		}
+
+		var schema jsonSchema
+		if err := json.Unmarshal(schemaFile, &schema); err != nil {
+			t.Fatalf("%s: failed to unmarshal schema: %v", definition.Type, err)
+		}
+
+		assertMatchesSchema(t, string(definition.Type), message, &schema)
+	}
+}
+
+type jsonSchema struct {
+	Properties map[string]*jsonSchema `json:"properties"`
+	Required   []string               `json:"required"`
+	Const      any                    `json:"const"`
+}
+
+func assertMatchesSchema(t *testing.T, path string, message map[string]any, schema *jsonSchema) {
+	t.Helper()
+
+	for _, field := range schema.Required {
+		if _, ok := message[field]; !ok {
+			t.Errorf("%s: required field %q is missing", path, field)
+		}
+	}
+
+	for field, value := range message {
+		property, ok := schema.Properties[field]
+		if !ok {
+			t.Errorf("%s: field %q is not in the schema", path, field)
+			continue
+		}
+
+		if property.Const != nil && property.Const != value {
+			t.Errorf("%s.%s: expected %v, got %v", path, field, property.Const, value)
+		}
+
+		if nested, ok := value.(map[string]any); ok {
+			assertMatchesSchema(t, path+"."+field, nested, property)
+		}
+	}
+}
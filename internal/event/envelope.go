@@ -0,0 +1,39 @@
+package event
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Envelope wraps every published event payload with the metadata consumers rely on
+type Envelope struct {
+	EventID       string `json:"event_id"`
+	EventType     Type   `json:"event_type"`
+	SchemaVersion int    `json:"schema_version"`
+	// IdempotencyKey lets consumers discard redelivered events, it defaults to the event ID
+	IdempotencyKey string    `json:"idempotency_key"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	CorrelationID  string    `json:"correlation_id,omitempty"`
+	UserID         string    `json:"user_id"`
+	Payload        Payload   `json:"payload"`
+}
+
+// NewEnvelope wraps the payload using the schema version of its catalog definition
+func NewEnvelope(userID string, payload Payload) (*Envelope, Definition, error) {
+	definition, err := Lookup(payload.EventType())
+	if err != nil {
+		return nil, Definition{}, err
+	}
+
+	eventID := uuid.New().String()
+	return &Envelope{
+		EventID:        eventID,
+		EventType:      definition.Type,
+		SchemaVersion:  definition.SchemaVersion,
+		IdempotencyKey: eventID,
+		OccurredAt:     time.Now().UTC(),
+		UserID:         userID,
+		Payload:        payload,
+	}, definition, nil
+}
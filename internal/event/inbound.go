@@ -0,0 +1,76 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// inbound event types, published by other services and consumed by this one
+const (
+	MailBounced          Type = "mail_bounced"
+	BillingAccountClosed Type = "billing_account_closed"
+	AdminUserRoleGranted Type = "admin_user_role_granted"
+)
+
+const (
+	MailBouncedRoutingKey          = "mail.bounced"
+	BillingAccountClosedRoutingKey = "billing.account_closed"
+	AdminUserRoleGrantedRoutingKey = "admin.user_role_granted"
+)
+
+const (
+	BounceTypeHard = "hard"
+	BounceTypeSoft = "soft"
+)
+
+var ErrInvalidEnvelope = errors.New("invalid event envelope")
+
+// InboundEnvelope is an envelope received from another service, its payload is decoded by the handler of its type
+type InboundEnvelope struct {
+	EventID       string          `json:"event_id"`
+	EventType     Type            `json:"event_type"`
+	SchemaVersion int             `json:"schema_version"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	UserID        string          `json:"user_id"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+type MailBouncedPayload struct {
+	Email      string `json:"email"`
+	BounceType string `json:"bounce_type"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+type BillingAccountClosedPayload struct {
+	AccountID string `json:"account_id"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type AdminUserRoleGrantedPayload struct {
+	Role      string `json:"role"`
+	GrantedBy string `json:"granted_by,omitempty"`
+}
+
+// DecodeInbound decodes an inbound envelope of the expected type and its payload into payload
+func DecodeInbound(body []byte, eventType Type, payload any) (*InboundEnvelope, error) {
+	var envelope InboundEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEnvelope, err.Error())
+	}
+
+	if envelope.EventID == "" {
+		return nil, fmt.Errorf("%w: missing event_id", ErrInvalidEnvelope)
+	}
+	if envelope.EventType != eventType {
+		return nil, fmt.Errorf("%w: expected event type %s, got %q", ErrInvalidEnvelope, eventType, envelope.EventType)
+	}
+
+	if err := json.Unmarshal(envelope.Payload, payload); err != nil {
+		return nil, fmt.Errorf("%w: invalid %s payload: %s", ErrInvalidEnvelope, eventType, err.Error())
+	}
+
+	return &envelope, nil
+}
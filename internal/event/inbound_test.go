@@ -0,0 +1,42 @@
+package event
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeInbound(t *testing.T) {
+	body := []byte(`{
+		"event_id": "9b7c",
+		"event_type": "mail_bounced",
+		"schema_version": 1,
+		"occurred_at": "2024-05-01T10:00:00Z",
+		"payload": {"email": "user@example.com", "bounce_type": "hard", "reason": "mailbox does not exist"}
+	}`)
+
+	var payload MailBouncedPayload
+	envelope, err := DecodeInbound(body, MailBounced, &payload)
+	if err != nil {
+		t.Fatalf("failed to decode envelope: %v", err)
+	}
+
+	if envelope.EventID != "9b7c" || payload.Email != "user@example.com" || payload.BounceType != BounceTypeHard {
+		t.Errorf("unexpected envelope %+v with payload %+v", envelope, payload)
+	}
+}
+
+func TestDecodeInboundRejectsInvalidEnvelopes(t *testing.T) {
+	tests := map[string]string{
+		"not json":         `not json`,
+		"missing event id": `{"event_type": "mail_bounced", "payload": {}}`,
+		"wrong event type": `{"event_id": "1", "event_type": "billing_account_closed", "payload": {}}`,
+		"invalid payload":  `{"event_id": "1", "event_type": "mail_bounced", "payload": {"email": 42}}`,
+	}
+
+	for name, body := range tests {
+		var payload MailBouncedPayload
+		if _, err := DecodeInbound([]byte(body), MailBounced, &payload); !errors.Is(err, ErrInvalidEnvelope) {
+			t.Errorf("%s: expected ErrInvalidEnvelope, got %v", name, err)
+		}
+	}
+}
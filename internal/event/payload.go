@@ -0,0 +1,53 @@
+package event
+
+import "time"
+
+// Payload is the typed body of an event, each payload belongs to exactly one event type
+type Payload interface {
+	EventType() Type
+}
+
+type PasswordResetRequestedPayload struct {
+	Email              string `json:"email"`
+	ResetPasswordToken int    `json:"reset_password_token"`
+}
+
+func (*PasswordResetRequestedPayload) EventType() Type { return UserResetPassword }
+
+type UserDeletedPayload struct {
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+func (*UserDeletedPayload) EventType() Type { return UserDeleted }
+
+type DataExportReadyPayload struct {
+	Email       string `json:"email"`
+	ExportID    string `json:"export_id"`
+	DownloadURL string `json:"download_url"`
+}
+
+func (*DataExportReadyPayload) EventType() Type { return UserDataExportReady }
+
+type LoginDevicePayload struct {
+	Email     string `json:"email"`
+	DeviceID  string `json:"device_id"`
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+	Country   string `json:"country,omitempty"`
+	City      string `json:"city,omitempty"`
+}
+
+func (*LoginDevicePayload) EventType() Type { return UserNewDeviceLogin }
+
+type SuspiciousLoginPayload struct {
+	LoginDevicePayload
+	Reason            string    `json:"reason"`
+	PreviousDeviceID  string    `json:"previous_device_id"`
+	PreviousCountry   string    `json:"previous_country,omitempty"`
+	PreviousCity      string    `json:"previous_city,omitempty"`
+	PreviousLoginAt   time.Time `json:"previous_login_at"`
+	TravelSpeedKmh    float64   `json:"travel_speed_kmh"`
+	MaxTravelSpeedKmh float64   `json:"max_travel_speed_kmh"`
+}
+
+func (*SuspiciousLoginPayload) EventType() Type { return UserSuspiciousLogin }
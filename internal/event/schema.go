@@ -0,0 +1,44 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/invopop/jsonschema"
+)
+
+const (
+	SchemaIDPrefix = "https://github.com/datpham/user-service-ms/docs/events/schemas/"
+)
+
+// GenerateSchema builds the JSON Schema of the envelope of an event, including its typed payload
+func GenerateSchema(definition Definition) ([]byte, error) {
+	reflector := &jsonschema.Reflector{
+		DoNotReference: true,
+		ExpandedStruct: true,
+	}
+
+	schema := reflector.Reflect(&Envelope{})
+	schema.ID = jsonschema.ID(SchemaIDPrefix + definition.SchemaFileName())
+	schema.Title = string(definition.Type)
+	schema.Description = fmt.Sprintf("%s Routing key: %s", definition.Description, definition.RoutingKey)
+
+	payloadSchema := reflector.ReflectFromType(definition.payloadType().Elem())
+	payloadSchema.Version = ""
+	payloadSchema.ID = ""
+	schema.Properties.Set("payload", payloadSchema)
+
+	if eventType, ok := schema.Properties.Get("event_type"); ok {
+		eventType.Const = string(definition.Type)
+	}
+	if schemaVersion, ok := schema.Properties.Get("schema_version"); ok {
+		schemaVersion.Const = definition.SchemaVersion
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema of %s: %w", definition.Type, err)
+	}
+
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,32 @@
+// Command schemagen writes the JSON Schema of every event in the event catalog.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/datpham/user-service-ms/internal/event"
+)
+
+func main() {
+	outDir := flag.String("out", "docs/events/schemas", "directory the schema files are written to")
+	flag.Parse()
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		log.Fatalf("Failed to create output directory: %v", err)
+	}
+
+	for _, definition := range event.Definitions() {
+		schema, err := event.GenerateSchema(definition)
+		if err != nil {
+			log.Fatalf("Failed to generate schema: %v", err)
+		}
+
+		path := filepath.Join(*outDir, definition.SchemaFileName())
+		if err := os.WriteFile(path, schema, 0o644); err != nil {
+			log.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+}
@@ -84,6 +84,22 @@ func (c *Cache) Incr(ctx context.Context, key string) (int64, error) {
 	return c.cacheClient.Incr(ctx, key).Result()
 }
 
+// IncrWithExpiration increments the counter and (re)sets its expiration in one round trip
+func (c *Cache) IncrWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	key = fmt.Sprintf("%s:%s", ServiceCachePrefix, key)
+
+	var incr *redis.IntCmd
+	if _, err := c.cacheClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, expiration)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
 func (c *Cache) Close() error {
 	return c.cacheClient.Close()
 }
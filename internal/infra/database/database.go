@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -52,6 +53,20 @@ func (d *Database) GetDB() *gorm.DB {
 	return d.DB
 }
 
+// Ping checks the database is reachable, for readiness checks
+func (d *Database) Ping(ctx context.Context) error {
+	if d.DB == nil {
+		return fmt.Errorf("database connection is not initialized")
+	}
+
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %v", err)
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
 func (d *Database) Close() error {
 	if d.DB != nil {
 		sqlDB, err := d.DB.DB()
@@ -65,10 +80,23 @@ func (d *Database) Close() error {
 	return nil
 }
 
-func (d *Database) AutoMigrate(models ...any) error {
-	if d.DB == nil {
-		return fmt.Errorf("database connection is not initialized")
+// Migrate applies the pending schema migrations
+func (d *Database) Migrate(ctx context.Context) error {
+	migrator, err := NewMigrator(d.DB)
+	if err != nil {
+		return err
+	}
+
+	_, err = migrator.Up(ctx, MigrateOptions{})
+	return err
+}
+
+// CheckSchema returns ErrSchemaBehind when a schema migration is pending
+func (d *Database) CheckSchema(ctx context.Context) error {
+	migrator, err := NewMigrator(d.DB)
+	if err != nil {
+		return err
 	}
 
-	return d.DB.AutoMigrate(models...)
+	return migrator.Check(ctx)
 }
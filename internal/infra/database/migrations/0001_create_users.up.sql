@@ -0,0 +1,23 @@
+CREATE TABLE users (
+    id text PRIMARY KEY,
+    email text CONSTRAINT uni_users_email UNIQUE,
+    password text NOT NULL,
+    username text NOT NULL,
+    refresh_token text NOT NULL,
+    role text NOT NULL DEFAULT 'user',
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    deletion_scheduled_at timestamptz,
+    anonymized_at timestamptz,
+    email_undeliverable_at timestamptz,
+    suspended_at timestamptz,
+    suspension_reason text,
+    locale text,
+    phone text,
+    phone_verified_at timestamptz
+);
+
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX idx_users_deletion_scheduled_at ON users (deletion_scheduled_at);
+CREATE UNIQUE INDEX idx_users_phone ON users (phone);
@@ -0,0 +1,13 @@
+CREATE TABLE data_exports (
+    id text PRIMARY KEY,
+    user_id text NOT NULL,
+    status text NOT NULL,
+    format text NOT NULL,
+    file_path text,
+    error text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    completed_at timestamptz
+);
+
+CREATE INDEX idx_data_exports_user_id ON data_exports (user_id);
@@ -0,0 +1,28 @@
+CREATE TABLE audit_events (
+    id text PRIMARY KEY,
+    sequence bigint,
+    actor_id text,
+    subject_user_id text,
+    event_type text NOT NULL,
+    ip text,
+    user_agent text,
+    request_id text,
+    outcome text NOT NULL,
+    metadata jsonb,
+    created_at timestamptz,
+    prev_hash text,
+    hash text
+);
+
+CREATE INDEX idx_audit_events_sequence ON audit_events (sequence);
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX idx_audit_events_subject_user_id ON audit_events (subject_user_id);
+CREATE INDEX idx_audit_events_event_type ON audit_events (event_type);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+
+CREATE TABLE audit_checkpoints (
+    sequence bigint PRIMARY KEY,
+    hash text NOT NULL,
+    signature text NOT NULL,
+    created_at timestamptz
+);
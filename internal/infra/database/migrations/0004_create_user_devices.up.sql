@@ -0,0 +1,16 @@
+CREATE TABLE user_devices (
+    id text PRIMARY KEY,
+    user_id text NOT NULL,
+    fingerprint text NOT NULL,
+    user_agent text,
+    last_ip text,
+    country text,
+    city text,
+    latitude decimal,
+    longitude decimal,
+    first_seen_at timestamptz,
+    last_seen_at timestamptz
+);
+
+CREATE UNIQUE INDEX idx_user_devices_user_fingerprint ON user_devices (user_id, fingerprint);
+CREATE INDEX idx_user_devices_last_seen_at ON user_devices (last_seen_at);
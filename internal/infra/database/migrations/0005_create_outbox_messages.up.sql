@@ -0,0 +1,16 @@
+CREATE TABLE outbox_messages (
+    id text PRIMARY KEY,
+    idempotency_key text NOT NULL,
+    event_type text NOT NULL,
+    routing_key text NOT NULL,
+    payload bytea NOT NULL,
+    status text NOT NULL,
+    attempts bigint NOT NULL,
+    last_error text,
+    next_attempt_at timestamptz,
+    created_at timestamptz,
+    sent_at timestamptz
+);
+
+CREATE UNIQUE INDEX idx_outbox_messages_idempotency_key ON outbox_messages (idempotency_key);
+CREATE INDEX idx_outbox_messages_status_next_attempt ON outbox_messages (status, next_attempt_at);
@@ -0,0 +1,5 @@
+CREATE TABLE inbox_messages (
+    event_id text PRIMARY KEY,
+    event_type text NOT NULL,
+    processed_at timestamptz
+);
@@ -0,0 +1,30 @@
+CREATE TABLE webhook_subscriptions (
+    id text PRIMARY KEY,
+    url text NOT NULL,
+    event_types jsonb NOT NULL,
+    secret text NOT NULL,
+    enabled boolean NOT NULL DEFAULT true,
+    consecutive_failures bigint NOT NULL DEFAULT 0,
+    disabled_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE webhook_deliveries (
+    id text PRIMARY KEY,
+    subscription_id text NOT NULL,
+    event_id text NOT NULL,
+    event_type text NOT NULL,
+    payload bytea NOT NULL,
+    status text NOT NULL,
+    attempts bigint NOT NULL,
+    next_attempt_at timestamptz,
+    last_status_code bigint,
+    last_error text,
+    last_attempt_at timestamptz,
+    delivered_at timestamptz,
+    created_at timestamptz
+);
+
+CREATE INDEX idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX idx_webhook_deliveries_status_next_attempt ON webhook_deliveries (status, next_attempt_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_audit_events_sequence;
+CREATE INDEX idx_audit_events_sequence ON audit_events (sequence);
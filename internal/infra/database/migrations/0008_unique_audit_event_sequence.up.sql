@@ -0,0 +1,3 @@
+-- the hash chain links every audit event to the one before it, two events must never share a sequence
+DROP INDEX IF EXISTS idx_audit_events_sequence;
+CREATE UNIQUE INDEX idx_audit_events_sequence ON audit_events (sequence);
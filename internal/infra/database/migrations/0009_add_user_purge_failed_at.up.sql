@@ -0,0 +1,2 @@
+-- failed purges are retried after the accounts that did not fail, so a few of them cannot stall the purge
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purge_failed_at timestamptz;
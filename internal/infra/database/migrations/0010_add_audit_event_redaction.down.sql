@@ -0,0 +1,2 @@
+ALTER TABLE audit_events DROP COLUMN IF EXISTS redacted_at;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS details_hash;
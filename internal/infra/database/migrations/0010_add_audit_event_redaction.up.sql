@@ -0,0 +1,3 @@
+-- the chain hash of new audit events covers a hash of their details, so purging an account can erase them
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS details_hash text NOT NULL DEFAULT '';
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS redacted_at timestamptz;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_data_exports_status;
+
+ALTER TABLE data_exports DROP COLUMN IF EXISTS lease_expires_at;
+ALTER TABLE data_exports DROP COLUMN IF EXISTS attempts;
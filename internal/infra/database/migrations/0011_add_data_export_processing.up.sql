@@ -0,0 +1,5 @@
+-- exports are claimed by workers with a lease, so the ones whose worker stopped are processed again
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS attempts bigint NOT NULL DEFAULT 0;
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS lease_expires_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_status ON data_exports (status);
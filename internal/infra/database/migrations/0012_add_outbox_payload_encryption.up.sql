@@ -0,0 +1,2 @@
+-- payloads carry reset tokens and download URLs, new ones are stored encrypted
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS payload_encrypted boolean NOT NULL DEFAULT false;
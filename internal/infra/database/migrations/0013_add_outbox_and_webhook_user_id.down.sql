@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_user_id;
+DROP INDEX IF EXISTS idx_outbox_messages_user_id;
+
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS user_id;
+ALTER TABLE outbox_messages DROP COLUMN IF EXISTS user_id;
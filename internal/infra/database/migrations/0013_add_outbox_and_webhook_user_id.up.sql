@@ -0,0 +1,10 @@
+-- the messages and deliveries of a user are deleted when the account is purged
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS user_id text;
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS user_id text;
+
+-- encrypted payloads cannot be read here, their messages keep no user ID
+UPDATE outbox_messages SET user_id = convert_from(payload, 'UTF8')::jsonb ->> 'user_id' WHERE NOT payload_encrypted;
+UPDATE webhook_deliveries SET user_id = convert_from(payload, 'UTF8')::jsonb ->> 'user_id';
+
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_user_id ON outbox_messages (user_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_user_id ON webhook_deliveries (user_id);
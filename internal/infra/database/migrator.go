@@ -0,0 +1,369 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const (
+	// migrationLockKey serializes the migration runs of instances that start at the same time
+	migrationLockKey = 7_031_030
+	migrationTable   = "schema_migrations"
+
+	createMigrationTable = `CREATE TABLE IF NOT EXISTS ` + migrationTable + ` (
+    version bigint PRIMARY KEY,
+    name text NOT NULL,
+    checksum text NOT NULL,
+    applied_at timestamptz NOT NULL
+)`
+)
+
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// migrationFileName is <version>_<name>.<up|down>.sql, e.g. 0001_create_users.up.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the sha256 of the up script, recorded when the migration is applied
+	Checksum string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// AppliedMigration is a row of the migration table
+type AppliedMigration struct {
+	Version   int64 `gorm:"primary_key;autoIncrement:false"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (AppliedMigration) TableName() string {
+	return migrationTable
+}
+
+type MigrationState string
+
+const (
+	MigrationStateApplied MigrationState = "applied"
+	MigrationStatePending MigrationState = "pending"
+	// MigrationStateChanged is a migration whose up script no longer matches the checksum recorded when it was applied
+	MigrationStateChanged MigrationState = "changed"
+	// MigrationStateUnknown is a migration applied by another build, e.g. a newer one
+	MigrationStateUnknown MigrationState = "unknown"
+)
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	State     MigrationState
+	AppliedAt *time.Time
+}
+
+type MigrateOptions struct {
+	// DryRun returns the migrations that would run without running them
+	DryRun bool
+	// Steps bounds how many migrations run: every pending one when up by default, the last applied one when down
+	Steps int
+}
+
+// Migrator applies the versioned SQL migrations embedded in the binary
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations: %w", err)
+	}
+
+	migrations, err := loadMigrations(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// loadMigrations reads the migration scripts at the root of files, ordered by version
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+
+		script, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(script)
+			sum := sha256.Sum256(script)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down script", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return int(a.Version - b.Version) })
+
+	return migrations, nil
+}
+
+// Up applies the pending migrations in version order, each in its own transaction
+func (m *Migrator) Up(ctx context.Context, opts MigrateOptions) ([]Migration, error) {
+	var ran []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyChecksums(m.migrations, applied); err != nil {
+			return err
+		}
+
+		pending := pendingMigrations(m.migrations, applied)
+		if opts.Steps > 0 && len(pending) > opts.Steps {
+			pending = pending[:opts.Steps]
+		}
+		if opts.DryRun {
+			ran = pending
+			return nil
+		}
+
+		if err := conn.Exec(createMigrationTable).Error; err != nil {
+			return fmt.Errorf("failed to create the migration table: %w", err)
+		}
+
+		for _, migration := range pending {
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+
+				return tx.Create(&AppliedMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					Checksum:  migration.Checksum,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", migration, err)
+			}
+			ran = append(ran, migration)
+		}
+
+		return nil
+	})
+
+	return ran, err
+}
+
+// Down reverts the last applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, opts MigrateOptions) ([]Migration, error) {
+	steps := max(opts.Steps, 1)
+
+	var ran []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		var reverted []Migration
+		for _, row := range slices.Backward(applied) {
+			if len(reverted) == steps {
+				break
+			}
+
+			i := slices.IndexFunc(m.migrations, func(migration Migration) bool { return migration.Version == row.Version })
+			if i < 0 {
+				return fmt.Errorf("migration %04d_%s was applied by another build and cannot be reverted", row.Version, row.Name)
+			}
+			reverted = append(reverted, m.migrations[i])
+		}
+		if opts.DryRun {
+			ran = reverted
+			return nil
+		}
+
+		for _, migration := range reverted {
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Down).Error; err != nil {
+					return err
+				}
+
+				return tx.Delete(&AppliedMigration{Version: migration.Version}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", migration, err)
+			}
+			ran = append(ran, migration)
+		}
+
+		return nil
+	})
+
+	return ran, err
+}
+
+// Status lists the migrations of this build and the ones applied by other builds, by version
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	appliedByVersion := map[int64]AppliedMigration{}
+	for _, row := range applied {
+		appliedByVersion[row.Version] = row
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name, State: MigrationStatePending}
+		if row, ok := appliedByVersion[migration.Version]; ok {
+			status.State = MigrationStateApplied
+			if row.Checksum != migration.Checksum {
+				status.State = MigrationStateChanged
+			}
+			status.AppliedAt = &row.AppliedAt
+			delete(appliedByVersion, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range appliedByVersion {
+		statuses = append(statuses, MigrationStatus{
+			Version:   row.Version,
+			Name:      row.Name,
+			State:     MigrationStateUnknown,
+			AppliedAt: &row.AppliedAt,
+		})
+	}
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int { return int(a.Version - b.Version) })
+
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind when a migration of this build was not applied, and an error when an applied
+// one was changed since. Migrations applied by a newer build are accepted, so a rolling deployment can
+// migrate before the old instances are replaced.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksums(m.migrations, applied); err != nil {
+		return err
+	}
+
+	if pending := pendingMigrations(m.migrations, applied); len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migrations from %s, run migrate up", ErrSchemaBehind, len(pending), pending[0])
+	}
+
+	return nil
+}
+
+// withLock runs fn while holding the migration lock. The lock belongs to a database session, so fn gets
+// the one connection it was taken on.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+			return fmt.Errorf("failed to acquire the migration lock: %w", err)
+		}
+		// unlocked even when ctx is done, the connection goes back to the pool
+		defer conn.WithContext(context.WithoutCancel(ctx)).Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+
+		return fn(conn)
+	})
+}
+
+// applied returns the applied migrations by version, none when the migration table does not exist yet
+func (m *Migrator) applied(db *gorm.DB) ([]AppliedMigration, error) {
+	if !db.Migrator().HasTable(&AppliedMigration{}) {
+		return nil, nil
+	}
+
+	var applied []AppliedMigration
+	if err := db.Order("version").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// verifyChecksums fails when an applied migration was edited, the schema would no longer match its scripts
+func verifyChecksums(migrations []Migration, applied []AppliedMigration) error {
+	for _, row := range applied {
+		i := slices.IndexFunc(migrations, func(migration Migration) bool { return migration.Version == row.Version })
+		if i >= 0 && migrations[i].Checksum != row.Checksum {
+			return fmt.Errorf("migration %s was changed after it was applied", migrations[i])
+		}
+	}
+
+	return nil
+}
+
+// pendingMigrations are the migrations that were not applied, including ones older than the last applied
+func pendingMigrations(migrations []Migration, applied []AppliedMigration) []Migration {
+	var pending []Migration
+	for _, migration := range migrations {
+		if !slices.ContainsFunc(applied, func(row AppliedMigration) bool { return row.Version == migration.Version }) {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatalf("open migrations: %v", err)
+	}
+
+	migrations, err := loadMigrations(files)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != int64(i+1) {
+			t.Fatalf("expected version %d, got %s", i+1, migration)
+		}
+		if migration.Checksum == "" {
+			t.Fatalf("expected a checksum for %s", migration)
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations(fstest.MapFS{
+		"0002_add_locale.up.sql":     {Data: []byte("ALTER TABLE users ADD COLUMN locale text;")},
+		"0002_add_locale.down.sql":   {Data: []byte("ALTER TABLE users DROP COLUMN locale;")},
+		"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id text);")},
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	})
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+
+	if len(migrations) != 2 || migrations[0].String() != "0001_create_users" || migrations[1].String() != "0002_add_locale" {
+		t.Fatalf("expected the migrations by version, got %v", migrations)
+	}
+	if migrations[0].Down != "DROP TABLE users;" || migrations[0].Checksum == migrations[1].Checksum {
+		t.Fatalf("unexpected migration %+v", migrations[0])
+	}
+
+	invalid := map[string]fstest.MapFS{
+		"missing down": {
+			"0001_create_users.up.sql": {Data: []byte("CREATE TABLE users (id text);")},
+		},
+		"unexpected file": {
+			"create_users.sql": {Data: []byte("CREATE TABLE users (id text);")},
+		},
+		"renamed": {
+			"0001_create_users.up.sql":    {Data: []byte("CREATE TABLE users (id text);")},
+			"0001_create_people.down.sql": {Data: []byte("DROP TABLE users;")},
+		},
+	}
+	for name, files := range invalid {
+		if _, err := loadMigrations(files); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "create_users", Checksum: "a"},
+		{Version: 2, Name: "create_devices", Checksum: "b"},
+		{Version: 3, Name: "add_locale", Checksum: "c"},
+	}
+
+	// a migration merged after a newer one was applied is still pending
+	applied := []AppliedMigration{
+		{Version: 1, Name: "create_users", Checksum: "a"},
+		{Version: 3, Name: "add_locale", Checksum: "c"},
+		{Version: 4, Name: "from_a_newer_build", Checksum: "d"},
+	}
+
+	pending := pendingMigrations(migrations, applied)
+	if len(pending) != 1 || pending[0].Version != 2 {
+		t.Fatalf("expected migration 2 to be pending, got %v", pending)
+	}
+
+	if err := verifyChecksums(migrations, applied); err != nil {
+		t.Fatalf("expected matching checksums, got %v", err)
+	}
+
+	applied[0].Checksum = "edited"
+	if err := verifyChecksums(migrations, applied); err == nil {
+		t.Fatal("expected an edited migration to be reported")
+	}
+}
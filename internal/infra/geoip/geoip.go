@@ -0,0 +1,70 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+type Location struct {
+	Country   string
+	City      string
+	Latitude  float64
+	Longitude float64
+}
+
+// GeoIP resolves IP addresses to locations using an offline MaxMind database file
+type GeoIP struct {
+	reader *geoip2.Reader
+}
+
+// NewGeoIP opens the database at path, an empty path disables lookups
+func NewGeoIP(path string) (*GeoIP, error) {
+	if path == "" {
+		return &GeoIP{}, nil
+	}
+
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geoip database: %v", err)
+	}
+
+	return &GeoIP{reader: reader}, nil
+}
+
+// Lookup returns the location of the ip, or nil when lookups are disabled or the ip is unknown
+func (g *GeoIP) Lookup(ip string) (*Location, error) {
+	if g.reader == nil {
+		return nil, nil
+	}
+
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return nil, fmt.Errorf("invalid ip address: %s", ip)
+	}
+
+	record, err := g.reader.City(parsedIP)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Location.Latitude == 0 && record.Location.Longitude == 0 {
+		return nil, nil
+	}
+
+	return &Location{
+		Country:   record.Country.IsoCode,
+		City:      record.City.Names["en"],
+		Latitude:  record.Location.Latitude,
+		Longitude: record.Location.Longitude,
+	}, nil
+}
+
+func (g *GeoIP) Close() error {
+	if g.reader == nil {
+		return nil
+	}
+
+	return g.reader.Close()
+}
@@ -0,0 +1,280 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/metrics"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	DefaultTopic          = "user-events"
+	DefaultConsumerTopic  = "user-service.inbound"
+	DefaultGroupID        = "user-service"
+	DefaultPublishTimeout = time.Second * 5
+
+	DefaultMaxAttempts     = 5
+	DefaultRetryDelay      = time.Second * 10
+	DefaultShutdownTimeout = time.Second * 30
+
+	DeadLetterTopicSuffix = ".dlq"
+
+	// message headers, the routing key is carried in a header since every event shares the topic
+	HeaderRoutingKey = "routing-key"
+	HeaderMessageID  = "message-id"
+	HeaderAttempts   = "x-attempts"
+	HeaderError      = "x-error"
+)
+
+// EventBus publishes every event to a single topic with its routing key in a header, and consumes
+// the consumer topic in a consumer group, dispatching messages by the routing key header.
+//
+// Events are keyed by the user they are about, so the events of a user land on one partition and
+// are consumed in the order they were published.
+//
+// Messages of a partition are handled one at a time to keep their order. A failed message is
+// retried in place after RetryDelay, after MaxAttempts or right away for poison messages it is
+// written to <consumer topic>.dlq. Offsets are committed once a message was handled or dead-lettered.
+type EventBus struct {
+	eventbus.Router
+
+	logger         *logger.Logger
+	config         config.KafkaConfig
+	consumerConfig config.ConsumerConfig
+
+	writer           messageWriter
+	deadLetterWriter messageWriter
+	newReader        func(cfg kafka.ReaderConfig) messageReader
+}
+
+type messageWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+var _ eventbus.EventBus = (*EventBus)(nil)
+
+func NewEventBus(logger *logger.Logger, cfg config.KafkaConfig, consumerCfg config.ConsumerConfig) (*EventBus, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("no kafka brokers configured")
+	}
+	if cfg.Topic == "" {
+		cfg.Topic = DefaultTopic
+	}
+	if cfg.ConsumerTopic == "" {
+		cfg.ConsumerTopic = DefaultConsumerTopic
+	}
+	if cfg.GroupID == "" {
+		cfg.GroupID = DefaultGroupID
+	}
+	if cfg.PublishTimeout <= 0 {
+		cfg.PublishTimeout = DefaultPublishTimeout
+	}
+	if consumerCfg.MaxAttempts <= 0 {
+		consumerCfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if consumerCfg.RetryDelay <= 0 {
+		consumerCfg.RetryDelay = DefaultRetryDelay
+	}
+	if consumerCfg.ShutdownTimeout <= 0 {
+		consumerCfg.ShutdownTimeout = DefaultShutdownTimeout
+	}
+
+	return &EventBus{
+		logger:           logger,
+		config:           cfg,
+		consumerConfig:   consumerCfg,
+		writer:           newWriter(cfg, cfg.Topic),
+		deadLetterWriter: newWriter(cfg, cfg.ConsumerTopic+DeadLetterTopicSuffix),
+		newReader: func(cfg kafka.ReaderConfig) messageReader {
+			return kafka.NewReader(cfg)
+		},
+	}, nil
+}
+
+func newWriter(cfg config.KafkaConfig, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		WriteTimeout: cfg.PublishTimeout,
+	}
+}
+
+// Publish writes the message keyed by the user of its event and waits for every in-sync replica to store it
+func (b *EventBus) Publish(ctx context.Context, topic string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, b.config.PublishTimeout)
+	defer cancel()
+
+	if err := b.writer.WriteMessages(ctx, kafka.Message{
+		Key:   messageKey(body),
+		Value: body,
+		Headers: []kafka.Header{
+			{Key: HeaderRoutingKey, Value: []byte(topic)},
+			{Key: HeaderMessageID, Value: []byte(uuid.New().String())},
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	return nil
+}
+
+// Start consumes the consumer topic until ctx is cancelled, the message being handled is finished
+// first unless that takes longer than ShutdownTimeout
+func (b *EventBus) Start(ctx context.Context) error {
+	if len(b.Patterns()) == 0 {
+		b.logger.Warnf("topic: %s, no message handlers registered, not consuming", b.config.ConsumerTopic)
+		return nil
+	}
+
+	reader := b.newReader(kafka.ReaderConfig{
+		Brokers: b.config.Brokers,
+		GroupID: b.config.GroupID,
+		Topic:   b.config.ConsumerTopic,
+	})
+	defer reader.Close()
+
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(b.consumerConfig.ShutdownTimeout, cancelHandlers)
+	})
+	defer stop()
+
+	for {
+		message, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("failed to fetch message: %w", err)
+		}
+
+		if !b.handle(ctx, handlerCtx, message) {
+			// stopped while waiting to retry, the uncommitted message is delivered again after a restart
+			return nil
+		}
+
+		if err := reader.CommitMessages(handlerCtx, message); err != nil {
+			b.logger.Errorf("topic: %s, offset: %d, failed to commit message: %s", message.Topic, message.Offset, err.Error())
+		}
+	}
+}
+
+func (b *EventBus) Close() error {
+	return errors.Join(b.writer.Close(), b.deadLetterWriter.Close())
+}
+
+// handle dispatches the message until it succeeds or is dead-lettered, it returns false when ctx
+// was cancelled before that
+func (b *EventBus) handle(ctx context.Context, handlerCtx context.Context, message kafka.Message) bool {
+	msg := toMessage(message)
+
+	for {
+		pattern, err := b.Dispatch(handlerCtx, msg)
+		if err == nil {
+			metrics.ConsumerMessagesTotal.WithLabelValues(pattern, metrics.ConsumeOutcomeAcked).Inc()
+			return true
+		}
+
+		if errors.Is(err, eventbus.ErrPoisonMessage) || msg.Attempt >= b.consumerConfig.MaxAttempts {
+			b.logger.Errorf(
+				"messageId: %s, routingKey: %s, attempts: %d, moving message to the dead letter topic: %s",
+				msg.ID, msg.Topic, msg.Attempt, err.Error(),
+			)
+			if err := b.deadLetter(handlerCtx, message, msg.Attempt, err); err != nil {
+				b.logger.Errorf("messageId: %s, failed to move message to the dead letter topic: %s", msg.ID, err.Error())
+				return false
+			}
+
+			metrics.ConsumerMessagesTotal.WithLabelValues(pattern, metrics.ConsumeOutcomeDeadLettered).Inc()
+			return true
+		}
+
+		b.logger.Warnf(
+			"messageId: %s, routingKey: %s, attempt: %d, failed to handle message, retrying in %s: %s",
+			msg.ID, msg.Topic, msg.Attempt, b.consumerConfig.RetryDelay, err.Error(),
+		)
+		metrics.ConsumerMessagesTotal.WithLabelValues(pattern, metrics.ConsumeOutcomeRetried).Inc()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(b.consumerConfig.RetryDelay):
+		}
+		msg.Attempt++
+	}
+}
+
+func (b *EventBus) deadLetter(ctx context.Context, message kafka.Message, attempts int, cause error) error {
+	headers := append([]kafka.Header(nil), message.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+	)
+
+	return b.deadLetterWriter.WriteMessages(ctx, kafka.Message{
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+	})
+}
+
+// messageKey returns the user ID of the event envelope, events without a user are their own aggregate
+// and are keyed by their event ID. Bodies that are not envelopes get no key and are spread over the partitions.
+func messageKey(body []byte) []byte {
+	var envelope struct {
+		EventID string `json:"event_id"`
+		UserID  string `json:"user_id"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil
+	}
+
+	if envelope.UserID != "" {
+		return []byte(envelope.UserID)
+	}
+	if envelope.EventID != "" {
+		return []byte(envelope.EventID)
+	}
+
+	return nil
+}
+
+func toMessage(message kafka.Message) eventbus.Message {
+	headers := make(map[string]string, len(message.Headers))
+	for _, header := range message.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+
+	routingKey := headers[HeaderRoutingKey]
+	if routingKey == "" {
+		routingKey = string(message.Key)
+	}
+
+	return eventbus.Message{
+		ID:      headers[HeaderMessageID],
+		Topic:   routingKey,
+		Body:    message.Value,
+		Headers: headers,
+		Attempt: 1,
+	}
+}
@@ -0,0 +1,265 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, messages ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.messages = append(w.messages, messages...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	return nil
+}
+
+func (w *fakeWriter) written() []kafka.Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]kafka.Message(nil), w.messages...)
+}
+
+// fakeReader hands out the queued messages, then blocks until the context is cancelled
+type fakeReader struct {
+	mu        sync.Mutex
+	config    kafka.ReaderConfig
+	messages  []kafka.Message
+	committed []kafka.Message
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		message := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return message, nil
+	}
+	r.mu.Unlock()
+
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, messages ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.committed = append(r.committed, messages...)
+	return nil
+}
+
+func (r *fakeReader) Close() error {
+	return nil
+}
+
+func (r *fakeReader) commits() []kafka.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]kafka.Message(nil), r.committed...)
+}
+
+func newTestEventBus(t *testing.T, consumerCfg config.ConsumerConfig, messages ...kafka.Message) (*EventBus, *fakeWriter, *fakeWriter, *fakeReader) {
+	t.Helper()
+
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+	bus, err := NewEventBus(testLogger, config.KafkaConfig{Brokers: []string{"localhost:9092"}}, consumerCfg)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+
+	writer, deadLetterWriter := &fakeWriter{}, &fakeWriter{}
+	reader := &fakeReader{messages: messages}
+	bus.writer = writer
+	bus.deadLetterWriter = deadLetterWriter
+	bus.newReader = func(cfg kafka.ReaderConfig) messageReader {
+		reader.config = cfg
+		return reader
+	}
+
+	return bus, writer, deadLetterWriter, reader
+}
+
+// waitFor polls condition until it holds or a second passed
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
+
+func TestNewEventBusRequiresBrokers(t *testing.T) {
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+	if _, err := NewEventBus(testLogger, config.KafkaConfig{}, config.ConsumerConfig{}); err == nil {
+		t.Fatal("expected an error without brokers")
+	}
+}
+
+func TestToMessage(t *testing.T) {
+	message := toMessage(kafka.Message{
+		Key:   []byte("mail.bounced"),
+		Value: []byte(`{}`),
+		Headers: []kafka.Header{
+			{Key: HeaderRoutingKey, Value: []byte("mail.bounced")},
+			{Key: HeaderMessageID, Value: []byte("42")},
+		},
+	})
+	if message.Topic != "mail.bounced" || message.ID != "42" || message.Attempt != 1 {
+		t.Errorf("unexpected message: %+v", message)
+	}
+
+	// messages produced by other clients may only carry the routing key as message key
+	if message := toMessage(kafka.Message{Key: []byte("billing.account_closed")}); message.Topic != "billing.account_closed" {
+		t.Errorf("expected the key to be used as routing key, got %q", message.Topic)
+	}
+}
+
+func TestPublishKeysMessagesByUser(t *testing.T) {
+	bus, writer, _, _ := newTestEventBus(t, config.ConsumerConfig{})
+	ctx := context.Background()
+
+	bodies := []string{
+		`{"event_id":"evt-1","user_id":"user-1","event_type":"user_deleted"}`,
+		`{"event_id":"evt-2","user_id":"","event_type":"user_created"}`,
+		`not an envelope`,
+	}
+	for _, body := range bodies {
+		if err := bus.Publish(ctx, "user.account.deleted", []byte(body)); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	messages := writer.written()
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	for i, key := range []string{"user-1", "evt-2", ""} {
+		if string(messages[i].Key) != key {
+			t.Errorf("message %d: expected key %q, got %q", i, key, messages[i].Key)
+		}
+		if string(messages[i].Value) != bodies[i] {
+			t.Errorf("message %d: unexpected value %s", i, messages[i].Value)
+		}
+	}
+
+	message := toMessage(messages[0])
+	if message.Topic != "user.account.deleted" || message.ID == "" {
+		t.Errorf("expected the routing key and message id headers, got %+v", message)
+	}
+}
+
+func TestStartConsumesAndCommitsMessages(t *testing.T) {
+	consumed := kafka.Message{
+		Topic:   DefaultConsumerTopic,
+		Offset:  1,
+		Value:   []byte(`{"user_id":"user-1"}`),
+		Headers: []kafka.Header{{Key: HeaderRoutingKey, Value: []byte("mail.bounced")}, {Key: HeaderMessageID, Value: []byte("msg-1")}},
+	}
+	poison := kafka.Message{
+		Topic:   DefaultConsumerTopic,
+		Offset:  2,
+		Key:     []byte("user-2"),
+		Value:   []byte(`{}`),
+		Headers: []kafka.Header{{Key: HeaderRoutingKey, Value: []byte("mail.unknown")}, {Key: HeaderMessageID, Value: []byte("msg-2")}},
+	}
+	bus, _, deadLetterWriter, reader := newTestEventBus(t, config.ConsumerConfig{}, consumed, poison)
+
+	var (
+		mu       sync.Mutex
+		received []eventbus.Message
+	)
+	bus.Subscribe("mail.bounced", func(_ context.Context, message eventbus.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, message)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- bus.Start(ctx) }()
+
+	waitFor(t, func() bool { return len(reader.commits()) == 2 })
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("expected a clean stop, got %v", err)
+	}
+
+	if reader.config.Topic != DefaultConsumerTopic || reader.config.GroupID != DefaultGroupID {
+		t.Errorf("unexpected reader config: %+v", reader.config)
+	}
+	if len(received) != 1 || received[0].ID != "msg-1" || string(received[0].Body) != `{"user_id":"user-1"}` {
+		t.Fatalf("expected the handled message to be received once, got %+v", received)
+	}
+
+	deadLettered := deadLetterWriter.written()
+	if len(deadLettered) != 1 || string(deadLettered[0].Key) != "user-2" {
+		t.Fatalf("expected the unhandled message to be dead-lettered with its key, got %+v", deadLettered)
+	}
+	headers := toMessage(deadLettered[0]).Headers
+	if headers[HeaderAttempts] != "1" || headers[HeaderError] == "" {
+		t.Errorf("expected the attempts and error headers, got %v", headers)
+	}
+}
+
+func TestStartRetriesFailedMessagesInPlace(t *testing.T) {
+	message := kafka.Message{
+		Topic:   DefaultConsumerTopic,
+		Headers: []kafka.Header{{Key: HeaderRoutingKey, Value: []byte("mail.bounced")}},
+	}
+	bus, _, deadLetterWriter, reader := newTestEventBus(t, config.ConsumerConfig{
+		MaxAttempts: 3,
+		RetryDelay:  time.Millisecond,
+	}, message)
+
+	var (
+		mu       sync.Mutex
+		attempts []int
+	)
+	bus.Subscribe("mail.bounced", func(_ context.Context, message eventbus.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, message.Attempt)
+		if message.Attempt < 2 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- bus.Start(ctx) }()
+
+	waitFor(t, func() bool { return len(reader.commits()) == 1 })
+	cancel()
+	<-done
+
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("expected the message to succeed on its second attempt, got attempts %v", attempts)
+	}
+	if len(deadLetterWriter.written()) != 0 {
+		t.Error("expected a retried message not to be dead-lettered")
+	}
+}
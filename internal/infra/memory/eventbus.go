@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultMaxAttempts = 5
+)
+
+// EventBus is an in-process event bus for tests. Published messages are recorded and delivered
+// synchronously to the matching handler, which is retried right away up to MaxAttempts times.
+type EventBus struct {
+	eventbus.Router
+
+	maxAttempts int
+
+	mu           sync.Mutex
+	published    []eventbus.Message
+	deadLettered []eventbus.Message
+}
+
+var _ eventbus.EventBus = (*EventBus)(nil)
+
+func NewEventBus(maxAttempts int) *EventBus {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	return &EventBus{maxAttempts: maxAttempts}
+}
+
+// Publish records the message and delivers it before returning, handler errors are not returned
+func (b *EventBus) Publish(ctx context.Context, topic string, body []byte) error {
+	message := eventbus.Message{
+		ID:      uuid.New().String(),
+		Topic:   topic,
+		Body:    append([]byte(nil), body...),
+		Headers: map[string]string{},
+	}
+
+	b.mu.Lock()
+	b.published = append(b.published, message)
+	b.mu.Unlock()
+
+	if len(b.Patterns()) > 0 {
+		b.deliver(ctx, message)
+	}
+
+	return nil
+}
+
+// Start blocks until ctx is cancelled, messages are delivered as they are published
+func (b *EventBus) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (b *EventBus) Close() error {
+	return nil
+}
+
+// Published returns the messages published so far
+func (b *EventBus) Published() []eventbus.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]eventbus.Message(nil), b.published...)
+}
+
+// DeadLettered returns the messages whose handler failed MaxAttempts times or reported a poison message
+func (b *EventBus) DeadLettered() []eventbus.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]eventbus.Message(nil), b.deadLettered...)
+}
+
+func (b *EventBus) deliver(ctx context.Context, message eventbus.Message) {
+	for attempt := 1; attempt <= b.maxAttempts; attempt++ {
+		message.Attempt = attempt
+
+		_, err := b.Dispatch(ctx, message)
+		if err == nil {
+			return
+		}
+
+		if errors.Is(err, eventbus.ErrPoisonMessage) {
+			break
+		}
+	}
+
+	b.mu.Lock()
+	b.deadLettered = append(b.deadLettered, message)
+	b.mu.Unlock()
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+)
+
+func TestEventBusDeliversPublishedMessages(t *testing.T) {
+	bus := NewEventBus(3)
+
+	var attempts []int
+	bus.Subscribe("mail.*", func(ctx context.Context, message eventbus.Message) error {
+		attempts = append(attempts, message.Attempt)
+		if message.Attempt < 2 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+	bus.Subscribe("billing.#", func(ctx context.Context, message eventbus.Message) error {
+		return fmt.Errorf("%w: invalid payload", eventbus.ErrPoisonMessage)
+	})
+
+	ctx := context.Background()
+	for _, topic := range []string{"mail.bounced", "billing.account_closed", "user.account.deleted"} {
+		if err := bus.Publish(ctx, topic, []byte(`{}`)); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	if len(bus.Published()) != 3 {
+		t.Errorf("expected 3 published messages, got %d", len(bus.Published()))
+	}
+	if len(attempts) != 2 || attempts[1] != 2 {
+		t.Errorf("expected the failed message to be retried once, got attempts %v", attempts)
+	}
+
+	deadLettered := bus.DeadLettered()
+	if len(deadLettered) != 2 || deadLettered[0].Topic != "billing.account_closed" || deadLettered[0].Attempt != 1 {
+		t.Errorf("expected the poison and the unhandled message to be dead-lettered, got %+v", deadLettered)
+	}
+}
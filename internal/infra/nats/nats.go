@@ -0,0 +1,353 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/metrics"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	"github.com/google/uuid"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	DefaultURL             = natsgo.DefaultURL
+	DefaultStream          = "USER_EVENTS"
+	DefaultSubject         = "user.>"
+	DefaultDurableName     = "user-service"
+	DefaultPublishTimeout  = time.Second * 5
+	DefaultConcurrency     = 4
+	DefaultPrefetch        = 16
+	DefaultMaxAttempts     = 5
+	DefaultRetryDelay      = time.Second * 10
+	DefaultShutdownTimeout = time.Second * 30
+
+	// DeadLetterStreamSuffix names the stream dead-lettered messages are kept in, under the subject
+	// <durable name>.dlq.<original subject>
+	DeadLetterStreamSuffix = "_DLQ"
+
+	HeaderAttempts = "X-Attempts"
+	HeaderError    = "X-Error"
+)
+
+// EventBus publishes events to JetStream under their routing key as subject and consumes the
+// consumer stream with a durable pull consumer filtered on the subjects of the registered handlers.
+//
+// Failed messages are redelivered by the server after RetryDelay. After MaxAttempts, or right away
+// for poison messages, they are copied to the dead letter stream and acknowledged.
+type EventBus struct {
+	eventbus.Router
+
+	logger         *logger.Logger
+	config         config.NatsConfig
+	consumerConfig config.ConsumerConfig
+
+	conn      *natsgo.Conn
+	jetStream jetstream.JetStream
+}
+
+var _ eventbus.EventBus = (*EventBus)(nil)
+
+// NewEventBus connects to the server and creates or updates the stream the service publishes to
+func NewEventBus(logger *logger.Logger, cfg config.NatsConfig, consumerCfg config.ConsumerConfig) (*EventBus, error) {
+	if cfg.URL == "" {
+		cfg.URL = DefaultURL
+	}
+	if cfg.Stream == "" {
+		cfg.Stream = DefaultStream
+	}
+	if len(cfg.Subjects) == 0 {
+		cfg.Subjects = []string{DefaultSubject}
+	}
+	if cfg.ConsumerStream == "" {
+		cfg.ConsumerStream = cfg.Stream
+	}
+	if cfg.DurableName == "" {
+		cfg.DurableName = DefaultDurableName
+	}
+	if cfg.PublishTimeout <= 0 {
+		cfg.PublishTimeout = DefaultPublishTimeout
+	}
+	if consumerCfg.Concurrency <= 0 {
+		consumerCfg.Concurrency = DefaultConcurrency
+	}
+	if consumerCfg.Prefetch <= 0 {
+		consumerCfg.Prefetch = DefaultPrefetch
+	}
+	if consumerCfg.MaxAttempts <= 0 {
+		consumerCfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if consumerCfg.RetryDelay <= 0 {
+		consumerCfg.RetryDelay = DefaultRetryDelay
+	}
+	if consumerCfg.ShutdownTimeout <= 0 {
+		consumerCfg.ShutdownTimeout = DefaultShutdownTimeout
+	}
+
+	conn, err := natsgo.Connect(
+		cfg.URL,
+		natsgo.Name(cfg.DurableName),
+		natsgo.MaxReconnects(-1),
+		natsgo.DisconnectErrHandler(func(_ *natsgo.Conn, err error) {
+			if err != nil {
+				logger.Warnf("nats connection lost, reconnecting: %s", err.Error())
+			}
+		}),
+		natsgo.ReconnectHandler(func(conn *natsgo.Conn) {
+			logger.Infof("nats reconnected to %s", conn.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %s", err.Error())
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create jetstream context: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.PublishTimeout)
+	defer cancel()
+
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.Stream,
+		Subjects: cfg.Subjects,
+		Storage:  jetstream.FileStorage,
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to declare stream %s: %s", cfg.Stream, err.Error())
+	}
+
+	return &EventBus{
+		logger:         logger,
+		config:         cfg,
+		consumerConfig: consumerCfg,
+		conn:           conn,
+		jetStream:      js,
+	}, nil
+}
+
+// Publish stores the message in the stream bound to its subject and waits for the server to acknowledge it
+func (b *EventBus) Publish(ctx context.Context, topic string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, b.config.PublishTimeout)
+	defer cancel()
+
+	if _, err := b.jetStream.Publish(ctx, topic, body, jetstream.WithMsgID(uuid.New().String())); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	return nil
+}
+
+// Start consumes messages until ctx is cancelled, then stops fetching and waits up to ShutdownTimeout
+// for the in-flight ones. Messages that were fetched but not handled are redelivered by the server.
+func (b *EventBus) Start(ctx context.Context) error {
+	patterns := b.Patterns()
+	if len(patterns) == 0 {
+		b.logger.Warnf("stream: %s, no message handlers registered, not consuming", b.config.ConsumerStream)
+		return nil
+	}
+
+	filterSubjects := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		subject, err := toSubject(pattern)
+		if err != nil {
+			return err
+		}
+		filterSubjects = append(filterSubjects, subject)
+	}
+
+	if _, err := b.jetStream.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     b.config.ConsumerStream + DeadLetterStreamSuffix,
+		Subjects: []string{b.deadLetterSubject(">")},
+		Storage:  jetstream.FileStorage,
+	}); err != nil {
+		return fmt.Errorf("failed to declare dead letter stream: %s", err.Error())
+	}
+
+	consumer, err := b.jetStream.CreateOrUpdateConsumer(ctx, b.config.ConsumerStream, jetstream.ConsumerConfig{
+		Durable:        b.config.DurableName,
+		FilterSubjects: filterSubjects,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		MaxAckPending:  b.consumerConfig.Prefetch,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create consumer %s: %s", b.config.DurableName, err.Error())
+	}
+
+	// handlers finish their message on shutdown, they are only cancelled once the drain times out
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	stopping := make(chan struct{})
+	messages := make(chan jetstream.Msg)
+
+	var wg sync.WaitGroup
+	for i := 0; i < b.consumerConfig.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stopping:
+					return
+				case msg := <-messages:
+					b.handle(handlerCtx, msg)
+				}
+			}
+		}()
+	}
+
+	consumeCtx, err := consumer.Consume(func(msg jetstream.Msg) {
+		select {
+		case messages <- msg:
+		case <-stopping:
+		}
+	}, jetstream.PullMaxMessages(b.consumerConfig.Prefetch))
+	if err != nil {
+		close(stopping)
+		wg.Wait()
+		return fmt.Errorf("failed to consume: %s", err.Error())
+	}
+
+	<-ctx.Done()
+
+	b.logger.Infof("stream: %s, draining in-flight messages", b.config.ConsumerStream)
+	close(stopping)
+	consumeCtx.Stop()
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(b.consumerConfig.ShutdownTimeout):
+		b.logger.Warnf(
+			"stream: %s, in-flight messages not drained after %s, they are redelivered",
+			b.config.ConsumerStream, b.consumerConfig.ShutdownTimeout,
+		)
+	}
+
+	return nil
+}
+
+func (b *EventBus) Close() error {
+	b.conn.Close()
+	return nil
+}
+
+func (b *EventBus) handle(ctx context.Context, msg jetstream.Msg) {
+	message := toMessage(msg)
+
+	pattern, err := b.Dispatch(ctx, message)
+	if err == nil {
+		metrics.ConsumerMessagesTotal.WithLabelValues(pattern, metrics.ConsumeOutcomeAcked).Inc()
+		if err := msg.Ack(); err != nil {
+			b.logger.Errorf("messageId: %s, failed to ack message: %s", message.ID, err.Error())
+		}
+		return
+	}
+
+	if !errors.Is(err, eventbus.ErrPoisonMessage) && message.Attempt < b.consumerConfig.MaxAttempts {
+		b.logger.Warnf(
+			"messageId: %s, subject: %s, attempt: %d, failed to handle message, retrying in %s: %s",
+			message.ID, message.Topic, message.Attempt, b.consumerConfig.RetryDelay, err.Error(),
+		)
+
+		metrics.ConsumerMessagesTotal.WithLabelValues(pattern, metrics.ConsumeOutcomeRetried).Inc()
+		if err := msg.NakWithDelay(b.consumerConfig.RetryDelay); err != nil {
+			b.logger.Errorf("messageId: %s, failed to nak message: %s", message.ID, err.Error())
+		}
+		return
+	}
+
+	b.logger.Errorf(
+		"messageId: %s, subject: %s, attempts: %d, moving message to the dead letter stream: %s",
+		message.ID, message.Topic, message.Attempt, err.Error(),
+	)
+
+	if err := b.deadLetter(ctx, msg, message.Attempt, err); err != nil {
+		// the message is redelivered and dead-lettered again on its next attempt
+		b.logger.Errorf("messageId: %s, failed to move message to the dead letter stream: %s", message.ID, err.Error())
+		msg.NakWithDelay(b.consumerConfig.RetryDelay)
+		return
+	}
+
+	metrics.ConsumerMessagesTotal.WithLabelValues(pattern, metrics.ConsumeOutcomeDeadLettered).Inc()
+	if err := msg.Ack(); err != nil {
+		b.logger.Errorf("messageId: %s, failed to ack message: %s", message.ID, err.Error())
+	}
+}
+
+func (b *EventBus) deadLetter(ctx context.Context, msg jetstream.Msg, attempts int, cause error) error {
+	header := natsgo.Header{}
+	for k, v := range msg.Headers() {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set(HeaderAttempts, strconv.Itoa(attempts))
+	header.Set(HeaderError, cause.Error())
+
+	ctx, cancel := context.WithTimeout(ctx, b.config.PublishTimeout)
+	defer cancel()
+
+	_, err := b.jetStream.PublishMsg(ctx, &natsgo.Msg{
+		Subject: b.deadLetterSubject(msg.Subject()),
+		Header:  header,
+		Data:    msg.Data(),
+	})
+
+	return err
+}
+
+func (b *EventBus) deadLetterSubject(subject string) string {
+	return fmt.Sprintf("%s.dlq.%s", b.config.DurableName, subject)
+}
+
+func toMessage(msg jetstream.Msg) eventbus.Message {
+	headers := make(map[string]string, len(msg.Headers()))
+	for k := range msg.Headers() {
+		headers[k] = msg.Headers().Get(k)
+	}
+
+	attempt := 1
+	if metadata, err := msg.Metadata(); err == nil {
+		attempt = int(metadata.NumDelivered)
+	}
+
+	return eventbus.Message{
+		ID:      msg.Headers().Get(jetstream.MsgIDHeader),
+		Topic:   msg.Subject(),
+		Body:    msg.Data(),
+		Headers: headers,
+		Attempt: attempt,
+	}
+}
+
+// toSubject converts a topic pattern to a subject filter, # is only supported as the last word
+// where it becomes > and, unlike the topic pattern, requires at least one more word
+func toSubject(pattern string) (string, error) {
+	words := strings.Split(pattern, ".")
+	for i, word := range words {
+		if word != "#" {
+			continue
+		}
+		if i != len(words)-1 {
+			return "", fmt.Errorf("topic pattern %q is not supported by nats, # must be the last word", pattern)
+		}
+		words[i] = ">"
+	}
+
+	return strings.Join(words, "."), nil
+}
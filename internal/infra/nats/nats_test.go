@@ -0,0 +1,200 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/sirupsen/logrus"
+)
+
+const testInboundStream = "INBOUND_EVENTS"
+
+func newTestServer(t *testing.T) *server.Server {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create nats server: %v", err)
+	}
+
+	go srv.Start()
+	if !srv.ReadyForConnections(time.Second * 5) {
+		t.Fatal("nats server did not start")
+	}
+	t.Cleanup(srv.Shutdown)
+
+	return srv
+}
+
+func newTestEventBus(t *testing.T, srv *server.Server, consumerCfg config.ConsumerConfig) *EventBus {
+	t.Helper()
+
+	if consumerCfg.RetryDelay == 0 {
+		consumerCfg.RetryDelay = time.Millisecond * 20
+	}
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+
+	bus, err := NewEventBus(testLogger, config.NatsConfig{
+		URL:            srv.ClientURL(),
+		Subjects:       []string{"user.>"},
+		ConsumerStream: testInboundStream,
+	}, consumerCfg)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+	t.Cleanup(func() { bus.Close() })
+
+	// upstream services own the stream of the events this service consumes
+	if _, err := bus.jetStream.CreateOrUpdateStream(context.Background(), jetstream.StreamConfig{
+		Name:     testInboundStream,
+		Subjects: []string{"mail.>", "billing.>"},
+	}); err != nil {
+		t.Fatalf("failed to create inbound stream: %v", err)
+	}
+
+	return bus
+}
+
+func startEventBus(t *testing.T, bus *EventBus) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- bus.Start(ctx) }()
+
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("event bus stopped with error: %v", err)
+			}
+		case <-time.After(time.Second * 5):
+			t.Error("event bus did not stop")
+		}
+	})
+}
+
+func eventually(t *testing.T, condition func() bool, msg string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	t.Fatal(msg)
+}
+
+func TestPublishStoresMessageInStream(t *testing.T) {
+	srv := newTestServer(t)
+	bus := newTestEventBus(t, srv, config.ConsumerConfig{})
+
+	ctx := context.Background()
+	if err := bus.Publish(ctx, "user.account.deleted", []byte(`{}`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	stream, err := bus.jetStream.Stream(ctx, DefaultStream)
+	if err != nil {
+		t.Fatalf("failed to get stream: %v", err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatalf("failed to get stream info: %v", err)
+	}
+	if info.State.Msgs != 1 {
+		t.Errorf("expected 1 message in the stream, got %d", info.State.Msgs)
+	}
+
+	if err := bus.Publish(ctx, "orders.created", []byte(`{}`)); err == nil {
+		t.Error("expected publishing to a subject without a stream to fail")
+	}
+}
+
+func TestSubscribeRetriesAndDeadLetters(t *testing.T) {
+	srv := newTestServer(t)
+	bus := newTestEventBus(t, srv, config.ConsumerConfig{MaxAttempts: 3})
+
+	var bounced, closedAttempts atomic.Int32
+	bus.Subscribe("mail.bounced", func(ctx context.Context, message eventbus.Message) error {
+		if message.Attempt == 1 {
+			return errors.New("database unavailable")
+		}
+		bounced.Add(1)
+		return nil
+	})
+	bus.Subscribe("billing.#", func(ctx context.Context, message eventbus.Message) error {
+		closedAttempts.Add(1)
+		return fmt.Errorf("attempt %d failed", message.Attempt)
+	})
+	startEventBus(t, bus)
+
+	ctx := context.Background()
+	for _, subject := range []string{"mail.bounced", "billing.account_closed"} {
+		if _, err := bus.jetStream.Publish(ctx, subject, []byte(`{}`)); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	eventually(t, func() bool { return bounced.Load() == 1 }, "failed message was not retried")
+
+	dlq, err := bus.jetStream.Stream(ctx, testInboundStream+DeadLetterStreamSuffix)
+	if err != nil {
+		t.Fatalf("failed to get dead letter stream: %v", err)
+	}
+	eventually(t, func() bool {
+		info, err := dlq.Info(ctx)
+		return err == nil && info.State.Msgs == 1
+	}, "message was not dead-lettered")
+
+	if got := closedAttempts.Load(); got != 3 {
+		t.Errorf("expected 3 attempts before dead-lettering, got %d", got)
+	}
+
+	msg, err := dlq.GetLastMsgForSubject(ctx, bus.deadLetterSubject("billing.account_closed"))
+	if err != nil {
+		t.Fatalf("failed to get dead-lettered message: %v", err)
+	}
+	if msg.Header.Get(HeaderAttempts) != "3" || msg.Header.Get(HeaderError) != "attempt 3 failed" {
+		t.Errorf("unexpected dead letter headers: %v", msg.Header)
+	}
+}
+
+func TestToSubject(t *testing.T) {
+	tests := map[string]string{
+		"mail.bounced": "mail.bounced",
+		"mail.*":       "mail.*",
+		"billing.#":    "billing.>",
+		"#":            ">",
+	}
+	for pattern, want := range tests {
+		if got, err := toSubject(pattern); err != nil || got != want {
+			t.Errorf("toSubject(%q) = %q, %v, want %q", pattern, got, err, want)
+		}
+	}
+
+	if _, err := toSubject("#.closed"); err == nil {
+		t.Error("expected # before the last word to be rejected")
+	}
+}
@@ -0,0 +1,626 @@
+package amqptest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	classConnection = 10
+	classChannel    = 20
+	classExchange   = 40
+	classQueue      = 50
+	classBasic      = 60
+	classConfirm    = 85
+)
+
+var protocolHeader = []byte("AMQP\x00\x00\x09\x01")
+
+type conn struct {
+	server   *Server
+	netConn  net.Conn
+	frameMax uint32
+
+	// channels is guarded by server.mu
+	channels map[uint16]*channel
+
+	outMu  sync.Mutex
+	outC   *sync.Cond
+	out    [][]byte
+	closed bool
+}
+
+type channel struct {
+	id      uint16
+	conn    *conn
+	closing bool
+
+	prefetch    int
+	confirm     bool
+	publishSeq  uint64
+	deliveryTag uint64
+	unacked     map[uint64]*delivery
+	consumers   map[string]*consumer
+
+	publish *pendingPublish
+}
+
+type delivery struct {
+	consumer *consumer
+	message  *message
+}
+
+type pendingPublish struct {
+	exchange   string
+	routingKey string
+	mandatory  bool
+	properties Properties
+	size       uint64
+	body       []byte
+	hasHeader  bool
+}
+
+func newConn(s *Server, netConn net.Conn) *conn {
+	c := &conn{
+		server:   s,
+		netConn:  netConn,
+		frameMax: defaultFrameMax,
+		channels: map[uint16]*channel{},
+	}
+	c.outC = sync.NewCond(&c.outMu)
+
+	return c
+}
+
+func (c *conn) serve() {
+	go c.writeLoop()
+	defer c.teardown()
+
+	reader := bufio.NewReader(c.netConn)
+	header := make([]byte, len(protocolHeader))
+	if _, err := io.ReadFull(reader, header); err != nil || !bytes.Equal(header, protocolHeader) {
+		c.netConn.Write(protocolHeader)
+		return
+	}
+
+	start := newMethod(classConnection, 10)
+	start.octet(0)
+	start.octet(9)
+	start.table(amqp.Table{
+		"product": "amqptest",
+		"capabilities": amqp.Table{
+			"publisher_confirms":         true,
+			"basic.nack":                 true,
+			"consumer_cancel_notify":     true,
+			"exchange_exchange_bindings": true,
+		},
+	})
+	start.longstr([]byte("PLAIN AMQPLAIN"))
+	start.longstr([]byte("en_US"))
+	c.sendMethod(0, start)
+
+	for {
+		f, err := readFrame(reader)
+		if err != nil {
+			return
+		}
+
+		switch f.kind {
+		case frameHeartbeat:
+			c.send(frame{kind: frameHeartbeat})
+		case frameMethod:
+			if !c.handleMethod(f) {
+				return
+			}
+		case frameHeader, frameBody:
+			c.handleContent(f)
+		}
+	}
+}
+
+// teardown releases the channels of a closed connection, requeueing their unacknowledged messages
+func (c *conn) teardown() {
+	s := c.server
+	s.mu.Lock()
+	for _, ch := range c.channels {
+		ch.releaseLocked()
+	}
+	c.channels = map[uint16]*channel{}
+	delete(s.conns, c)
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	c.shutdown()
+}
+
+func (c *conn) send(frames ...frame) {
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+
+	if c.closed {
+		return
+	}
+	for _, f := range frames {
+		c.out = append(c.out, f.encode())
+	}
+	c.outC.Signal()
+}
+
+func (c *conn) sendMethod(channelID uint16, method *encoder) {
+	c.send(frame{kind: frameMethod, channel: channelID, payload: method.bytes()})
+}
+
+// sendContent sends a method followed by its content header and body frames
+func (c *conn) sendContent(channelID uint16, method *encoder, properties Properties, body []byte) {
+	header := &encoder{}
+	header.short(classBasic)
+	header.short(0)
+	header.longlong(uint64(len(body)))
+	properties.encode(header)
+
+	frames := []frame{
+		{kind: frameMethod, channel: channelID, payload: method.bytes()},
+		{kind: frameHeader, channel: channelID, payload: header.bytes()},
+	}
+
+	chunk := int(c.frameMax) - 8
+	for len(body) > 0 {
+		n := min(chunk, len(body))
+		frames = append(frames, frame{kind: frameBody, channel: channelID, payload: body[:n]})
+		body = body[n:]
+	}
+
+	c.send(frames...)
+}
+
+// shutdown closes the socket once every queued frame is written
+func (c *conn) shutdown() {
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+
+	c.closed = true
+	c.outC.Signal()
+}
+
+func (c *conn) writeLoop() {
+	for {
+		c.outMu.Lock()
+		for len(c.out) == 0 && !c.closed {
+			c.outC.Wait()
+		}
+		pending := c.out
+		c.out = nil
+		closed := c.closed
+		c.outMu.Unlock()
+
+		for _, buf := range pending {
+			if _, err := c.netConn.Write(buf); err != nil {
+				c.netConn.Close()
+				return
+			}
+		}
+
+		if closed {
+			c.netConn.Close()
+			return
+		}
+	}
+}
+
+// handleMethod processes a method frame and reports whether the connection stays open
+func (c *conn) handleMethod(f frame) bool {
+	d := &decoder{buf: f.payload}
+	classID, methodID := d.short(), d.short()
+	if d.err != nil {
+		return false
+	}
+
+	if classID == classConnection {
+		return c.handleConnectionMethod(methodID, d)
+	}
+
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if classID == classChannel && methodID == 10 {
+		ch := &channel{
+			id:        f.channel,
+			conn:      c,
+			unacked:   map[uint64]*delivery{},
+			consumers: map[string]*consumer{},
+		}
+		c.channels[f.channel] = ch
+
+		openOk := newMethod(classChannel, 11)
+		openOk.longstr(nil)
+		c.sendMethod(f.channel, openOk)
+		return true
+	}
+
+	ch, ok := c.channels[f.channel]
+	if !ok {
+		return true
+	}
+
+	if ch.closing {
+		// everything but the close handshake is discarded on a closing channel
+		if classID == classChannel && (methodID == 40 || methodID == 41) {
+			if methodID == 40 {
+				c.sendMethod(ch.id, newMethod(classChannel, 41))
+			}
+			delete(c.channels, ch.id)
+		}
+		return true
+	}
+
+	ch.handleMethodLocked(classID, methodID, d)
+	s.dispatchLocked()
+	return true
+}
+
+func (c *conn) handleConnectionMethod(methodID uint16, d *decoder) bool {
+	switch methodID {
+	case 11: // start-ok
+		tune := newMethod(classConnection, 30)
+		tune.short(2047)
+		tune.long(defaultFrameMax)
+		tune.short(0)
+		c.sendMethod(0, tune)
+	case 31: // tune-ok
+		d.short()
+		if frameMax := d.long(); frameMax > 0 && frameMax < c.frameMax {
+			c.frameMax = frameMax
+		}
+	case 40: // open
+		openOk := newMethod(classConnection, 41)
+		openOk.shortstr("")
+		c.sendMethod(0, openOk)
+	case 50: // close
+		c.sendMethod(0, newMethod(classConnection, 51))
+		return false
+	case 51: // close-ok
+		return false
+	}
+
+	return true
+}
+
+func (c *conn) handleContent(f frame) {
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, ok := c.channels[f.channel]
+	if !ok || ch.closing || ch.publish == nil {
+		return
+	}
+
+	p := ch.publish
+	if f.kind == frameHeader {
+		d := &decoder{buf: f.payload}
+		d.short()
+		d.short()
+		p.size = d.longlong()
+		p.properties = decodeProperties(d)
+		p.hasHeader = true
+	} else if p.hasHeader {
+		p.body = append(p.body, f.payload...)
+	}
+
+	if p.hasHeader && uint64(len(p.body)) >= p.size {
+		ch.publish = nil
+		ch.completePublishLocked(p)
+		s.dispatchLocked()
+	}
+}
+
+func (ch *channel) handleMethodLocked(classID, methodID uint16, d *decoder) {
+	s := ch.conn.server
+
+	switch {
+	case classID == classChannel && methodID == 40: // close
+		ch.releaseLocked()
+		delete(ch.conn.channels, ch.id)
+		ch.conn.sendMethod(ch.id, newMethod(classChannel, 41))
+
+	case classID == classExchange && methodID == 10: // declare
+		d.short()
+		name, kind := d.shortstr(), d.shortstr()
+		passive := d.bit()
+		d.bit()
+		d.bit()
+		d.bit()
+		noWait := d.bit()
+
+		if _, ok := s.exchanges[name]; !ok {
+			if passive {
+				ch.closeLocked(404, fmt.Sprintf("NOT_FOUND - no exchange '%s' in vhost '/'", name), classID, methodID)
+				return
+			}
+			s.exchanges[name] = &exchange{name: name, kind: kind}
+		}
+		if !noWait {
+			ch.conn.sendMethod(ch.id, newMethod(classExchange, 11))
+		}
+
+	case classID == classQueue && methodID == 10: // declare
+		d.short()
+		name := d.shortstr()
+		passive := d.bit()
+		d.bit()
+		d.bit()
+		d.bit()
+		noWait := d.bit()
+		args := d.table()
+
+		if name == "" {
+			name = s.generateNameLocked("amq.gen-")
+		}
+		q, ok := s.queues[name]
+		if !ok {
+			if passive {
+				ch.closeLocked(404, fmt.Sprintf("NOT_FOUND - no queue '%s' in vhost '/'", name), classID, methodID)
+				return
+			}
+			q = &queue{name: name, args: args}
+			s.queues[name] = q
+		}
+		if !noWait {
+			declareOk := newMethod(classQueue, 11)
+			declareOk.shortstr(name)
+			declareOk.long(uint32(len(q.messages)))
+			declareOk.long(uint32(len(q.consumers)))
+			ch.conn.sendMethod(ch.id, declareOk)
+		}
+
+	case classID == classQueue && methodID == 20: // bind
+		d.short()
+		queueName, exchangeName, routingKey := d.shortstr(), d.shortstr(), d.shortstr()
+		noWait := d.bit()
+
+		q, ok := s.queues[queueName]
+		if !ok {
+			ch.closeLocked(404, fmt.Sprintf("NOT_FOUND - no queue '%s' in vhost '/'", queueName), classID, methodID)
+			return
+		}
+		if _, ok := s.exchanges[exchangeName]; !ok {
+			ch.closeLocked(404, fmt.Sprintf("NOT_FOUND - no exchange '%s' in vhost '/'", exchangeName), classID, methodID)
+			return
+		}
+
+		binding := Binding{Exchange: exchangeName, RoutingKey: routingKey}
+		if !containsBinding(q.bindings, binding) {
+			q.bindings = append(q.bindings, binding)
+		}
+		if !noWait {
+			ch.conn.sendMethod(ch.id, newMethod(classQueue, 21))
+		}
+
+	case classID == classBasic && methodID == 10: // qos
+		d.long()
+		ch.prefetch = int(d.short())
+		ch.conn.sendMethod(ch.id, newMethod(classBasic, 11))
+
+	case classID == classBasic && methodID == 20: // consume
+		d.short()
+		queueName, tag := d.shortstr(), d.shortstr()
+		d.bit()
+		noAck := d.bit()
+		d.bit()
+		noWait := d.bit()
+
+		q, ok := s.queues[queueName]
+		if !ok {
+			ch.closeLocked(404, fmt.Sprintf("NOT_FOUND - no queue '%s' in vhost '/'", queueName), classID, methodID)
+			return
+		}
+		if tag == "" {
+			tag = s.generateNameLocked("amq.ctag-")
+		}
+
+		c := &consumer{tag: tag, queue: q, channel: ch, noAck: noAck}
+		ch.consumers[tag] = c
+		q.consumers = append(q.consumers, c)
+		if !noWait {
+			consumeOk := newMethod(classBasic, 21)
+			consumeOk.shortstr(tag)
+			ch.conn.sendMethod(ch.id, consumeOk)
+		}
+
+	case classID == classBasic && methodID == 30: // cancel
+		tag := d.shortstr()
+		noWait := d.bit()
+
+		if c, ok := ch.consumers[tag]; ok {
+			c.queue.removeConsumer(c)
+			delete(ch.consumers, tag)
+		}
+		if !noWait {
+			cancelOk := newMethod(classBasic, 31)
+			cancelOk.shortstr(tag)
+			ch.conn.sendMethod(ch.id, cancelOk)
+		}
+
+	case classID == classBasic && methodID == 40: // publish
+		d.short()
+		exchangeName, routingKey := d.shortstr(), d.shortstr()
+		mandatory := d.bit()
+
+		if ch.confirm {
+			ch.publishSeq++
+		}
+		if _, ok := s.exchanges[exchangeName]; !ok {
+			ch.closeLocked(404, fmt.Sprintf("NOT_FOUND - no exchange '%s' in vhost '/'", exchangeName), classID, methodID)
+			return
+		}
+		ch.publish = &pendingPublish{exchange: exchangeName, routingKey: routingKey, mandatory: mandatory}
+
+	case classID == classBasic && methodID == 80: // ack
+		tag := d.longlong()
+		multiple := d.bit()
+		ch.settleLocked(tag, multiple, func(q *queue, m *message) {})
+
+	case classID == classBasic && methodID == 90: // reject
+		tag := d.longlong()
+		requeue := d.bit()
+		ch.settleLocked(tag, false, ch.rejectFunc(requeue))
+
+	case classID == classBasic && methodID == 120: // nack
+		tag := d.longlong()
+		multiple := d.bit()
+		requeue := d.bit()
+		ch.settleLocked(tag, multiple, ch.rejectFunc(requeue))
+
+	case classID == classConfirm && methodID == 10: // select
+		noWait := d.bit()
+		ch.confirm = true
+		if !noWait {
+			ch.conn.sendMethod(ch.id, newMethod(classConfirm, 11))
+		}
+
+	default:
+		ch.closeLocked(540, fmt.Sprintf("NOT_IMPLEMENTED - method %d.%d", classID, methodID), classID, methodID)
+	}
+}
+
+func containsBinding(bindings []Binding, binding Binding) bool {
+	for _, b := range bindings {
+		if b == binding {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ch *channel) completePublishLocked(p *pendingPublish) {
+	s := ch.conn.server
+
+	if ch.confirm && s.confirmMode == ConfirmNack {
+		nack := newMethod(classBasic, 120)
+		nack.longlong(ch.publishSeq)
+		nack.bits(false, false)
+		ch.conn.sendMethod(ch.id, nack)
+		return
+	}
+
+	queues := s.routeLocked(s.exchanges[p.exchange], p.routingKey)
+	if len(queues) == 0 && p.mandatory {
+		basicReturn := newMethod(classBasic, 50)
+		basicReturn.short(312)
+		basicReturn.shortstr("NO_ROUTE")
+		basicReturn.shortstr(p.exchange)
+		basicReturn.shortstr(p.routingKey)
+		ch.conn.sendContent(ch.id, basicReturn, p.properties, p.body)
+	}
+
+	for _, q := range queues {
+		s.enqueueLocked(q, &message{
+			exchange:   p.exchange,
+			routingKey: p.routingKey,
+			properties: p.properties.clone(),
+			body:       p.body,
+		})
+	}
+
+	if ch.confirm && s.confirmMode == ConfirmAck {
+		ack := newMethod(classBasic, 80)
+		ack.longlong(ch.publishSeq)
+		ack.bits(false)
+		ch.conn.sendMethod(ch.id, ack)
+	}
+}
+
+func (ch *channel) deliverLocked(c *consumer, m *message) {
+	ch.deliveryTag++
+	if !c.noAck {
+		c.unacked++
+		ch.unacked[ch.deliveryTag] = &delivery{consumer: c, message: m}
+	}
+
+	deliver := newMethod(classBasic, 60)
+	deliver.shortstr(c.tag)
+	deliver.longlong(ch.deliveryTag)
+	deliver.bits(m.redelivered)
+	deliver.shortstr(m.exchange)
+	deliver.shortstr(m.routingKey)
+	ch.conn.sendContent(ch.id, deliver, m.properties, m.body)
+}
+
+// settleLocked applies fn to the acknowledged deliveries, a tag of 0 with multiple settles all of them
+func (ch *channel) settleLocked(tag uint64, multiple bool, fn func(q *queue, m *message)) {
+	var tags []uint64
+	if multiple {
+		for t := range ch.unacked {
+			if tag == 0 || t <= tag {
+				tags = append(tags, t)
+			}
+		}
+		sort.Slice(tags, func(i, j int) bool { return tags[i] > tags[j] })
+	} else {
+		if _, ok := ch.unacked[tag]; !ok {
+			ch.closeLocked(406, fmt.Sprintf("PRECONDITION_FAILED - unknown delivery tag %d", tag), classBasic, 80)
+			return
+		}
+		tags = []uint64{tag}
+	}
+
+	// tags are settled newest first so requeued messages keep their original order
+	for _, t := range tags {
+		d := ch.unacked[t]
+		delete(ch.unacked, t)
+		d.consumer.unacked--
+		fn(d.consumer.queue, d.message)
+	}
+}
+
+func (ch *channel) rejectFunc(requeue bool) func(q *queue, m *message) {
+	return func(q *queue, m *message) {
+		if requeue {
+			q.requeueLocked(m)
+			return
+		}
+		ch.conn.server.deadLetterLocked(q, m, "rejected")
+	}
+}
+
+// releaseLocked cancels the consumers of the channel and requeues its unacknowledged messages
+func (ch *channel) releaseLocked() {
+	for _, c := range ch.consumers {
+		c.queue.removeConsumer(c)
+	}
+	ch.consumers = map[string]*consumer{}
+
+	tags := make([]uint64, 0, len(ch.unacked))
+	for t := range ch.unacked {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] > tags[j] })
+	for _, t := range tags {
+		d := ch.unacked[t]
+		d.consumer.queue.requeueLocked(d.message)
+	}
+	ch.unacked = map[uint64]*delivery{}
+	ch.publish = nil
+}
+
+// closeLocked closes the channel with a soft error, as the broker does on e.g. a missing exchange
+func (ch *channel) closeLocked(code uint16, text string, classID, methodID uint16) {
+	ch.releaseLocked()
+	ch.closing = true
+
+	closeMethod := newMethod(classChannel, 40)
+	closeMethod.short(code)
+	closeMethod.shortstr(text)
+	closeMethod.short(classID)
+	closeMethod.short(methodID)
+	ch.conn.sendMethod(ch.id, closeMethod)
+}
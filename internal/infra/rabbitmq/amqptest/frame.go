@@ -0,0 +1,347 @@
+package amqptest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+	frameEnd       = 0xCE
+
+	defaultFrameMax = 131072
+)
+
+var errMalformedFrame = errors.New("malformed frame")
+
+type frame struct {
+	kind    byte
+	channel uint16
+	payload []byte
+}
+
+func readFrame(r *bufio.Reader) (frame, error) {
+	var header [7]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return frame{}, err
+	}
+
+	size := binary.BigEndian.Uint32(header[3:])
+	payload := make([]byte, size+1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return frame{}, err
+	}
+	if payload[size] != frameEnd {
+		return frame{}, errMalformedFrame
+	}
+
+	return frame{
+		kind:    header[0],
+		channel: binary.BigEndian.Uint16(header[1:3]),
+		payload: payload[:size],
+	}, nil
+}
+
+func (f frame) encode() []byte {
+	buf := make([]byte, 7, 8+len(f.payload))
+	buf[0] = f.kind
+	binary.BigEndian.PutUint16(buf[1:3], f.channel)
+	binary.BigEndian.PutUint32(buf[3:7], uint32(len(f.payload)))
+	buf = append(buf, f.payload...)
+
+	return append(buf, frameEnd)
+}
+
+// decoder reads the argument list of a method or the property list of a content header
+type decoder struct {
+	buf    []byte
+	err    error
+	bits   byte
+	bitPos int
+}
+
+func (d *decoder) take(n int) []byte {
+	d.bitPos = 0
+	if d.err != nil {
+		return nil
+	}
+	if len(d.buf) < n {
+		d.err = errMalformedFrame
+		return nil
+	}
+
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) octet() uint8 {
+	if b := d.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) short() uint16 {
+	if b := d.take(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) long() uint32 {
+	if b := d.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) longlong() uint64 {
+	if b := d.take(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) shortstr() string {
+	return string(d.take(int(d.octet())))
+}
+
+func (d *decoder) longstr() []byte {
+	return d.take(int(d.long()))
+}
+
+// bit reads the next packed bit, consecutive bit fields share an octet
+func (d *decoder) bit() bool {
+	if d.bitPos == 0 || d.bitPos == 8 {
+		d.bits = d.octet()
+		d.bitPos = 0
+	}
+
+	value := d.bits&(1<<d.bitPos) != 0
+	d.bitPos++
+	return value
+}
+
+func (d *decoder) table() amqp.Table {
+	raw := d.longstr()
+	if d.err != nil {
+		return nil
+	}
+
+	table := amqp.Table{}
+	fields := &decoder{buf: raw}
+	for len(fields.buf) > 0 && fields.err == nil {
+		name := fields.shortstr()
+		table[name] = fields.field()
+	}
+	if fields.err != nil {
+		d.err = fields.err
+	}
+
+	return table
+}
+
+func (d *decoder) array() []any {
+	raw := d.longstr()
+	if d.err != nil {
+		return nil
+	}
+
+	values := []any{}
+	fields := &decoder{buf: raw}
+	for len(fields.buf) > 0 && fields.err == nil {
+		values = append(values, fields.field())
+	}
+	if fields.err != nil {
+		d.err = fields.err
+	}
+
+	return values
+}
+
+func (d *decoder) field() any {
+	switch kind := d.octet(); kind {
+	case 't':
+		return d.octet() != 0
+	case 'b':
+		return int8(d.octet())
+	case 'B':
+		return d.octet()
+	case 's':
+		return int16(d.short())
+	case 'u':
+		return d.short()
+	case 'I':
+		return int32(d.long())
+	case 'i':
+		return d.long()
+	case 'l':
+		return int64(d.longlong())
+	case 'f':
+		return math.Float32frombits(d.long())
+	case 'd':
+		return math.Float64frombits(d.longlong())
+	case 'D':
+		return amqp.Decimal{Scale: d.octet(), Value: int32(d.long())}
+	case 'S':
+		return string(d.longstr())
+	case 'x':
+		return append([]byte(nil), d.longstr()...)
+	case 'A':
+		return d.array()
+	case 'T':
+		return time.Unix(int64(d.longlong()), 0)
+	case 'F':
+		return d.table()
+	case 'V':
+		return nil
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unsupported field type %q", kind)
+		}
+		return nil
+	}
+}
+
+// encoder writes method arguments and content header properties
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func newMethod(classID, methodID uint16) *encoder {
+	e := &encoder{}
+	e.short(classID)
+	e.short(methodID)
+	return e
+}
+
+func (e *encoder) octet(v uint8) {
+	e.buf.WriteByte(v)
+}
+
+func (e *encoder) short(v uint16) {
+	_ = binary.Write(&e.buf, binary.BigEndian, v)
+}
+
+func (e *encoder) long(v uint32) {
+	_ = binary.Write(&e.buf, binary.BigEndian, v)
+}
+
+func (e *encoder) longlong(v uint64) {
+	_ = binary.Write(&e.buf, binary.BigEndian, v)
+}
+
+func (e *encoder) shortstr(v string) {
+	e.octet(uint8(len(v)))
+	e.buf.WriteString(v)
+}
+
+func (e *encoder) longstr(v []byte) {
+	e.long(uint32(len(v)))
+	e.buf.Write(v)
+}
+
+func (e *encoder) bits(values ...bool) {
+	var octet uint8
+	for i, value := range values {
+		if value {
+			octet |= 1 << i
+		}
+	}
+	e.octet(octet)
+}
+
+func (e *encoder) table(table amqp.Table) {
+	fields := &encoder{}
+	for name, value := range table {
+		fields.shortstr(name)
+		fields.field(value)
+	}
+	e.longstr(fields.buf.Bytes())
+}
+
+func (e *encoder) field(value any) {
+	switch v := value.(type) {
+	case bool:
+		e.octet('t')
+		if v {
+			e.octet(1)
+		} else {
+			e.octet(0)
+		}
+	case int8:
+		e.octet('b')
+		e.octet(uint8(v))
+	case uint8:
+		e.octet('B')
+		e.octet(v)
+	case int16:
+		e.octet('s')
+		e.short(uint16(v))
+	case uint16:
+		e.octet('u')
+		e.short(v)
+	case int32:
+		e.octet('I')
+		e.long(uint32(v))
+	case uint32:
+		e.octet('i')
+		e.long(v)
+	case int:
+		e.octet('l')
+		e.longlong(uint64(v))
+	case int64:
+		e.octet('l')
+		e.longlong(uint64(v))
+	case float32:
+		e.octet('f')
+		e.long(math.Float32bits(v))
+	case float64:
+		e.octet('d')
+		e.longlong(math.Float64bits(v))
+	case amqp.Decimal:
+		e.octet('D')
+		e.octet(v.Scale)
+		e.long(uint32(v.Value))
+	case string:
+		e.octet('S')
+		e.longstr([]byte(v))
+	case []byte:
+		e.octet('x')
+		e.longstr(v)
+	case []any:
+		e.octet('A')
+		values := &encoder{}
+		for _, item := range v {
+			values.field(item)
+		}
+		e.longstr(values.buf.Bytes())
+	case time.Time:
+		e.octet('T')
+		e.longlong(uint64(v.Unix()))
+	case amqp.Table:
+		e.octet('F')
+		e.table(v)
+	case map[string]any:
+		e.octet('F')
+		e.table(v)
+	default:
+		e.octet('V')
+	}
+}
+
+func (e *encoder) bytes() []byte {
+	return e.buf.Bytes()
+}
@@ -0,0 +1,160 @@
+package amqptest
+
+import (
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	flagContentType     = 0x8000
+	flagContentEncoding = 0x4000
+	flagHeaders         = 0x2000
+	flagDeliveryMode    = 0x1000
+	flagPriority        = 0x0800
+	flagCorrelationID   = 0x0400
+	flagReplyTo         = 0x0200
+	flagExpiration      = 0x0100
+	flagMessageID       = 0x0080
+	flagTimestamp       = 0x0040
+	flagType            = 0x0020
+	flagUserID          = 0x0010
+	flagAppID           = 0x0008
+)
+
+// Properties are the basic content header properties of a message
+type Properties struct {
+	ContentType     string
+	ContentEncoding string
+	Headers         amqp.Table
+	DeliveryMode    uint8
+	Priority        uint8
+	CorrelationID   string
+	ReplyTo         string
+	Expiration      string
+	MessageID       string
+	Timestamp       time.Time
+	Type            string
+	UserID          string
+	AppID           string
+}
+
+func decodeProperties(d *decoder) Properties {
+	var p Properties
+
+	flags := d.short()
+	if flags&flagContentType != 0 {
+		p.ContentType = d.shortstr()
+	}
+	if flags&flagContentEncoding != 0 {
+		p.ContentEncoding = d.shortstr()
+	}
+	if flags&flagHeaders != 0 {
+		p.Headers = d.table()
+	}
+	if flags&flagDeliveryMode != 0 {
+		p.DeliveryMode = d.octet()
+	}
+	if flags&flagPriority != 0 {
+		p.Priority = d.octet()
+	}
+	if flags&flagCorrelationID != 0 {
+		p.CorrelationID = d.shortstr()
+	}
+	if flags&flagReplyTo != 0 {
+		p.ReplyTo = d.shortstr()
+	}
+	if flags&flagExpiration != 0 {
+		p.Expiration = d.shortstr()
+	}
+	if flags&flagMessageID != 0 {
+		p.MessageID = d.shortstr()
+	}
+	if flags&flagTimestamp != 0 {
+		p.Timestamp = time.Unix(int64(d.longlong()), 0)
+	}
+	if flags&flagType != 0 {
+		p.Type = d.shortstr()
+	}
+	if flags&flagUserID != 0 {
+		p.UserID = d.shortstr()
+	}
+	if flags&flagAppID != 0 {
+		p.AppID = d.shortstr()
+	}
+
+	return p
+}
+
+func (p Properties) encode(e *encoder) {
+	var flags uint16
+	fields := &encoder{}
+
+	if p.ContentType != "" {
+		flags |= flagContentType
+		fields.shortstr(p.ContentType)
+	}
+	if p.ContentEncoding != "" {
+		flags |= flagContentEncoding
+		fields.shortstr(p.ContentEncoding)
+	}
+	if len(p.Headers) > 0 {
+		flags |= flagHeaders
+		fields.table(p.Headers)
+	}
+	if p.DeliveryMode != 0 {
+		flags |= flagDeliveryMode
+		fields.octet(p.DeliveryMode)
+	}
+	if p.Priority != 0 {
+		flags |= flagPriority
+		fields.octet(p.Priority)
+	}
+	if p.CorrelationID != "" {
+		flags |= flagCorrelationID
+		fields.shortstr(p.CorrelationID)
+	}
+	if p.ReplyTo != "" {
+		flags |= flagReplyTo
+		fields.shortstr(p.ReplyTo)
+	}
+	if p.Expiration != "" {
+		flags |= flagExpiration
+		fields.shortstr(p.Expiration)
+	}
+	if p.MessageID != "" {
+		flags |= flagMessageID
+		fields.shortstr(p.MessageID)
+	}
+	if !p.Timestamp.IsZero() {
+		flags |= flagTimestamp
+		fields.longlong(uint64(p.Timestamp.Unix()))
+	}
+	if p.Type != "" {
+		flags |= flagType
+		fields.shortstr(p.Type)
+	}
+	if p.UserID != "" {
+		flags |= flagUserID
+		fields.shortstr(p.UserID)
+	}
+	if p.AppID != "" {
+		flags |= flagAppID
+		fields.shortstr(p.AppID)
+	}
+
+	e.short(flags)
+	e.buf.Write(fields.bytes())
+}
+
+func (p Properties) clone() Properties {
+	if p.Headers != nil {
+		headers := make(amqp.Table, len(p.Headers))
+		for k, v := range p.Headers {
+			headers[k] = v
+		}
+		p.Headers = headers
+	}
+
+	return p
+}
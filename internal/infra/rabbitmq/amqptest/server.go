@@ -0,0 +1,540 @@
+// Package amqptest provides an in-process AMQP 0-9-1 broker for integration tests.
+//
+// It implements the subset of RabbitMQ used by this service: direct, fanout and topic exchanges,
+// durable queues and bindings, publisher confirms, mandatory publishing, consumers with prefetch,
+// acknowledgements and dead-lettering of rejected or expired messages. State is kept in memory
+// and survives dropped connections, which lets tests exercise reconnect handling.
+package amqptest
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	ExchangeDirect = "direct"
+	ExchangeFanout = "fanout"
+	ExchangeTopic  = "topic"
+)
+
+// ConfirmMode selects how the broker answers publishes on channels in confirm mode
+type ConfirmMode int
+
+const (
+	// ConfirmAck acks every publish, the default
+	ConfirmAck ConfirmMode = iota
+	// ConfirmNack nacks every publish and drops the message
+	ConfirmNack
+	// ConfirmNone routes the message but never confirms it
+	ConfirmNone
+)
+
+// Message is a copy of a message held by a queue
+type Message struct {
+	Exchange    string
+	RoutingKey  string
+	Properties  Properties
+	Body        []byte
+	Redelivered bool
+}
+
+// Binding routes messages published to Exchange with a matching RoutingKey to a queue
+type Binding struct {
+	Exchange   string
+	RoutingKey string
+}
+
+type exchange struct {
+	name string
+	kind string
+}
+
+type queue struct {
+	name     string
+	args     amqp.Table
+	bindings []Binding
+	messages []*message
+	// consumers are served round-robin starting at next
+	consumers []*consumer
+	next      int
+}
+
+type message struct {
+	exchange    string
+	routingKey  string
+	properties  Properties
+	body        []byte
+	redelivered bool
+	expiry      *time.Timer
+}
+
+type consumer struct {
+	tag     string
+	queue   *queue
+	channel *channel
+	noAck   bool
+	unacked int
+}
+
+// Server is an in-process AMQP broker listening on a loopback TCP port
+type Server struct {
+	listener net.Listener
+
+	mu        sync.Mutex
+	exchanges map[string]*exchange
+	queues    map[string]*queue
+	conns     map[*conn]struct{}
+	nameSeq   int
+	closed    bool
+
+	confirmMode ConfirmMode
+}
+
+// NewServer starts a broker on a random loopback port
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener:  listener,
+		exchanges: map[string]*exchange{},
+		queues:    map[string]*queue{},
+		conns:     map[*conn]struct{}{},
+	}
+	for name, kind := range map[string]string{
+		"":           ExchangeDirect,
+		"amq.direct": ExchangeDirect,
+		"amq.fanout": ExchangeFanout,
+		"amq.topic":  ExchangeTopic,
+	} {
+		s.exchanges[name] = &exchange{name: name, kind: kind}
+	}
+
+	go s.acceptLoop()
+
+	return s, nil
+}
+
+// Host returns the host the broker listens on
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host
+}
+
+// Port returns the port the broker listens on
+func (s *Server) Port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+// URL returns an amqp URL for the broker, any credentials are accepted
+func (s *Server) URL() string {
+	return fmt.Sprintf("amqp://guest:guest@%s/", s.listener.Addr().String())
+}
+
+// Close stops accepting connections and drops the open ones
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.DropConnections()
+	return err
+}
+
+// DropConnections closes every client connection without the AMQP close handshake,
+// as if the network failed. Exchanges, queues and ready messages are kept.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.netConn.Close()
+	}
+}
+
+// Reset drops every connection and forgets all exchanges, queues and messages,
+// as if the broker restarted without persistent state
+func (s *Server) Reset() {
+	s.DropConnections()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, ex := range s.exchanges {
+		if name != "" && !strings.HasPrefix(name, "amq.") {
+			delete(s.exchanges, ex.name)
+		}
+	}
+	for _, q := range s.queues {
+		for _, m := range q.messages {
+			if m.expiry != nil {
+				m.expiry.Stop()
+			}
+		}
+	}
+	s.queues = map[string]*queue{}
+}
+
+// SetConfirmMode changes how subsequent publishes are confirmed
+func (s *Server) SetConfirmMode(mode ConfirmMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.confirmMode = mode
+}
+
+// ConnectionCount returns the number of open client connections
+func (s *Server) ConnectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// HasExchange reports whether an exchange was declared
+func (s *Server) HasExchange(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.exchanges[name]
+	return ok
+}
+
+// HasQueue reports whether a queue was declared
+func (s *Server) HasQueue(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.queues[name]
+	return ok
+}
+
+// QueueArgs returns the arguments a queue was declared with
+func (s *Server) QueueArgs(name string) amqp.Table {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[name]
+	if !ok {
+		return nil
+	}
+
+	return q.args
+}
+
+// Bindings returns the bindings of a queue
+func (s *Server) Bindings(queueName string) []Binding {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[queueName]
+	if !ok {
+		return nil
+	}
+
+	return append([]Binding(nil), q.bindings...)
+}
+
+// Messages returns the messages ready for delivery in a queue, unacknowledged deliveries are not included
+func (s *Server) Messages(queueName string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[queueName]
+	if !ok {
+		return nil
+	}
+
+	messages := make([]Message, 0, len(q.messages))
+	for _, m := range q.messages {
+		messages = append(messages, Message{
+			Exchange:    m.exchange,
+			RoutingKey:  m.routingKey,
+			Properties:  m.properties.clone(),
+			Body:        append([]byte(nil), m.body...),
+			Redelivered: m.redelivered,
+		})
+	}
+
+	return messages
+}
+
+// ConsumerCount returns the number of consumers subscribed to a queue
+func (s *Server) ConsumerCount(queueName string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[queueName]
+	if !ok {
+		return 0
+	}
+
+	return len(q.consumers)
+}
+
+func (s *Server) acceptLoop() {
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			netConn.Close()
+			return
+		}
+		c := newConn(s, netConn)
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		go c.serve()
+	}
+}
+
+func (s *Server) generateNameLocked(prefix string) string {
+	s.nameSeq++
+	return fmt.Sprintf("%s%d", prefix, s.nameSeq)
+}
+
+// routeLocked returns the queues a message published to the exchange with the routing key reaches
+func (s *Server) routeLocked(ex *exchange, routingKey string) []*queue {
+	if ex.name == "" {
+		if q, ok := s.queues[routingKey]; ok {
+			return []*queue{q}
+		}
+		return nil
+	}
+
+	var routed []*queue
+	for _, q := range s.queues {
+		for _, b := range q.bindings {
+			if b.Exchange == ex.name && bindingMatches(ex.kind, b.RoutingKey, routingKey) {
+				routed = append(routed, q)
+				break
+			}
+		}
+	}
+
+	return routed
+}
+
+func bindingMatches(kind, bindingKey, routingKey string) bool {
+	switch kind {
+	case ExchangeFanout:
+		return true
+	case ExchangeTopic:
+		return topicMatches(strings.Split(bindingKey, "."), strings.Split(routingKey, "."))
+	default:
+		return bindingKey == routingKey
+	}
+}
+
+// topicMatches matches routing key words against a pattern where * is one word and # is zero or more
+func topicMatches(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if topicMatches(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && topicMatches(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && topicMatches(pattern[1:], words[1:])
+	}
+}
+
+func (s *Server) enqueueLocked(q *queue, m *message) {
+	q.messages = append(q.messages, m)
+
+	if ttl, ok := messageTTL(q, m); ok {
+		m.expiry = time.AfterFunc(ttl, func() {
+			s.expire(q, m)
+		})
+	}
+}
+
+// messageTTL returns the shorter of the queue message TTL and the per-message expiration
+func messageTTL(q *queue, m *message) (time.Duration, bool) {
+	var (
+		ttl time.Duration
+		ok  bool
+	)
+
+	if millis, found := tableInt(q.args, "x-message-ttl"); found {
+		ttl, ok = time.Duration(millis)*time.Millisecond, true
+	}
+	if m.properties.Expiration != "" {
+		if millis, err := strconv.ParseInt(m.properties.Expiration, 10, 64); err == nil {
+			expiration := time.Duration(millis) * time.Millisecond
+			if !ok || expiration < ttl {
+				ttl, ok = expiration, true
+			}
+		}
+	}
+
+	return ttl, ok
+}
+
+func (s *Server) expire(q *queue, m *message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, ready := range q.messages {
+		if ready == m {
+			q.messages = append(q.messages[:i], q.messages[i+1:]...)
+			s.deadLetterLocked(q, m, "expired")
+			s.dispatchLocked()
+			return
+		}
+	}
+}
+
+// deadLetterLocked republishes a rejected or expired message to the dead letter exchange of its queue
+func (s *Server) deadLetterLocked(q *queue, m *message, reason string) {
+	exchangeName, ok := q.args["x-dead-letter-exchange"].(string)
+	if !ok {
+		return
+	}
+	ex, ok := s.exchanges[exchangeName]
+	if !ok {
+		return
+	}
+
+	routingKey := m.routingKey
+	if key, ok := q.args["x-dead-letter-routing-key"].(string); ok {
+		routingKey = key
+	}
+
+	properties := m.properties.clone()
+	properties.Expiration = ""
+	if properties.Headers == nil {
+		properties.Headers = amqp.Table{}
+	}
+	properties.Headers["x-death"] = recordDeath(properties.Headers["x-death"], q.name, reason, m)
+
+	for _, target := range s.routeLocked(ex, routingKey) {
+		s.enqueueLocked(target, &message{
+			exchange:   ex.name,
+			routingKey: routingKey,
+			properties: properties.clone(),
+			body:       m.body,
+		})
+	}
+}
+
+// recordDeath increments the x-death entry of the queue and reason, adding one if missing
+func recordDeath(deaths any, queueName, reason string, m *message) []any {
+	entries, _ := deaths.([]any)
+	for _, entry := range entries {
+		death, ok := entry.(amqp.Table)
+		if ok && death["queue"] == queueName && death["reason"] == reason {
+			count, _ := tableInt(death, "count")
+			death["count"] = count + 1
+			return entries
+		}
+	}
+
+	return append([]any{amqp.Table{
+		"count":        int64(1),
+		"reason":       reason,
+		"queue":        queueName,
+		"exchange":     m.exchange,
+		"routing-keys": []any{m.routingKey},
+		"time":         time.Now(),
+	}}, entries...)
+}
+
+func tableInt(table amqp.Table, key string) (int64, bool) {
+	switch v := table[key].(type) {
+	case int8:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// dispatchLocked delivers ready messages to consumers that have prefetch capacity left
+func (s *Server) dispatchLocked() {
+	for _, q := range s.queues {
+		for len(q.messages) > 0 {
+			c := q.nextConsumer()
+			if c == nil {
+				break
+			}
+
+			m := q.messages[0]
+			q.messages = q.messages[1:]
+			if m.expiry != nil {
+				m.expiry.Stop()
+				m.expiry = nil
+			}
+
+			c.channel.deliverLocked(c, m)
+		}
+	}
+}
+
+func (q *queue) nextConsumer() *consumer {
+	for i := range q.consumers {
+		c := q.consumers[(q.next+i)%len(q.consumers)]
+		if c.noAck || c.channel.prefetch == 0 || c.unacked < c.channel.prefetch {
+			q.next = (q.next + i + 1) % len(q.consumers)
+			return c
+		}
+	}
+
+	return nil
+}
+
+func (q *queue) removeConsumer(c *consumer) {
+	for i, existing := range q.consumers {
+		if existing == c {
+			q.consumers = append(q.consumers[:i], q.consumers[i+1:]...)
+			break
+		}
+	}
+	q.next = 0
+}
+
+// requeueLocked puts a message back at the head of its queue for redelivery
+func (q *queue) requeueLocked(m *message) {
+	m.redelivered = true
+	q.messages = append([]*message{m}, q.messages...)
+}
@@ -0,0 +1,359 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/metrics"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	DefaultConsumerQueueName       = "user-service.inbound"
+	DefaultConsumerConcurrency     = 4
+	DefaultConsumerPrefetch        = 16
+	DefaultConsumerMaxAttempts     = 5
+	DefaultConsumerRetryDelay      = time.Second * 10
+	DefaultConsumerShutdownTimeout = time.Second * 30
+
+	RetryQueueSuffix      = ".retry"
+	DeadLetterQueueSuffix = ".dlq"
+
+	// poisonBodyLogLimit caps how much of a poison message body is logged
+	poisonBodyLogLimit = 1024
+)
+
+// ErrPoisonMessage marks messages that can never be processed, handlers wrap it to skip the retries
+// and move the message to the dead letter queue right away
+var ErrPoisonMessage = eventbus.ErrPoisonMessage
+
+// Handler processes a delivered message, the message is retried when it returns an error.
+// Deliveries are acknowledged by the consumer, handlers must not ack them.
+type Handler func(ctx context.Context, delivery amqp.Delivery) error
+
+type route struct {
+	routingKey string
+	handler    Handler
+}
+
+// Consumer dispatches messages of its queue to the handler registered for their routing key.
+//
+// Failed messages are rejected into a retry queue whose TTL delays them before they are dead-lettered
+// back into the consumer queue. After MaxAttempts, or right away for poison messages, they are moved
+// to a dead letter queue. The queues are named after the consumer queue:
+//
+//	<queue>        bound to the exchange with the routing key of every handler
+//	<queue>.retry  holds failed messages for RetryDelay
+//	<queue>.dlq    keeps messages that will not be retried
+type Consumer struct {
+	logger *logger.Logger
+	client *RabbitMQ
+	config config.ConsumerConfig
+	routes []route
+}
+
+func NewConsumer(logger *logger.Logger, client *RabbitMQ, cfg config.ConsumerConfig) *Consumer {
+	if cfg.QueueName == "" {
+		cfg.QueueName = DefaultConsumerQueueName
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultConsumerConcurrency
+	}
+	if cfg.Prefetch <= 0 {
+		cfg.Prefetch = DefaultConsumerPrefetch
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultConsumerMaxAttempts
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = DefaultConsumerRetryDelay
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = DefaultConsumerShutdownTimeout
+	}
+
+	return &Consumer{
+		logger: logger,
+		client: client,
+		config: cfg,
+	}
+}
+
+// Handle registers the handler for a routing key, which may use the topic wildcards * and #.
+// Handlers must be registered before Start, the first matching registration wins.
+func (c *Consumer) Handle(routingKey string, handler Handler) {
+	c.routes = append(c.routes, route{routingKey: routingKey, handler: handler})
+}
+
+// Start consumes messages until ctx is cancelled, then stops receiving new messages and waits up to
+// ShutdownTimeout for the in-flight ones. Consuming resumes when the client reconnects.
+func (c *Consumer) Start(ctx context.Context) error {
+	if len(c.routes) == 0 {
+		c.logger.Warnf("queue: %s, no message handlers registered, not consuming", c.config.QueueName)
+		return nil
+	}
+
+	if err := c.declareTopology(); err != nil {
+		return err
+	}
+
+	reconnected := c.client.NotifyReconnect(make(chan struct{}, 1))
+	consumerTag := fmt.Sprintf("%s-%s", c.config.QueueName, uuid.New().String())
+
+	for {
+		subscription, err := c.client.Subscribe(c.config.QueueName, consumerTag, c.config.Prefetch)
+		if err != nil {
+			c.logger.Errorf("queue: %s, failed to subscribe, waiting for reconnect: %s", c.config.QueueName, err.Error())
+		} else {
+			c.consume(ctx, subscription)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-reconnected:
+			if !ok {
+				return ErrNotConnected
+			}
+		}
+	}
+}
+
+func (c *Consumer) declareTopology() error {
+	queueName := c.config.QueueName
+	retryQueueName := queueName + RetryQueueSuffix
+
+	// rejected messages go through the default exchange to the retry queue and back once their TTL expired
+	if _, err := c.client.DeclareQueueWithArgs(queueName, amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": retryQueueName,
+	}); err != nil {
+		return fmt.Errorf("failed to declare queue %s: %s", queueName, err.Error())
+	}
+
+	if _, err := c.client.DeclareQueueWithArgs(retryQueueName, amqp.Table{
+		"x-message-ttl":             c.config.RetryDelay.Milliseconds(),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queueName,
+	}); err != nil {
+		return fmt.Errorf("failed to declare queue %s: %s", retryQueueName, err.Error())
+	}
+
+	if _, err := c.client.DeclareQueue(queueName + DeadLetterQueueSuffix); err != nil {
+		return fmt.Errorf("failed to declare queue %s: %s", queueName+DeadLetterQueueSuffix, err.Error())
+	}
+
+	for _, route := range c.routes {
+		if err := c.client.BindQueue(queueName, route.routingKey); err != nil {
+			return fmt.Errorf("failed to bind queue %s to %s: %s", queueName, route.routingKey, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// consume handles deliveries until the subscription ends because the connection dropped or ctx is done
+func (c *Consumer) consume(ctx context.Context, subscription *Subscription) {
+	// handlers finish their message on shutdown, they are only cancelled once the drain times out
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range subscription.Deliveries {
+				c.handle(handlerCtx, delivery)
+			}
+		}()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		c.logger.Warnf("queue: %s, subscription closed", c.config.QueueName)
+		subscription.Close()
+		return
+	case <-ctx.Done():
+	}
+
+	c.logger.Infof("queue: %s, draining in-flight messages", c.config.QueueName)
+	if err := subscription.Cancel(); err != nil {
+		c.logger.Errorf("queue: %s, failed to cancel subscription: %s", c.config.QueueName, err.Error())
+	}
+
+	select {
+	case <-drained:
+	case <-time.After(c.config.ShutdownTimeout):
+		c.logger.Warnf(
+			"queue: %s, in-flight messages not drained after %s, they are requeued",
+			c.config.QueueName, c.config.ShutdownTimeout,
+		)
+	}
+
+	subscription.Close()
+}
+
+func (c *Consumer) handle(ctx context.Context, delivery amqp.Delivery) {
+	rejected := c.rejection(delivery)
+	if rejected.routingKey != "" {
+		delivery.RoutingKey = rejected.routingKey
+	}
+
+	route, ok := c.route(delivery.RoutingKey)
+
+	var err error
+	if ok {
+		err = c.invoke(ctx, route.handler, delivery)
+	} else {
+		route.routingKey = "unhandled"
+		err = fmt.Errorf("%w: no handler for routing key %s", ErrPoisonMessage, delivery.RoutingKey)
+	}
+
+	if err == nil {
+		metrics.ConsumerMessagesTotal.WithLabelValues(route.routingKey, metrics.ConsumeOutcomeAcked).Inc()
+		if err := delivery.Ack(false); err != nil {
+			c.logger.Errorf("messageId: %s, failed to ack message: %s", delivery.MessageId, err.Error())
+		}
+		return
+	}
+
+	attempts := rejected.count + 1
+	if !errors.Is(err, ErrPoisonMessage) && attempts < c.config.MaxAttempts {
+		c.logger.Warnf(
+			"messageId: %s, routingKey: %s, attempt: %d, failed to handle message, retrying in %s: %s",
+			delivery.MessageId, delivery.RoutingKey, attempts, c.config.RetryDelay, err.Error(),
+		)
+
+		metrics.ConsumerMessagesTotal.WithLabelValues(route.routingKey, metrics.ConsumeOutcomeRetried).Inc()
+		if err := delivery.Nack(false, false); err != nil {
+			c.logger.Errorf("messageId: %s, failed to nack message: %s", delivery.MessageId, err.Error())
+		}
+		return
+	}
+
+	c.logger.Errorf(
+		"messageId: %s, routingKey: %s, attempts: %d, moving message to the dead letter queue: %s, body: %s",
+		delivery.MessageId, delivery.RoutingKey, attempts, err.Error(), truncate(delivery.Body, poisonBodyLogLimit),
+	)
+
+	if err := c.deadLetter(ctx, delivery, attempts, err); err != nil {
+		// the message goes through the retry queue and reaches the dead letter queue on its next attempt
+		c.logger.Errorf("messageId: %s, failed to move message to the dead letter queue: %s", delivery.MessageId, err.Error())
+		delivery.Nack(false, false)
+		return
+	}
+
+	metrics.ConsumerMessagesTotal.WithLabelValues(route.routingKey, metrics.ConsumeOutcomeDeadLettered).Inc()
+	if err := delivery.Ack(false); err != nil {
+		c.logger.Errorf("messageId: %s, failed to ack message: %s", delivery.MessageId, err.Error())
+	}
+}
+
+// invoke runs the handler, turning a panic into an error so the message is retried
+func (c *Consumer) invoke(ctx context.Context, handler Handler, delivery amqp.Delivery) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			c.logger.Errorf("messageId: %s, handler panicked: %v\n%s", delivery.MessageId, recovered, debug.Stack())
+			err = fmt.Errorf("handler panicked: %v", recovered)
+		}
+	}()
+
+	return handler(ctx, delivery)
+}
+
+func (c *Consumer) route(routingKey string) (route, bool) {
+	for _, route := range c.routes {
+		if eventbus.MatchTopic(route.routingKey, routingKey) {
+			return route, true
+		}
+	}
+
+	return route{}, false
+}
+
+type rejection struct {
+	count      int
+	routingKey string
+}
+
+// rejection reads how often the message was rejected by the consumer queue before from the x-death
+// header the broker maintains, along with its original routing key which the retry queue replaced
+func (c *Consumer) rejection(delivery amqp.Delivery) rejection {
+	deaths, _ := delivery.Headers["x-death"].([]any)
+	for _, entry := range deaths {
+		death, ok := entry.(amqp.Table)
+		if !ok || death["queue"] != c.config.QueueName || death["reason"] != "rejected" {
+			continue
+		}
+
+		var r rejection
+		switch count := death["count"].(type) {
+		case int64:
+			r.count = int(count)
+		case int32:
+			r.count = int(count)
+		}
+		if routingKeys, ok := death["routing-keys"].([]any); ok && len(routingKeys) > 0 {
+			r.routingKey, _ = routingKeys[0].(string)
+		}
+
+		return r
+	}
+
+	return rejection{}
+}
+
+func (c *Consumer) deadLetter(ctx context.Context, delivery amqp.Delivery, attempts int, cause error) error {
+	headers := amqp.Table{}
+	for k, v := range delivery.Headers {
+		headers[k] = v
+	}
+	headers["x-original-routing-key"] = delivery.RoutingKey
+	headers["x-attempts"] = int64(attempts)
+	headers["x-error"] = cause.Error()
+
+	messageID := delivery.MessageId
+	if messageID == "" {
+		messageID = uuid.New().String()
+	}
+
+	return c.client.publishAndConfirm(ctx, "", c.config.QueueName+DeadLetterQueueSuffix, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     delivery.ContentType,
+		ContentEncoding: delivery.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		CorrelationId:   delivery.CorrelationId,
+		MessageId:       messageID,
+		Timestamp:       delivery.Timestamp,
+		Type:            delivery.Type,
+		AppId:           delivery.AppId,
+		Body:            delivery.Body,
+	})
+}
+
+func truncate(body []byte, limit int) string {
+	if len(body) <= limit {
+		return string(body)
+	}
+
+	return string(body[:limit]) + "..."
+}
@@ -0,0 +1,300 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+const testConsumerQueue = "user-service.inbound"
+
+func newTestConsumer(t *testing.T, client *RabbitMQ, cfg config.ConsumerConfig) *Consumer {
+	t.Helper()
+
+	cfg.QueueName = testConsumerQueue
+	if cfg.RetryDelay == 0 {
+		cfg.RetryDelay = time.Millisecond * 20
+	}
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+
+	return NewConsumer(testLogger, client, cfg)
+}
+
+// startConsumer runs the consumer until the returned stop function is called
+func startConsumer(t *testing.T, consumer *Consumer) (stop func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumer.Start(ctx) }()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			cancel()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("consumer stopped with error: %v", err)
+				}
+			case <-time.After(time.Second * 5):
+				t.Error("consumer did not stop")
+			}
+		})
+	}
+	t.Cleanup(stop)
+
+	return stop
+}
+
+func waitForConsumer(t *testing.T, server interface{ ConsumerCount(string) int }) {
+	t.Helper()
+
+	eventually(t, func() bool { return server.ConsumerCount(testConsumerQueue) == 1 }, "consumer did not subscribe")
+}
+
+func TestConsumerDispatchesByRoutingKey(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+	consumer := newTestConsumer(t, client, config.ConsumerConfig{})
+
+	var bounced, closed atomic.Int32
+	consumer.Handle("mail.bounced", func(ctx context.Context, delivery amqp.Delivery) error {
+		bounced.Add(1)
+		return nil
+	})
+	consumer.Handle("billing.#", func(ctx context.Context, delivery amqp.Delivery) error {
+		closed.Add(1)
+		return nil
+	})
+	startConsumer(t, consumer)
+	waitForConsumer(t, server)
+
+	ctx := context.Background()
+	for _, key := range []string{"mail.bounced", "billing.account.closed", "billing.account.reopened"} {
+		if err := client.PublishAndConfirm(ctx, key, []byte(`{}`)); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	eventually(t, func() bool { return bounced.Load() == 1 && closed.Load() == 2 }, "messages were not dispatched by routing key")
+	eventually(t, func() bool { return len(server.Messages(testConsumerQueue)) == 0 }, "messages were not acked")
+}
+
+func TestConsumerRetriesFailedMessages(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+	consumer := newTestConsumer(t, client, config.ConsumerConfig{MaxAttempts: 5})
+
+	var attempts atomic.Int32
+	consumer.Handle("mail.bounced", func(ctx context.Context, delivery amqp.Delivery) error {
+		switch attempts.Add(1) {
+		case 1:
+			panic("handler bug")
+		case 2:
+			return errors.New("database unavailable")
+		default:
+			return nil
+		}
+	})
+	startConsumer(t, consumer)
+	waitForConsumer(t, server)
+
+	if err := client.PublishAndConfirm(context.Background(), "mail.bounced", []byte(`{}`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	eventually(t, func() bool { return attempts.Load() == 3 }, "message was not retried until it succeeded")
+
+	time.Sleep(time.Millisecond * 100)
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("message was handled %d times after it succeeded", got)
+	}
+	if dlq := server.Messages(testConsumerQueue + DeadLetterQueueSuffix); len(dlq) != 0 {
+		t.Errorf("expected an empty dead letter queue, got %d messages", len(dlq))
+	}
+}
+
+func TestConsumerDeadLettersAfterMaxAttempts(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+	consumer := newTestConsumer(t, client, config.ConsumerConfig{MaxAttempts: 3})
+
+	var attempts atomic.Int32
+	consumer.Handle("mail.bounced", func(ctx context.Context, delivery amqp.Delivery) error {
+		attempts.Add(1)
+		return errors.New("database unavailable")
+	})
+	startConsumer(t, consumer)
+	waitForConsumer(t, server)
+
+	if err := client.PublishAndConfirm(context.Background(), "mail.bounced", []byte(`{"email":"a@b.c"}`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	dlqName := testConsumerQueue + DeadLetterQueueSuffix
+	eventually(t, func() bool { return len(server.Messages(dlqName)) == 1 }, "message was not dead-lettered")
+
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+
+	message := server.Messages(dlqName)[0]
+	if string(message.Body) != `{"email":"a@b.c"}` {
+		t.Errorf("unexpected dead-lettered body: %s", message.Body)
+	}
+	if message.Properties.Headers["x-original-routing-key"] != "mail.bounced" {
+		t.Errorf("unexpected original routing key: %v", message.Properties.Headers["x-original-routing-key"])
+	}
+	if message.Properties.Headers["x-attempts"] != int64(3) {
+		t.Errorf("unexpected attempts header: %v", message.Properties.Headers["x-attempts"])
+	}
+}
+
+func TestConsumerDeadLettersPoisonMessages(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+	consumer := newTestConsumer(t, client, config.ConsumerConfig{MaxAttempts: 5})
+
+	var attempts atomic.Int32
+	consumer.Handle("mail.bounced", func(ctx context.Context, delivery amqp.Delivery) error {
+		attempts.Add(1)
+		return fmt.Errorf("%w: invalid json", ErrPoisonMessage)
+	})
+	startConsumer(t, consumer)
+	waitForConsumer(t, server)
+
+	if err := client.PublishAndConfirm(context.Background(), "mail.bounced", []byte(`not json`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	eventually(t, func() bool {
+		return len(server.Messages(testConsumerQueue+DeadLetterQueueSuffix)) == 1
+	}, "poison message was not dead-lettered")
+
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("poison message was handled %d times", got)
+	}
+}
+
+func TestConsumerRespectsPrefetch(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+	consumer := newTestConsumer(t, client, config.ConsumerConfig{Concurrency: 4, Prefetch: 2})
+
+	release := make(chan struct{})
+	var inFlight, maxInFlight atomic.Int32
+	consumer.Handle("mail.bounced", func(ctx context.Context, delivery amqp.Delivery) error {
+		current := inFlight.Add(1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		<-release
+		inFlight.Add(-1)
+		return nil
+	})
+	startConsumer(t, consumer)
+	waitForConsumer(t, server)
+
+	for i := 0; i < 5; i++ {
+		if err := client.PublishAndConfirm(context.Background(), "mail.bounced", []byte(`{}`)); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	eventually(t, func() bool { return inFlight.Load() == 2 }, "prefetched messages were not handled")
+	if ready := len(server.Messages(testConsumerQueue)); ready != 3 {
+		t.Errorf("expected 3 messages held back by the prefetch limit, got %d", ready)
+	}
+
+	close(release)
+	eventually(t, func() bool { return len(server.Messages(testConsumerQueue)) == 0 && inFlight.Load() == 0 }, "messages were not consumed")
+
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("handled %d messages at once with a prefetch of 2", got)
+	}
+}
+
+func TestConsumerDrainsInFlightMessagesOnShutdown(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+	consumer := newTestConsumer(t, client, config.ConsumerConfig{ShutdownTimeout: time.Second * 5})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handled atomic.Int32
+	consumer.Handle("mail.bounced", func(ctx context.Context, delivery amqp.Delivery) error {
+		close(started)
+		<-release
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		handled.Add(1)
+		return nil
+	})
+	stop := startConsumer(t, consumer)
+	waitForConsumer(t, server)
+
+	if err := client.PublishAndConfirm(context.Background(), "mail.bounced", []byte(`{}`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+
+	eventually(t, func() bool { return server.ConsumerCount(testConsumerQueue) == 0 }, "subscription was not cancelled")
+	select {
+	case <-stopped:
+		t.Fatal("consumer stopped before the in-flight message was handled")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(release)
+	<-stopped
+
+	if handled.Load() != 1 {
+		t.Fatal("in-flight message was not handled")
+	}
+	if ready := len(server.Messages(testConsumerQueue)); ready != 0 {
+		t.Errorf("in-flight message was requeued instead of acked, %d messages ready", ready)
+	}
+}
+
+func TestConsumerResumesAfterReconnect(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+	consumer := newTestConsumer(t, client, config.ConsumerConfig{})
+
+	var handled atomic.Int32
+	consumer.Handle("mail.bounced", func(ctx context.Context, delivery amqp.Delivery) error {
+		handled.Add(1)
+		return nil
+	})
+	startConsumer(t, consumer)
+	waitForConsumer(t, server)
+
+	server.DropConnections()
+	eventually(t, func() bool { return server.ConsumerCount(testConsumerQueue) == 1 }, "consumer did not resubscribe")
+
+	if err := client.PublishAndConfirm(context.Background(), "mail.bounced", []byte(`{}`)); err != nil {
+		t.Fatalf("publish after reconnect failed: %v", err)
+	}
+	eventually(t, func() bool { return handled.Load() == 1 }, "message was not consumed after reconnect")
+}
@@ -0,0 +1,65 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// EventBus adapts the client and its consumer to the event bus interfaces: events are published
+// with publisher confirms and consumed through the retry and dead letter queues of the consumer
+type EventBus struct {
+	client   *RabbitMQ
+	consumer *Consumer
+}
+
+var _ eventbus.EventBus = (*EventBus)(nil)
+
+func NewEventBus(client *RabbitMQ, consumer *Consumer) *EventBus {
+	return &EventBus{
+		client:   client,
+		consumer: consumer,
+	}
+}
+
+func (b *EventBus) Publish(ctx context.Context, topic string, body []byte) error {
+	return b.client.PublishAndConfirm(ctx, topic, body)
+}
+
+func (b *EventBus) Subscribe(topic string, handler eventbus.Handler) {
+	b.consumer.Handle(topic, func(ctx context.Context, delivery amqp.Delivery) error {
+		return handler(ctx, b.toMessage(delivery))
+	})
+}
+
+func (b *EventBus) Start(ctx context.Context) error {
+	return b.consumer.Start(ctx)
+}
+
+func (b *EventBus) Ping(ctx context.Context) error {
+	return b.client.Ping(ctx)
+}
+
+func (b *EventBus) Close() error {
+	return b.client.Close()
+}
+
+func (b *EventBus) toMessage(delivery amqp.Delivery) eventbus.Message {
+	headers := make(map[string]string, len(delivery.Headers))
+	for k, v := range delivery.Headers {
+		if k == "x-death" {
+			continue
+		}
+		headers[k] = fmt.Sprint(v)
+	}
+
+	return eventbus.Message{
+		ID:      delivery.MessageId,
+		Topic:   delivery.RoutingKey,
+		Body:    delivery.Body,
+		Headers: headers,
+		Attempt: b.consumer.rejection(delivery).count + 1,
+	}
+}
@@ -0,0 +1,57 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+)
+
+func TestEventBusDeliversMessagesWithAttempts(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+	bus := NewEventBus(client, newTestConsumer(t, client, config.ConsumerConfig{MaxAttempts: 5}))
+
+	var mu sync.Mutex
+	var received []eventbus.Message
+	bus.Subscribe("mail.bounced", func(ctx context.Context, message eventbus.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		received = append(received, message)
+		if message.Attempt < 2 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- bus.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	waitForConsumer(t, server)
+
+	if err := bus.Publish(context.Background(), "mail.bounced", []byte(`{}`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2
+	}, "message was not retried")
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, message := range received {
+		if message.Topic != "mail.bounced" || message.Attempt != i+1 || message.ID == "" {
+			t.Errorf("unexpected message on delivery %d: %+v", i+1, message)
+		}
+	}
+}
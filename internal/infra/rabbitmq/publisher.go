@@ -0,0 +1,162 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// returnBufferSize must hold the returns the broker sends before the confirmation of a publish,
+	// a full buffer blocks every channel of the connection
+	returnBufferSize = 16
+)
+
+var (
+	ErrPublishNacked = errors.New("message was nacked by the broker")
+	ErrUnroutable    = errors.New("message could not be routed to any queue")
+)
+
+// ReturnHandler is called with every mandatory message the broker returned as unroutable
+type ReturnHandler func(ret amqp.Return)
+
+// publisherChannel is a pooled channel in confirm mode, used by one publisher at a time
+type publisherChannel struct {
+	*amqp.Channel
+	returns chan amqp.Return
+}
+
+func (r *RabbitMQ) openPublisherChannel(conn *amqp.Connection) (*publisherChannel, error) {
+	ch, err := r.openChannel(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to put channel into confirm mode: %v", err)
+	}
+
+	return &publisherChannel{
+		Channel: ch,
+		returns: ch.NotifyReturn(make(chan amqp.Return, returnBufferSize)),
+	}, nil
+}
+
+// SetReturnHandler replaces the handler for returned messages, which logs them by default
+func (r *RabbitMQ) SetReturnHandler(handler ReturnHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.returnHandler = handler
+}
+
+func (r *RabbitMQ) logReturn(ret amqp.Return) {
+	r.logger.Warnf(
+		"messageId: %s, routingKey: %s, message returned by RabbitMQ: %d %s",
+		ret.MessageId, ret.RoutingKey, ret.ReplyCode, ret.ReplyText,
+	)
+}
+
+// PublishAndConfirm publishes a mandatory message and waits until the broker confirmed it.
+// It fails with ErrUnroutable when no queue is bound for the routing key, with ErrPublishNacked when
+// the broker could not take responsibility for the message, and when no confirmation arrives within
+// the configured publish timeout.
+func (r *RabbitMQ) PublishAndConfirm(ctx context.Context, routingKey string, message []byte) error {
+	return r.publishAndConfirm(ctx, r.config.RabbitMQ.ExchangeName, routingKey, newPublishing(message))
+}
+
+func (r *RabbitMQ) publishAndConfirm(
+	ctx context.Context,
+	exchange string,
+	routingKey string,
+	publishing amqp.Publishing,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, r.config.RabbitMQ.PublishTimeout)
+	defer cancel()
+
+	err := r.withChannel(ctx, func(ch *publisherChannel) error {
+		// returns left over from a publish that timed out on this channel
+		r.takeReturn(ch, "")
+
+		confirmation, err := ch.PublishWithDeferredConfirmWithContext(
+			ctx,
+			exchange,   // exchange
+			routingKey, // routing key
+			true,       // mandatory
+			false,      // immediate
+			publishing,
+		)
+		if err != nil {
+			return err
+		}
+
+		acked, err := confirmation.WaitContext(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for confirmation: %w", err)
+		}
+
+		// the broker sends the return of an unroutable message before its ack
+		if ret, ok := r.takeReturn(ch, publishing.MessageId); ok {
+			return fmt.Errorf("%w: %s", ErrUnroutable, ret.ReplyText)
+		}
+
+		if !acked {
+			if ch.IsClosed() {
+				return ErrNotConnected
+			}
+			return ErrPublishNacked
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish a message: %w", err)
+	}
+
+	return nil
+}
+
+// takeReturn hands the buffered returns of the channel to the return handler and reports
+// the one of the message with the given id
+func (r *RabbitMQ) takeReturn(ch *publisherChannel, messageID string) (amqp.Return, bool) {
+	r.mu.RLock()
+	handler := r.returnHandler
+	r.mu.RUnlock()
+
+	var (
+		found    amqp.Return
+		returned bool
+	)
+	for {
+		select {
+		case ret, ok := <-ch.returns:
+			if !ok {
+				return found, returned
+			}
+
+			handler(ret)
+			if messageID != "" && ret.MessageId == messageID {
+				found, returned = ret, true
+			}
+			continue
+		default:
+		}
+
+		return found, returned
+	}
+}
+
+func newPublishing(message []byte) amqp.Publishing {
+	return amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    uuid.New().String(),
+		Timestamp:    time.Now(),
+		Body:         message,
+	}
+}
@@ -0,0 +1,106 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/infra/rabbitmq/amqptest"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestPublishAndConfirm(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	if err := client.Setup(testQueue, "user.#"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	if err := client.PublishAndConfirm(context.Background(), "user.password_reset.requested", []byte(`{}`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	// the message is queued by the time the broker confirms it
+	messages := server.Messages(testQueue)
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 queued message, got %d", len(messages))
+	}
+	if messages[0].Properties.MessageID == "" {
+		t.Error("message was published without a message id")
+	}
+}
+
+func TestPublishAndConfirmUnroutable(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	if err := client.Setup(testQueue, "user.#"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	var (
+		mu       sync.Mutex
+		returned []amqp.Return
+	)
+	client.SetReturnHandler(func(ret amqp.Return) {
+		mu.Lock()
+		defer mu.Unlock()
+		returned = append(returned, ret)
+	})
+
+	err := client.PublishAndConfirm(context.Background(), "billing.account.closed", []byte(`{}`))
+	if !errors.Is(err, ErrUnroutable) {
+		t.Fatalf("expected ErrUnroutable, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(returned) != 1 || returned[0].RoutingKey != "billing.account.closed" || returned[0].ReplyCode != 312 {
+		t.Fatalf("return handler was not called with the returned message: %+v", returned)
+	}
+
+	// routable messages on the same channel are unaffected by the earlier return
+	if err := client.PublishAndConfirm(context.Background(), "user.account.deleted", []byte(`{}`)); err != nil {
+		t.Fatalf("publish after return failed: %v", err)
+	}
+}
+
+func TestPublishAndConfirmNacked(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	if err := client.Setup(testQueue, "user.#"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	server.SetConfirmMode(amqptest.ConfirmNack)
+
+	err := client.PublishAndConfirm(context.Background(), "user.account.deleted", []byte(`{}`))
+	if !errors.Is(err, ErrPublishNacked) {
+		t.Fatalf("expected ErrPublishNacked, got %v", err)
+	}
+}
+
+func TestPublishAndConfirmTimesOut(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 1)
+	client.config.RabbitMQ.PublishTimeout = time.Millisecond * 100
+
+	if err := client.Setup(testQueue, "user.#"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	server.SetConfirmMode(amqptest.ConfirmNone)
+
+	err := client.PublishAndConfirm(context.Background(), "user.account.deleted", []byte(`{}`))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+
+	// the late confirmation of the timed out message must not be taken for the next one
+	server.SetConfirmMode(amqptest.ConfirmAck)
+	if err := client.PublishAndConfirm(context.Background(), "user.account.deleted", []byte(`{}`)); err != nil {
+		t.Fatalf("publish after timeout failed: %v", err)
+	}
+}
@@ -2,7 +2,11 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/datpham/user-service-ms/config"
@@ -10,11 +14,44 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+const (
+	DefaultChannelPoolSize         = 8
+	DefaultReconnectInitialBackoff = time.Millisecond * 500
+	DefaultReconnectMaxBackoff     = time.Second * 30
+	DefaultPublishTimeout          = time.Second * 5
+)
+
+var ErrNotConnected = errors.New("not connected to RabbitMQ")
+
+type queueBinding struct {
+	queueName  string
+	routingKey string
+}
+
+// RabbitMQ is a RabbitMQ client that reconnects with exponential backoff when the connection drops
+// and re-declares the exchange, queues and bindings it declared before. Publishers share a bounded
+// pool of channels, so it is safe for concurrent use.
 type RabbitMQ struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	logger  *logger.Logger
-	config  *config.Config
+	logger *logger.Logger
+	config *config.Config
+	url    string
+
+	mu   sync.RWMutex
+	conn *amqp.Connection
+	// idle holds open channels of the current connection ready for reuse
+	idle chan *publisherChannel
+	// queues holds the arguments of the declared queues by name, bindings the declared bindings, so
+	// declaring them again replaces the previous declaration instead of adding another one
+	queues   map[string]amqp.Table
+	bindings map[queueBinding]struct{}
+	// reconnectListeners are notified after every successful reconnect
+	reconnectListeners []chan struct{}
+	returnHandler      ReturnHandler
+
+	// slots bounds the number of channels in use at the same time
+	slots     chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 // NewRabbitMQClient creates a new RabbitMQ client
@@ -26,37 +63,269 @@ func NewRabbitMQClient(logger *logger.Logger, cfg *config.Config) (*RabbitMQ, er
 		cfg.RabbitMQ.Port,
 	)
 
-	conn, err := amqp.Dial(rabbitUrl)
+	if cfg.RabbitMQ.ChannelPoolSize <= 0 {
+		cfg.RabbitMQ.ChannelPoolSize = DefaultChannelPoolSize
+	}
+	if cfg.RabbitMQ.ReconnectInitialBackoff <= 0 {
+		cfg.RabbitMQ.ReconnectInitialBackoff = DefaultReconnectInitialBackoff
+	}
+	if cfg.RabbitMQ.ReconnectMaxBackoff <= 0 {
+		cfg.RabbitMQ.ReconnectMaxBackoff = DefaultReconnectMaxBackoff
+	}
+	if cfg.RabbitMQ.PublishTimeout <= 0 {
+		cfg.RabbitMQ.PublishTimeout = DefaultPublishTimeout
+	}
+
+	r := &RabbitMQ{
+		logger:   logger,
+		config:   cfg,
+		url:      rabbitUrl,
+		slots:    make(chan struct{}, cfg.RabbitMQ.ChannelPoolSize),
+		done:     make(chan struct{}),
+		queues:   map[string]amqp.Table{},
+		bindings: map[queueBinding]struct{}{},
+	}
+	r.returnHandler = r.logReturn
+
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// connect dials the broker, declares the topology and starts watching the new connection
+func (r *RabbitMQ) connect() error {
+	conn, err := amqp.Dial(r.url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %v", err)
+		return fmt.Errorf("failed to connect to RabbitMQ: %v", err)
 	}
 
-	ch, err := conn.Channel()
+	ch, err := r.openPublisherChannel(conn)
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to open a channel: %v", err)
+		return err
 	}
 
-	if err = ch.ExchangeDeclare(
-		cfg.RabbitMQ.ExchangeName, // exchange name
-		"topic",                   // exchange type (topic allows routing based on wildcards)
-		true,                      // durable
-		false,                     // auto-deleted
-		false,                     // internal
-		false,                     // no-wait
-		nil,                       // arguments
-	); err != nil {
+	if err := r.declareTopology(ch.Channel); err != nil {
 		ch.Close()
 		conn.Close()
-		return nil, fmt.Errorf("failed to declare an exchange: %v", err)
+		return err
 	}
 
-	return &RabbitMQ{
-		conn:    conn,
-		channel: ch,
-		logger:  logger,
-		config:  cfg,
-	}, nil
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	r.mu.Lock()
+	select {
+	case <-r.done:
+		// closed while connecting
+		r.mu.Unlock()
+		conn.Close()
+		return ErrNotConnected
+	default:
+	}
+	r.conn = conn
+	r.idle = make(chan *publisherChannel, r.config.RabbitMQ.ChannelPoolSize)
+	r.idle <- ch
+	r.mu.Unlock()
+
+	go r.watch(closed)
+
+	return nil
+}
+
+func (r *RabbitMQ) declareTopology(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(
+		r.config.RabbitMQ.ExchangeName, // exchange name
+		"topic",                        // exchange type (topic allows routing based on wildcards)
+		true,                           // durable
+		false,                          // auto-deleted
+		false,                          // internal
+		false,                          // no-wait
+		nil,                            // arguments
+	); err != nil {
+		return fmt.Errorf("failed to declare an exchange: %v", err)
+	}
+
+	r.mu.RLock()
+	queues := maps.Clone(r.queues)
+	bindings := slices.Collect(maps.Keys(r.bindings))
+	r.mu.RUnlock()
+
+	for name, args := range queues {
+		if _, err := declareQueue(ch, name, args); err != nil {
+			return fmt.Errorf("failed to declare queue %s: %v", name, err)
+		}
+	}
+
+	for _, binding := range bindings {
+		if err := r.bindQueue(ch, binding.queueName, binding.routingKey); err != nil {
+			return fmt.Errorf("failed to bind queue %s: %v", binding.queueName, err)
+		}
+	}
+
+	return nil
+}
+
+// watch reconnects once the connection is closed by anything but Close
+func (r *RabbitMQ) watch(closed <-chan *amqp.Error) {
+	closeErr := <-closed
+
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+
+	r.logger.Errorf("RabbitMQ connection lost: %v", closeErr)
+	r.reconnect()
+}
+
+func (r *RabbitMQ) reconnect() {
+	backoff := r.config.RabbitMQ.ReconnectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-r.done:
+			return
+		case <-time.After(backoff):
+		}
+
+		if err := r.connect(); err != nil {
+			r.logger.Warnf("attempt: %d, failed to reconnect to RabbitMQ: %s", attempt, err.Error())
+			backoff = min(backoff*2, r.config.RabbitMQ.ReconnectMaxBackoff)
+			continue
+		}
+
+		r.logger.Infof("reconnected to RabbitMQ after %d attempts", attempt)
+		r.notifyReconnect()
+		return
+	}
+}
+
+// Ping reports ErrNotConnected while the client has no open connection, e.g. while reconnecting
+func (r *RabbitMQ) Ping(ctx context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.conn == nil || r.conn.IsClosed() {
+		return ErrNotConnected
+	}
+
+	return nil
+}
+
+// NotifyReconnect registers a listener that receives a value after every successful reconnect,
+// e.g. to resubscribe consumers whose delivery channels were closed with the old connection.
+// The listener is closed when the client is closed.
+func (r *RabbitMQ) NotifyReconnect(listener chan struct{}) chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		close(listener)
+	default:
+		r.reconnectListeners = append(r.reconnectListeners, listener)
+	}
+
+	return listener
+}
+
+func (r *RabbitMQ) notifyReconnect() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, listener := range r.reconnectListeners {
+		select {
+		case listener <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (r *RabbitMQ) openChannel(conn *amqp.Connection) (*amqp.Channel, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a channel: %v", err)
+	}
+
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		// the broker closes a channel on soft errors such as publishing to a missing exchange
+		if err := <-closed; err != nil && !conn.IsClosed() {
+			r.logger.Warnf("RabbitMQ channel closed: %s", err.Error())
+		}
+	}()
+
+	return ch, nil
+}
+
+// acquireChannel takes an idle channel from the pool or opens a new one, waiting while the pool is exhausted
+func (r *RabbitMQ) acquireChannel(ctx context.Context) (*publisherChannel, error) {
+	select {
+	case r.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	r.mu.RLock()
+	conn, idle := r.conn, r.idle
+	r.mu.RUnlock()
+
+	for {
+		select {
+		case ch := <-idle:
+			if ch.IsClosed() {
+				continue
+			}
+			return ch, nil
+		default:
+		}
+
+		break
+	}
+
+	if conn == nil || conn.IsClosed() {
+		<-r.slots
+		return nil, ErrNotConnected
+	}
+
+	ch, err := r.openPublisherChannel(conn)
+	if err != nil {
+		<-r.slots
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+// releaseChannel returns a channel to the pool, channels closed in the meantime are dropped
+func (r *RabbitMQ) releaseChannel(ch *publisherChannel) {
+	defer func() { <-r.slots }()
+
+	if ch.IsClosed() {
+		return
+	}
+
+	r.mu.RLock()
+	idle := r.idle
+	r.mu.RUnlock()
+
+	select {
+	case idle <- ch:
+	default:
+		ch.Close()
+	}
+}
+
+func (r *RabbitMQ) withChannel(ctx context.Context, fn func(ch *publisherChannel) error) error {
+	ch, err := r.acquireChannel(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.releaseChannel(ch)
+
+	return fn(ch)
 }
 
 func (r *RabbitMQ) Setup(queueName, routingKey string) error {
@@ -72,21 +341,59 @@ func (r *RabbitMQ) Setup(queueName, routingKey string) error {
 	return nil
 }
 
-// DeclareQueue declares a new queue
+// DeclareQueue declares a new queue, it is declared again after every reconnect
 func (r *RabbitMQ) DeclareQueue(name string) (amqp.Queue, error) {
-	return r.channel.QueueDeclare(
+	return r.DeclareQueueWithArgs(name, nil)
+}
+
+// DeclareQueueWithArgs declares a new queue with arguments such as x-message-ttl or x-dead-letter-exchange,
+// it is declared again after every reconnect
+func (r *RabbitMQ) DeclareQueueWithArgs(name string, args amqp.Table) (amqp.Queue, error) {
+	var queue amqp.Queue
+	err := r.withChannel(context.Background(), func(ch *publisherChannel) error {
+		var err error
+		queue, err = declareQueue(ch.Channel, name, args)
+		return err
+	})
+	if err != nil {
+		return queue, err
+	}
+
+	r.mu.Lock()
+	r.queues[queue.Name] = args
+	r.mu.Unlock()
+
+	return queue, nil
+}
+
+func declareQueue(ch *amqp.Channel, name string, args amqp.Table) (amqp.Queue, error) {
+	return ch.QueueDeclare(
 		name,  // queue name
 		true,  // durable
 		false, // delete when unused
 		false, // exclusive
 		false, // no-wait
-		nil,   // arguments
+		args,  // arguments
 	)
 }
 
-// BindQueue binds a queue to the exchange with a routing key
+// BindQueue binds a queue to the exchange with a routing key, it is bound again after every reconnect
 func (r *RabbitMQ) BindQueue(queueName, routingKey string) error {
-	return r.channel.QueueBind(
+	if err := r.withChannel(context.Background(), func(ch *publisherChannel) error {
+		return r.bindQueue(ch.Channel, queueName, routingKey)
+	}); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.bindings[queueBinding{queueName: queueName, routingKey: routingKey}] = struct{}{}
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *RabbitMQ) bindQueue(ch *amqp.Channel, queueName, routingKey string) error {
+	return ch.QueueBind(
 		queueName,                      // queue name
 		routingKey,                     // routing key
 		r.config.RabbitMQ.ExchangeName, // exchange
@@ -95,54 +402,132 @@ func (r *RabbitMQ) BindQueue(queueName, routingKey string) error {
 	)
 }
 
-// Publish publishes a message to a queue
+// Publish publishes a message to a queue without waiting for the broker to confirm it,
+// use PublishAndConfirm when the message must not be lost
 func (r *RabbitMQ) Publish(ctx context.Context, routingKey string, message []byte) error {
-	err := r.channel.PublishWithContext(
-		ctx,
-		r.config.RabbitMQ.ExchangeName, // exchange
-		routingKey,                     // routing key
-		false,                          // mandatory
-		false,                          // immediate
-		amqp.Publishing{
-			ContentType:  "application/json",
-			DeliveryMode: amqp.Persistent,
-			Timestamp:    time.Now(),
-			Body:         message,
-		},
-	)
+	err := r.withChannel(ctx, func(ch *publisherChannel) error {
+		return ch.PublishWithContext(
+			ctx,
+			r.config.RabbitMQ.ExchangeName, // exchange
+			routingKey,                     // routing key
+			false,                          // mandatory
+			false,                          // immediate
+			newPublishing(message),
+		)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to publish a message: %v", err)
+		return fmt.Errorf("failed to publish a message: %w", err)
 	}
 
 	return nil
 }
 
-// Consume consumes messages from a queue
+// Consume consumes messages from a queue on a dedicated channel. The delivery channel is closed when
+// the connection drops, use NotifyReconnect to consume again once the client has reconnected.
 func (r *RabbitMQ) Consume(queueName, consumerName string) (<-chan amqp.Delivery, error) {
-	return r.channel.Consume(
-		queueName,    // queue
-		consumerName, // consumer
-		false,        // auto-ack
-		false,        // exclusive
-		false,        // no-local
-		false,        // no-wait
-		nil,          // arguments
+	subscription, err := r.Subscribe(queueName, consumerName, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription.Deliveries, nil
+}
+
+// Subscription is a consumer on a dedicated channel
+type Subscription struct {
+	channel     *amqp.Channel
+	consumerTag string
+	// Deliveries is closed once the subscription is cancelled or the connection drops
+	Deliveries <-chan amqp.Delivery
+}
+
+// Subscribe consumes messages from a queue, the broker sends at most prefetch unacknowledged
+// messages at a time, 0 means no limit
+func (r *RabbitMQ) Subscribe(queueName, consumerTag string, prefetch int) (*Subscription, error) {
+	r.mu.RLock()
+	conn := r.conn
+	r.mu.RUnlock()
+
+	if conn == nil || conn.IsClosed() {
+		return nil, ErrNotConnected
+	}
+
+	ch, err := r.openChannel(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to set prefetch: %v", err)
+	}
+
+	deliveries, err := ch.Consume(
+		queueName,   // queue
+		consumerTag, // consumer
+		false,       // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // arguments
 	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to consume queue %s: %v", queueName, err)
+	}
+
+	return &Subscription{
+		channel:     ch,
+		consumerTag: consumerTag,
+		Deliveries:  deliveries,
+	}, nil
 }
 
-// Close closes the RabbitMQ connection and channel
+// Cancel stops the delivery of new messages, deliveries already received are still handed out
+// and can be acknowledged until the subscription is closed
+func (s *Subscription) Cancel() error {
+	return s.channel.Cancel(s.consumerTag, false)
+}
+
+// Close closes the channel of the subscription, the broker requeues unacknowledged messages
+func (s *Subscription) Close() error {
+	if s.channel.IsClosed() {
+		return nil
+	}
+
+	return s.channel.Close()
+}
+
+// Close closes the RabbitMQ connection and pooled channels and stops reconnecting
 func (r *RabbitMQ) Close() error {
-	if r.channel != nil {
-		if err := r.channel.Close(); err != nil {
-			return fmt.Errorf("failed to close channel: %v", err)
+	var err error
+	r.closeOnce.Do(func() {
+		r.mu.Lock()
+		close(r.done)
+		for _, listener := range r.reconnectListeners {
+			close(listener)
 		}
-	}
+		r.reconnectListeners = nil
+		conn, idle := r.conn, r.idle
+		r.mu.Unlock()
+
+		for {
+			select {
+			case ch := <-idle:
+				ch.Close()
+				continue
+			default:
+			}
 
-	if r.conn != nil {
-		if err := r.conn.Close(); err != nil {
-			return fmt.Errorf("failed to close connection: %v", err)
+			break
 		}
-	}
 
-	return nil
+		if conn != nil && !conn.IsClosed() {
+			if closeErr := conn.Close(); closeErr != nil {
+				err = fmt.Errorf("failed to close connection: %v", closeErr)
+			}
+		}
+	})
+
+	return err
 }
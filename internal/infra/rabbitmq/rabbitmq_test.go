@@ -0,0 +1,302 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/infra/rabbitmq/amqptest"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	testExchange = "user-service"
+	testQueue    = "user-events"
+)
+
+func newTestServer(t *testing.T) *amqptest.Server {
+	t.Helper()
+
+	server, err := amqptest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start amqp server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return server
+}
+
+func newTestClient(t *testing.T, server *amqptest.Server, poolSize int) *RabbitMQ {
+	t.Helper()
+
+	cfg := &config.Config{
+		RabbitMQ: config.RabbitMQConfig{
+			Host:                    server.Host(),
+			Port:                    server.Port(),
+			User:                    "guest",
+			Password:                "guest",
+			ExchangeName:            testExchange,
+			ChannelPoolSize:         poolSize,
+			ReconnectInitialBackoff: time.Millisecond * 10,
+			ReconnectMaxBackoff:     time.Millisecond * 100,
+		},
+	}
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+
+	client, err := NewRabbitMQClient(testLogger, cfg)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func eventually(t *testing.T, condition func() bool, msg string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	t.Fatal(msg)
+}
+
+func TestSetupDeclaresTopology(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	if err := client.Setup(testQueue, "user.#"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	if !server.HasExchange(testExchange) {
+		t.Errorf("exchange %s was not declared", testExchange)
+	}
+
+	bindings := server.Bindings(testQueue)
+	if len(bindings) != 1 || bindings[0] != (amqptest.Binding{Exchange: testExchange, RoutingKey: "user.#"}) {
+		t.Errorf("unexpected bindings: %+v", bindings)
+	}
+}
+
+func TestPublishRoutesByRoutingKey(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	if err := client.Setup(testQueue, "user.#"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := client.Publish(ctx, "user.account.deleted", []byte(`{"user_id":"1"}`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if err := client.Publish(ctx, "billing.account.closed", []byte(`{}`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	eventually(t, func() bool { return len(server.Messages(testQueue)) == 1 }, "message was not routed to the queue")
+
+	message := server.Messages(testQueue)[0]
+	if message.RoutingKey != "user.account.deleted" || string(message.Body) != `{"user_id":"1"}` {
+		t.Errorf("unexpected message: %s %s", message.RoutingKey, message.Body)
+	}
+	if message.Properties.ContentType != "application/json" {
+		t.Errorf("unexpected content type: %s", message.Properties.ContentType)
+	}
+}
+
+func TestConcurrentPublishersShareChannelPool(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 3)
+
+	if err := client.Setup(testQueue, "user.#"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	const publishers, messagesPerPublisher = 20, 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, publishers*messagesPerPublisher)
+	for p := 0; p < publishers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for m := 0; m < messagesPerPublisher; m++ {
+				body := []byte(fmt.Sprintf("%d-%d", p, m))
+				if err := client.Publish(context.Background(), "user.login.new_device", body); err != nil {
+					errs <- err
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	eventually(t, func() bool {
+		return len(server.Messages(testQueue)) == publishers*messagesPerPublisher
+	}, "not every message reached the queue")
+
+	if idle := len(client.idle); idle > 3 {
+		t.Errorf("pool holds %d idle channels, expected at most 3", idle)
+	}
+}
+
+func TestReconnectRedeclaresTopology(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	if err := client.Setup(testQueue, "user.#"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	reconnected := client.NotifyReconnect(make(chan struct{}, 1))
+
+	// a broker restart loses the topology along with the connection
+	server.Reset()
+
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second * 5):
+		t.Fatal("client did not reconnect")
+	}
+
+	if !server.HasExchange(testExchange) || !server.HasQueue(testQueue) || len(server.Bindings(testQueue)) != 1 {
+		t.Fatal("topology was not declared again after reconnect")
+	}
+
+	if err := client.Publish(context.Background(), "user.account.deleted", []byte(`{}`)); err != nil {
+		t.Fatalf("publish after reconnect failed: %v", err)
+	}
+	eventually(t, func() bool { return len(server.Messages(testQueue)) == 1 }, "message was not routed after reconnect")
+}
+
+func TestRedeclaringTopologyReplacesDeclarations(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	for i := 0; i < 3; i++ {
+		if err := client.Setup(testQueue, "user.#"); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+	}
+	if _, err := client.DeclareQueueWithArgs(testQueue, amqp.Table{"x-message-ttl": int32(1000)}); err != nil {
+		t.Fatalf("declare failed: %v", err)
+	}
+	if err := client.BindQueue(testQueue, "user.account.*"); err != nil {
+		t.Fatalf("bind failed: %v", err)
+	}
+
+	client.mu.RLock()
+	queues, bindings := len(client.queues), len(client.bindings)
+	args := client.queues[testQueue]
+	client.mu.RUnlock()
+	if queues != 1 || bindings != 2 {
+		t.Fatalf("expected 1 queue and 2 bindings to be kept, got %d queues and %d bindings", queues, bindings)
+	}
+	if args["x-message-ttl"] != int32(1000) {
+		t.Errorf("expected the last arguments of the queue to be kept, got %v", args)
+	}
+
+	reconnected := client.NotifyReconnect(make(chan struct{}, 1))
+	server.Reset()
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second * 5):
+		t.Fatal("client did not reconnect")
+	}
+
+	if bindings := server.Bindings(testQueue); len(bindings) != 2 {
+		t.Errorf("expected the 2 bindings to be declared again once, got %+v", bindings)
+	}
+}
+
+func TestPublishRecoversAfterConnectionDrop(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	if err := client.Setup(testQueue, "user.#"); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	reconnected := client.NotifyReconnect(make(chan struct{}, 1))
+	server.DropConnections()
+
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second * 5):
+		t.Fatal("client did not reconnect")
+	}
+
+	if server.ConnectionCount() != 1 {
+		t.Fatalf("expected a single connection after reconnect, got %d", server.ConnectionCount())
+	}
+	if err := client.Publish(context.Background(), "user.account.deleted", []byte(`{}`)); err != nil {
+		t.Fatalf("publish after reconnect failed: %v", err)
+	}
+	eventually(t, func() bool { return len(server.Messages(testQueue)) == 1 }, "message was not routed after reconnect")
+}
+
+func TestPublishFailsWhileBrokerIsDown(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	server.Close()
+
+	eventually(t, func() bool {
+		err := client.Publish(context.Background(), "user.account.deleted", []byte(`{}`))
+		return errors.Is(err, ErrNotConnected)
+	}, "publish did not report the lost connection")
+}
+
+func TestPingReportsConnectionState(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("ping while connected failed: %v", err)
+	}
+
+	server.Close()
+
+	eventually(t, func() bool {
+		return errors.Is(client.Ping(context.Background()), ErrNotConnected)
+	}, "ping did not report the lost connection")
+}
+
+func TestCloseStopsReconnecting(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server, 2)
+
+	reconnected := client.NotifyReconnect(make(chan struct{}, 1))
+	if err := client.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	if _, ok := <-reconnected; ok {
+		t.Fatal("reconnect listener was not closed")
+	}
+
+	eventually(t, func() bool { return server.ConnectionCount() == 0 }, "connection was not closed")
+
+	time.Sleep(time.Millisecond * 100)
+	if count := server.ConnectionCount(); count != 0 {
+		t.Fatalf("client reconnected after close, %d connections open", count)
+	}
+}
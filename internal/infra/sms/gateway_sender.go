@@ -0,0 +1,62 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/httpclient"
+)
+
+const (
+	DefaultGatewayTimeout = time.Second * 10
+)
+
+// GatewaySender sends text messages through an HTTP gateway that accepts
+// POST {"from", "to", "text"} authenticated with a bearer API key
+type GatewaySender struct {
+	client *httpclient.Client
+	config config.SMSGatewayConfig
+}
+
+type gatewayRequest struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+func NewGatewaySender(cfg config.SMSGatewayConfig) (*GatewaySender, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("sms.gateway.url is required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultGatewayTimeout
+	}
+
+	return &GatewaySender{
+		client: httpclient.NewClient(cfg.Timeout),
+		config: cfg,
+	}, nil
+}
+
+func (s *GatewaySender) Send(ctx context.Context, to string, body string) error {
+	headers := map[string]string{}
+	if s.config.APIKey != "" {
+		headers["Authorization"] = "Bearer " + s.config.APIKey
+	}
+
+	resp, err := s.client.Post(ctx, s.config.URL, &httpclient.RequestOptions{
+		Headers: headers,
+		Body:    &gatewayRequest{From: s.config.From, To: to, Text: body},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to call sms gateway: %w", err)
+	}
+
+	if !resp.IsSuccess() {
+		return fmt.Errorf("sms gateway responded with status %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+)
+
+// LogSender logs text messages instead of sending them, for local development.
+// When a file path is set every message is also appended to it as a JSON line.
+type LogSender struct {
+	logger   *logger.Logger
+	filePath string
+	mu       sync.Mutex
+}
+
+type loggedMessage struct {
+	SentAt time.Time `json:"sent_at"`
+	To     string    `json:"to"`
+	Body   string    `json:"body"`
+}
+
+func NewLogSender(logger *logger.Logger, filePath string) *LogSender {
+	return &LogSender{
+		logger:   logger,
+		filePath: filePath,
+	}
+}
+
+func (s *LogSender) Send(ctx context.Context, to string, body string) error {
+	s.logger.Infof("SMS to %s: %s", to, body)
+
+	if s.filePath == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(&loggedMessage{SentAt: time.Now(), To: to, Body: body})
+	if err != nil {
+		return fmt.Errorf("failed to marshal sms: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0o755); err != nil {
+		return fmt.Errorf("failed to create sms log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(s.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open sms log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write sms log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package sms
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogSenderAppendsToFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "sms", "outbox.jsonl")
+	sender := NewLogSender(logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard}), filePath)
+
+	for _, body := range []string{"first", "second"} {
+		if err := sender.Send(context.Background(), "+84901234567", body); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		t.Fatalf("open sms log: %v", err)
+	}
+	defer file.Close()
+
+	var messages []loggedMessage
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var message loggedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			t.Fatalf("unmarshal sms log line: %v", err)
+		}
+		messages = append(messages, message)
+	}
+
+	if len(messages) != 2 || messages[0].Body != "first" || messages[1].To != "+84901234567" {
+		t.Fatalf("unexpected sms log %+v", messages)
+	}
+}
+
+func TestGatewaySenderSend(t *testing.T) {
+	var received gatewayRequest
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sender, err := NewGatewaySender(config.SMSGatewayConfig{URL: server.URL, APIKey: "secret", From: "UserMS"})
+	if err != nil {
+		t.Fatalf("new gateway sender: %v", err)
+	}
+
+	if err := sender.Send(context.Background(), "+84901234567", "123456 is your code"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if authorization != "Bearer secret" {
+		t.Fatalf("unexpected authorization %q", authorization)
+	}
+	if received.From != "UserMS" || received.To != "+84901234567" || received.Text != "123456 is your code" {
+		t.Fatalf("unexpected request %+v", received)
+	}
+}
+
+func TestGatewaySenderRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid number", http.StatusUnprocessableEntity)
+	}))
+	defer server.Close()
+
+	sender, err := NewGatewaySender(config.SMSGatewayConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("new gateway sender: %v", err)
+	}
+
+	if err := sender.Send(context.Background(), "+84901234567", "hello"); err == nil {
+		t.Fatal("expected error for rejected message")
+	}
+}
+
+func TestNewGatewaySenderRequiresURL(t *testing.T) {
+	if _, err := NewGatewaySender(config.SMSGatewayConfig{}); err == nil {
+		t.Fatal("expected error without url")
+	}
+}
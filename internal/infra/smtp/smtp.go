@@ -0,0 +1,205 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/service/notifier"
+)
+
+const (
+	DefaultPort    = 25
+	DefaultTimeout = time.Second * 10
+
+	TLSModeStartTLS = "starttls"
+	TLSModeImplicit = "tls"
+	TLSModeNone     = "none"
+)
+
+// Mailer sends emails through an SMTP relay, opening a connection per message
+type Mailer struct {
+	config config.SMTPConfig
+}
+
+func NewMailer(cfg config.SMTPConfig) *Mailer {
+	if cfg.Port == 0 {
+		cfg.Port = DefaultPort
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	return &Mailer{config: cfg}
+}
+
+// Send delivers the message to every recipient, the whole exchange is bounded by the configured timeout
+func (m *Mailer) Send(ctx context.Context, message *notifier.Message) error {
+	from, err := mail.ParseAddress(message.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", message.From, err)
+	}
+
+	data, err := buildMessage(message, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM failed: %w", err)
+	}
+	for _, to := range message.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s failed: %w", to, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA failed: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("smtp server rejected the message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func (m *Mailer) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	tlsConfig := &tls.Config{ServerName: m.config.Host}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to smtp server %s: %w", addr, err)
+	}
+	// net/smtp has no context support, the deadline bounds every command instead
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if m.config.TLSMode == TLSModeImplicit {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start smtp session: %w", err)
+	}
+
+	if m.config.TLSMode != TLSModeImplicit && m.config.TLSMode != TLSModeNone {
+		supported, _ := client.Extension("STARTTLS")
+		if supported {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, fmt.Errorf("smtp STARTTLS failed: %w", err)
+			}
+		} else if m.config.TLSMode == TLSModeStartTLS {
+			client.Close()
+			return nil, fmt.Errorf("smtp server %s does not support STARTTLS", addr)
+		}
+	}
+
+	if m.config.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted connection to a remote host
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// buildMessage encodes the message as multipart/alternative with quoted-printable text and html parts
+func buildMessage(message *notifier.Message, now time.Time) ([]byte, error) {
+	from, err := mail.ParseAddress(message.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", message.From, err)
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", message.Text},
+		{"text/html; charset=UTF-8", message.HTML},
+	} {
+		writer, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create message part: %w", err)
+		}
+
+		encoder := quotedprintable.NewWriter(writer)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, fmt.Errorf("failed to encode message part: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode message part: %w", err)
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close message parts: %w", err)
+	}
+
+	_, domain, _ := strings.Cut(from.Address, "@")
+	headers := []string{
+		"From: " + from.String(),
+		"To: " + strings.Join(message.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("UTF-8", message.Subject),
+		"Date: " + now.Format(time.RFC1123Z),
+		"Message-ID: " + messageID(domain),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + parts.Boundary(),
+	}
+
+	var data bytes.Buffer
+	for _, header := range headers {
+		data.WriteString(header + "\r\n")
+	}
+	data.WriteString("\r\n")
+	data.Write(body.Bytes())
+
+	return data.Bytes(), nil
+}
+
+func messageID(domain string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -0,0 +1,128 @@
+package smtp
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/infra/smtp/smtptest"
+	"github.com/datpham/user-service-ms/internal/service/notifier"
+)
+
+func newTestServer(t *testing.T) *smtptest.Server {
+	t.Helper()
+
+	server, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start smtp server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return server
+}
+
+func newTestMailer(server *smtptest.Server, tlsMode string) *Mailer {
+	return NewMailer(config.SMTPConfig{
+		Host:    server.Host(),
+		Port:    server.Port(),
+		TLSMode: tlsMode,
+		Timeout: time.Second * 5,
+	})
+}
+
+func testMessage() *notifier.Message {
+	return &notifier.Message{
+		From: "User Service <no-reply@example.com>",
+		To:   []string{"jane@example.com"},
+		Content: notifier.Content{
+			Subject: "Đặt lại mật khẩu",
+			Text:    "Mã của bạn: 482913\n",
+			HTML:    "<p>Mã của bạn: <strong>482913</strong></p>\n",
+		},
+	}
+}
+
+func TestMailerSendsMultipartMessage(t *testing.T) {
+	server := newTestServer(t)
+	mailer := newTestMailer(server, "")
+
+	if err := mailer.Send(context.Background(), testMessage()); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	if messages[0].From != "no-reply@example.com" || len(messages[0].To) != 1 || messages[0].To[0] != "jane@example.com" {
+		t.Errorf("unexpected envelope: %s -> %v", messages[0].From, messages[0].To)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(messages[0].Data)))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "Đặt lại mật khẩu" {
+		t.Errorf("unexpected subject %q: %v", subject, err)
+	}
+	if parsed.Header.Get("Message-ID") == "" || parsed.Header.Get("Date") == "" {
+		t.Error("message is missing its Message-ID or Date header")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %s: %v", mediaType, err)
+	}
+
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", "Mã của bạn: 482913\n"},
+		{"text/html; charset=UTF-8", "<p>Mã của bạn: <strong>482913</strong></p>\n"},
+	}
+	for _, w := range want {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("missing %s part: %v", w.contentType, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != w.contentType {
+			t.Errorf("unexpected part content type %s", got)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil || string(body) != w.body {
+			t.Errorf("unexpected %s body %q: %v", w.contentType, body, err)
+		}
+	}
+}
+
+func TestMailerReportsRejectedRecipient(t *testing.T) {
+	server := newTestServer(t)
+	server.RejectRecipient("jane@example.com")
+	mailer := newTestMailer(server, "")
+
+	err := mailer.Send(context.Background(), testMessage())
+	if err == nil || !strings.Contains(err.Error(), "RCPT TO") {
+		t.Fatalf("expected a recipient error, got %v", err)
+	}
+	if len(server.Messages()) != 0 {
+		t.Error("message was delivered to a rejected recipient")
+	}
+}
+
+func TestMailerRequiresStartTLSWhenConfigured(t *testing.T) {
+	server := newTestServer(t)
+	mailer := newTestMailer(server, TLSModeStartTLS)
+
+	err := mailer.Send(context.Background(), testMessage())
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("expected a STARTTLS error, got %v", err)
+	}
+}
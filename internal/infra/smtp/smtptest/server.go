@@ -0,0 +1,180 @@
+// Package smtptest provides an in-process SMTP server for integration tests.
+//
+// It accepts every envelope without authentication or TLS and keeps the received messages in memory,
+// which is enough to assert on what a mailer sent without a real mail server.
+package smtptest
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Message is a message received by the server with its SMTP envelope
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+type Server struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+	// rejectRecipients answers RCPT TO with a permanent failure for these addresses
+	rejectRecipients map[string]bool
+}
+
+// NewServer starts a server listening on a random local port
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s := &Server{
+		listener:         listener,
+		rejectRecipients: map[string]bool{},
+	}
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+
+	return s, nil
+}
+
+// Host returns the host the server listens on
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host
+}
+
+// Port returns the port the server listens on
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+// Messages returns a copy of the messages received so far
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// RejectRecipient makes the server refuse messages to the address
+func (s *Server) RejectRecipient(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rejectRecipients[strings.ToLower(address)] = true
+}
+
+// Close stops accepting connections and waits for the open sessions to end
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.serve(conn)
+		}()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	text := textproto.NewConn(conn)
+	reply := func(code int, message string) bool {
+		return text.PrintfLine("%d %s", code, message) == nil
+	}
+
+	if !reply(220, "smtptest ready") {
+		return
+	}
+
+	var from string
+	var to []string
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if text.PrintfLine("250-smtptest\r\n250-8BITMIME\r\n250 SMTPUTF8") != nil {
+				return
+			}
+		case "HELO", "NOOP":
+			reply(250, "OK")
+		case "MAIL":
+			from, to = parsePath(arg), nil
+			reply(250, "OK")
+		case "RCPT":
+			recipient := parsePath(arg)
+			s.mu.Lock()
+			rejected := s.rejectRecipients[strings.ToLower(recipient)]
+			s.mu.Unlock()
+			if rejected {
+				reply(550, "mailbox unavailable")
+				continue
+			}
+			to = append(to, recipient)
+			reply(250, "OK")
+		case "DATA":
+			if len(to) == 0 {
+				reply(503, "no valid recipients")
+				continue
+			}
+			if !reply(354, "end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, Message{From: from, To: to, Data: data})
+			s.mu.Unlock()
+			from, to = "", nil
+			reply(250, "queued")
+		case "RSET":
+			from, to = "", nil
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			reply(502, "command not implemented")
+		}
+	}
+}
+
+// parsePath extracts the address of a "FROM:<a@b.c>" or "TO:<a@b.c>" argument
+func parsePath(arg string) string {
+	_, path, _ := strings.Cut(arg, ":")
+	path, _, _ = strings.Cut(strings.TrimSpace(path), " ")
+	return strings.Trim(path, "<>")
+}
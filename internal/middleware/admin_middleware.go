@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/datpham/user-service-ms/internal/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type IRoleChecker interface {
+	IsAdmin(ctx context.Context, userID string) (bool, error)
+}
+
+type AdminMiddleware struct {
+	roleChecker IRoleChecker
+}
+
+func NewAdminMiddleware(roleChecker IRoleChecker) *AdminMiddleware {
+	return &AdminMiddleware{roleChecker: roleChecker}
+}
+
+// Handle rejects requests from users without the admin role, it must run after AuthMiddleware
+func (am *AdminMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		isAdmin, err := am.roleChecker.IsAdmin(c.Request.Context(), GetUserID(c))
+		if err != nil {
+			response.ErrorService(c, err)
+			c.Abort()
+			return
+		}
+
+		if !isAdmin {
+			response.Error(c, http.StatusForbidden, errors.New("admin role required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
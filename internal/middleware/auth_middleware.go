@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	AUTHORIZATION_HEADER = "Authorization"
+	BEARER_PREFIX        = "Bearer "
+)
+
+type ITokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (string, error)
+}
+
+type AuthMiddleware struct {
+	tokenValidator ITokenValidator
+}
+
+func NewAuthMiddleware(tokenValidator ITokenValidator) *AuthMiddleware {
+	return &AuthMiddleware{tokenValidator: tokenValidator}
+}
+
+// Handle rejects requests without a valid bearer access token and stores the user ID in the context
+func (am *AuthMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader(AUTHORIZATION_HEADER)
+		if !strings.HasPrefix(authHeader, BEARER_PREFIX) {
+			response.Error(c, http.StatusUnauthorized, errors.New("missing bearer token"))
+			c.Abort()
+			return
+		}
+
+		userID, err := am.tokenValidator.ValidateToken(c.Request.Context(), strings.TrimPrefix(authHeader, BEARER_PREFIX))
+		if err != nil {
+			response.Error(c, http.StatusUnauthorized, errors.New("invalid access token"))
+			c.Abort()
+			return
+		}
+
+		c.Set(logger.FieldUserID, userID)
+		c.Request = c.Request.WithContext(contextutil.WithUserID(c.Request.Context(), userID))
+		c.Next()
+	}
+}
+
+// GetUserID returns the authenticated user ID set by AuthMiddleware
+func GetUserID(c *gin.Context) string {
+	return c.GetString(logger.FieldUserID)
+}
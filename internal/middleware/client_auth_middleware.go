@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/datpham/user-service-ms/internal/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type ClientAuthMiddleware struct {
+	// clientSecrets maps the ID of a client to its secret
+	clientSecrets map[string]string
+}
+
+func NewClientAuthMiddleware(clientSecrets map[string]string) *ClientAuthMiddleware {
+	return &ClientAuthMiddleware{clientSecrets: clientSecrets}
+}
+
+// Handle rejects requests without the HTTP basic credentials of a known client
+func (cm *ClientAuthMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, clientSecret, ok := c.Request.BasicAuth()
+		secret, known := cm.clientSecrets[clientID]
+		if !ok || !known || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(secret)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			response.Error(c, http.StatusUnauthorized, errors.New("invalid client credentials"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
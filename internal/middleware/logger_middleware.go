@@ -3,6 +3,7 @@ package middleware
 import (
 	"time"
 
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
 	"github.com/datpham/user-service-ms/internal/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -36,6 +37,13 @@ func (lm *LoggerMiddleware) Handle() gin.HandlerFunc {
 		// Set request ID in the context
 		c.Set(logger.FieldRequestID, requestID)
 
+		// Expose request metadata to the service layer
+		c.Request = c.Request.WithContext(contextutil.WithRequestMetadata(c.Request.Context(), contextutil.RequestMetadata{
+			RequestID: requestID,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}))
+
 		// Set response header
 		c.Writer.Header().Set(X_REQUEST_ID, requestID)
 
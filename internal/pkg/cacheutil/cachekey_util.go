@@ -1,11 +1,55 @@
 package cacheutil
 
-import "fmt"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
 
 const (
 	ResetPasswordTokenPrefix = "reset_password_user_id"
+	PhoneVerificationPrefix  = "phone_verification"
+	PhoneLoginPrefix         = "phone_login"
+	PhoneOTPCooldownPrefix   = "phone_otp_cooldown"
+	TokenDenylistPrefix      = "token_denylist"
+	TokenIntrospectionPrefix = "token_introspection"
+
+	// OTPAttemptsSuffix is appended to the key of a code to count the wrong attempts at it
+	OTPAttemptsSuffix = "attempts"
+	// OTPDailyAttemptsSuffix is appended to the key of a code to count the attempts at all the codes sent under it
+	OTPDailyAttemptsSuffix = "daily_attempts"
 )
 
 func ConstructResetPasswordTokenKey(token int) string {
 	return fmt.Sprintf("%s:%d", ResetPasswordTokenPrefix, token)
 }
+
+func ConstructPhoneVerificationKey(userID string) string {
+	return fmt.Sprintf("%s:%s", PhoneVerificationPrefix, userID)
+}
+
+func ConstructPhoneLoginKey(phone string) string {
+	return fmt.Sprintf("%s:%s", PhoneLoginPrefix, phone)
+}
+
+func ConstructPhoneOTPCooldownKey(phone string) string {
+	return fmt.Sprintf("%s:%s", PhoneOTPCooldownPrefix, phone)
+}
+
+func ConstructOTPAttemptsKey(otpKey string) string {
+	return fmt.Sprintf("%s:%s", otpKey, OTPAttemptsSuffix)
+}
+
+func ConstructOTPDailyAttemptsKey(otpKey string) string {
+	return fmt.Sprintf("%s:%s", otpKey, OTPDailyAttemptsSuffix)
+}
+
+func ConstructTokenDenylistKey(tokenID string) string {
+	return fmt.Sprintf("%s:%s", TokenDenylistPrefix, tokenID)
+}
+
+// ConstructTokenIntrospectionKey keys introspection results by the token hash so tokens are not stored in the cache
+func ConstructTokenIntrospectionKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("%s:%s", TokenIntrospectionPrefix, hex.EncodeToString(hash[:]))
+}
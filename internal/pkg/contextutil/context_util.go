@@ -0,0 +1,46 @@
+package contextutil
+
+import "context"
+
+type contextKey string
+
+const (
+	requestMetadataKey contextKey = "request_metadata"
+	userIDKey          contextKey = "user_id"
+	clientIDKey        contextKey = "client_id"
+)
+
+// RequestMetadata describes the client request a service call originates from
+type RequestMetadata struct {
+	RequestID string
+	IP        string
+	UserAgent string
+}
+
+func WithRequestMetadata(ctx context.Context, metadata RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataKey, metadata)
+}
+
+func GetRequestMetadata(ctx context.Context) RequestMetadata {
+	metadata, _ := ctx.Value(requestMetadataKey).(RequestMetadata)
+	return metadata
+}
+
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+func GetUserID(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
+
+// WithClientID marks the call as made by a client service authenticated with its own credentials
+func WithClientID(ctx context.Context, clientID string) context.Context {
+	return context.WithValue(ctx, clientIDKey, clientID)
+}
+
+func GetClientID(ctx context.Context) string {
+	clientID, _ := ctx.Value(clientIDKey).(string)
+	return clientID
+}
@@ -0,0 +1,22 @@
+package geoutil
+
+import "math"
+
+const (
+	earthRadiusKm = 6371.0
+)
+
+// DistanceKm returns the great-circle distance between two coordinates using the haversine formula
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
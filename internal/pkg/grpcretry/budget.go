@@ -0,0 +1,54 @@
+package grpcretry
+
+import "sync"
+
+// Budget limits the share of retries a client makes, like the retry throttling of gRPC service configs.
+// Every failed attempt takes a token and every success gives back TokenRatio of one; retries stop while
+// at most half of the tokens are left, so a struggling server is not flooded with retries.
+type Budget struct {
+	mu         sync.Mutex
+	tokens     float64
+	maxTokens  float64
+	tokenRatio float64
+}
+
+func NewBudget(maxTokens float64, tokenRatio float64) *Budget {
+	return &Budget{
+		tokens:     maxTokens,
+		maxTokens:  maxTokens,
+		tokenRatio: tokenRatio,
+	}
+}
+
+func (b *Budget) allowRetry() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.tokens > b.maxTokens/2
+}
+
+func (b *Budget) onFailure() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = max(b.tokens-1, 0)
+}
+
+func (b *Budget) onSuccess() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+b.tokenRatio, b.maxTokens)
+}
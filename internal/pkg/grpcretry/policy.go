@@ -0,0 +1,73 @@
+package grpcretry
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	DefaultMaxAttempts       = 3
+	DefaultInitialBackoff    = time.Millisecond * 100
+	DefaultMaxBackoff        = time.Second * 5
+	DefaultBackoffMultiplier = 2.0
+	DefaultJitter            = 0.2
+)
+
+// DefaultRetryableCodes are the codes of failures that are safe to retry for any method,
+// the call did not reach the handler or the server asked to come back later
+var DefaultRetryableCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted}
+
+// Policy describes how the calls to a method are retried, zero fields take the default values
+type Policy struct {
+	// MaxAttempts includes the first attempt, 1 disables retries
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	// Jitter spreads every backoff uniformly over ±Jitter of its value, 0.2 is ±20%
+	Jitter         float64
+	RetryableCodes []codes.Code
+	// HedgingDelay, when set, sends another attempt every HedgingDelay until one succeeds instead of
+	// waiting for an attempt to fail. Only set it for idempotent methods.
+	HedgingDelay time.Duration
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultMaxBackoff
+	}
+	if p.BackoffMultiplier < 1 {
+		p.BackoffMultiplier = DefaultBackoffMultiplier
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		p.Jitter = DefaultJitter
+	}
+	if len(p.RetryableCodes) == 0 {
+		p.RetryableCodes = DefaultRetryableCodes
+	}
+
+	return p
+}
+
+func (p Policy) retryable(code codes.Code) bool {
+	return slices.Contains(p.RetryableCodes, code)
+}
+
+// backoff returns the wait before the retry following the given failed attempt, counted from 1
+func (p Policy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff) * math.Pow(p.BackoffMultiplier, float64(attempt-1))
+	backoff = math.Min(backoff, float64(p.MaxBackoff))
+	backoff *= 1 + p.Jitter*(2*rand.Float64()-1)
+
+	return time.Duration(backoff)
+}
@@ -0,0 +1,221 @@
+package grpcretry
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// PushbackMetadataKey is the trailer a server sets to tell how many milliseconds to wait before
+	// retrying, a negative or malformed value asks not to retry at all
+	PushbackMetadataKey = "grpc-retry-pushback-ms"
+)
+
+type Config struct {
+	// Default is the policy of the methods without one in Methods
+	Default Policy
+	// Methods maps a full method name ("/user.v1.UserService/GetUser") or a service
+	// ("/user.v1.UserService/") to its policy
+	Methods map[string]Policy
+	// Budget is shared by every call of the interceptor, nil for no budget
+	Budget *Budget
+}
+
+// Interceptor retries failed unary calls of a client connection, streams are never retried
+type Interceptor struct {
+	defaultPolicy Policy
+	policies      map[string]Policy
+	budget        *Budget
+}
+
+func New(config Config) *Interceptor {
+	policies := make(map[string]Policy, len(config.Methods))
+	for method, policy := range config.Methods {
+		policies[method] = policy.withDefaults()
+	}
+
+	return &Interceptor{
+		defaultPolicy: config.Default.withDefaults(),
+		policies:      policies,
+		budget:        config.Budget,
+	}
+}
+
+// Unary returns the interceptor to pass to grpc.WithChainUnaryInterceptor when dialing
+func (i *Interceptor) Unary() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		policy := i.policy(method)
+		if _, ok := reply.(proto.Message); ok && policy.HedgingDelay > 0 {
+			return i.invokeHedged(ctx, policy, method, req, reply.(proto.Message), cc, invoker, opts)
+		}
+
+		return i.invokeWithRetry(ctx, policy, method, req, reply, cc, invoker, opts)
+	}
+}
+
+func (i *Interceptor) policy(method string) Policy {
+	if policy, ok := i.policies[method]; ok {
+		return policy
+	}
+
+	if end := strings.LastIndex(method, "/"); end > 0 {
+		if policy, ok := i.policies[method[:end+1]]; ok {
+			return policy
+		}
+	}
+
+	return i.defaultPolicy
+}
+
+func (i *Interceptor) invokeWithRetry(
+	ctx context.Context,
+	policy Policy,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts []grpc.CallOption,
+) error {
+	for attempt := 1; ; attempt++ {
+		var trailer metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+		if err == nil {
+			i.budget.onSuccess()
+			return nil
+		}
+
+		if !policy.retryable(status.Code(err)) {
+			return err
+		}
+
+		i.budget.onFailure()
+		if attempt >= policy.MaxAttempts || !i.budget.allowRetry() {
+			return err
+		}
+
+		delay, retry := pushback(trailer)
+		if !retry {
+			return err
+		}
+		if delay < 0 {
+			delay = policy.backoff(attempt)
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+type hedgeResult struct {
+	reply proto.Message
+	err   error
+}
+
+// invokeHedged starts an attempt every HedgingDelay, or as soon as one fails, until one succeeds or
+// the attempts are used up. The first success wins and the other attempts are cancelled.
+func (i *Interceptor) invokeHedged(
+	ctx context.Context,
+	policy Policy,
+	method string,
+	req any,
+	reply proto.Message,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts []grpc.CallOption,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, policy.MaxAttempts)
+	started := 0
+	start := func() {
+		started++
+		attemptReply := reply.ProtoReflect().New().Interface()
+		go func() {
+			err := invoker(ctx, method, req, attemptReply, cc, opts...)
+			results <- hedgeResult{reply: attemptReply, err: err}
+		}()
+	}
+
+	start()
+	timer := time.NewTimer(policy.HedgingDelay)
+	defer timer.Stop()
+
+	var lastErr error
+	finished := 0
+	for {
+		select {
+		case <-timer.C:
+			if started < policy.MaxAttempts && i.budget.allowRetry() {
+				start()
+				timer.Reset(policy.HedgingDelay)
+			}
+		case result := <-results:
+			finished++
+			if result.err == nil {
+				i.budget.onSuccess()
+				proto.Reset(reply)
+				proto.Merge(reply, result.reply)
+				return nil
+			}
+
+			if !policy.retryable(status.Code(result.err)) {
+				return result.err
+			}
+
+			i.budget.onFailure()
+			lastErr = result.err
+			if started < policy.MaxAttempts && i.budget.allowRetry() {
+				start()
+				timer.Reset(policy.HedgingDelay)
+			} else if finished == started {
+				return lastErr
+			}
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// pushback returns the delay asked by the server, -1 when it did not ask for one,
+// and whether the call may be retried
+func pushback(trailer metadata.MD) (time.Duration, bool) {
+	values := trailer.Get(PushbackMetadataKey)
+	if len(values) == 0 {
+		return -1, true
+	}
+
+	milliseconds, err := strconv.Atoi(values[0])
+	if err != nil || milliseconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(milliseconds) * time.Millisecond, true
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
@@ -0,0 +1,238 @@
+package grpcretry
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	userv1 "github.com/datpham/user-service-ms/api/proto/user/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// scriptedUserServer fails GetUser with the scripted errors before answering
+type scriptedUserServer struct {
+	userv1.UnimplementedUserServiceServer
+
+	mu       sync.Mutex
+	errs     []error
+	pushback []string
+	delays   []time.Duration
+	calls    []time.Time
+}
+
+func (s *scriptedUserServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	s.mu.Lock()
+	attempt := len(s.calls)
+	s.calls = append(s.calls, time.Now())
+	var err error
+	if attempt < len(s.errs) {
+		err = s.errs[attempt]
+	}
+	if attempt < len(s.pushback) && s.pushback[attempt] != "" {
+		grpc.SetTrailer(ctx, metadata.Pairs(PushbackMetadataKey, s.pushback[attempt]))
+	}
+	var delay time.Duration
+	if attempt < len(s.delays) {
+		delay = s.delays[attempt]
+	}
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &userv1.GetUserResponse{User: &userv1.User{Id: req.GetUserId()}}, nil
+}
+
+func (s *scriptedUserServer) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+func newTestClient(t *testing.T, server *scriptedUserServer, config Config) userv1.UserServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	userv1.RegisterUserServiceServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(New(config).Unary()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return userv1.NewUserServiceClient(conn)
+}
+
+func fastPolicy() Policy {
+	return Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond * 5}
+}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	server := &scriptedUserServer{errs: []error{unavailable, unavailable}}
+	client := newTestClient(t, server, Config{Default: fastPolicy()})
+
+	resp, err := client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-1"})
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if resp.GetUser().GetId() != "user-1" || server.callCount() != 3 {
+		t.Fatalf("unexpected response %v after %d calls", resp, server.callCount())
+	}
+}
+
+func TestRetryStopsAfterMaxAttempts(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	server := &scriptedUserServer{errs: []error{unavailable, unavailable, unavailable, unavailable}}
+	client := newTestClient(t, server, Config{Default: fastPolicy()})
+
+	_, err := client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-1"})
+	if status.Code(err) != codes.Unavailable || server.callCount() != 3 {
+		t.Fatalf("expected unavailable after 3 calls, got %v after %d calls", err, server.callCount())
+	}
+}
+
+func TestNonRetryableCodeIsNotRetried(t *testing.T) {
+	server := &scriptedUserServer{errs: []error{status.Error(codes.NotFound, "no user")}}
+	client := newTestClient(t, server, Config{Default: fastPolicy()})
+
+	_, err := client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-1"})
+	if status.Code(err) != codes.NotFound || server.callCount() != 1 {
+		t.Fatalf("expected not found after 1 call, got %v after %d calls", err, server.callCount())
+	}
+}
+
+func TestMethodPolicyOverridesDefault(t *testing.T) {
+	aborted := status.Error(codes.Aborted, "conflict")
+	server := &scriptedUserServer{errs: []error{aborted}}
+	methodPolicy := fastPolicy()
+	methodPolicy.RetryableCodes = []codes.Code{codes.Aborted}
+	client := newTestClient(t, server, Config{
+		Default: Policy{MaxAttempts: 1},
+		Methods: map[string]Policy{userv1.UserService_GetUser_FullMethodName: methodPolicy},
+	})
+
+	if _, err := client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-1"}); err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if server.callCount() != 2 {
+		t.Fatalf("expected 2 calls, got %d", server.callCount())
+	}
+}
+
+func TestRetryWaitsForBackoffAndContext(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	server := &scriptedUserServer{errs: []error{unavailable, unavailable}}
+	client := newTestClient(t, server, Config{Default: Policy{MaxAttempts: 3, InitialBackoff: time.Second}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetUser(ctx, &userv1.GetUserRequest{UserId: "user-1"})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Fatalf("retry did not stop when the context expired, took %s", elapsed)
+	}
+	if server.callCount() != 1 {
+		t.Fatalf("expected 1 call before the backoff, got %d", server.callCount())
+	}
+}
+
+func TestServerPushback(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	t.Run("delay", func(t *testing.T) {
+		server := &scriptedUserServer{errs: []error{unavailable}, pushback: []string{"50"}}
+		client := newTestClient(t, server, Config{Default: fastPolicy()})
+
+		if _, err := client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-1"}); err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+		if wait := server.calls[1].Sub(server.calls[0]); wait < time.Millisecond*50 {
+			t.Fatalf("expected the pushback delay, retried after %s", wait)
+		}
+	})
+
+	t.Run("do not retry", func(t *testing.T) {
+		server := &scriptedUserServer{errs: []error{unavailable}, pushback: []string{"-1"}}
+		client := newTestClient(t, server, Config{Default: fastPolicy()})
+
+		_, err := client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-1"})
+		if status.Code(err) != codes.Unavailable || server.callCount() != 1 {
+			t.Fatalf("expected unavailable after 1 call, got %v after %d calls", err, server.callCount())
+		}
+	})
+}
+
+func TestBudgetStopsRetries(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	server := &scriptedUserServer{errs: []error{unavailable, unavailable, unavailable, unavailable}}
+	// the first failure leaves half of the tokens, which is not enough to retry
+	client := newTestClient(t, server, Config{Default: fastPolicy(), Budget: NewBudget(2, 0.1)})
+
+	_, err := client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-1"})
+	if status.Code(err) != codes.Unavailable || server.callCount() != 1 {
+		t.Fatalf("expected unavailable after 1 call, got %v after %d calls", err, server.callCount())
+	}
+}
+
+func TestHedging(t *testing.T) {
+	// the first attempt hangs, the hedged one answers
+	server := &scriptedUserServer{delays: []time.Duration{time.Second * 5}}
+	policy := fastPolicy()
+	policy.HedgingDelay = time.Millisecond * 20
+	client := newTestClient(t, server, Config{Default: policy})
+
+	start := time.Now()
+	resp, err := client.GetUser(context.Background(), &userv1.GetUserRequest{UserId: "user-1"})
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if resp.GetUser().GetId() != "user-1" || server.callCount() != 2 {
+		t.Fatalf("unexpected response %v after %d calls", resp, server.callCount())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("hedged call waited for the slow attempt, took %s", elapsed)
+	}
+}
+
+func TestBackoffIsBoundedAndJittered(t *testing.T) {
+	policy := Policy{InitialBackoff: time.Millisecond * 100, MaxBackoff: time.Millisecond * 300, Jitter: 0.2}.withDefaults()
+
+	for attempt, want := range map[int]time.Duration{1: 100, 2: 200, 3: 300, 10: 300} {
+		want *= time.Millisecond
+		backoff := policy.backoff(attempt)
+		if backoff < want*8/10 || backoff > want*12/10 {
+			t.Fatalf("attempt %d: backoff %s not within 20%% of %s", attempt, backoff, want)
+		}
+	}
+}
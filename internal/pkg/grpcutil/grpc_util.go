@@ -0,0 +1,51 @@
+package grpcutil
+
+import (
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Validate checks the binding tags of a request DTO the same way gin does for HTTP requests
+func Validate(req any) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return nil
+}
+
+// InvalidArgument returns an INVALID_ARGUMENT status carrying the error message
+func InvalidArgument(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// ErrorService converts an error returned by a service to a status, mapping custom error codes
+// like response.ErrorService maps them to HTTP status codes
+func ErrorService(err error) error {
+	customError, ok := err.(*customErr.CustomError)
+	if !ok {
+		customError = customErr.NewCustomError(customErr.ErrInternalServer, err.Error())
+	}
+
+	code := codes.Internal
+	switch customError.Code {
+	case customErr.ErrInvalidRequest:
+		code = codes.InvalidArgument
+	case customErr.ErrInternalServer:
+		code = codes.Internal
+	case customErr.ErrNotFound:
+		code = codes.NotFound
+	case customErr.ErrUnauthorized:
+		code = codes.Unauthenticated
+	case customErr.ErrForbidden:
+		code = codes.PermissionDenied
+	case customErr.ErrConflict:
+		code = codes.AlreadyExists
+	case customErr.ErrTooManyRequests:
+		code = codes.ResourceExhausted
+	}
+
+	return status.Error(code, customError.Error())
+}
@@ -0,0 +1,245 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+)
+
+const (
+	DefaultDrainTimeout = time.Second * 30
+	// ForceStopTimeout is how long each component still gets to stop once the drain timeout is over,
+	// e.g. to close its connections
+	ForceStopTimeout = time.Second
+)
+
+var (
+	ErrNotStarted = errors.New("not started")
+	ErrDraining   = errors.New("draining")
+)
+
+// Component is a part of the process with a start and stop step, either may be nil.
+// Start must return once the component is started, long running work belongs in a goroutine.
+type Component struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+type state int
+
+const (
+	stateNew state = iota
+	stateRunning
+	stateDraining
+)
+
+// Manager starts components in the order they were added, so a component can use the ones added before it,
+// and stops them in reverse order on SIGINT or SIGTERM. Every stop shares one drain timeout; once it is over
+// the remaining components get ForceStopTimeout each and a component that does not stop in time is abandoned,
+// so the process still exits.
+type Manager struct {
+	logger       *logger.Logger
+	drainTimeout time.Duration
+	drainDelay   time.Duration
+
+	mu         sync.Mutex
+	components []Component
+	started    []Component
+	state      state
+}
+
+// New returns a manager whose components must stop within drainTimeout. Readiness reports false for
+// drainDelay before the first component stops, so load balancers stop sending requests first.
+func New(logger *logger.Logger, drainTimeout time.Duration, drainDelay time.Duration) *Manager {
+	if drainTimeout <= 0 {
+		drainTimeout = DefaultDrainTimeout
+	}
+
+	return &Manager{
+		logger:       logger,
+		drainTimeout: drainTimeout,
+		drainDelay:   max(drainDelay, 0),
+	}
+}
+
+// Add appends components, in dependency order
+func (m *Manager) Add(components ...Component) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.components = append(m.components, components...)
+}
+
+// Run starts the components, waits for SIGINT, SIGTERM or the end of ctx, then stops them.
+// When a component fails to start the ones already started are stopped and the error is returned.
+func (m *Manager) Run(ctx context.Context) error {
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := m.Start(signalCtx); err != nil {
+		if stopErr := m.Stop(context.Background()); stopErr != nil {
+			m.logger.Errorf("failed to stop after a failed start: %s", stopErr.Error())
+		}
+		return err
+	}
+
+	<-signalCtx.Done()
+	m.logger.Infof("shutting down, draining for up to %s", m.drainTimeout)
+
+	return m.Stop(context.Background())
+}
+
+// Start starts the components in order and stops at the first failure
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	components := m.components
+	m.mu.Unlock()
+
+	for _, component := range components {
+		if component.Start != nil {
+			if err := component.Start(ctx); err != nil {
+				return fmt.Errorf("failed to start %s: %w", component.Name, err)
+			}
+		}
+
+		m.mu.Lock()
+		m.started = append(m.started, component)
+		m.mu.Unlock()
+		m.logger.Infof("started %s", component.Name)
+	}
+
+	m.mu.Lock()
+	m.state = stateRunning
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Stop stops the started components in reverse order within the drain timeout, a failed stop does not
+// keep the next components from stopping
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	m.state = stateDraining
+	started := m.started
+	m.started = nil
+	m.mu.Unlock()
+
+	if len(started) == 0 {
+		return nil
+	}
+
+	if m.drainDelay > 0 {
+		select {
+		case <-time.After(m.drainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.drainTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		component := started[i]
+		if component.Stop == nil {
+			continue
+		}
+
+		stopCtx := ctx
+		if ctx.Err() != nil {
+			var cancelForceStop context.CancelFunc
+			stopCtx, cancelForceStop = context.WithTimeout(context.WithoutCancel(ctx), ForceStopTimeout)
+			defer cancelForceStop()
+		}
+
+		if err := stopWithContext(stopCtx, component.Stop); err != nil {
+			m.logger.Errorf("failed to stop %s: %s", component.Name, err.Error())
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", component.Name, err))
+			continue
+		}
+		m.logger.Infof("stopped %s", component.Name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// stopWithContext returns when ctx is done even if the stop function ignores it
+func stopWithContext(ctx context.Context, stop func(ctx context.Context) error) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- stop(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ping reports whether every component is started and none is draining, for the readiness checks
+func (m *Manager) Ping(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch m.state {
+	case stateRunning:
+		return nil
+	case stateDraining:
+		return ErrDraining
+	default:
+		return ErrNotStarted
+	}
+}
+
+// Background is a component running fn in a goroutine until it is stopped. Stopping cancels the context
+// of fn and waits for fn to return.
+func Background(name string, fn func(ctx context.Context)) Component {
+	var (
+		cancel context.CancelFunc
+		done   chan struct{}
+	)
+
+	return Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			// the component outlives the start context, only Stop ends it
+			var runCtx context.Context
+			runCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+			done = make(chan struct{})
+			go func() {
+				defer close(done)
+				fn(runCtx)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// Closer is a component that is already started, e.g. a connection opened at startup, and is stopped by close
+func Closer(name string, close func() error) Component {
+	return Component{
+		Name: name,
+		Stop: func(ctx context.Context) error {
+			return close()
+		},
+	}
+}
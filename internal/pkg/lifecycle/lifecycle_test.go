@@ -0,0 +1,205 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/sirupsen/logrus"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+func (r *recorder) component(name string, startErr error) Component {
+	return Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			r.record("start " + name)
+			return startErr
+		},
+		Stop: func(ctx context.Context) error {
+			r.record("stop " + name)
+			return nil
+		},
+	}
+}
+
+func newTestManager(drainTimeout time.Duration) *Manager {
+	return New(logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard}), drainTimeout, 0)
+}
+
+func TestRunStartsInOrderAndStopsInReverse(t *testing.T) {
+	r := &recorder{}
+	m := newTestManager(time.Second)
+	m.Add(r.component("database", nil), r.component("cache", nil), r.component("http", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for m.Ping(ctx) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("manager did not become ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	want := []string{"start database", "start cache", "start http", "stop http", "stop cache", "stop database"}
+	if got := r.recorded(); !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRunStopsOnSignal(t *testing.T) {
+	r := &recorder{}
+	m := newTestManager(time.Second)
+	m.Add(r.component("http", nil))
+
+	done := make(chan error)
+	go func() { done <- m.Run(context.Background()) }()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for m.Ping(context.Background()) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("manager did not become ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("failed to signal: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("manager did not stop on SIGTERM")
+	}
+	if got := r.recorded(); !slices.Equal(got, []string{"start http", "stop http"}) {
+		t.Fatalf("unexpected events %v", got)
+	}
+}
+
+func TestFailedStartStopsStartedComponents(t *testing.T) {
+	r := &recorder{}
+	m := newTestManager(time.Second)
+	m.Add(r.component("database", nil), r.component("cache", errors.New("refused")), r.component("http", nil))
+
+	err := m.Run(context.Background())
+	if err == nil || err.Error() != "failed to start cache: refused" {
+		t.Fatalf("expected start failure, got %v", err)
+	}
+
+	want := []string{"start database", "start cache", "stop database"}
+	if got := r.recorded(); !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestStopAbandonsComponentsAfterDrainTimeout(t *testing.T) {
+	r := &recorder{}
+	m := newTestManager(time.Millisecond * 50)
+	m.Add(r.component("database", nil), Component{
+		Name: "stuck",
+		Stop: func(ctx context.Context) error {
+			time.Sleep(time.Second * 5)
+			return nil
+		},
+	})
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	start := time.Now()
+	err := m.Stop(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the drain timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("stop waited for the stuck component, took %s", elapsed)
+	}
+	// the components before the stuck one are still stopped
+	if got := r.recorded(); !slices.Contains(got, "stop database") {
+		t.Fatalf("database was not stopped, got %v", got)
+	}
+}
+
+func TestPingReportsState(t *testing.T) {
+	m := newTestManager(time.Second)
+	ready := make(chan error, 1)
+	m.Add(Component{
+		Name: "http",
+		Stop: func(ctx context.Context) error {
+			ready <- m.Ping(ctx)
+			return nil
+		},
+	})
+
+	if err := m.Ping(context.Background()); !errors.Is(err, ErrNotStarted) {
+		t.Fatalf("expected not started, got %v", err)
+	}
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := m.Ping(context.Background()); err != nil {
+		t.Fatalf("expected ready, got %v", err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := <-ready; !errors.Is(err, ErrDraining) {
+		t.Fatalf("expected draining while stopping, got %v", err)
+	}
+}
+
+func TestBackgroundWaitsForReturn(t *testing.T) {
+	stopped := make(chan struct{})
+	component := Background("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+
+	if err := component.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := component.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("stop returned before the worker did")
+	}
+}
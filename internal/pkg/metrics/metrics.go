@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	Namespace = "user_service"
+
+	ConsumeOutcomeAcked        = "acked"
+	ConsumeOutcomeRetried      = "retried"
+	ConsumeOutcomeDeadLettered = "dead_lettered"
+)
+
+var (
+	OutboxPendingMessages = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "outbox",
+		Name:      "pending_messages",
+		Help:      "Number of outbox messages waiting to be published.",
+	})
+
+	OutboxLagSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "outbox",
+		Name:      "lag_seconds",
+		Help:      "Age of the oldest outbox message waiting to be published.",
+	})
+
+	OutboxPublishedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "outbox",
+		Name:      "published_total",
+		Help:      "Number of outbox messages published, by event type.",
+	}, []string{"event_type"})
+
+	OutboxPublishFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "outbox",
+		Name:      "publish_failures_total",
+		Help:      "Number of failed outbox publish attempts, by event type.",
+	}, []string{"event_type"})
+
+	ConsumerMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "consumer",
+		Name:      "messages_total",
+		Help:      "Number of consumed messages, by handler routing key and outcome (acked, retried, dead_lettered).",
+	}, []string{"routing_key", "outcome"})
+
+	WebhookDeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "webhook",
+		Name:      "deliveries_total",
+		Help:      "Number of webhook delivery attempts, by event type and outcome (succeeded, retried, failed).",
+	}, []string{"event_type", "outcome"})
+
+	NotificationEmailsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "notifier",
+		Name:      "emails_total",
+		Help:      "Number of notification emails, by template and outcome (sent, skipped, failed).",
+	}, []string{"template", "outcome"})
+
+	GRPCServerHandledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "grpc_server",
+		Name:      "handled_total",
+		Help:      "Number of gRPC calls completed by the server, by service, method and status code.",
+	}, []string{"grpc_service", "grpc_method", "grpc_code"})
+
+	GRPCServerHandlingSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "grpc_server",
+		Name:      "handling_seconds",
+		Help:      "Time taken by the server to handle gRPC calls, by service and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"grpc_service", "grpc_method"})
+)
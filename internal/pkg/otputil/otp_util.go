@@ -0,0 +1,31 @@
+package otputil
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"math/big"
+	"strings"
+)
+
+const (
+	DefaultLength = 6
+)
+
+// Generate returns a random numeric code of the given length, leading zeros included
+func Generate(length int) (string, error) {
+	var code strings.Builder
+	for range length {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(byte('0' + digit.Int64()))
+	}
+
+	return code.String(), nil
+}
+
+// Equal compares a submitted code with the expected one in constant time
+func Equal(code string, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(code), []byte(expected)) == 1
+}
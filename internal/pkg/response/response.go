@@ -8,8 +8,9 @@ import (
 )
 
 const (
-	CREATED = "Created"
-	OK      = "Ok"
+	CREATED  = "Created"
+	ACCEPTED = "Accepted"
+	OK       = "Ok"
 )
 
 type Response struct {
@@ -30,6 +31,10 @@ func Created(c *gin.Context, data any) {
 	c.JSON(http.StatusCreated, NewResponse(http.StatusCreated, "", data))
 }
 
+func Accepted(c *gin.Context, data any) {
+	c.JSON(http.StatusAccepted, NewResponse(http.StatusAccepted, "", data))
+}
+
 func Success(c *gin.Context, data any) {
 	c.JSON(http.StatusOK, NewResponse(http.StatusOK, "", data))
 }
@@ -62,6 +67,8 @@ func ErrorService(c *gin.Context, err error) {
 		statusCode = http.StatusForbidden
 	case errors.ErrConflict:
 		statusCode = http.StatusConflict
+	case errors.ErrTooManyRequests:
+		statusCode = http.StatusTooManyRequests
 	}
 
 	c.JSON(statusCode, NewResponse(statusCode, customErr.Error(), nil))
@@ -0,0 +1,40 @@
+package sealutil
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	plaintext := []byte(`{"reset_password_token":48291}`)
+
+	sealed, err := Seal("secret", plaintext)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("48291")) {
+		t.Fatal("expected the plaintext to be encrypted")
+	}
+	if again, _ := Seal("secret", plaintext); bytes.Equal(again, sealed) {
+		t.Fatal("expected every seal to use a new nonce")
+	}
+
+	opened, err := Open("secret", sealed)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("expected the plaintext back, got %q, %v", opened, err)
+	}
+
+	if _, err := Open("other-secret", sealed); !errors.Is(err, ErrInvalidSealedData) {
+		t.Fatalf("expected another secret to be rejected, got %v", err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := Open("secret", sealed); !errors.Is(err, ErrInvalidSealedData) {
+		t.Fatalf("expected tampered data to be rejected, got %v", err)
+	}
+
+	if _, err := Open("secret", []byte("short")); !errors.Is(err, ErrInvalidSealedData) {
+		t.Fatalf("expected truncated data to be rejected, got %v", err)
+	}
+}
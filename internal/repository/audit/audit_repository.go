@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/repository/common"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"gorm.io/gorm"
+)
+
+// AuditFilter narrows down audit event queries, zero values are ignored
+type AuditFilter struct {
+	ActorID       string
+	SubjectUserID string
+	EventType     string
+	Outcome       string
+	IP            string
+	From          time.Time
+	To            time.Time
+	Limit         int
+	Offset        int
+}
+
+const (
+	// auditChainLockKey serializes appends so that every event links to its predecessor
+	auditChainLockKey = 7_031_029
+
+	DefaultCheckpointInterval = 1000
+)
+
+type ChainConfig struct {
+	SigningKey         string
+	CheckpointInterval int64
+}
+
+// AuditRepository is append-only: audit events can be appended and queried but never deleted, and the only
+// update is the redaction of their details, which the chain hash does not cover directly
+type AuditRepository struct {
+	db          *gorm.DB
+	chainConfig ChainConfig
+}
+
+func New(db *gorm.DB, chainConfig ChainConfig) *AuditRepository {
+	if chainConfig.CheckpointInterval <= 0 {
+		chainConfig.CheckpointInterval = DefaultCheckpointInterval
+	}
+
+	return &AuditRepository{db, chainConfig}
+}
+
+func (r *AuditRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+// WithContext returns the database handle for ctx, joining the transaction it carries if any
+func (r *AuditRepository) WithContext(ctx context.Context) *gorm.DB {
+	return common.WithContext(ctx, r.db)
+}
+
+// Append links the event to the head of the audit chain and stores it,
+// writing a signed checkpoint every CheckpointInterval events.
+func (r *AuditRepository) Append(ctx context.Context, event *entity.AuditEvent) error {
+	return r.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockKey).Error; err != nil {
+			return fmt.Errorf("failed to lock audit chain: %w", err)
+		}
+
+		var head entity.AuditEvent
+		err := tx.Order("sequence DESC").Limit(1).Take(&head).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get audit chain head: %w", err)
+		}
+
+		event.Sequence = head.Sequence + 1
+		event.PrevHash = head.Hash
+		// postgres stores microseconds, truncate so the hash still matches after a round trip
+		event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+		detailsHash, err := event.ComputeDetailsHash()
+		if err != nil {
+			return err
+		}
+		event.DetailsHash = detailsHash
+
+		event.Hash = event.ComputeHash()
+
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+
+		if event.Sequence%r.chainConfig.CheckpointInterval != 0 {
+			return nil
+		}
+
+		checkpoint := &entity.AuditCheckpoint{
+			Sequence:  event.Sequence,
+			Hash:      event.Hash,
+			CreatedAt: event.CreatedAt,
+		}
+		checkpoint.Signature = checkpoint.ComputeSignature(r.chainConfig.SigningKey)
+
+		return tx.Create(checkpoint).Error
+	})
+}
+
+// RedactBySubjectUserId erases the IP, user agent and metadata of the events about the user
+func (r *AuditRepository) RedactBySubjectUserId(ctx context.Context, userID string, redactedAt time.Time) error {
+	return r.WithContext(ctx).
+		Model(&entity.AuditEvent{}).
+		Where("subject_user_id = ? AND redacted_at IS NULL", userID).
+		Updates(map[string]any{
+			"ip":          "",
+			"user_agent":  "",
+			"metadata":    nil,
+			"redacted_at": redactedAt,
+		}).Error
+}
+
+// ListChain returns up to limit events of the audit chain after the given sequence, in chain order
+func (r *AuditRepository) ListChain(ctx context.Context, afterSequence int64, limit int) ([]entity.AuditEvent, error) {
+	var events []entity.AuditEvent
+	if err := r.WithContext(ctx).
+		Where("sequence > ?", afterSequence).
+		Order("sequence").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *AuditRepository) ListCheckpoints(ctx context.Context) ([]entity.AuditCheckpoint, error) {
+	var checkpoints []entity.AuditCheckpoint
+	if err := r.WithContext(ctx).
+		Order("sequence").
+		Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
+
+func (r *AuditRepository) List(ctx context.Context, filter AuditFilter) ([]entity.AuditEvent, error) {
+	query := r.WithContext(ctx).Model(&entity.AuditEvent{})
+
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.SubjectUserID != "" {
+		query = query.Where("subject_user_id = ?", filter.SubjectUserID)
+	}
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var events []entity.AuditEvent
+	if err := query.
+		Order("created_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/datpham/user-service-ms/internal/repository/common"
 	"github.com/datpham/user-service-ms/internal/repository/entity"
@@ -20,7 +22,7 @@ func New(db *gorm.DB) *AuthRepository {
 
 func (r *AuthRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
 	var user entity.User
-	if err := r.GetDB().WithContext(ctx).
+	if err := r.WithContext(ctx).
 		Where("email = ?", email).
 		First(&user).Error; err != nil {
 		return nil, err
@@ -31,7 +33,7 @@ func (r *AuthRepository) GetByEmail(ctx context.Context, email string) (*entity.
 
 func (r *AuthRepository) GetByRefreshToken(ctx context.Context, refreshToken string) (*entity.User, error) {
 	var user entity.User
-	if err := r.GetDB().WithContext(ctx).
+	if err := r.WithContext(ctx).
 		Where("refresh_token = ?", refreshToken).
 		First(&user).Error; err != nil {
 		return nil, err
@@ -39,3 +41,166 @@ func (r *AuthRepository) GetByRefreshToken(ctx context.Context, refreshToken str
 
 	return &user, nil
 }
+
+func (r *AuthRepository) GetPendingDeletionByEmail(ctx context.Context, email string) (*entity.User, error) {
+	var user entity.User
+	if err := r.WithContext(ctx).
+		Unscoped().
+		Where("email = ?", email).
+		Where("deleted_at IS NOT NULL AND anonymized_at IS NULL").
+		First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *AuthRepository) ScheduleDeletionById(ctx context.Context, id string, scheduledAt time.Time) error {
+	return r.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"deletion_scheduled_at": scheduledAt,
+			"refresh_token":         "",
+			"deleted_at":            time.Now(),
+		}).Error
+}
+
+func (r *AuthRepository) CancelDeletionById(ctx context.Context, id string) error {
+	return r.WithContext(ctx).
+		Unscoped().
+		Model(&entity.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"deletion_scheduled_at": nil,
+			"deleted_at":            nil,
+		}).Error
+}
+
+// GetDueForPurge returns the accounts whose grace period ended before the given time. Accounts whose last
+// purge failed come last, the ones that failed longest ago first, so they cannot fill every batch.
+func (r *AuthRepository) GetDueForPurge(ctx context.Context, before time.Time, limit int) ([]entity.User, error) {
+	var users []entity.User
+	if err := r.WithContext(ctx).
+		Unscoped().
+		Where("deleted_at IS NOT NULL AND anonymized_at IS NULL").
+		Where("deletion_scheduled_at <= ?", before).
+		Order("purge_failed_at NULLS FIRST, deletion_scheduled_at").
+		Limit(limit).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *AuthRepository) AnonymizeById(ctx context.Context, id string) error {
+	return r.WithContext(ctx).
+		Unscoped().
+		Model(&entity.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"email":                  fmt.Sprintf("deleted-%s@anonymized.invalid", id),
+			"password":               "",
+			"username":               "",
+			"refresh_token":          "",
+			"phone":                  nil,
+			"phone_verified_at":      nil,
+			"locale":                 "",
+			"suspension_reason":      "",
+			"email_undeliverable_at": nil,
+			"anonymized_at":          time.Now(),
+		}).Error
+}
+
+func (r *AuthRepository) MarkPurgeFailedById(ctx context.Context, id string, failedAt time.Time) error {
+	return r.WithContext(ctx).
+		Unscoped().
+		Model(&entity.User{}).
+		Where("id = ?", id).
+		Update("purge_failed_at", failedAt).Error
+}
+
+func (r *AuthRepository) MarkEmailUndeliverableById(ctx context.Context, id string, undeliverableAt time.Time) error {
+	return r.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ?", id).
+		Update("email_undeliverable_at", undeliverableAt).Error
+}
+
+func (r *AuthRepository) SuspendById(ctx context.Context, id string, suspendedAt time.Time, reason string) error {
+	return r.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"suspended_at":      suspendedAt,
+			"suspension_reason": reason,
+			"refresh_token":     "",
+		}).Error
+}
+
+// GetByPhone returns the user the verified phone number belongs to, including a user pending deletion
+// so logging in can cancel it like an email login does
+func (r *AuthRepository) GetByPhone(ctx context.Context, phone string) (*entity.User, error) {
+	var user entity.User
+	if err := r.WithContext(ctx).
+		Unscoped().
+		Where("phone = ?", phone).
+		Where("anonymized_at IS NULL").
+		First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *AuthRepository) GetByIds(ctx context.Context, ids []string) ([]entity.User, error) {
+	var users []entity.User
+	if err := r.WithContext(ctx).
+		Where("id IN ?", ids).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *AuthRepository) RevokeRefreshTokenById(ctx context.Context, id string) error {
+	return r.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ?", id).
+		Update("refresh_token", "").Error
+}
+
+func (r *AuthRepository) SetPhoneById(ctx context.Context, id string, phone string, verifiedAt time.Time) error {
+	return r.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"phone":             phone,
+			"phone_verified_at": verifiedAt,
+		}).Error
+}
+
+// GetWithRefreshTokenAfterId pages through the users holding a refresh token in id order
+func (r *AuthRepository) GetWithRefreshTokenAfterId(ctx context.Context, afterID string, limit int) ([]entity.User, error) {
+	var users []entity.User
+	if err := r.WithContext(ctx).
+		Where("refresh_token <> ''").
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// RevokeRefreshToken clears the refresh token of the user unless it was replaced in the meantime
+func (r *AuthRepository) RevokeRefreshToken(ctx context.Context, id string, refreshToken string) error {
+	return r.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ? AND refresh_token = ?", id, refreshToken).
+		Update("refresh_token", "").Error
+}
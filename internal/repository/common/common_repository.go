@@ -18,9 +18,14 @@ func (r *GenericRepository[T]) GetDB() *gorm.DB {
 	return r.db
 }
 
+// WithContext returns the database handle for ctx, joining the transaction it carries if any
+func (r *GenericRepository[T]) WithContext(ctx context.Context) *gorm.DB {
+	return WithContext(ctx, r.db)
+}
+
 func (r *GenericRepository[T]) GetById(ctx context.Context, id string) (*T, error) {
 	var entity T
-	if err := r.GetDB().WithContext(ctx).
+	if err := r.WithContext(ctx).
 		Where("id = ?", id).
 		First(&entity).Error; err != nil {
 		return nil, err
@@ -30,18 +35,18 @@ func (r *GenericRepository[T]) GetById(ctx context.Context, id string) (*T, erro
 }
 
 func (r *GenericRepository[T]) Create(ctx context.Context, entity *T) error {
-	return r.GetDB().WithContext(ctx).Create(entity).Error
+	return r.WithContext(ctx).Create(entity).Error
 }
 
 func (r *GenericRepository[T]) UpdateById(ctx context.Context, id string, entity *T) error {
-	return r.GetDB().WithContext(ctx).
+	return r.WithContext(ctx).
 		Where("id = ?", id).
 		Updates(entity).Error
 }
 
 func (r *GenericRepository[T]) DeleteById(ctx context.Context, id string) error {
 	var entity T
-	return r.GetDB().WithContext(ctx).
+	return r.WithContext(ctx).
 		Where("id = ?", id).
 		Delete(&entity).Error
 }
@@ -8,6 +8,7 @@ import (
 
 type IGenericRepository[T any] interface {
 	GetDB() *gorm.DB
+	WithContext(ctx context.Context) *gorm.DB
 	GetById(ctx context.Context, id string) (*T, error)
 	Create(ctx context.Context, entity *T) error
 	UpdateById(ctx context.Context, id string, entity *T) error
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txContextKey struct{}
+
+// TransactionManager runs functions in a database transaction that repositories pick up from the context
+type TransactionManager struct {
+	db *gorm.DB
+}
+
+func NewTransactionManager(db *gorm.DB) *TransactionManager {
+	return &TransactionManager{db}
+}
+
+// WithTransaction runs fn in a transaction, every repository call made with the context passed to fn
+// joins it. The transaction is committed when fn returns nil and rolled back otherwise.
+func (m *TransactionManager) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return WithContext(ctx, m.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txContextKey{}, tx))
+	})
+}
+
+// WithContext returns the transaction stored in the context, or db bound to the context when there is none
+func WithContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+
+	return db.WithContext(ctx)
+}
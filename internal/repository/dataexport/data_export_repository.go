@@ -0,0 +1,144 @@
+package dataexport
+
+import (
+	"context"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/repository/common"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"gorm.io/gorm"
+)
+
+type DataExportRepository struct {
+	*common.GenericRepository[entity.DataExport]
+}
+
+func New(db *gorm.DB) *DataExportRepository {
+	return &DataExportRepository{
+		GenericRepository: common.NewGenericRepository[entity.DataExport](db),
+	}
+}
+
+func (r *DataExportRepository) GetByIdAndUserId(ctx context.Context, id string, userID string) (*entity.DataExport, error) {
+	var dataExport entity.DataExport
+	if err := r.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&dataExport).Error; err != nil {
+		return nil, err
+	}
+
+	return &dataExport, nil
+}
+
+func (r *DataExportRepository) ListByUserId(ctx context.Context, userID string) ([]entity.DataExport, error) {
+	var dataExports []entity.DataExport
+	if err := r.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Find(&dataExports).Error; err != nil {
+		return nil, err
+	}
+
+	return dataExports, nil
+}
+
+func (r *DataExportRepository) DeleteByUserId(ctx context.Context, userID string) error {
+	return r.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&entity.DataExport{}).Error
+}
+
+// ClaimPending marks up to limit exports processing until leaseExpiresAt and returns them: pending ones, and
+// processing ones whose lease expired because their worker stopped. The claim is committed on its own, so
+// the exports are processed outside of any transaction.
+func (r *DataExportRepository) ClaimPending(
+	ctx context.Context,
+	now time.Time,
+	leaseExpiresAt time.Time,
+	limit int,
+) ([]entity.DataExport, error) {
+	var dataExports []entity.DataExport
+	if err := r.WithContext(ctx).Raw(`UPDATE data_exports
+SET status = ?, attempts = attempts + 1, lease_expires_at = ?, updated_at = ?
+WHERE id IN (
+    SELECT id FROM data_exports
+    WHERE status = ? OR (status = ? AND (lease_expires_at IS NULL OR lease_expires_at <= ?))
+    ORDER BY created_at
+    LIMIT ?
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *`,
+		entity.DataExportStatusProcessing, leaseExpiresAt, now,
+		entity.DataExportStatusPending, entity.DataExportStatusProcessing, now,
+		limit,
+	).Scan(&dataExports).Error; err != nil {
+		return nil, err
+	}
+
+	return dataExports, nil
+}
+
+// MarkReady records the archive of a processing export, it reports false when the export is no longer
+// processing, e.g. because it was deleted with its user in the meantime
+func (r *DataExportRepository) MarkReady(
+	ctx context.Context,
+	id string,
+	filePath string,
+	completedAt time.Time,
+) (bool, error) {
+	result := r.WithContext(ctx).
+		Model(&entity.DataExport{}).
+		Where("id = ? AND status = ?", id, entity.DataExportStatusProcessing).
+		Updates(map[string]any{
+			"status":           entity.DataExportStatusReady,
+			"file_path":        filePath,
+			"completed_at":     completedAt,
+			"lease_expires_at": nil,
+			"error":            "",
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// MarkAttemptFailed releases a processing export, back to pending to be retried or failed for good
+func (r *DataExportRepository) MarkAttemptFailed(
+	ctx context.Context,
+	id string,
+	status entity.DataExportStatus,
+	lastError string,
+) error {
+	return r.WithContext(ctx).
+		Model(&entity.DataExport{}).
+		Where("id = ? AND status = ?", id, entity.DataExportStatusProcessing).
+		Updates(map[string]any{
+			"status":           status,
+			"error":            lastError,
+			"lease_expires_at": nil,
+		}).Error
+}
+
+// ListExpired returns up to limit ready exports completed before the given time
+func (r *DataExportRepository) ListExpired(ctx context.Context, completedBefore time.Time, limit int) ([]entity.DataExport, error) {
+	var dataExports []entity.DataExport
+	if err := r.WithContext(ctx).
+		Where("status = ? AND completed_at <= ?", entity.DataExportStatusReady, completedBefore).
+		Order("completed_at").
+		Limit(limit).
+		Find(&dataExports).Error; err != nil {
+		return nil, err
+	}
+
+	return dataExports, nil
+}
+
+func (r *DataExportRepository) MarkExpired(ctx context.Context, id string) error {
+	return r.WithContext(ctx).
+		Model(&entity.DataExport{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":    entity.DataExportStatusExpired,
+			"file_path": "",
+		}).Error
+}
@@ -0,0 +1,64 @@
+package device
+
+import (
+	"context"
+
+	"github.com/datpham/user-service-ms/internal/repository/common"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"gorm.io/gorm"
+)
+
+type DeviceRepository struct {
+	*common.GenericRepository[entity.UserDevice]
+}
+
+func New(db *gorm.DB) *DeviceRepository {
+	return &DeviceRepository{
+		GenericRepository: common.NewGenericRepository[entity.UserDevice](db),
+	}
+}
+
+func (r *DeviceRepository) GetByUserIdAndFingerprint(
+	ctx context.Context,
+	userID string,
+	fingerprint string,
+) (*entity.UserDevice, error) {
+	var device entity.UserDevice
+	if err := r.WithContext(ctx).
+		Where("user_id = ? AND fingerprint = ?", userID, fingerprint).
+		First(&device).Error; err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+func (r *DeviceRepository) GetLastSeenByUserId(ctx context.Context, userID string) (*entity.UserDevice, error) {
+	var device entity.UserDevice
+	if err := r.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("last_seen_at DESC").
+		First(&device).Error; err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+func (r *DeviceRepository) ListByUserId(ctx context.Context, userID string) ([]entity.UserDevice, error) {
+	var devices []entity.UserDevice
+	if err := r.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("last_seen_at DESC").
+		Find(&devices).Error; err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+func (r *DeviceRepository) DeleteByUserId(ctx context.Context, userID string) error {
+	return r.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&entity.UserDevice{}).Error
+}
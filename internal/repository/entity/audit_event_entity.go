@@ -0,0 +1,91 @@
+package entity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type AuditOutcome string
+
+const (
+	AuditOutcomeSuccess AuditOutcome = "success"
+	AuditOutcomeFailure AuditOutcome = "failure"
+)
+
+type AuditEvent struct {
+	ID            string `gorm:"primary_key"`
+	Sequence      int64  `gorm:"uniqueIndex"`
+	ActorID       string `gorm:"index"`
+	SubjectUserID string `gorm:"index"`
+	EventType     string `gorm:"index;not null"`
+	IP            string
+	UserAgent     string
+	RequestID     string
+	Outcome       AuditOutcome `gorm:"not null"`
+	Metadata      JSONMap      `gorm:"type:jsonb"`
+	CreatedAt     time.Time    `gorm:"index"`
+	PrevHash      string
+	Hash          string
+	// DetailsHash covers the IP, user agent and metadata, the chain hash covers DetailsHash instead of them
+	// so the details can be redacted without breaking the chain
+	DetailsHash string
+	// RedactedAt is set once the details of the event were erased with the PII of its subject
+	RedactedAt *time.Time
+}
+
+// ComputeDetailsHash hashes the redactable details of the event
+func (e *AuditEvent) ComputeDetailsHash() (string, error) {
+	metadata, err := json.Marshal(e.Metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	content := strings.Join([]string{e.IP, e.UserAgent, string(metadata)}, "\x1f")
+
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HasDetails reports whether the event still holds details that a redaction erases
+func (e *AuditEvent) HasDetails() bool {
+	return e.IP != "" || e.UserAgent != "" || len(e.Metadata) > 0
+}
+
+// ComputeHash hashes the previous record's hash together with the canonical content of the event,
+// so that changing or removing any record breaks every hash after it.
+func (e *AuditEvent) ComputeHash() string {
+	content := strings.Join([]string{
+		e.PrevHash,
+		fmt.Sprintf("%d", e.Sequence),
+		e.ID,
+		e.ActorID,
+		e.SubjectUserID,
+		e.EventType,
+		e.DetailsHash,
+		e.RequestID,
+		string(e.Outcome),
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}, "\x1f")
+
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditCheckpoint is a signed snapshot of the audit chain head at a given sequence
+type AuditCheckpoint struct {
+	Sequence  int64  `gorm:"primary_key;autoIncrement:false"`
+	Hash      string `gorm:"not null"`
+	Signature string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (c *AuditCheckpoint) ComputeSignature(signingKey string) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(fmt.Sprintf("%d:%s", c.Sequence, c.Hash)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestAuditEvent() *AuditEvent {
+	return &AuditEvent{
+		ID:            "evt-1",
+		Sequence:      1,
+		ActorID:       "user-1",
+		SubjectUserID: "user-1",
+		EventType:     "user_login",
+		IP:            "203.0.113.7",
+		UserAgent:     "test-agent",
+		RequestID:     "req-1",
+		Outcome:       AuditOutcomeSuccess,
+		Metadata:      JSONMap{"method": "password"},
+		DetailsHash:   "details",
+		CreatedAt:     time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC),
+	}
+}
+
+func TestAuditEventComputeHash(t *testing.T) {
+	hash := newTestAuditEvent().ComputeHash()
+	if newTestAuditEvent().ComputeHash() != hash {
+		t.Fatal("expected the hash to be deterministic")
+	}
+
+	// the hash covers the link to the previous record and every field of the content
+	changes := map[string]func(e *AuditEvent){
+		"prev hash":  func(e *AuditEvent) { e.PrevHash = "other" },
+		"sequence":   func(e *AuditEvent) { e.Sequence = 2 },
+		"actor":      func(e *AuditEvent) { e.ActorID = "user-2" },
+		"event type": func(e *AuditEvent) { e.EventType = "user_logout" },
+		"details":    func(e *AuditEvent) { e.DetailsHash = "other" },
+		"request id": func(e *AuditEvent) { e.RequestID = "req-2" },
+		"outcome":    func(e *AuditEvent) { e.Outcome = AuditOutcomeFailure },
+		"created at": func(e *AuditEvent) { e.CreatedAt = e.CreatedAt.Add(time.Microsecond) },
+	}
+	for name, change := range changes {
+		event := newTestAuditEvent()
+		change(event)
+		if event.ComputeHash() == hash {
+			t.Errorf("%s: expected the hash to change", name)
+		}
+	}
+
+	// the time zone of the creation time does not matter, only the instant
+	event := newTestAuditEvent()
+	event.CreatedAt = event.CreatedAt.In(time.FixedZone("UTC+7", 7*60*60))
+	if event.ComputeHash() != hash {
+		t.Error("expected the hash to ignore the time zone")
+	}
+}
+
+func TestAuditCheckpointComputeSignature(t *testing.T) {
+	checkpoint := &AuditCheckpoint{Sequence: 1000, Hash: "head"}
+	signature := checkpoint.ComputeSignature("key")
+
+	if checkpoint.ComputeSignature("other-key") == signature {
+		t.Error("expected the signature to depend on the key")
+	}
+	if (&AuditCheckpoint{Sequence: 1001, Hash: "head"}).ComputeSignature("key") == signature {
+		t.Error("expected the signature to cover the sequence")
+	}
+	if (&AuditCheckpoint{Sequence: 1000, Hash: "other"}).ComputeSignature("key") == signature {
+		t.Error("expected the signature to cover the hash")
+	}
+}
+
+func TestAuditEventComputeDetailsHash(t *testing.T) {
+	event := newTestAuditEvent()
+	detailsHash, err := event.ComputeDetailsHash()
+	if err != nil {
+		t.Fatalf("compute details hash: %v", err)
+	}
+	event.DetailsHash = detailsHash
+	hash := event.ComputeHash()
+
+	// the chain hash covers the details through their hash only, so they can be erased
+	event.IP = ""
+	event.UserAgent = ""
+	event.Metadata = nil
+	if event.ComputeHash() != hash {
+		t.Error("expected erasing the details to keep the hash")
+	}
+	if changed, _ := event.ComputeDetailsHash(); changed == detailsHash {
+		t.Error("expected the details hash to cover the details")
+	}
+}
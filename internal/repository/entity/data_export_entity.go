@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+type DataExportStatus string
+
+const (
+	DataExportStatusPending    DataExportStatus = "pending"
+	DataExportStatusProcessing DataExportStatus = "processing"
+	DataExportStatusReady      DataExportStatus = "ready"
+	DataExportStatusFailed     DataExportStatus = "failed"
+	// DataExportStatusExpired exports had their archive deleted once the retention period ended
+	DataExportStatusExpired DataExportStatus = "expired"
+)
+
+type DataExport struct {
+	ID          string           `gorm:"primary_key"`
+	UserID      string           `gorm:"index;not null"`
+	Status      DataExportStatus `gorm:"index;not null"`
+	Format      string           `gorm:"not null"`
+	FilePath    string
+	Error       string
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+	CompletedAt *time.Time
+	Attempts    int `gorm:"not null;default:0"`
+	// LeaseExpiresAt is when a processing export whose worker stopped can be claimed again
+	LeaseExpiresAt *time.Time
+}
@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// InboxMessage records an inbound event that was applied, so a redelivered event is applied only once
+type InboxMessage struct {
+	EventID     string    `gorm:"primary_key"`
+	EventType   string    `gorm:"not null"`
+	ProcessedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "pending"
+	OutboxStatusSent    OutboxStatus = "sent"
+	OutboxStatusFailed  OutboxStatus = "failed"
+)
+
+type OutboxMessage struct {
+	ID             string       `gorm:"primary_key"`
+	UserID         string       `gorm:"index"`
+	IdempotencyKey string       `gorm:"uniqueIndex;not null"`
+	EventType      string       `gorm:"not null"`
+	RoutingKey     string       `gorm:"not null"`
+	Payload        []byte       `gorm:"not null"`
+	Status         OutboxStatus `gorm:"index:idx_outbox_messages_status_next_attempt;not null"`
+	Attempts       int          `gorm:"not null"`
+	LastError      string
+	NextAttemptAt  time.Time `gorm:"index:idx_outbox_messages_status_next_attempt"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	SentAt         *time.Time
+	// PayloadEncrypted is set on the messages whose stored payload is sealed with the outbox encryption key
+	PayloadEncrypted bool `gorm:"not null;default:false"`
+}
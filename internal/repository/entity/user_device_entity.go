@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+type UserDevice struct {
+	ID          string `gorm:"primary_key"`
+	UserID      string `gorm:"uniqueIndex:idx_user_devices_user_fingerprint;not null"`
+	Fingerprint string `gorm:"uniqueIndex:idx_user_devices_user_fingerprint;not null"`
+	UserAgent   string
+	LastIP      string
+	Country     string
+	City        string
+	Latitude    *float64
+	Longitude   *float64
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time `gorm:"index"`
+}
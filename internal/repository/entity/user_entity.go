@@ -1,13 +1,37 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
 
 type User struct {
-	ID           string    `gorm:"primary_key"`
-	Email        string    `gorm:"unique"`
-	Password     string    `gorm:"not null"`
-	Username     string    `gorm:"not null"`
-	RefreshToken string    `gorm:"not null"`
-	CreatedAt    time.Time `gorm:"autoCreateTime"`
-	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+	ID                  string         `gorm:"primary_key"`
+	Email               string         `gorm:"unique"`
+	Password            string         `gorm:"not null"`
+	Username            string         `gorm:"not null"`
+	RefreshToken        string         `gorm:"not null"`
+	Role                string         `gorm:"not null;default:user"`
+	CreatedAt           time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt           time.Time      `gorm:"autoUpdateTime"`
+	DeletedAt           gorm.DeletedAt `gorm:"index"`
+	DeletionScheduledAt *time.Time     `gorm:"index"`
+	AnonymizedAt        *time.Time
+	// PurgeFailedAt is set when the last purge of the account failed, the purge retries it after the others
+	PurgeFailedAt *time.Time
+	// EmailUndeliverableAt is set when the mail service reported a hard bounce for the email
+	EmailUndeliverableAt *time.Time
+	SuspendedAt          *time.Time
+	SuspensionReason     string
+	// Locale is the BCP 47 tag the user's emails are written in, empty for the default locale
+	Locale string
+	// Phone is an E.164 number, it is only set once the user proved they own it with a code sent to it
+	Phone           *string `gorm:"uniqueIndex"`
+	PhoneVerifiedAt *time.Time
 }
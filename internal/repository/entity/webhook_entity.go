@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookSubscription is a partner endpoint that receives the events of the listed types over HTTP
+type WebhookSubscription struct {
+	ID         string   `gorm:"primary_key"`
+	URL        string   `gorm:"not null"`
+	EventTypes []string `gorm:"serializer:json;type:jsonb;not null"`
+	// Secret signs the deliveries, it is shown once when the subscription is created
+	Secret              string `gorm:"not null"`
+	Enabled             bool   `gorm:"not null;default:true"`
+	ConsecutiveFailures int    `gorm:"not null;default:0"`
+	DisabledAt          *time.Time
+	CreatedAt           time.Time `gorm:"autoCreateTime"`
+	UpdatedAt           time.Time `gorm:"autoUpdateTime"`
+}
+
+// WebhookDelivery is one event sent to one subscription, it records the outcome of the last attempt
+type WebhookDelivery struct {
+	ID             string                `gorm:"primary_key"`
+	SubscriptionID string                `gorm:"index;not null"`
+	UserID         string                `gorm:"index"`
+	EventID        string                `gorm:"not null"`
+	EventType      string                `gorm:"not null"`
+	Payload        []byte                `gorm:"not null"`
+	Status         WebhookDeliveryStatus `gorm:"index:idx_webhook_deliveries_status_next_attempt;not null"`
+	Attempts       int                   `gorm:"not null"`
+	NextAttemptAt  time.Time             `gorm:"index:idx_webhook_deliveries_status_next_attempt"`
+	LastStatusCode int
+	LastError      string
+	LastAttemptAt  *time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
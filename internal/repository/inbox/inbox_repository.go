@@ -0,0 +1,33 @@
+package inbox
+
+import (
+	"context"
+
+	"github.com/datpham/user-service-ms/internal/repository/common"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InboxRepository struct {
+	*common.GenericRepository[entity.InboxMessage]
+}
+
+func New(db *gorm.DB) *InboxRepository {
+	return &InboxRepository{
+		GenericRepository: common.NewGenericRepository[entity.InboxMessage](db),
+	}
+}
+
+// CreateIfNotExists records the message and reports whether it was new, false means the event
+// was already recorded. Inside a transaction a concurrent insert of the same event blocks until it settles.
+func (r *InboxRepository) CreateIfNotExists(ctx context.Context, message *entity.InboxMessage) (bool, error) {
+	result := r.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(message)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
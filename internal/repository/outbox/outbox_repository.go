@@ -0,0 +1,144 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/pkg/sealutil"
+	"github.com/datpham/user-service-ms/internal/repository/common"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"gorm.io/gorm"
+)
+
+type OutboxRepository struct {
+	*common.GenericRepository[entity.OutboxMessage]
+	encryptionKey string
+}
+
+func New(db *gorm.DB, encryptionKey string) *OutboxRepository {
+	return &OutboxRepository{
+		GenericRepository: common.NewGenericRepository[entity.OutboxMessage](db),
+		encryptionKey:     encryptionKey,
+	}
+}
+
+// Create stores the message with its payload encrypted, the payloads carry reset tokens and download URLs
+func (r *OutboxRepository) Create(ctx context.Context, message *entity.OutboxMessage) error {
+	sealed, err := sealutil.Seal(r.encryptionKey, message.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt outbox payload: %s", err.Error())
+	}
+
+	stored := *message
+	stored.Payload = sealed
+	stored.PayloadEncrypted = true
+
+	return r.GenericRepository.Create(ctx, &stored)
+}
+
+// ClaimPending hides up to limit due pending messages from the other relays until leaseExpiresAt and returns
+// them decrypted, marking failed the ones that cannot be decrypted. The claim is committed on its own.
+func (r *OutboxRepository) ClaimPending(
+	ctx context.Context,
+	now time.Time,
+	leaseExpiresAt time.Time,
+	limit int,
+) ([]entity.OutboxMessage, error) {
+	var messages []entity.OutboxMessage
+	if err := r.WithContext(ctx).Raw(`UPDATE outbox_messages
+SET next_attempt_at = ?
+WHERE id IN (
+    SELECT id FROM outbox_messages
+    WHERE status = ? AND next_attempt_at <= ?
+    ORDER BY created_at
+    LIMIT ?
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *`,
+		leaseExpiresAt, entity.OutboxStatusPending, now, limit,
+	).Scan(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not keep the order of the subquery
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+
+	claimed := messages[:0]
+	for _, message := range messages {
+		if message.PayloadEncrypted {
+			payload, err := sealutil.Open(r.encryptionKey, message.Payload)
+			if err != nil {
+				// a payload sealed with another key can never be published, so it must not block the others
+				if err := r.MarkAttemptFailed(
+					ctx, message.ID, message.Attempts, entity.OutboxStatusFailed, now, "failed to decrypt payload",
+				); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			message.Payload = payload
+			message.PayloadEncrypted = false
+		}
+
+		claimed = append(claimed, message)
+	}
+
+	return claimed, nil
+}
+
+func (r *OutboxRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	return r.WithContext(ctx).
+		Model(&entity.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":     entity.OutboxStatusSent,
+			"sent_at":    sentAt,
+			"last_error": "",
+		}).Error
+}
+
+func (r *OutboxRepository) MarkAttemptFailed(
+	ctx context.Context,
+	id string,
+	attempts int,
+	status entity.OutboxStatus,
+	nextAttemptAt time.Time,
+	lastError string,
+) error {
+	return r.WithContext(ctx).
+		Model(&entity.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":          status,
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+}
+
+func (r *OutboxRepository) DeleteByUserId(ctx context.Context, userID string) error {
+	return r.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&entity.OutboxMessage{}).Error
+}
+
+// GetPendingStats returns the number of pending messages and the creation time of the oldest one
+func (r *OutboxRepository) GetPendingStats(ctx context.Context) (int64, *time.Time, error) {
+	var stats struct {
+		Count  int64
+		Oldest *time.Time
+	}
+	if err := r.WithContext(ctx).
+		Model(&entity.OutboxMessage{}).
+		Select("COUNT(*) AS count, MIN(created_at) AS oldest").
+		Where("status = ?", entity.OutboxStatusPending).
+		Scan(&stats).Error; err != nil {
+		return 0, nil, err
+	}
+
+	return stats.Count, stats.Oldest, nil
+}
@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/repository/common"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"gorm.io/gorm"
+)
+
+type DeliveryFilter struct {
+	SubscriptionID string
+	Status         entity.WebhookDeliveryStatus
+	Limit          int
+	Offset         int
+}
+
+type DeliveryRepository struct {
+	*common.GenericRepository[entity.WebhookDelivery]
+}
+
+func NewDeliveryRepository(db *gorm.DB) *DeliveryRepository {
+	return &DeliveryRepository{
+		GenericRepository: common.NewGenericRepository[entity.WebhookDelivery](db),
+	}
+}
+
+func (r *DeliveryRepository) CreateBatch(ctx context.Context, deliveries []entity.WebhookDelivery) error {
+	return r.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *DeliveryRepository) DeleteByUserId(ctx context.Context, userID string) error {
+	return r.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&entity.WebhookDelivery{}).Error
+}
+
+// ClaimPending hides up to limit due pending deliveries from the other workers until leaseExpiresAt and
+// returns them. The claim is committed on its own, so no transaction is held while the deliveries are sent,
+// and a delivery becomes due again when the worker that claimed it stops before marking it.
+func (r *DeliveryRepository) ClaimPending(
+	ctx context.Context,
+	now time.Time,
+	leaseExpiresAt time.Time,
+	limit int,
+) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	if err := r.WithContext(ctx).Raw(`UPDATE webhook_deliveries
+SET next_attempt_at = ?
+WHERE id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = ? AND next_attempt_at <= ?
+    ORDER BY created_at
+    LIMIT ?
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *`,
+		leaseExpiresAt, entity.WebhookDeliveryStatusPending, now, limit,
+	).Scan(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not keep the order of the subquery
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+	})
+
+	return deliveries, nil
+}
+
+func (r *DeliveryRepository) MarkSucceeded(ctx context.Context, id string, attempts int, statusCode int, deliveredAt time.Time) error {
+	return r.WithContext(ctx).
+		Model(&entity.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":           entity.WebhookDeliveryStatusSucceeded,
+			"attempts":         attempts,
+			"last_status_code": statusCode,
+			"last_error":       "",
+			"last_attempt_at":  deliveredAt,
+			"delivered_at":     deliveredAt,
+		}).Error
+}
+
+func (r *DeliveryRepository) MarkAttemptFailed(
+	ctx context.Context,
+	id string,
+	attempts int,
+	status entity.WebhookDeliveryStatus,
+	nextAttemptAt time.Time,
+	statusCode int,
+	lastError string,
+) error {
+	return r.WithContext(ctx).
+		Model(&entity.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":           status,
+			"attempts":         attempts,
+			"next_attempt_at":  nextAttemptAt,
+			"last_status_code": statusCode,
+			"last_error":       lastError,
+			"last_attempt_at":  time.Now(),
+		}).Error
+}
+
+// ResetForReplay makes a failed delivery pending again with a fresh attempt budget,
+// it returns false when the delivery does not exist or has not failed
+func (r *DeliveryRepository) ResetForReplay(ctx context.Context, id string, now time.Time) (bool, error) {
+	result := r.WithContext(ctx).
+		Model(&entity.WebhookDelivery{}).
+		Where("id = ? AND status = ?", id, entity.WebhookDeliveryStatusFailed).
+		Updates(map[string]any{
+			"status":          entity.WebhookDeliveryStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *DeliveryRepository) List(ctx context.Context, filter DeliveryFilter) ([]entity.WebhookDelivery, error) {
+	query := r.WithContext(ctx).Model(&entity.WebhookDelivery{})
+	if filter.SubscriptionID != "" {
+		query = query.Where("subscription_id = ?", filter.SubscriptionID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var deliveries []entity.WebhookDelivery
+	if err := query.
+		Order("created_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
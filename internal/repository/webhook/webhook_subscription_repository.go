@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/repository/common"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SubscriptionRepository struct {
+	*common.GenericRepository[entity.WebhookSubscription]
+}
+
+func NewSubscriptionRepository(db *gorm.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{
+		GenericRepository: common.NewGenericRepository[entity.WebhookSubscription](db),
+	}
+}
+
+func (r *SubscriptionRepository) List(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	var subscriptions []entity.WebhookSubscription
+	if err := r.WithContext(ctx).
+		Order("created_at").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// ListEnabledByEventType returns the enabled subscriptions whose event types contain eventType
+func (r *SubscriptionRepository) ListEnabledByEventType(ctx context.Context, eventType string) ([]entity.WebhookSubscription, error) {
+	var subscriptions []entity.WebhookSubscription
+	if err := r.WithContext(ctx).
+		Where("enabled = ?", true).
+		Where("event_types @> jsonb_build_array(?::text)", eventType).
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// UpdateFieldsById updates the given columns, including zero values such as enabled = false
+func (r *SubscriptionRepository) UpdateFieldsById(ctx context.Context, id string, fields map[string]any) error {
+	return r.WithContext(ctx).
+		Model(&entity.WebhookSubscription{}).
+		Where("id = ?", id).
+		Updates(fields).Error
+}
+
+func (r *SubscriptionRepository) ResetFailuresById(ctx context.Context, id string) error {
+	return r.WithContext(ctx).
+		Model(&entity.WebhookSubscription{}).
+		Where("id = ? AND consecutive_failures > 0", id).
+		Update("consecutive_failures", 0).Error
+}
+
+// RecordFailureById counts a failed delivery attempt and disables the subscription once it failed
+// disableAfter times in a row. It reports whether this failure disabled the subscription.
+func (r *SubscriptionRepository) RecordFailureById(ctx context.Context, id string, disableAfter int, now time.Time) (bool, error) {
+	// postgres stores microseconds, the returned disabled_at is compared with now
+	now = now.Truncate(time.Microsecond)
+
+	var subscription entity.WebhookSubscription
+	if err := r.WithContext(ctx).
+		Model(&subscription).
+		Clauses(clause.Returning{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+			"enabled":              gorm.Expr("enabled AND consecutive_failures + 1 < ?", disableAfter),
+			"disabled_at":          gorm.Expr("CASE WHEN enabled AND consecutive_failures + 1 >= ? THEN ? ELSE disabled_at END", disableAfter, now),
+		}).Error; err != nil {
+		return false, err
+	}
+
+	return subscription.DisabledAt != nil && subscription.DisabledAt.Equal(now), nil
+}
@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"fmt"
+
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+)
+
+const (
+	VerifyBatchSize = 1000
+)
+
+// ChainBreak describes the first audit record whose link to the chain does not verify
+type ChainBreak struct {
+	Sequence int64
+	EventID  string
+	Reason   string
+}
+
+type ChainVerificationReport struct {
+	VerifiedEvents      int64
+	VerifiedCheckpoints int
+	// RedactedEvents are verified events whose details were erased, only their hash is left to check
+	RedactedEvents int64
+	FirstBreak     *ChainBreak
+}
+
+type AuditChainService struct {
+	logger          *logger.Logger
+	auditRepository IAuditChainRepository
+	signingKey      string
+}
+
+func New(logger *logger.Logger, auditRepository IAuditChainRepository, signingKey string) *AuditChainService {
+	return &AuditChainService{
+		logger:          logger,
+		auditRepository: auditRepository,
+		signingKey:      signingKey,
+	}
+}
+
+// VerifyChain walks the whole audit chain in sequence order, recomputing every hash and checking
+// the signed checkpoints, and stops at the first broken link.
+func (s *AuditChainService) VerifyChain(ctx context.Context) (*ChainVerificationReport, error) {
+	checkpoints, err := s.auditRepository.ListCheckpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit checkpoints: %s", err.Error())
+	}
+
+	checkpointsBySequence := make(map[int64]entity.AuditCheckpoint, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		if !hmac.Equal([]byte(checkpoint.Signature), []byte(checkpoint.ComputeSignature(s.signingKey))) {
+			return &ChainVerificationReport{
+				FirstBreak: &ChainBreak{
+					Sequence: checkpoint.Sequence,
+					Reason:   "checkpoint signature is invalid",
+				},
+			}, nil
+		}
+
+		checkpointsBySequence[checkpoint.Sequence] = checkpoint
+	}
+
+	report := &ChainVerificationReport{}
+	var prev entity.AuditEvent
+	for {
+		events, err := s.auditRepository.ListChain(ctx, prev.Sequence, VerifyBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list audit chain: %s", err.Error())
+		}
+
+		for i := range events {
+			if chainBreak := s.verifyLink(&prev, &events[i], checkpointsBySequence); chainBreak != nil {
+				report.FirstBreak = chainBreak
+				return report, nil
+			}
+
+			if _, ok := checkpointsBySequence[events[i].Sequence]; ok {
+				report.VerifiedCheckpoints++
+			}
+			if events[i].RedactedAt != nil {
+				report.RedactedEvents++
+			}
+			report.VerifiedEvents++
+			prev = events[i]
+		}
+
+		if len(events) < VerifyBatchSize {
+			break
+		}
+	}
+
+	// a checkpoint past the chain head means trailing records were removed
+	if report.VerifiedCheckpoints != len(checkpoints) {
+		report.FirstBreak = &ChainBreak{
+			Sequence: prev.Sequence + 1,
+			Reason:   "chain ends before the latest checkpoint, records were removed",
+		}
+	}
+
+	return report, nil
+}
+
+func (s *AuditChainService) verifyLink(
+	prev *entity.AuditEvent,
+	event *entity.AuditEvent,
+	checkpoints map[int64]entity.AuditCheckpoint,
+) *ChainBreak {
+	newBreak := func(reason string) *ChainBreak {
+		return &ChainBreak{Sequence: event.Sequence, EventID: event.ID, Reason: reason}
+	}
+
+	if event.Sequence != prev.Sequence+1 {
+		return newBreak(fmt.Sprintf("expected sequence %d, records were removed", prev.Sequence+1))
+	}
+
+	if event.PrevHash != prev.Hash {
+		return newBreak("previous hash does not match the preceding record")
+	}
+
+	if event.ComputeHash() != event.Hash {
+		return newBreak("record content does not match its hash")
+	}
+
+	// details hashed apart from the chain must match their hash, unless a redaction erased them
+	if event.RedactedAt != nil && event.HasDetails() {
+		return newBreak("redacted record holds details")
+	}
+	if event.RedactedAt == nil {
+		detailsHash, err := event.ComputeDetailsHash()
+		if err != nil {
+			return newBreak(err.Error())
+		}
+		if detailsHash != event.DetailsHash {
+			return newBreak("record details do not match their hash")
+		}
+	}
+
+	if checkpoint, ok := checkpoints[event.Sequence]; ok && checkpoint.Hash != event.Hash {
+		return newBreak("record hash does not match the signed checkpoint")
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	testSigningKey         = "signing-key"
+	testCheckpointInterval = 3
+)
+
+// fakeChainRepository returns its events by sequence, the way the audit repository reads them
+type fakeChainRepository struct {
+	events      []entity.AuditEvent
+	checkpoints []entity.AuditCheckpoint
+}
+
+func (r *fakeChainRepository) ListChain(ctx context.Context, afterSequence int64, limit int) ([]entity.AuditEvent, error) {
+	sorted := append([]entity.AuditEvent(nil), r.events...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Sequence < sorted[j].Sequence })
+
+	var events []entity.AuditEvent
+	for _, event := range sorted {
+		if event.Sequence > afterSequence && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *fakeChainRepository) ListCheckpoints(ctx context.Context) ([]entity.AuditCheckpoint, error) {
+	return r.checkpoints, nil
+}
+
+// append links the event to the chain head like the audit repository does
+func (r *fakeChainRepository) append(t *testing.T, event entity.AuditEvent) {
+	t.Helper()
+
+	if len(r.events) > 0 {
+		head := r.events[len(r.events)-1]
+		event.Sequence = head.Sequence + 1
+		event.PrevHash = head.Hash
+	} else {
+		event.Sequence = 1
+	}
+
+	event.Hash = event.ComputeHash()
+	r.events = append(r.events, event)
+
+	if event.Sequence%testCheckpointInterval == 0 {
+		checkpoint := entity.AuditCheckpoint{Sequence: event.Sequence, Hash: event.Hash}
+		checkpoint.Signature = checkpoint.ComputeSignature(testSigningKey)
+		r.checkpoints = append(r.checkpoints, checkpoint)
+	}
+}
+
+// rehash recomputes the hashes after the given index, as someone rewriting the chain without the signing key would
+func (r *fakeChainRepository) rehash(t *testing.T, from int) {
+	t.Helper()
+
+	for i := from; i < len(r.events); i++ {
+		if i > 0 {
+			r.events[i].PrevHash = r.events[i-1].Hash
+		}
+		r.events[i].Hash = r.events[i].ComputeHash()
+	}
+}
+
+// newTestChain returns a chain of login events of one user
+func newTestChain(t *testing.T, length int) *fakeChainRepository {
+	t.Helper()
+
+	repo := &fakeChainRepository{}
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < length; i++ {
+		event := entity.AuditEvent{
+			ID:            fmt.Sprintf("evt-%d", i+1),
+			SubjectUserID: "user-1",
+			EventType:     "user_login",
+			IP:            "203.0.113.7",
+			Outcome:       entity.AuditOutcomeSuccess,
+			Metadata:      entity.JSONMap{"attempt": float64(i)},
+			CreatedAt:     createdAt.Add(time.Duration(i) * time.Minute),
+		}
+		detailsHash, err := event.ComputeDetailsHash()
+		if err != nil {
+			t.Fatalf("compute details hash: %v", err)
+		}
+		event.DetailsHash = detailsHash
+		repo.append(t, event)
+	}
+	return repo
+}
+
+// redact erases the details of the event the way the audit repository does
+func (r *fakeChainRepository) redact(i int) {
+	redactedAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	r.events[i].IP = ""
+	r.events[i].UserAgent = ""
+	r.events[i].Metadata = nil
+	r.events[i].RedactedAt = &redactedAt
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := map[string]struct {
+		tamper      func(t *testing.T, repo *fakeChainRepository)
+		breakAt     int64
+		breakReason string
+		redacted    int64
+	}{
+		"intact": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {},
+		},
+		"modified content": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {
+				repo.events[3].Outcome = entity.AuditOutcomeFailure
+			},
+			breakAt:     4,
+			breakReason: "does not match its hash",
+		},
+		"modified metadata": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {
+				repo.events[1].Metadata = entity.JSONMap{"attempt": float64(42)}
+			},
+			breakAt:     2,
+			breakReason: "details do not match their hash",
+		},
+		"modified and rehashed": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {
+				repo.events[3].Outcome = entity.AuditOutcomeFailure
+				repo.rehash(t, 3)
+			},
+			breakAt:     6,
+			breakReason: "signed checkpoint",
+		},
+		"redacted details": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {
+				repo.redact(6)
+			},
+			redacted: 1,
+		},
+		"modified details": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {
+				repo.events[6].IP = "198.51.100.1"
+			},
+			breakAt:     7,
+			breakReason: "details do not match their hash",
+		},
+		"details written back after redaction": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {
+				repo.redact(6)
+				repo.events[6].IP = "198.51.100.1"
+			},
+			breakAt:     7,
+			breakReason: "redacted record holds details",
+		},
+		"deleted row": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {
+				repo.events = append(repo.events[:4], repo.events[5:]...)
+			},
+			breakAt:     6,
+			breakReason: "expected sequence 5",
+		},
+		"deleted trailing rows": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {
+				repo.events = repo.events[:7]
+			},
+			breakAt:     8,
+			breakReason: "before the latest checkpoint",
+		},
+		"reordered rows": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {
+				repo.events[1].Sequence, repo.events[2].Sequence = repo.events[2].Sequence, repo.events[1].Sequence
+			},
+			breakAt:     2,
+			breakReason: "previous hash",
+		},
+		"forged checkpoint": {
+			tamper: func(t *testing.T, repo *fakeChainRepository) {
+				repo.checkpoints[0].Hash = "forged"
+			},
+			breakAt:     3,
+			breakReason: "checkpoint signature is invalid",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := newTestChain(t, 9)
+			tt.tamper(t, repo)
+
+			s := New(logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard}), repo, testSigningKey)
+			report, err := s.VerifyChain(context.Background())
+			if err != nil {
+				t.Fatalf("verify chain: %v", err)
+			}
+
+			if tt.breakAt == 0 {
+				if report.FirstBreak != nil {
+					t.Fatalf("expected an intact chain, got a break at %d: %s", report.FirstBreak.Sequence, report.FirstBreak.Reason)
+				}
+				if report.VerifiedEvents != 9 || report.VerifiedCheckpoints != 3 || report.RedactedEvents != tt.redacted {
+					t.Fatalf("expected 9 events and 3 checkpoints, got %+v", report)
+				}
+				return
+			}
+
+			if report.FirstBreak == nil {
+				t.Fatal("expected the chain to be broken")
+			}
+			if report.FirstBreak.Sequence != tt.breakAt || !strings.Contains(report.FirstBreak.Reason, tt.breakReason) {
+				t.Fatalf("expected a break at %d (%s), got %d: %s",
+					tt.breakAt, tt.breakReason, report.FirstBreak.Sequence, report.FirstBreak.Reason)
+			}
+		})
+	}
+}
+
+func TestVerifyChainRejectsCheckpointsSignedWithAnotherKey(t *testing.T) {
+	repo := newTestChain(t, 3)
+
+	s := New(logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard}), repo, "other-key")
+	report, err := s.VerifyChain(context.Background())
+	if err != nil {
+		t.Fatalf("verify chain: %v", err)
+	}
+	if report.FirstBreak == nil || report.FirstBreak.Sequence != 3 {
+		t.Fatalf("expected the checkpoint to be rejected, got %+v", report.FirstBreak)
+	}
+}
@@ -0,0 +1,12 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+)
+
+type IAuditChainRepository interface {
+	ListChain(ctx context.Context, afterSequence int64, limit int) ([]entity.AuditEvent, error)
+	ListCheckpoints(ctx context.Context) ([]entity.AuditCheckpoint, error)
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultDeletionGracePeriod = time.Hour * 24 * 30
+	DefaultPurgeBatchSize      = 100
+)
+
+func (s *AuthService) ScheduleAccountDeletion(
+	ctx context.Context,
+	userID string,
+) (resp *respDto.AccountDeletionResponse, err error) {
+	defer func() {
+		s.recordAuditEvent(ctx, UserDeletionScheduledEvent, userID, err, nil)
+	}()
+
+	user, err := s.authRepository.GetById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customErr.NewCustomError(customErr.ErrNotFound, "User not found")
+		}
+
+		return nil, fmt.Errorf("failed to get user by id: %s", err.Error())
+	}
+
+	scheduledAt := time.Now().Add(s.deletionGracePeriod())
+	if err := s.authRepository.ScheduleDeletionById(ctx, user.ID, scheduledAt); err != nil {
+		return nil, fmt.Errorf("failed to schedule user deletion: %s", err.Error())
+	}
+
+	return &respDto.AccountDeletionResponse{
+		DeletionScheduledAt: scheduledAt,
+	}, nil
+}
+
+// PurgeDeletedAccounts erases the PII of every account whose deletion grace period has expired and returns
+// the number of purged accounts. An account that fails to purge is marked so it is retried after the others.
+func (s *AuthService) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	users, err := s.authRepository.GetDueForPurge(ctx, time.Now(), s.purgeBatchSize())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get users due for purge: %s", err.Error())
+	}
+
+	purged := 0
+	for _, user := range users {
+		err := s.purgeAccount(ctx, &user)
+		s.recordAuditEvent(ctx, UserDeletedEvent, user.ID, err, map[string]any{
+			"deletion_scheduled_at": user.DeletionScheduledAt,
+		})
+		if err != nil {
+			s.logger.Errorf("userId: %s, failed to purge user: %s", user.ID, err.Error())
+
+			if err := s.authRepository.MarkPurgeFailedById(ctx, user.ID, time.Now()); err != nil {
+				s.logger.Errorf("userId: %s, failed to mark user purge failed: %s", user.ID, err.Error())
+			}
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// purgeAccount anonymizes the user and deletes or redacts everything else that holds their PII, in one
+// transaction. The export archives are removed once it committed, so a rolled back purge still has them.
+func (s *AuthService) purgeAccount(ctx context.Context, user *entity.User) error {
+	var dataExports []entity.DataExport
+	err := s.txManager.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.authRepository.AnonymizeById(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to anonymize user: %s", err.Error())
+		}
+
+		if err := s.deviceRepository.DeleteByUserId(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete user devices: %s", err.Error())
+		}
+
+		if err := s.auditRepository.RedactBySubjectUserId(ctx, user.ID, time.Now()); err != nil {
+			return fmt.Errorf("failed to redact user audit events: %s", err.Error())
+		}
+
+		var err error
+		dataExports, err = s.dataExportRepository.ListByUserId(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list user data exports: %s", err.Error())
+		}
+
+		if err := s.dataExportRepository.DeleteByUserId(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete user data exports: %s", err.Error())
+		}
+
+		// the events about the user carry their email, address and location, the deleted event does not
+		if err := s.outboxRepository.DeleteByUserId(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete user outbox messages: %s", err.Error())
+		}
+
+		if err := s.webhookService.DeleteUserDeliveries(ctx, user.ID); err != nil {
+			return err
+		}
+
+		if err := s.publishUserEventOnce(ctx, user.ID, &event.UserDeletedPayload{
+			DeletionScheduledAt: user.DeletionScheduledAt,
+		}, fmt.Sprintf("%s:%s", UserDeletedEvent, user.ID)); err != nil {
+			return fmt.Errorf("failed to publish user deleted event: %s", err.Error())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, dataExport := range dataExports {
+		if err := removeDataExportFile(dataExport.FilePath); err != nil {
+			s.logger.Errorf("exportId: %s, failed to remove purged data export: %s", dataExport.ID, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// getLoginUserByEmail looks up an active user, falling back to accounts that
+// are still within their deletion grace period.
+func (s *AuthService) getLoginUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	user, err := s.authRepository.GetByEmail(ctx, email)
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	return s.authRepository.GetPendingDeletionByEmail(ctx, email)
+}
+
+func (s *AuthService) cancelAccountDeletion(ctx context.Context, user *entity.User) error {
+	err := s.authRepository.CancelDeletionById(ctx, user.ID)
+	s.recordAuditEvent(ctx, UserDeletionCancelledEvent, user.ID, err, nil)
+	if err != nil {
+		return fmt.Errorf("failed to cancel user deletion: %s", err.Error())
+	}
+
+	s.logger.Infof("userId: %s, account deletion cancelled by login", user.ID)
+	return nil
+}
+
+func (s *AuthService) deletionGracePeriod() time.Duration {
+	if s.accountConfig.DeletionGracePeriod <= 0 {
+		return DefaultDeletionGracePeriod
+	}
+
+	return s.accountConfig.DeletionGracePeriod
+}
+
+func (s *AuthService) purgeBatchSize() int {
+	if s.accountConfig.PurgeBatchSize <= 0 {
+		return DefaultPurgeBatchSize
+	}
+
+	return s.accountConfig.PurgeBatchSize
+}
@@ -0,0 +1,409 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/infra/geoip"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/passwordutil"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	tokensvc "github.com/datpham/user-service-ms/internal/service/token"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func (r *fakeAuthRepository) ScheduleDeletionById(ctx context.Context, id string, scheduledAt time.Time) error {
+	r.users[id].DeletionScheduledAt = &scheduledAt
+	r.users[id].RefreshToken = ""
+	r.users[id].DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (r *fakeAuthRepository) CancelDeletionById(ctx context.Context, id string) error {
+	r.users[id].DeletionScheduledAt = nil
+	r.users[id].DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+// GetDueForPurge orders the accounts like the auth repository: failed purges last, the oldest failure first
+func (r *fakeAuthRepository) GetDueForPurge(ctx context.Context, before time.Time, limit int) ([]entity.User, error) {
+	var users []entity.User
+	for _, user := range r.users {
+		if user.DeletedAt.Valid && user.AnonymizedAt == nil && !user.DeletionScheduledAt.After(before) {
+			users = append(users, *user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		a, b := users[i], users[j]
+		if (a.PurgeFailedAt == nil) != (b.PurgeFailedAt == nil) {
+			return a.PurgeFailedAt == nil
+		}
+		if a.PurgeFailedAt != nil && !a.PurgeFailedAt.Equal(*b.PurgeFailedAt) {
+			return a.PurgeFailedAt.Before(*b.PurgeFailedAt)
+		}
+		return a.DeletionScheduledAt.Before(*b.DeletionScheduledAt)
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *fakeAuthRepository) AnonymizeById(ctx context.Context, id string) error {
+	if err := r.anonymizeErrs[id]; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user := r.users[id]
+	user.Email = "deleted-" + id + "@anonymized.invalid"
+	user.Password = ""
+	user.Username = ""
+	user.RefreshToken = ""
+	user.Phone = nil
+	user.PhoneVerifiedAt = nil
+	user.Locale = ""
+	user.SuspensionReason = ""
+	user.EmailUndeliverableAt = nil
+	user.AnonymizedAt = &now
+	return nil
+}
+
+func (r *fakeAuthRepository) MarkPurgeFailedById(ctx context.Context, id string, failedAt time.Time) error {
+	r.users[id].PurgeFailedAt = &failedAt
+	return nil
+}
+
+func (r *fakeAuditRepository) RedactBySubjectUserId(ctx context.Context, userID string, redactedAt time.Time) error {
+	for _, event := range r.events {
+		if event.SubjectUserID == userID && event.RedactedAt == nil {
+			event.IP = ""
+			event.UserAgent = ""
+			event.Metadata = nil
+			event.RedactedAt = &redactedAt
+		}
+	}
+	return nil
+}
+
+type fakeDeviceRepository struct {
+	IDeviceRepository
+	devices []entity.UserDevice
+}
+
+func (r *fakeDeviceRepository) ListByUserId(ctx context.Context, userID string) ([]entity.UserDevice, error) {
+	var devices []entity.UserDevice
+	for _, device := range r.devices {
+		if device.UserID == userID {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+func (r *fakeDeviceRepository) DeleteByUserId(ctx context.Context, userID string) error {
+	var kept []entity.UserDevice
+	for _, device := range r.devices {
+		if device.UserID != userID {
+			kept = append(kept, device)
+		}
+	}
+	r.devices = kept
+	return nil
+}
+
+type fakeOutboxRepository struct {
+	messages []*entity.OutboxMessage
+}
+
+func (r *fakeOutboxRepository) Create(ctx context.Context, message *entity.OutboxMessage) error {
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func (r *fakeOutboxRepository) DeleteByUserId(ctx context.Context, userID string) error {
+	var kept []*entity.OutboxMessage
+	for _, message := range r.messages {
+		if message.UserID != userID {
+			kept = append(kept, message)
+		}
+	}
+	r.messages = kept
+	return nil
+}
+
+// fakeWebhookService keeps the user ID of every enqueued delivery
+type fakeWebhookService struct {
+	deliveryUserIDs []string
+}
+
+func (s *fakeWebhookService) Enqueue(
+	ctx context.Context,
+	userID string,
+	eventType string,
+	eventID string,
+	payload []byte,
+) error {
+	s.deliveryUserIDs = append(s.deliveryUserIDs, userID)
+	return nil
+}
+
+func (s *fakeWebhookService) DeleteUserDeliveries(ctx context.Context, userID string) error {
+	s.deliveryUserIDs = slices.DeleteFunc(s.deliveryUserIDs, func(id string) bool { return id == userID })
+	return nil
+}
+
+// serviceTest is an auth service over fakes of every repository
+type serviceTest struct {
+	s              *AuthService
+	repo           *fakeAuthRepository
+	auditRepo      *fakeAuditRepository
+	deviceRepo     *fakeDeviceRepository
+	dataExportRepo *fakeDataExportRepository
+	outboxRepo     *fakeOutboxRepository
+	webhookSvc     *fakeWebhookService
+	geoIP          *fakeGeoIP
+}
+
+func newServiceTest(t *testing.T, users ...*entity.User) *serviceTest {
+	t.Helper()
+
+	test := &serviceTest{
+		repo:           &fakeAuthRepository{users: map[string]*entity.User{}},
+		auditRepo:      &fakeAuditRepository{},
+		deviceRepo:     &fakeDeviceRepository{},
+		dataExportRepo: &fakeDataExportRepository{},
+		outboxRepo:     &fakeOutboxRepository{},
+		webhookSvc:     &fakeWebhookService{},
+		geoIP:          &fakeGeoIP{locations: map[string]*geoip.Location{}},
+	}
+	for _, user := range users {
+		test.repo.users[user.ID] = user
+	}
+
+	test.s = New(
+		logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard}),
+		test.repo, tokensvc.NewJwtToken("secret"), nil, &fakeCache{values: map[string]string{}},
+		test.outboxRepo, nil, test.webhookSvc, &fakeTransactionManager{},
+		config.AccountConfig{},
+		test.dataExportRepo, config.DataExportConfig{StorageDir: t.TempDir(), SigningSecret: "export-secret"},
+		test.auditRepo, test.deviceRepo, test.geoIP, config.LoginSecurityConfig{},
+		&fakeSMSSender{}, config.PhoneConfig{},
+		config.IntrospectionConfig{},
+	)
+	return test
+}
+
+func (test *serviceTest) auditEventTypes() []string {
+	var eventTypes []string
+	for _, event := range test.auditRepo.events {
+		eventTypes = append(eventTypes, event.EventType)
+	}
+	return eventTypes
+}
+
+// newPendingDeletionUser returns a user whose deletion was scheduled at the given time
+func newPendingDeletionUser(id string, scheduledAt time.Time) *entity.User {
+	phone := "+84900000" + id
+	return &entity.User{
+		ID:                  id,
+		Email:               id + "@example.com",
+		Username:            id,
+		Password:            "hashed",
+		Locale:              "vi",
+		SuspensionReason:    "spam",
+		Phone:               &phone,
+		DeletedAt:           gorm.DeletedAt{Time: scheduledAt.Add(-DefaultDeletionGracePeriod), Valid: true},
+		DeletionScheduledAt: &scheduledAt,
+	}
+}
+
+func TestScheduleAccountDeletion(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: "user-1", Email: "user@example.com", RefreshToken: "refresh"}
+	test := newServiceTest(t, user)
+
+	resp, err := test.s.ScheduleAccountDeletion(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("schedule account deletion: %v", err)
+	}
+
+	expected := time.Now().Add(DefaultDeletionGracePeriod)
+	if resp.DeletionScheduledAt.Sub(expected).Abs() > time.Minute {
+		t.Fatalf("expected the deletion after the grace period, got %s", resp.DeletionScheduledAt)
+	}
+	if !user.DeletedAt.Valid || user.DeletionScheduledAt == nil || !user.DeletionScheduledAt.Equal(resp.DeletionScheduledAt) {
+		t.Fatalf("expected the user to be soft deleted, got %+v", user)
+	}
+	if user.RefreshToken != "" {
+		t.Fatal("expected the user to be signed out")
+	}
+	if eventTypes := test.auditEventTypes(); len(eventTypes) != 1 || eventTypes[0] != string(UserDeletionScheduledEvent) {
+		t.Fatalf("expected a deletion scheduled audit event, got %v", eventTypes)
+	}
+
+	_, err = test.s.ScheduleAccountDeletion(ctx, "unknown")
+	assertErrorCode(t, err, customErr.ErrNotFound)
+}
+
+func TestLoginCancelsAccountDeletion(t *testing.T) {
+	ctx := context.Background()
+	hashedPassword, err := passwordutil.HashPassword("Password123")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user := newPendingDeletionUser("user-1", time.Now().Add(time.Hour))
+	user.Password = hashedPassword
+	test := newServiceTest(t, user)
+
+	_, err = test.s.Login(ctx, &reqDto.UserLoginRequest{Email: user.Email, Password: "wrong"})
+	assertErrorCode(t, err, customErr.ErrInvalidRequest)
+	if !user.DeletedAt.Valid {
+		t.Fatal("expected a failed login to keep the deletion")
+	}
+
+	resp, err := test.s.Login(ctx, &reqDto.UserLoginRequest{Email: user.Email, Password: "Password123"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.AccessToken == "" || user.RefreshToken == "" {
+		t.Fatal("expected the user to be signed in")
+	}
+	if user.DeletedAt.Valid || user.DeletionScheduledAt != nil {
+		t.Fatalf("expected the deletion to be cancelled, got %+v", user)
+	}
+
+	eventTypes := test.auditEventTypes()
+	if len(eventTypes) != 3 || eventTypes[1] != string(UserDeletionCancelledEvent) {
+		t.Fatalf("expected a deletion cancelled audit event, got %v", eventTypes)
+	}
+}
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	due := newPendingDeletionUser("user-1", now.Add(-time.Hour))
+	notDue := newPendingDeletionUser("user-2", now.Add(time.Hour))
+	test := newServiceTest(t, due, notDue)
+
+	test.deviceRepo.devices = []entity.UserDevice{
+		{ID: "device-1", UserID: due.ID, LastIP: "203.0.113.7", City: "Hanoi"},
+		{ID: "device-2", UserID: notDue.ID, LastIP: "198.51.100.1"},
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "export-1.json")
+	if err := os.WriteFile(archivePath, []byte(`{"profile":{}}`), 0o600); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	test.dataExportRepo.dataExports = []entity.DataExport{
+		{ID: "export-1", UserID: due.ID, Status: entity.DataExportStatusReady, FilePath: archivePath},
+		{ID: "export-2", UserID: due.ID, Status: entity.DataExportStatusFailed},
+		{ID: "export-3", UserID: notDue.ID, Status: entity.DataExportStatusPending},
+	}
+
+	redactable := &entity.AuditEvent{
+		ID: "evt-1", SubjectUserID: due.ID, IP: "203.0.113.7", DetailsHash: "details",
+		Metadata: entity.JSONMap{"email": due.Email},
+	}
+	other := &entity.AuditEvent{ID: "evt-2", SubjectUserID: notDue.ID, IP: "198.51.100.1", DetailsHash: "details"}
+	test.auditRepo.events = []*entity.AuditEvent{redactable, other}
+
+	test.outboxRepo.messages = []*entity.OutboxMessage{
+		{ID: "msg-1", UserID: due.ID, EventType: string(event.UserNewDeviceLogin)},
+		{ID: "msg-2", UserID: notDue.ID, EventType: string(event.UserNewDeviceLogin)},
+	}
+	test.webhookSvc.deliveryUserIDs = []string{due.ID, notDue.ID}
+
+	purged, err := test.s.PurgeDeletedAccounts(ctx)
+	if err != nil {
+		t.Fatalf("purge deleted accounts: %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected 1 purged account, got %d", purged)
+	}
+
+	if due.AnonymizedAt == nil || due.Email == "user-1@example.com" || due.Phone != nil ||
+		due.Locale != "" || due.SuspensionReason != "" {
+		t.Fatalf("expected the PII of the due user to be erased, got %+v", due)
+	}
+	if notDue.AnonymizedAt != nil {
+		t.Fatal("expected the user within the grace period to be kept")
+	}
+
+	if len(test.deviceRepo.devices) != 1 || test.deviceRepo.devices[0].UserID != notDue.ID {
+		t.Fatalf("expected only the devices of the due user to be deleted, got %+v", test.deviceRepo.devices)
+	}
+	if len(test.dataExportRepo.dataExports) != 1 || test.dataExportRepo.dataExports[0].UserID != notDue.ID {
+		t.Fatalf("expected only the data exports of the due user to be deleted, got %+v", test.dataExportRepo.dataExports)
+	}
+	if _, err := os.Stat(archivePath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the export archive to be removed, got %v", err)
+	}
+
+	if !slices.Equal(test.webhookSvc.deliveryUserIDs, []string{notDue.ID, due.ID}) {
+		t.Fatalf("expected the webhook deliveries of the due user to be deleted, got %v", test.webhookSvc.deliveryUserIDs)
+	}
+
+	if redactable.RedactedAt == nil || redactable.IP != "" || redactable.Metadata != nil {
+		t.Fatalf("expected the audit event details to be redacted, got %+v", redactable)
+	}
+	if other.RedactedAt != nil {
+		t.Fatal("expected the events of other users to be kept")
+	}
+
+	// the earlier events about the due user are deleted, the message of the other user is kept
+	if len(test.outboxRepo.messages) != 2 || test.outboxRepo.messages[0].ID != "msg-2" ||
+		test.outboxRepo.messages[1].IdempotencyKey != "user_deleted:user-1" {
+		t.Fatalf("expected the other user's message and one user deleted event, got %v", test.publishedEventTypes())
+	}
+}
+
+func TestPurgeDeletedAccountsRetriesFailedAccountsLast(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	failing := newPendingDeletionUser("user-1", now.Add(-2*time.Hour))
+	next := newPendingDeletionUser("user-2", now.Add(-time.Hour))
+	test := newServiceTest(t, failing, next)
+	test.s.accountConfig.PurgeBatchSize = 1
+	test.repo.anonymizeErrs = map[string]error{failing.ID: errors.New("database unavailable")}
+
+	archivePath := filepath.Join(t.TempDir(), "export-1.json")
+	if err := os.WriteFile(archivePath, []byte(`{}`), 0o600); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	test.dataExportRepo.dataExports = []entity.DataExport{
+		{ID: "export-1", UserID: failing.ID, Status: entity.DataExportStatusReady, FilePath: archivePath},
+	}
+
+	purged, err := test.s.PurgeDeletedAccounts(ctx)
+	if err != nil {
+		t.Fatalf("purge deleted accounts: %v", err)
+	}
+	if purged != 0 || failing.PurgeFailedAt == nil {
+		t.Fatalf("expected the failed purge to be marked, got %d purged and %+v", purged, failing)
+	}
+	if _, err := os.Stat(archivePath); err != nil {
+		t.Fatalf("expected the archive to be kept until the purge succeeds, got %v", err)
+	}
+
+	// the failed account no longer takes the whole batch
+	purged, err = test.s.PurgeDeletedAccounts(ctx)
+	if err != nil {
+		t.Fatalf("purge deleted accounts: %v", err)
+	}
+	if purged != 1 || next.AnonymizedAt == nil {
+		t.Fatalf("expected the next account to be purged, got %d purged and %+v", purged, next)
+	}
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/repository/audit"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultAuditEventLimit = 50
+
+	// auditInternalError replaces the errors that are not meant for clients, users can read their audit events
+	auditInternalError = "internal error"
+)
+
+// recordAuditEvent appends an entry to the security audit log. The outcome is derived from err, only the
+// code and message of client errors are stored and other errors are logged instead. Failures to write the
+// entry are logged rather than failing the audited operation.
+func (s *AuthService) recordAuditEvent(
+	ctx context.Context,
+	eventType AuthEventType,
+	subjectUserID string,
+	err error,
+	metadata map[string]any,
+) {
+	requestMetadata := contextutil.GetRequestMetadata(ctx)
+
+	actorID := contextutil.GetUserID(ctx)
+	if actorID == "" {
+		actorID = subjectUserID
+	}
+
+	outcome := entity.AuditOutcomeSuccess
+	if err != nil {
+		outcome = entity.AuditOutcomeFailure
+		if metadata == nil {
+			metadata = map[string]any{}
+		}
+
+		var serviceErr *customErr.CustomError
+		if errors.As(err, &serviceErr) {
+			metadata["error_code"] = serviceErr.Code
+			metadata["error"] = serviceErr.Message
+		} else {
+			metadata["error_code"] = customErr.ErrInternalServer
+			metadata["error"] = auditInternalError
+			s.logger.Errorf("userId: %s, eventType: %s, audited operation failed: %s", subjectUserID, eventType, err.Error())
+		}
+	}
+
+	event := &entity.AuditEvent{
+		ID:            uuid.New().String(),
+		ActorID:       actorID,
+		SubjectUserID: subjectUserID,
+		EventType:     string(eventType),
+		IP:            requestMetadata.IP,
+		UserAgent:     requestMetadata.UserAgent,
+		RequestID:     requestMetadata.RequestID,
+		Outcome:       outcome,
+		Metadata:      metadata,
+	}
+
+	// the audit entry must be written even when the request context was cancelled
+	if err := s.auditRepository.Append(context.WithoutCancel(ctx), event); err != nil {
+		s.logger.Errorf(
+			"userId: %s, eventType: %s, failed to record audit event: %s",
+			subjectUserID, eventType, err.Error(),
+		)
+	}
+}
+
+func (s *AuthService) ListSecurityEvents(
+	ctx context.Context,
+	userID string,
+	req *reqDto.SecurityEventFilterRequest,
+) ([]*respDto.AuditEventResponse, error) {
+	filter := s.mapToAuditFilter(req)
+	filter.SubjectUserID = userID
+
+	return s.listAuditEvents(ctx, filter)
+}
+
+func (s *AuthService) ListAuditEvents(
+	ctx context.Context,
+	req *reqDto.AuditEventFilterRequest,
+) ([]*respDto.AuditEventResponse, error) {
+	filter := s.mapToAuditFilter(&req.SecurityEventFilterRequest)
+	filter.ActorID = req.ActorID
+	filter.SubjectUserID = req.SubjectUserID
+	filter.IP = req.IP
+
+	return s.listAuditEvents(ctx, filter)
+}
+
+func (s *AuthService) IsAdmin(ctx context.Context, userID string) (bool, error) {
+	user, err := s.authRepository.GetById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to get user by id: %s", err.Error())
+	}
+
+	return user.Role == entity.UserRoleAdmin, nil
+}
+
+func (s *AuthService) listAuditEvents(ctx context.Context, filter audit.AuditFilter) ([]*respDto.AuditEventResponse, error) {
+	events, err := s.auditRepository.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %s", err.Error())
+	}
+
+	resp := make([]*respDto.AuditEventResponse, 0, len(events))
+	for i := range events {
+		resp = append(resp, s.mapToAuditEventResponse(&events[i]))
+	}
+
+	return resp, nil
+}
+
+func (s *AuthService) exportSecurityEventsSection(ctx context.Context, user *entity.User) (any, error) {
+	return s.listAuditEvents(ctx, audit.AuditFilter{
+		SubjectUserID: user.ID,
+		Limit:         -1,
+	})
+}
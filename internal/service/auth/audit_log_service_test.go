@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+)
+
+func TestRecordAuditEvent(t *testing.T) {
+	test := newServiceTest(t)
+	ctx := contextutil.WithUserID(context.Background(), "admin-1")
+	ctx = contextutil.WithRequestMetadata(ctx, contextutil.RequestMetadata{
+		IP:        "203.0.113.7",
+		UserAgent: "curl/8.0",
+		RequestID: "req-1",
+	})
+
+	test.s.recordAuditEvent(ctx, UserPasswordSetEvent, "user-1", nil, map[string]any{"reason": "support"})
+	test.s.recordAuditEvent(ctx, UserLoginEvent, "user-1", customErr.NewCustomError(customErr.ErrUnauthorized, "Invalid password"), nil)
+	test.s.recordAuditEvent(
+		ctx, UserLoginEvent, "user-1",
+		fmt.Errorf("failed to get user by email: %s", errors.New("dial tcp 10.0.0.5:5432: connection refused")), nil,
+	)
+
+	if len(test.auditRepo.events) != 3 {
+		t.Fatalf("expected 3 audit events, got %d", len(test.auditRepo.events))
+	}
+
+	succeeded := test.auditRepo.events[0]
+	if succeeded.ActorID != "admin-1" || succeeded.SubjectUserID != "user-1" || succeeded.Outcome != entity.AuditOutcomeSuccess {
+		t.Errorf("unexpected actor, subject or outcome: %+v", succeeded)
+	}
+	if succeeded.IP != "203.0.113.7" || succeeded.UserAgent != "curl/8.0" || succeeded.RequestID != "req-1" {
+		t.Errorf("expected the request metadata to be recorded, got %+v", succeeded)
+	}
+	if _, ok := succeeded.Metadata["error"]; ok || succeeded.Metadata["reason"] != "support" {
+		t.Errorf("unexpected metadata of a successful event: %v", succeeded.Metadata)
+	}
+
+	clientErr := test.auditRepo.events[1]
+	if clientErr.Outcome != entity.AuditOutcomeFailure ||
+		clientErr.Metadata["error"] != "Invalid password" ||
+		clientErr.Metadata["error_code"] != customErr.ErrUnauthorized {
+		t.Errorf("expected the client error to be recorded, got %s with %v", clientErr.Outcome, clientErr.Metadata)
+	}
+
+	internalErr := test.auditRepo.events[2]
+	if internalErr.Metadata["error"] != auditInternalError || internalErr.Metadata["error_code"] != customErr.ErrInternalServer {
+		t.Errorf("expected the internal error to be hidden, got %v", internalErr.Metadata)
+	}
+}
+
+func TestAuditEventsDoNotRecordEmails(t *testing.T) {
+	test := newServiceTest(t)
+	ctx := context.Background()
+
+	if err := test.s.Signup(ctx, &reqDto.UserSignupRequest{Email: "new@example.com", Password: "Password1"}); err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	if _, err := test.s.Login(ctx, &reqDto.UserLoginRequest{Email: "unknown@example.com", Password: "Password1"}); err == nil {
+		t.Fatal("expected the login of an unknown user to fail")
+	}
+	if err := test.s.ForgotPassword(ctx, &reqDto.ForgotPasswordRequest{Email: "unknown@example.com"}); err == nil {
+		t.Fatal("expected the reset of an unknown user to fail")
+	}
+
+	if len(test.auditRepo.events) != 3 {
+		t.Fatalf("expected 3 audit events, got %v", test.auditEventTypes())
+	}
+	for _, event := range test.auditRepo.events {
+		if _, ok := event.Metadata["email"]; ok {
+			t.Errorf("%s: expected no email in the audit metadata, got %v", event.EventType, event.Metadata)
+		}
+	}
+	if failed := test.auditRepo.events[1]; failed.Metadata["error_code"] != customErr.ErrNotFound {
+		t.Errorf("expected the failed login to record its error code, got %v", failed.Metadata)
+	}
+}
+
+func TestListSecurityEventsIsScopedToUser(t *testing.T) {
+	test := newServiceTest(t)
+	ctx := context.Background()
+
+	test.s.recordAuditEvent(ctx, UserLoginEvent, "user-1", nil, nil)
+	test.s.recordAuditEvent(ctx, UserLoginEvent, "user-2", nil, nil)
+
+	events, err := test.s.ListSecurityEvents(ctx, "user-1", &reqDto.SecurityEventFilterRequest{Outcome: "success"})
+	if err != nil {
+		t.Fatalf("list security events: %v", err)
+	}
+	if len(events) != 1 || events[0].SubjectUserID != "user-1" {
+		t.Fatalf("expected only the events of the user, got %+v", events)
+	}
+
+	filter := test.auditRepo.filters[0]
+	if filter.SubjectUserID != "user-1" || filter.Outcome != "success" || filter.Limit != DefaultAuditEventLimit {
+		t.Errorf("unexpected filter: %+v", filter)
+	}
+}
+
+func TestListAuditEventsFilters(t *testing.T) {
+	test := newServiceTest(t)
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := test.s.ListAuditEvents(context.Background(), &reqDto.AuditEventFilterRequest{
+		SecurityEventFilterRequest: reqDto.SecurityEventFilterRequest{
+			EventType: string(UserLoginEvent),
+			Outcome:   "failure",
+			From:      from,
+			Limit:     10,
+			Offset:    20,
+		},
+		ActorID:       "admin-1",
+		SubjectUserID: "user-1",
+		IP:            "203.0.113.7",
+	}); err != nil {
+		t.Fatalf("list audit events: %v", err)
+	}
+
+	filter := test.auditRepo.filters[0]
+	if filter.ActorID != "admin-1" || filter.SubjectUserID != "user-1" || filter.IP != "203.0.113.7" {
+		t.Errorf("expected the admin filters to be applied, got %+v", filter)
+	}
+	if filter.EventType != string(UserLoginEvent) || filter.Outcome != "failure" || !filter.From.Equal(from) ||
+		filter.Limit != 10 || filter.Offset != 20 {
+		t.Errorf("expected the event filters to be applied, got %+v", filter)
+	}
+}
@@ -5,26 +5,91 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/google/uuid"
 )
 
 type AuthEventType string
 
 const (
-	UserResetPasswordEvent AuthEventType = "user_reset_password"
+	// published event types, see the event catalog for their routing keys and payloads
+	UserResetPasswordEvent   AuthEventType = AuthEventType(event.UserResetPassword)
+	UserDeletedEvent         AuthEventType = AuthEventType(event.UserDeleted)
+	UserDataExportReadyEvent AuthEventType = AuthEventType(event.UserDataExportReady)
+	UserNewDeviceLoginEvent  AuthEventType = AuthEventType(event.UserNewDeviceLogin)
+	UserSuspiciousLoginEvent AuthEventType = AuthEventType(event.UserSuspiciousLogin)
+
+	// audit-only event types, recorded in the security audit log but not published
+	UserSignupEvent              AuthEventType = "user_signup"
+	UserLoginEvent               AuthEventType = "user_login"
+	UserGoogleLoginEvent         AuthEventType = "user_google_login"
+	UserTokenRefreshEvent        AuthEventType = "user_token_refresh"
+	UserPasswordChangedEvent     AuthEventType = "user_password_changed"
+	UserDeletionScheduledEvent   AuthEventType = "user_deletion_scheduled"
+	UserDeletionCancelledEvent   AuthEventType = "user_deletion_cancelled"
+	UserDataExportRequestedEvent AuthEventType = "user_data_export_requested"
+	UserPhoneVerifiedEvent       AuthEventType = "user_phone_verified"
+	UserPhoneLoginEvent          AuthEventType = "user_phone_login"
+	UserLogoutEvent              AuthEventType = "user_logout"
+
+	// audit-only event types recorded when applying events consumed from other services
+	UserEmailUndeliverableEvent AuthEventType = "user_email_undeliverable"
+	UserSuspendedEvent          AuthEventType = "user_suspended"
+	UserRoleGrantedEvent        AuthEventType = "user_role_granted"
+
+	// audit-only event types recorded by the operator commands of the CLI
+	UserAdminCreatedEvent    AuthEventType = "user_admin_created"
+	UserPasswordSetEvent     AuthEventType = "user_password_set"
+	UserSessionsExpiredEvent AuthEventType = "user_sessions_expired"
 )
 
-type UserEvent struct {
-	UserID    string        `json:"user_id"`
-	EventType AuthEventType `json:"event_type"`
-	Timestamp time.Time     `json:"timestamp"`
-	Data      any           `json:"data"`
+// publishUserEvent stores the event in the outbox, joining the transaction carried by ctx if any.
+// The outbox relay worker publishes it afterwards and waits for the broker to confirm it.
+func (s *AuthService) publishUserEvent(ctx context.Context, userID string, payload event.Payload) error {
+	return s.publishUserEventOnce(ctx, userID, payload, "")
 }
 
-func (s *AuthService) publishUserEvent(ctx context.Context, event *UserEvent) error {
-	eventJSON, err := json.Marshal(event)
+// publishUserEventOnce publishes the event unless one with the same idempotency key was already published,
+// an empty key falls back to the event ID.
+func (s *AuthService) publishUserEventOnce(
+	ctx context.Context,
+	userID string,
+	payload event.Payload,
+	idempotencyKey string,
+) error {
+	envelope, definition, err := event.NewEnvelope(userID, payload)
+	if err != nil {
+		return err
+	}
+
+	envelope.CorrelationID = contextutil.GetRequestMetadata(ctx).RequestID
+	if idempotencyKey != "" {
+		envelope.IdempotencyKey = idempotencyKey
+	}
+
+	eventJSON, err := json.Marshal(envelope)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	return s.rabbitMQ.Publish(ctx, "", eventJSON)
+	// the webhook deliveries are created with the outbox message so partners only hear about published events
+	return s.txManager.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.outboxRepository.Create(ctx, &entity.OutboxMessage{
+			ID:             uuid.New().String(),
+			UserID:         userID,
+			IdempotencyKey: envelope.IdempotencyKey,
+			EventType:      string(envelope.EventType),
+			RoutingKey:     definition.RoutingKey,
+			Payload:        eventJSON,
+			Status:         entity.OutboxStatusPending,
+			NextAttemptAt:  time.Now(),
+		}); err != nil {
+			return err
+		}
+
+		return s.webhookService.Enqueue(ctx, userID, string(envelope.EventType), envelope.EventID, eventJSON)
+	})
 }
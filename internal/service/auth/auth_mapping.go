@@ -1,7 +1,12 @@
 package auth
 
 import (
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
 	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/repository/audit"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	tokensvc "github.com/datpham/user-service-ms/internal/service/token"
 )
 
 func (s *AuthService) mapToUserLoginResponse(accessToken string, refreshToken string) *respDto.UserLoginResponse {
@@ -10,3 +15,105 @@ func (s *AuthService) mapToUserLoginResponse(accessToken string, refreshToken st
 		RefreshToken: refreshToken,
 	}
 }
+
+func (s *AuthService) mapToUserResponse(user *entity.User) *respDto.UserResponse {
+	resp := &respDto.UserResponse{
+		ID:              user.ID,
+		Email:           user.Email,
+		Username:        user.Username,
+		Role:            user.Role,
+		Locale:          user.Locale,
+		PhoneVerifiedAt: user.PhoneVerifiedAt,
+		Suspended:       user.SuspendedAt != nil,
+		CreatedAt:       user.CreatedAt,
+	}
+	if user.Phone != nil {
+		resp.PhoneNumber = *user.Phone
+	}
+
+	return resp
+}
+
+func (s *AuthService) mapToTokenIntrospectionResponse(
+	claims *tokensvc.TokenClaims,
+	user *entity.User,
+) *respDto.TokenIntrospectionResponse {
+	return &respDto.TokenIntrospectionResponse{
+		Active:    true,
+		Subject:   user.ID,
+		TokenID:   claims.TokenID,
+		TokenType: introspectionTokenType(claims.TokenType),
+		IssuedAt:  claims.IssuedAt.Unix(),
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		Email:     user.Email,
+		Username:  user.Username,
+		Role:      user.Role,
+	}
+}
+
+func (s *AuthService) mapToDataExportResponse(dataExport *entity.DataExport) *respDto.DataExportResponse {
+	resp := &respDto.DataExportResponse{
+		ID:          dataExport.ID,
+		Status:      string(dataExport.Status),
+		Format:      dataExport.Format,
+		CreatedAt:   dataExport.CreatedAt,
+		CompletedAt: dataExport.CompletedAt,
+	}
+
+	if dataExport.Status == entity.DataExportStatusReady {
+		downloadURL, err := s.signDataExportURL(dataExport.ID)
+		if err != nil {
+			s.logger.Errorf("exportId: %s, failed to sign download url: %s", dataExport.ID, err.Error())
+		}
+		resp.DownloadURL = downloadURL
+
+		if dataExport.CompletedAt != nil {
+			expiresAt := dataExport.CompletedAt.Add(s.dataExportRetention())
+			resp.ExpiresAt = &expiresAt
+		}
+	}
+
+	return resp
+}
+
+func (s *AuthService) mapToAuditFilter(req *reqDto.SecurityEventFilterRequest) audit.AuditFilter {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultAuditEventLimit
+	}
+
+	return audit.AuditFilter{
+		EventType: req.EventType,
+		Outcome:   req.Outcome,
+		From:      req.From,
+		To:        req.To,
+		Limit:     limit,
+		Offset:    req.Offset,
+	}
+}
+
+func (s *AuthService) mapToAuditEventResponse(event *entity.AuditEvent) *respDto.AuditEventResponse {
+	return &respDto.AuditEventResponse{
+		ID:            event.ID,
+		ActorID:       event.ActorID,
+		SubjectUserID: event.SubjectUserID,
+		EventType:     event.EventType,
+		IP:            event.IP,
+		UserAgent:     event.UserAgent,
+		RequestID:     event.RequestID,
+		Outcome:       string(event.Outcome),
+		Metadata:      event.Metadata,
+		CreatedAt:     event.CreatedAt,
+	}
+}
+
+func (s *AuthService) mapToLoginDevicePayload(user *entity.User, device *entity.UserDevice) event.LoginDevicePayload {
+	return event.LoginDevicePayload{
+		Email:     user.Email,
+		DeviceID:  device.ID,
+		UserAgent: device.UserAgent,
+		IP:        device.LastIP,
+		Country:   device.Country,
+		City:      device.City,
+	}
+}
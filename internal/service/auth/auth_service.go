@@ -6,14 +6,17 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/datpham/user-service-ms/config"
 	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
 	respDto "github.com/datpham/user-service-ms/internal/dto/response"
 	customErr "github.com/datpham/user-service-ms/internal/errors"
-	"github.com/datpham/user-service-ms/internal/infra/rabbitmq"
+	"github.com/datpham/user-service-ms/internal/event"
 	"github.com/datpham/user-service-ms/internal/pkg/cacheutil"
 	"github.com/datpham/user-service-ms/internal/pkg/logger"
 	"github.com/datpham/user-service-ms/internal/pkg/passwordutil"
 	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/datpham/user-service-ms/internal/service/sms"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
@@ -28,7 +31,27 @@ type AuthService struct {
 	jwtTokenSvc    IJwtTokenService
 	oauthSvc       IOAuthService
 	cacheSvc       ICacheService
-	rabbitMQ       *rabbitmq.RabbitMQ
+	accountConfig  config.AccountConfig
+
+	outboxRepository IOutboxRepository
+	inboxRepository  IInboxRepository
+	webhookService   IWebhookService
+	txManager        ITransactionManager
+
+	dataExportRepository IDataExportRepository
+	dataExportConfig     config.DataExportConfig
+	dataExportSections   map[string]DataExportSection
+
+	auditRepository IAuditRepository
+
+	deviceRepository    IDeviceRepository
+	geoIPSvc            IGeoIPService
+	loginSecurityConfig config.LoginSecurityConfig
+
+	smsSender   sms.SMSSender
+	phoneConfig config.PhoneConfig
+
+	introspectionConfig config.IntrospectionConfig
 }
 
 func New(
@@ -37,20 +60,66 @@ func New(
 	jwtTokenSvc IJwtTokenService,
 	oauthSvc IOAuthService,
 	cacheSvc ICacheService,
-	rabbitMQ *rabbitmq.RabbitMQ,
+	outboxRepository IOutboxRepository,
+	inboxRepository IInboxRepository,
+	webhookService IWebhookService,
+	txManager ITransactionManager,
+	accountConfig config.AccountConfig,
+	dataExportRepository IDataExportRepository,
+	dataExportConfig config.DataExportConfig,
+	auditRepository IAuditRepository,
+	deviceRepository IDeviceRepository,
+	geoIPSvc IGeoIPService,
+	loginSecurityConfig config.LoginSecurityConfig,
+	smsSender sms.SMSSender,
+	phoneConfig config.PhoneConfig,
+	introspectionConfig config.IntrospectionConfig,
 ) *AuthService {
-	return &AuthService{
-		logger:         logger,
-		authRepository: authRepository,
-		jwtTokenSvc:    jwtTokenSvc,
-		oauthSvc:       oauthSvc,
-		cacheSvc:       cacheSvc,
-		rabbitMQ:       rabbitMQ,
+	s := &AuthService{
+		logger:               logger,
+		authRepository:       authRepository,
+		jwtTokenSvc:          jwtTokenSvc,
+		oauthSvc:             oauthSvc,
+		cacheSvc:             cacheSvc,
+		outboxRepository:     outboxRepository,
+		inboxRepository:      inboxRepository,
+		webhookService:       webhookService,
+		txManager:            txManager,
+		accountConfig:        accountConfig,
+		dataExportRepository: dataExportRepository,
+		dataExportConfig:     dataExportConfig,
+		auditRepository:      auditRepository,
+		deviceRepository:     deviceRepository,
+		geoIPSvc:             geoIPSvc,
+		loginSecurityConfig:  loginSecurityConfig,
+		smsSender:            smsSender,
+		phoneConfig:          phoneConfig,
+		introspectionConfig:  introspectionConfig,
+	}
+
+	s.dataExportSections = map[string]DataExportSection{
+		"profile":           s.exportProfileSection,
+		"sessions":          s.exportSessionsSection,
+		"linked_identities": s.exportLinkedIdentitiesSection,
+		"consents":          s.exportConsentsSection,
+		"security_events":   s.exportSecurityEventsSection,
+		"devices":           s.exportDevicesSection,
 	}
+
+	return s
 }
 
-func (s *AuthService) Signup(ctx context.Context, req *reqDto.UserSignupRequest) error {
-	user, err := s.authRepository.GetByEmail(ctx, req.Email)
+func (s *AuthService) Signup(ctx context.Context, req *reqDto.UserSignupRequest) (err error) {
+	var user *entity.User
+	defer func() {
+		var userID string
+		if user != nil && err == nil {
+			userID = user.ID
+		}
+		s.recordAuditEvent(ctx, UserSignupEvent, userID, err, nil)
+	}()
+
+	user, err = s.getLoginUserByEmail(ctx, req.Email)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -64,8 +133,10 @@ func (s *AuthService) Signup(ctx context.Context, req *reqDto.UserSignupRequest)
 	}
 
 	user = &entity.User{
+		ID:       uuid.New().String(),
 		Email:    req.Email,
 		Password: hashedPassword,
+		Locale:   req.Locale,
 	}
 
 	if err := s.authRepository.Create(ctx, user); err != nil {
@@ -75,8 +146,20 @@ func (s *AuthService) Signup(ctx context.Context, req *reqDto.UserSignupRequest)
 	return nil
 }
 
-func (s *AuthService) Login(ctx context.Context, req *reqDto.UserLoginRequest) (*respDto.UserLoginResponse, error) {
-	user, err := s.authRepository.GetByEmail(ctx, req.Email)
+func (s *AuthService) Login(
+	ctx context.Context,
+	req *reqDto.UserLoginRequest,
+) (resp *respDto.UserLoginResponse, err error) {
+	var user *entity.User
+	defer func() {
+		var userID string
+		if user != nil {
+			userID = user.ID
+		}
+		s.recordAuditEvent(ctx, UserLoginEvent, userID, err, nil)
+	}()
+
+	user, err = s.getLoginUserByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, customErr.NewCustomError(customErr.ErrNotFound, "User not found")
@@ -89,6 +172,21 @@ func (s *AuthService) Login(ctx context.Context, req *reqDto.UserLoginRequest) (
 		return nil, customErr.NewCustomError(customErr.ErrInvalidRequest, "Incorrect password")
 	}
 
+	return s.completeLogin(ctx, user)
+}
+
+// completeLogin issues the tokens of an authenticated user, cancelling a pending account deletion
+func (s *AuthService) completeLogin(ctx context.Context, user *entity.User) (*respDto.UserLoginResponse, error) {
+	if user.SuspendedAt != nil {
+		return nil, customErr.NewCustomError(customErr.ErrForbidden, "Account is suspended")
+	}
+
+	if user.DeletedAt.Valid {
+		if err := s.cancelAccountDeletion(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
 	accessToken, refreshToken, err := s.jwtTokenSvc.GenerateTokenPair(user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %s", err.Error())
@@ -100,6 +198,8 @@ func (s *AuthService) Login(ctx context.Context, req *reqDto.UserLoginRequest) (
 		return nil, fmt.Errorf("failed to update user: %s", err.Error())
 	}
 
+	s.checkLoginDevice(ctx, user)
+
 	return s.mapToUserLoginResponse(accessToken, refreshToken), nil
 }
 
@@ -110,7 +210,16 @@ func (s *AuthService) GetGoogleAuthUrl() string {
 func (s *AuthService) ProcessGoogleCallback(
 	ctx context.Context,
 	req *reqDto.GoogleCallbackRequest,
-) (*respDto.UserLoginResponse, error) {
+) (resp *respDto.UserLoginResponse, err error) {
+	var user *entity.User
+	defer func() {
+		var userID string
+		if user != nil {
+			userID = user.ID
+		}
+		s.recordAuditEvent(ctx, UserGoogleLoginEvent, userID, err, nil)
+	}()
+
 	if err := s.oauthSvc.VerifyGoogleState(req.State); err != nil {
 		return nil, fmt.Errorf("failed to verify google state: %s", err.Error())
 	}
@@ -125,7 +234,7 @@ func (s *AuthService) ProcessGoogleCallback(
 		return nil, fmt.Errorf("failed to get google user info: %s", err.Error())
 	}
 
-	user, err := s.authRepository.GetByEmail(ctx, userInfo["email"].(string))
+	user, err = s.authRepository.GetByEmail(ctx, userInfo["email"].(string))
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
@@ -135,6 +244,7 @@ func (s *AuthService) ProcessGoogleCallback(
 	}
 
 	user = &entity.User{
+		ID:    uuid.New().String(),
 		Email: userInfo["email"].(string),
 	}
 
@@ -145,8 +255,20 @@ func (s *AuthService) ProcessGoogleCallback(
 	return nil, nil
 }
 
-func (s *AuthService) RefreshToken(ctx context.Context, req *reqDto.RefreshTokenRequest) (*respDto.UserLoginResponse, error) {
-	user, err := s.authRepository.GetByRefreshToken(ctx, req.RefreshToken)
+func (s *AuthService) RefreshToken(
+	ctx context.Context,
+	req *reqDto.RefreshTokenRequest,
+) (resp *respDto.UserLoginResponse, err error) {
+	var user *entity.User
+	defer func() {
+		var userID string
+		if user != nil {
+			userID = user.ID
+		}
+		s.recordAuditEvent(ctx, UserTokenRefreshEvent, userID, err, nil)
+	}()
+
+	user, err = s.authRepository.GetByRefreshToken(ctx, req.RefreshToken)
 	if err != nil {
 		return nil, err
 	}
@@ -155,6 +277,10 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *reqDto.RefreshToken
 		return nil, customErr.NewCustomError(customErr.ErrNotFound, "User not found")
 	}
 
+	if user.SuspendedAt != nil {
+		return nil, customErr.NewCustomError(customErr.ErrForbidden, "Account is suspended")
+	}
+
 	accessToken, refreshToken, err := s.jwtTokenSvc.GenerateTokenPair(user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token pair: %s", err.Error())
@@ -169,8 +295,17 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *reqDto.RefreshToken
 	return s.mapToUserLoginResponse(accessToken, refreshToken), nil
 }
 
-func (s *AuthService) ForgotPassword(ctx context.Context, req *reqDto.ForgotPasswordRequest) error {
-	user, err := s.authRepository.GetByEmail(ctx, req.Email)
+func (s *AuthService) ForgotPassword(ctx context.Context, req *reqDto.ForgotPasswordRequest) (err error) {
+	var user *entity.User
+	defer func() {
+		var userID string
+		if user != nil {
+			userID = user.ID
+		}
+		s.recordAuditEvent(ctx, UserResetPasswordEvent, userID, err, nil)
+	}()
+
+	user, err = s.authRepository.GetByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return customErr.NewCustomError(customErr.ErrNotFound, "User not found")
@@ -183,6 +318,10 @@ func (s *AuthService) ForgotPassword(ctx context.Context, req *reqDto.ForgotPass
 		return customErr.NewCustomError(customErr.ErrNotFound, "User not found")
 	}
 
+	if user.EmailUndeliverableAt != nil {
+		return customErr.NewCustomError(customErr.ErrInvalidRequest, "Email address is undeliverable")
+	}
+
 	token, err := passwordutil.GenerateResetPasswordToken()
 	if err != nil {
 		return fmt.Errorf("failed to generate reset password token: %s", err.Error())
@@ -198,14 +337,9 @@ func (s *AuthService) ForgotPassword(ctx context.Context, req *reqDto.ForgotPass
 		return fmt.Errorf("failed to set reset password token: %s", err.Error())
 	}
 
-	if err = s.publishUserEvent(ctx, &UserEvent{
-		UserID:    user.ID,
-		EventType: UserResetPasswordEvent,
-		Timestamp: time.Now(),
-		Data: map[string]any{
-			"email":                user.Email,
-			"reset_password_token": token,
-		},
+	if err = s.publishUserEvent(ctx, user.ID, &event.PasswordResetRequestedPayload{
+		Email:              user.Email,
+		ResetPasswordToken: token,
 	}); err != nil {
 		s.logger.Errorf(
 			"userId: %s, email: %s, failed to publish user reset password event: %s",
@@ -222,8 +356,12 @@ func (s *AuthService) ResetPassword(
 	ctx context.Context,
 	resetPasswordToken int,
 	req *reqDto.ResetPasswordRequest,
-) error {
+) (err error) {
 	var userID string
+	defer func() {
+		s.recordAuditEvent(ctx, UserPasswordChangedEvent, userID, err, nil)
+	}()
+
 	cacheKey := cacheutil.ConstructResetPasswordTokenKey(resetPasswordToken)
 	if err := s.cacheSvc.Get(ctx, cacheKey, &userID); err != nil {
 		if err == redis.Nil {
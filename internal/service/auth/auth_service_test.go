@@ -1 +1,34 @@
 package auth
+
+import (
+	"context"
+	"testing"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+)
+
+func TestSignupAssignsUserIds(t *testing.T) {
+	test := newServiceTest(t)
+	ctx := context.Background()
+
+	for _, email := range []string{"first@example.com", "second@example.com"} {
+		if err := test.s.Signup(ctx, &reqDto.UserSignupRequest{Email: email, Password: "Password1"}); err != nil {
+			t.Fatalf("signup %s: %v", email, err)
+		}
+	}
+
+	if len(test.repo.users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(test.repo.users))
+	}
+	for id, user := range test.repo.users {
+		if id == "" || user.ID != id {
+			t.Errorf("expected %s to get an id, got %q", user.Email, user.ID)
+		}
+	}
+
+	for i, event := range test.auditRepo.events {
+		if event.SubjectUserID == "" || test.repo.users[event.SubjectUserID] == nil {
+			t.Errorf("expected signup %d to be audited with the new user id, got %q", i, event.SubjectUserID)
+		}
+	}
+}
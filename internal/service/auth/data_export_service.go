@@ -0,0 +1,438 @@
+package auth
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/pkg/signedurlutil"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DataExportFormatJSON = "json"
+	DataExportFormatZip  = "zip"
+
+	DefaultDataExportStorageDir     = "./exports"
+	DefaultDataExportURLTTL         = time.Hour * 24
+	DefaultDataExportBatchSize      = 10
+	DefaultDataExportMaxAttempts    = 3
+	DefaultDataExportProcessTimeout = time.Minute * 5
+	DefaultDataExportRetention      = time.Hour * 24 * 7
+)
+
+// DataExportSection collects one part of the data held on a user for a data export archive
+type DataExportSection func(ctx context.Context, user *entity.User) (any, error)
+
+func (s *AuthService) RequestDataExport(
+	ctx context.Context,
+	userID string,
+	req *reqDto.DataExportRequest,
+) (resp *respDto.DataExportResponse, err error) {
+	defer func() {
+		s.recordAuditEvent(ctx, UserDataExportRequestedEvent, userID, err, map[string]any{"format": req.Format})
+	}()
+
+	user, err := s.authRepository.GetById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customErr.NewCustomError(customErr.ErrNotFound, "User not found")
+		}
+
+		return nil, fmt.Errorf("failed to get user by id: %s", err.Error())
+	}
+
+	format := req.Format
+	if format == "" {
+		format = DataExportFormatJSON
+	}
+
+	dataExport := &entity.DataExport{
+		ID:     uuid.New().String(),
+		UserID: user.ID,
+		Status: entity.DataExportStatusPending,
+		Format: format,
+	}
+	// the data export worker claims the pending export and writes its archive
+	if err := s.dataExportRepository.Create(ctx, dataExport); err != nil {
+		return nil, fmt.Errorf("failed to create data export: %s", err.Error())
+	}
+
+	return s.mapToDataExportResponse(dataExport), nil
+}
+
+func (s *AuthService) GetDataExport(ctx context.Context, userID string, exportID string) (*respDto.DataExportResponse, error) {
+	dataExport, err := s.dataExportRepository.GetByIdAndUserId(ctx, exportID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customErr.NewCustomError(customErr.ErrNotFound, "Data export not found")
+		}
+
+		return nil, fmt.Errorf("failed to get data export: %s", err.Error())
+	}
+
+	return s.mapToDataExportResponse(dataExport), nil
+}
+
+// GetDataExportFile validates a signed download URL and returns the ready export it points to
+func (s *AuthService) GetDataExportFile(
+	ctx context.Context,
+	exportID string,
+	req *reqDto.DataExportDownloadRequest,
+) (*entity.DataExport, error) {
+	if err := signedurlutil.Verify(s.dataExportConfig.SigningSecret, exportID, req.Expires, req.Signature); err != nil {
+		return nil, customErr.NewCustomError(customErr.ErrForbidden, err.Error())
+	}
+
+	dataExport, err := s.dataExportRepository.GetById(ctx, exportID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customErr.NewCustomError(customErr.ErrNotFound, "Data export not found")
+		}
+
+		return nil, fmt.Errorf("failed to get data export: %s", err.Error())
+	}
+
+	if dataExport.Status != entity.DataExportStatusReady {
+		return nil, customErr.NewCustomError(customErr.ErrNotFound, "Data export is not ready")
+	}
+
+	return dataExport, nil
+}
+
+// ProcessDataExports claims the pending data exports, and the ones left processing by a worker that stopped,
+// writes their archives and returns the number of exports that became ready. A failed export is retried
+// until it used DataExportConfig.MaxAttempts attempts.
+func (s *AuthService) ProcessDataExports(ctx context.Context) (int, error) {
+	now := time.Now()
+	dataExports, err := s.dataExportRepository.ClaimPending(
+		ctx, now, now.Add(s.dataExportProcessTimeout()), s.dataExportBatchSize(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim data exports: %s", err.Error())
+	}
+
+	ready := 0
+	for i := range dataExports {
+		isReady, err := s.processDataExport(ctx, &dataExports[i])
+		if err != nil {
+			s.failDataExportAttempt(ctx, &dataExports[i], err)
+			continue
+		}
+		if isReady {
+			ready++
+		}
+	}
+
+	return ready, nil
+}
+
+// processDataExport writes the archive of a claimed export and marks it ready. It reports false when the
+// export was deleted while the archive was written, the archive is removed then.
+func (s *AuthService) processDataExport(ctx context.Context, dataExport *entity.DataExport) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.dataExportProcessTimeout())
+	defer cancel()
+
+	user, err := s.authRepository.GetById(ctx, dataExport.UserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user by id: %s", err.Error())
+	}
+
+	filePath, err := s.writeDataExportArchive(ctx, dataExport, user)
+	if err != nil {
+		return false, fmt.Errorf("failed to write data export: %s", err.Error())
+	}
+
+	downloadURL, err := s.signDataExportURL(dataExport.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to sign download url: %s", err.Error())
+	}
+
+	isReady := false
+	if err := s.txManager.WithTransaction(ctx, func(ctx context.Context) error {
+		isReady, err = s.dataExportRepository.MarkReady(ctx, dataExport.ID, filePath, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to mark data export ready: %s", err.Error())
+		}
+		if !isReady {
+			return nil
+		}
+
+		if err := s.publishUserEventOnce(ctx, user.ID, &event.DataExportReadyPayload{
+			Email:       user.Email,
+			ExportID:    dataExport.ID,
+			DownloadURL: downloadURL,
+		}, fmt.Sprintf("%s:%s", UserDataExportReadyEvent, dataExport.ID)); err != nil {
+			return fmt.Errorf("failed to publish data export ready event: %s", err.Error())
+		}
+
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	if !isReady {
+		s.logger.Infof("exportId: %s, data export was deleted while it was processed", dataExport.ID)
+
+		if err := removeDataExportFile(filePath); err != nil {
+			s.logger.Errorf("exportId: %s, failed to remove data export: %s", dataExport.ID, err.Error())
+		}
+	}
+
+	return isReady, nil
+}
+
+// failDataExportAttempt releases the export to be retried, or fails it once it used all its attempts
+func (s *AuthService) failDataExportAttempt(ctx context.Context, dataExport *entity.DataExport, err error) {
+	status := entity.DataExportStatusPending
+	if dataExport.Attempts >= s.dataExportMaxAttempts() {
+		status = entity.DataExportStatusFailed
+		s.logger.Errorf(
+			"exportId: %s, giving up data export after %d attempts: %s",
+			dataExport.ID, dataExport.Attempts, err.Error(),
+		)
+	} else {
+		s.logger.Warnf("exportId: %s, data export attempt %d failed: %s", dataExport.ID, dataExport.Attempts, err.Error())
+	}
+
+	if err := s.dataExportRepository.MarkAttemptFailed(ctx, dataExport.ID, status, err.Error()); err != nil {
+		s.logger.Errorf("exportId: %s, failed to mark data export attempt failed: %s", dataExport.ID, err.Error())
+	}
+}
+
+// ExpireDataExports deletes the archives of the exports completed more than DataExportConfig.Retention ago
+// and returns the number of expired exports
+func (s *AuthService) ExpireDataExports(ctx context.Context) (int, error) {
+	dataExports, err := s.dataExportRepository.ListExpired(
+		ctx, time.Now().Add(-s.dataExportRetention()), s.dataExportBatchSize(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired data exports: %s", err.Error())
+	}
+
+	expired := 0
+	for _, dataExport := range dataExports {
+		// the archive is removed first, an export marked expired would not be listed again
+		if err := removeDataExportFile(dataExport.FilePath); err != nil {
+			s.logger.Errorf("exportId: %s, failed to remove expired data export: %s", dataExport.ID, err.Error())
+			continue
+		}
+
+		if err := s.dataExportRepository.MarkExpired(ctx, dataExport.ID); err != nil {
+			s.logger.Errorf("exportId: %s, failed to mark data export expired: %s", dataExport.ID, err.Error())
+			continue
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+func (s *AuthService) writeDataExportArchive(
+	ctx context.Context,
+	dataExport *entity.DataExport,
+	user *entity.User,
+) (string, error) {
+	archive := map[string]any{
+		"generated_at": time.Now(),
+	}
+	for name, section := range s.dataExportSections {
+		data, err := section(ctx, user)
+		if err != nil {
+			return "", fmt.Errorf("failed to export %s: %w", name, err)
+		}
+
+		archive[name] = data
+	}
+
+	content, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal data export: %w", err)
+	}
+
+	storageDir := s.dataExportConfig.StorageDir
+	if storageDir == "" {
+		storageDir = DefaultDataExportStorageDir
+	}
+	if err := os.MkdirAll(storageDir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create storage dir: %w", err)
+	}
+
+	filePath := filepath.Join(storageDir, fmt.Sprintf("%s.%s", dataExport.ID, dataExport.Format))
+	if dataExport.Format != DataExportFormatZip {
+		return filePath, os.WriteFile(filePath, content, 0o600)
+	}
+
+	return filePath, writeZipFile(filePath, "user-data.json", content)
+}
+
+func writeZipFile(filePath string, name string, content []byte) error {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zipWriter := zip.NewWriter(file)
+	entry, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := entry.Write(content); err != nil {
+		return err
+	}
+
+	return zipWriter.Close()
+}
+
+// removeDataExportFile removes the archive of a data export, an archive that was never written or is already
+// gone is not an error
+func removeDataExportFile(filePath string) error {
+	if filePath == "" {
+		return nil
+	}
+
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *AuthService) signDataExportURL(exportID string) (string, error) {
+	ttl := s.dataExportConfig.URLTTL
+	if ttl <= 0 {
+		ttl = DefaultDataExportURLTTL
+	}
+
+	baseURL, err := url.JoinPath(s.dataExportConfig.PublicBaseURL, "/api/v1/exports", exportID, "download")
+	if err != nil {
+		return "", err
+	}
+
+	return signedurlutil.Sign(s.dataExportConfig.SigningSecret, baseURL, exportID, ttl)
+}
+
+// exportSessionsSection lists the sessions the user is signed in with, without their tokens. A user holds
+// one refresh token at a time, a token that expired or was signed with a retired secret is listed as invalid.
+func (s *AuthService) exportSessionsSection(_ context.Context, user *entity.User) (any, error) {
+	sessions := []map[string]any{}
+	if user.RefreshToken == "" {
+		return sessions, nil
+	}
+
+	claims, err := s.jwtTokenSvc.ParseToken(user.RefreshToken)
+	if err != nil {
+		return append(sessions, map[string]any{"valid": false}), nil
+	}
+
+	return append(sessions, map[string]any{
+		"id":         claims.TokenID,
+		"valid":      true,
+		"issued_at":  claims.IssuedAt,
+		"expires_at": claims.ExpiresAt,
+	}), nil
+}
+
+// exportLinkedIdentitiesSection lists the ways the user can sign in. Accounts created with Google sign-in are
+// the only ones without a password, the service keeps no other trace of the provider.
+func (s *AuthService) exportLinkedIdentitiesSection(_ context.Context, user *entity.User) (any, error) {
+	provider := "password"
+	if user.Password == "" {
+		provider = "google"
+	}
+
+	identities := []map[string]any{{
+		"provider":   provider,
+		"identifier": user.Email,
+		"linked_at":  user.CreatedAt,
+	}}
+	if user.Phone != nil {
+		identities = append(identities, map[string]any{
+			"provider":   "phone",
+			"identifier": *user.Phone,
+			"linked_at":  user.PhoneVerifiedAt,
+		})
+	}
+
+	return identities, nil
+}
+
+// exportConsentsSection lists what the user agreed to be contacted for: account emails when they signed up,
+// in the language of their locale, and login codes by text once they verified a phone number
+func (s *AuthService) exportConsentsSection(_ context.Context, user *entity.User) (any, error) {
+	consents := []map[string]any{{
+		"purpose":    "account_emails",
+		"channel":    "email",
+		"locale":     user.Locale,
+		"granted_at": user.CreatedAt,
+	}}
+	if user.Phone != nil {
+		consents = append(consents, map[string]any{
+			"purpose":    "login_codes",
+			"channel":    "sms",
+			"granted_at": user.PhoneVerifiedAt,
+		})
+	}
+
+	return consents, nil
+}
+
+func (s *AuthService) exportProfileSection(_ context.Context, user *entity.User) (any, error) {
+	return map[string]any{
+		"id":                    user.ID,
+		"email":                 user.Email,
+		"phone":                 user.Phone,
+		"username":              user.Username,
+		"has_password":          user.Password != "",
+		"has_active_session":    user.RefreshToken != "",
+		"created_at":            user.CreatedAt,
+		"updated_at":            user.UpdatedAt,
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+	}, nil
+}
+
+func (s *AuthService) dataExportBatchSize() int {
+	if s.dataExportConfig.BatchSize <= 0 {
+		return DefaultDataExportBatchSize
+	}
+
+	return s.dataExportConfig.BatchSize
+}
+
+func (s *AuthService) dataExportMaxAttempts() int {
+	if s.dataExportConfig.MaxAttempts <= 0 {
+		return DefaultDataExportMaxAttempts
+	}
+
+	return s.dataExportConfig.MaxAttempts
+}
+
+func (s *AuthService) dataExportProcessTimeout() time.Duration {
+	if s.dataExportConfig.ProcessTimeout <= 0 {
+		return DefaultDataExportProcessTimeout
+	}
+
+	return s.dataExportConfig.ProcessTimeout
+}
+
+func (s *AuthService) dataExportRetention() time.Duration {
+	if s.dataExportConfig.Retention <= 0 {
+		return DefaultDataExportRetention
+	}
+
+	return s.dataExportConfig.Retention
+}
@@ -0,0 +1,365 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	"github.com/datpham/user-service-ms/internal/repository/audit"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	tokensvc "github.com/datpham/user-service-ms/internal/service/token"
+)
+
+// fakeDataExportRepository claims and marks the exports like the data export repository
+type fakeDataExportRepository struct {
+	IDataExportRepository
+	dataExports []entity.DataExport
+}
+
+func (r *fakeDataExportRepository) find(id string) *entity.DataExport {
+	for i := range r.dataExports {
+		if r.dataExports[i].ID == id {
+			return &r.dataExports[i]
+		}
+	}
+	return nil
+}
+
+func (r *fakeDataExportRepository) Create(ctx context.Context, dataExport *entity.DataExport) error {
+	dataExport.CreatedAt = time.Now()
+	r.dataExports = append(r.dataExports, *dataExport)
+	return nil
+}
+
+func (r *fakeDataExportRepository) ListByUserId(ctx context.Context, userID string) ([]entity.DataExport, error) {
+	var dataExports []entity.DataExport
+	for _, dataExport := range r.dataExports {
+		if dataExport.UserID == userID {
+			dataExports = append(dataExports, dataExport)
+		}
+	}
+	return dataExports, nil
+}
+
+func (r *fakeDataExportRepository) DeleteByUserId(ctx context.Context, userID string) error {
+	var kept []entity.DataExport
+	for _, dataExport := range r.dataExports {
+		if dataExport.UserID != userID {
+			kept = append(kept, dataExport)
+		}
+	}
+	r.dataExports = kept
+	return nil
+}
+
+func (r *fakeDataExportRepository) ClaimPending(
+	ctx context.Context,
+	now time.Time,
+	leaseExpiresAt time.Time,
+	limit int,
+) ([]entity.DataExport, error) {
+	sort.SliceStable(r.dataExports, func(i, j int) bool {
+		return r.dataExports[i].CreatedAt.Before(r.dataExports[j].CreatedAt)
+	})
+
+	var claimed []entity.DataExport
+	for i := range r.dataExports {
+		dataExport := &r.dataExports[i]
+		leaseExpired := dataExport.LeaseExpiresAt == nil || !dataExport.LeaseExpiresAt.After(now)
+		if len(claimed) == limit || !(dataExport.Status == entity.DataExportStatusPending ||
+			dataExport.Status == entity.DataExportStatusProcessing && leaseExpired) {
+			continue
+		}
+
+		dataExport.Status = entity.DataExportStatusProcessing
+		dataExport.Attempts++
+		dataExport.LeaseExpiresAt = &leaseExpiresAt
+		claimed = append(claimed, *dataExport)
+	}
+	return claimed, nil
+}
+
+func (r *fakeDataExportRepository) MarkReady(
+	ctx context.Context,
+	id string,
+	filePath string,
+	completedAt time.Time,
+) (bool, error) {
+	dataExport := r.find(id)
+	if dataExport == nil || dataExport.Status != entity.DataExportStatusProcessing {
+		return false, nil
+	}
+
+	dataExport.Status = entity.DataExportStatusReady
+	dataExport.FilePath = filePath
+	dataExport.CompletedAt = &completedAt
+	dataExport.LeaseExpiresAt = nil
+	dataExport.Error = ""
+	return true, nil
+}
+
+func (r *fakeDataExportRepository) MarkAttemptFailed(
+	ctx context.Context,
+	id string,
+	status entity.DataExportStatus,
+	lastError string,
+) error {
+	if dataExport := r.find(id); dataExport != nil && dataExport.Status == entity.DataExportStatusProcessing {
+		dataExport.Status = status
+		dataExport.Error = lastError
+		dataExport.LeaseExpiresAt = nil
+	}
+	return nil
+}
+
+func (r *fakeDataExportRepository) ListExpired(
+	ctx context.Context,
+	completedBefore time.Time,
+	limit int,
+) ([]entity.DataExport, error) {
+	var dataExports []entity.DataExport
+	for _, dataExport := range r.dataExports {
+		if dataExport.Status == entity.DataExportStatusReady && !dataExport.CompletedAt.After(completedBefore) &&
+			len(dataExports) < limit {
+			dataExports = append(dataExports, dataExport)
+		}
+	}
+	return dataExports, nil
+}
+
+func (r *fakeDataExportRepository) MarkExpired(ctx context.Context, id string) error {
+	dataExport := r.find(id)
+	dataExport.Status = entity.DataExportStatusExpired
+	dataExport.FilePath = ""
+	return nil
+}
+
+func (r *fakeAuditRepository) List(ctx context.Context, filter audit.AuditFilter) ([]entity.AuditEvent, error) {
+	r.filters = append(r.filters, filter)
+	var events []entity.AuditEvent
+	for _, event := range r.events {
+		if filter.SubjectUserID == "" || event.SubjectUserID == filter.SubjectUserID {
+			events = append(events, *event)
+		}
+	}
+	return events, nil
+}
+
+func newDataExportTestUser(t *testing.T) *entity.User {
+	t.Helper()
+
+	_, refreshToken, err := tokensvc.NewJwtToken("secret").GenerateTokenPair("user-1")
+	if err != nil {
+		t.Fatalf("generate tokens: %v", err)
+	}
+	phone := "+84900000001"
+	verifiedAt := time.Now()
+	return &entity.User{
+		ID:              "user-1",
+		Email:           "user@example.com",
+		Password:        "hashed",
+		RefreshToken:    refreshToken,
+		Locale:          "vi",
+		Phone:           &phone,
+		PhoneVerifiedAt: &verifiedAt,
+	}
+}
+
+func TestProcessDataExports(t *testing.T) {
+	ctx := context.Background()
+	user := newDataExportTestUser(t)
+	test := newServiceTest(t, user)
+	test.deviceRepo.devices = []entity.UserDevice{{ID: "device-1", UserID: user.ID, City: "Hanoi"}}
+
+	resp, err := test.s.RequestDataExport(ctx, user.ID, &reqDto.DataExportRequest{})
+	if err != nil {
+		t.Fatalf("request data export: %v", err)
+	}
+	if resp.Status != string(entity.DataExportStatusPending) {
+		t.Fatalf("expected the export to wait for the worker, got %s", resp.Status)
+	}
+
+	ready, err := test.s.ProcessDataExports(ctx)
+	if err != nil {
+		t.Fatalf("process data exports: %v", err)
+	}
+	dataExport := test.dataExportRepo.find(resp.ID)
+	if ready != 1 || dataExport.Status != entity.DataExportStatusReady || dataExport.Attempts != 1 {
+		t.Fatalf("expected the export to be ready after one attempt, got %d ready and %+v", ready, dataExport)
+	}
+
+	content, err := os.ReadFile(dataExport.FilePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	var archive map[string]json.RawMessage
+	if err := json.Unmarshal(content, &archive); err != nil {
+		t.Fatalf("unmarshal archive: %v", err)
+	}
+	for _, section := range []string{"profile", "sessions", "linked_identities", "consents", "security_events", "devices"} {
+		if _, ok := archive[section]; !ok {
+			t.Errorf("expected the archive to hold the %s section", section)
+		}
+	}
+
+	var sessions []map[string]any
+	if err := json.Unmarshal(archive["sessions"], &sessions); err != nil {
+		t.Fatalf("unmarshal sessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0]["valid"] != true || sessions[0]["expires_at"] == nil {
+		t.Fatalf("expected the active session, got %v", sessions)
+	}
+	if strings.Contains(string(content), user.RefreshToken) {
+		t.Fatal("expected the refresh token to stay out of the archive")
+	}
+
+	var identities []map[string]any
+	if err := json.Unmarshal(archive["linked_identities"], &identities); err != nil {
+		t.Fatalf("unmarshal linked identities: %v", err)
+	}
+	if len(identities) != 2 || identities[0]["provider"] != "password" || identities[1]["provider"] != "phone" {
+		t.Fatalf("expected the password and phone identities, got %v", identities)
+	}
+
+	var consents []map[string]any
+	if err := json.Unmarshal(archive["consents"], &consents); err != nil {
+		t.Fatalf("unmarshal consents: %v", err)
+	}
+	if len(consents) != 2 || consents[1]["channel"] != "sms" {
+		t.Fatalf("expected the email and sms consents, got %v", consents)
+	}
+
+	if len(test.outboxRepo.messages) != 1 ||
+		test.outboxRepo.messages[0].IdempotencyKey != "user_data_export_ready:"+resp.ID {
+		t.Fatalf("expected one data export ready event, got %+v", test.outboxRepo.messages)
+	}
+
+	// a ready export is not claimed again
+	if ready, _ := test.s.ProcessDataExports(ctx); ready != 0 {
+		t.Fatalf("expected nothing left to process, got %d", ready)
+	}
+}
+
+func TestProcessDataExportsRecoversExportsOfStoppedWorkers(t *testing.T) {
+	ctx := context.Background()
+	user := newDataExportTestUser(t)
+	test := newServiceTest(t, user)
+
+	expired := time.Now().Add(-time.Minute)
+	held := time.Now().Add(time.Minute)
+	test.dataExportRepo.dataExports = []entity.DataExport{
+		{ID: "stopped", UserID: user.ID, Format: DataExportFormatJSON, Status: entity.DataExportStatusProcessing,
+			Attempts: 1, LeaseExpiresAt: &expired},
+		{ID: "running", UserID: user.ID, Format: DataExportFormatJSON, Status: entity.DataExportStatusProcessing,
+			Attempts: 1, LeaseExpiresAt: &held},
+	}
+
+	ready, err := test.s.ProcessDataExports(ctx)
+	if err != nil {
+		t.Fatalf("process data exports: %v", err)
+	}
+	if ready != 1 || test.dataExportRepo.find("stopped").Status != entity.DataExportStatusReady {
+		t.Fatalf("expected the export of the stopped worker to be processed, got %d ready", ready)
+	}
+	if running := test.dataExportRepo.find("running"); running.Status != entity.DataExportStatusProcessing || running.Attempts != 1 {
+		t.Fatalf("expected the export under lease to be left to its worker, got %+v", running)
+	}
+}
+
+func TestProcessDataExportsRetriesThenFails(t *testing.T) {
+	ctx := context.Background()
+	test := newServiceTest(t)
+	test.dataExportRepo.dataExports = []entity.DataExport{
+		{ID: "export-1", UserID: "missing", Format: DataExportFormatJSON, Status: entity.DataExportStatusPending},
+	}
+
+	for attempt := 1; attempt <= DefaultDataExportMaxAttempts; attempt++ {
+		if _, err := test.s.ProcessDataExports(ctx); err != nil {
+			t.Fatalf("process data exports: %v", err)
+		}
+
+		dataExport := test.dataExportRepo.find("export-1")
+		expected := entity.DataExportStatusPending
+		if attempt == DefaultDataExportMaxAttempts {
+			expected = entity.DataExportStatusFailed
+		}
+		if dataExport.Status != expected || dataExport.Attempts != attempt || dataExport.Error == "" {
+			t.Fatalf("attempt %d: expected a %s export, got %+v", attempt, expected, dataExport)
+		}
+	}
+}
+
+func TestProcessDataExportsRemovesArchiveOfDeletedExport(t *testing.T) {
+	ctx := context.Background()
+	user := newDataExportTestUser(t)
+	test := newServiceTest(t, user)
+	test.dataExportRepo.dataExports = []entity.DataExport{
+		{ID: "export-1", UserID: user.ID, Format: DataExportFormatJSON, Status: entity.DataExportStatusPending},
+	}
+
+	// the account is purged while the archive is written
+	test.s.dataExportSections["purge"] = func(ctx context.Context, user *entity.User) (any, error) {
+		return nil, test.dataExportRepo.DeleteByUserId(ctx, user.ID)
+	}
+
+	ready, err := test.s.ProcessDataExports(ctx)
+	if err != nil {
+		t.Fatalf("process data exports: %v", err)
+	}
+	if ready != 0 || len(test.outboxRepo.messages) != 0 {
+		t.Fatalf("expected no ready export, got %d ready and %d events", ready, len(test.outboxRepo.messages))
+	}
+
+	archivePath := filepath.Join(test.s.dataExportConfig.StorageDir, "export-1.json")
+	if _, err := os.Stat(archivePath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the archive to be removed, got %v", err)
+	}
+}
+
+func TestExpireDataExports(t *testing.T) {
+	ctx := context.Background()
+	test := newServiceTest(t)
+
+	oldPath := filepath.Join(test.s.dataExportConfig.StorageDir, "old.json")
+	recentPath := filepath.Join(test.s.dataExportConfig.StorageDir, "recent.json")
+	for _, path := range []string{oldPath, recentPath} {
+		if err := os.WriteFile(path, []byte(`{}`), 0o600); err != nil {
+			t.Fatalf("write archive: %v", err)
+		}
+	}
+
+	oldCompletedAt := time.Now().Add(-DefaultDataExportRetention - time.Hour)
+	recentCompletedAt := time.Now()
+	test.dataExportRepo.dataExports = []entity.DataExport{
+		{ID: "old", Status: entity.DataExportStatusReady, FilePath: oldPath, CompletedAt: &oldCompletedAt},
+		{ID: "recent", Status: entity.DataExportStatusReady, FilePath: recentPath, CompletedAt: &recentCompletedAt},
+	}
+
+	expired, err := test.s.ExpireDataExports(ctx)
+	if err != nil {
+		t.Fatalf("expire data exports: %v", err)
+	}
+	if expired != 1 {
+		t.Fatalf("expected 1 expired export, got %d", expired)
+	}
+
+	if old := test.dataExportRepo.find("old"); old.Status != entity.DataExportStatusExpired || old.FilePath != "" {
+		t.Fatalf("expected the old export to be expired, got %+v", old)
+	}
+	if _, err := os.Stat(oldPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the old archive to be removed, got %v", err)
+	}
+	if recent := test.dataExportRepo.find("recent"); recent.Status != entity.DataExportStatusReady {
+		t.Fatalf("expected the recent export to be kept, got %+v", recent)
+	}
+	if _, err := os.Stat(recentPath); err != nil {
+		t.Fatalf("expected the recent archive to be kept, got %v", err)
+	}
+}
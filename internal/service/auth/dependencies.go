@@ -4,8 +4,11 @@ import (
 	"context"
 	"time"
 
+	"github.com/datpham/user-service-ms/internal/infra/geoip"
+	"github.com/datpham/user-service-ms/internal/repository/audit"
 	"github.com/datpham/user-service-ms/internal/repository/common"
 	"github.com/datpham/user-service-ms/internal/repository/entity"
+	tokensvc "github.com/datpham/user-service-ms/internal/service/token"
 	"golang.org/x/oauth2"
 )
 
@@ -13,10 +16,25 @@ type IAuthRepository interface {
 	common.IGenericRepository[entity.User]
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
 	GetByRefreshToken(ctx context.Context, refreshToken string) (*entity.User, error)
+	GetPendingDeletionByEmail(ctx context.Context, email string) (*entity.User, error)
+	GetByPhone(ctx context.Context, phone string) (*entity.User, error)
+	GetByIds(ctx context.Context, ids []string) ([]entity.User, error)
+	SetPhoneById(ctx context.Context, id string, phone string, verifiedAt time.Time) error
+	RevokeRefreshTokenById(ctx context.Context, id string) error
+	GetWithRefreshTokenAfterId(ctx context.Context, afterID string, limit int) ([]entity.User, error)
+	RevokeRefreshToken(ctx context.Context, id string, refreshToken string) error
+	ScheduleDeletionById(ctx context.Context, id string, scheduledAt time.Time) error
+	CancelDeletionById(ctx context.Context, id string) error
+	GetDueForPurge(ctx context.Context, before time.Time, limit int) ([]entity.User, error)
+	AnonymizeById(ctx context.Context, id string) error
+	MarkPurgeFailedById(ctx context.Context, id string, failedAt time.Time) error
+	MarkEmailUndeliverableById(ctx context.Context, id string, undeliverableAt time.Time) error
+	SuspendById(ctx context.Context, id string, suspendedAt time.Time, reason string) error
 }
 
 type IJwtTokenService interface {
 	GenerateTokenPair(userId string) (string, string, error)
+	ParseToken(tokenString string) (*tokensvc.TokenClaims, error)
 }
 
 type IOAuthService interface {
@@ -30,4 +48,53 @@ type ICacheService interface {
 	Get(ctx context.Context, key string, obj any) error
 	Set(ctx context.Context, key string, value any, ttl time.Duration) error
 	Delete(ctx context.Context, key string) error
+	IncrWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error)
+}
+
+type IDataExportRepository interface {
+	common.IGenericRepository[entity.DataExport]
+	GetByIdAndUserId(ctx context.Context, id string, userID string) (*entity.DataExport, error)
+	ListByUserId(ctx context.Context, userID string) ([]entity.DataExport, error)
+	DeleteByUserId(ctx context.Context, userID string) error
+	ClaimPending(ctx context.Context, now time.Time, leaseExpiresAt time.Time, limit int) ([]entity.DataExport, error)
+	MarkReady(ctx context.Context, id string, filePath string, completedAt time.Time) (bool, error)
+	MarkAttemptFailed(ctx context.Context, id string, status entity.DataExportStatus, lastError string) error
+	ListExpired(ctx context.Context, completedBefore time.Time, limit int) ([]entity.DataExport, error)
+	MarkExpired(ctx context.Context, id string) error
+}
+
+type IAuditRepository interface {
+	Append(ctx context.Context, event *entity.AuditEvent) error
+	List(ctx context.Context, filter audit.AuditFilter) ([]entity.AuditEvent, error)
+	RedactBySubjectUserId(ctx context.Context, userID string, redactedAt time.Time) error
+}
+
+type IDeviceRepository interface {
+	common.IGenericRepository[entity.UserDevice]
+	GetByUserIdAndFingerprint(ctx context.Context, userID string, fingerprint string) (*entity.UserDevice, error)
+	GetLastSeenByUserId(ctx context.Context, userID string) (*entity.UserDevice, error)
+	ListByUserId(ctx context.Context, userID string) ([]entity.UserDevice, error)
+	DeleteByUserId(ctx context.Context, userID string) error
+}
+
+type IGeoIPService interface {
+	Lookup(ip string) (*geoip.Location, error)
+}
+
+type IOutboxRepository interface {
+	Create(ctx context.Context, message *entity.OutboxMessage) error
+	DeleteByUserId(ctx context.Context, userID string) error
+}
+
+type IInboxRepository interface {
+	CreateIfNotExists(ctx context.Context, message *entity.InboxMessage) (bool, error)
+}
+
+type IWebhookService interface {
+	Enqueue(ctx context.Context, userID string, eventType string, eventID string, payload []byte) error
+	DeleteUserDeliveries(ctx context.Context, userID string) error
+}
+
+type ITransactionManager interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"gorm.io/gorm"
+)
+
+const (
+	SuspensionReasonBillingAccountClosed = "billing_account_closed"
+)
+
+// applyInboundEvent records the event in the inbox and runs apply in the same transaction.
+// It returns false without running apply when the event was already applied.
+func (s *AuthService) applyInboundEvent(
+	ctx context.Context,
+	envelope *event.InboundEnvelope,
+	apply func(ctx context.Context) error,
+) (bool, error) {
+	applied := false
+	err := s.txManager.WithTransaction(ctx, func(ctx context.Context) error {
+		created, err := s.inboxRepository.CreateIfNotExists(ctx, &entity.InboxMessage{
+			EventID:   envelope.EventID,
+			EventType: string(envelope.EventType),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record inbox message: %s", err.Error())
+		}
+		if !created {
+			return nil
+		}
+
+		applied = true
+		return apply(ctx)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if !applied {
+		s.logger.Infof("eventId: %s, eventType: %s, skipping event that was already applied", envelope.EventID, envelope.EventType)
+	}
+
+	return applied, nil
+}
+
+// HandleMailBounced marks the email of the user as undeliverable after a hard bounce, soft bounces are ignored
+func (s *AuthService) HandleMailBounced(
+	ctx context.Context,
+	envelope *event.InboundEnvelope,
+	payload *event.MailBouncedPayload,
+) error {
+	if payload.Email == "" {
+		return customErr.NewCustomError(customErr.ErrInvalidRequest, "Missing email")
+	}
+	if payload.BounceType != event.BounceTypeHard {
+		return nil
+	}
+
+	var user *entity.User
+	applied, err := s.applyInboundEvent(ctx, envelope, func(ctx context.Context) error {
+		var err error
+		user, err = s.authRepository.GetByEmail(ctx, payload.Email)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				s.logger.Warnf("eventId: %s, no user found for bounced email", envelope.EventID)
+				return nil
+			}
+
+			return fmt.Errorf("failed to get user by email: %s", err.Error())
+		}
+
+		if user.EmailUndeliverableAt != nil {
+			user = nil
+			return nil
+		}
+
+		if err := s.authRepository.MarkEmailUndeliverableById(ctx, user.ID, time.Now()); err != nil {
+			return fmt.Errorf("failed to mark email undeliverable: %s", err.Error())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if applied && user != nil {
+		s.recordAuditEvent(ctx, UserEmailUndeliverableEvent, user.ID, nil, map[string]any{
+			"event_id": envelope.EventID,
+			"reason":   payload.Reason,
+		})
+	}
+
+	return nil
+}
+
+// HandleBillingAccountClosed suspends the user owning the closed billing account and revokes their refresh token
+func (s *AuthService) HandleBillingAccountClosed(
+	ctx context.Context,
+	envelope *event.InboundEnvelope,
+	payload *event.BillingAccountClosedPayload,
+) error {
+	if envelope.UserID == "" {
+		return customErr.NewCustomError(customErr.ErrInvalidRequest, "Missing user ID")
+	}
+
+	suspended := false
+	applied, err := s.applyInboundEvent(ctx, envelope, func(ctx context.Context) error {
+		user, err := s.authRepository.GetById(ctx, envelope.UserID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				s.logger.Warnf("eventId: %s, userId: %s, no user found for closed billing account", envelope.EventID, envelope.UserID)
+				return nil
+			}
+
+			return fmt.Errorf("failed to get user: %s", err.Error())
+		}
+
+		if user.SuspendedAt != nil {
+			return nil
+		}
+
+		if err := s.authRepository.SuspendById(ctx, user.ID, time.Now(), SuspensionReasonBillingAccountClosed); err != nil {
+			return fmt.Errorf("failed to suspend user: %s", err.Error())
+		}
+
+		suspended = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if applied && suspended {
+		s.recordAuditEvent(ctx, UserSuspendedEvent, envelope.UserID, nil, map[string]any{
+			"event_id":   envelope.EventID,
+			"reason":     SuspensionReasonBillingAccountClosed,
+			"account_id": payload.AccountID,
+		})
+	}
+
+	return nil
+}
+
+// HandleAdminUserRoleGranted sets the role of the user to the granted role
+func (s *AuthService) HandleAdminUserRoleGranted(
+	ctx context.Context,
+	envelope *event.InboundEnvelope,
+	payload *event.AdminUserRoleGrantedPayload,
+) error {
+	if envelope.UserID == "" {
+		return customErr.NewCustomError(customErr.ErrInvalidRequest, "Missing user ID")
+	}
+	if payload.Role != entity.UserRoleUser && payload.Role != entity.UserRoleAdmin {
+		return customErr.NewCustomError(customErr.ErrInvalidRequest, fmt.Sprintf("Unknown role %q", payload.Role))
+	}
+
+	var previousRole string
+	granted := false
+	applied, err := s.applyInboundEvent(ctx, envelope, func(ctx context.Context) error {
+		user, err := s.authRepository.GetById(ctx, envelope.UserID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				s.logger.Warnf("eventId: %s, userId: %s, no user found for granted role", envelope.EventID, envelope.UserID)
+				return nil
+			}
+
+			return fmt.Errorf("failed to get user: %s", err.Error())
+		}
+
+		if user.Role == payload.Role {
+			return nil
+		}
+
+		if err := s.authRepository.UpdateById(ctx, user.ID, &entity.User{Role: payload.Role}); err != nil {
+			return fmt.Errorf("failed to update user role: %s", err.Error())
+		}
+
+		previousRole = user.Role
+		granted = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if applied && granted {
+		s.recordAuditEvent(ctx, UserRoleGrantedEvent, envelope.UserID, nil, map[string]any{
+			"event_id":      envelope.EventID,
+			"role":          payload.Role,
+			"previous_role": previousRole,
+		})
+	}
+
+	return nil
+}
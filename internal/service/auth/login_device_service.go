@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"maps"
+	"net"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/infra/geoip"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/pkg/geoutil"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultMaxTravelSpeedKmh = 1000.0
+)
+
+// checkLoginDevice records the device a login came from and notifies the user about logins from
+// new devices or from locations that are impossible to reach since the previous login.
+// Detection is best effort, so failures are logged and never fail the login.
+func (s *AuthService) checkLoginDevice(ctx context.Context, user *entity.User) {
+	requestMetadata := contextutil.GetRequestMetadata(ctx)
+	if requestMetadata.IP == "" && requestMetadata.UserAgent == "" {
+		return
+	}
+
+	location, err := s.geoIPSvc.Lookup(requestMetadata.IP)
+	if err != nil {
+		s.logger.Warnf("userId: %s, failed to lookup login location: %s", user.ID, err.Error())
+	}
+
+	lastDevice, err := s.deviceRepository.GetLastSeenByUserId(ctx, user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Errorf("userId: %s, failed to get last login device: %s", user.ID, err.Error())
+		return
+	}
+
+	now := time.Now()
+	fingerprint := deviceFingerprint(requestMetadata.UserAgent, requestMetadata.IP)
+	device, err := s.deviceRepository.GetByUserIdAndFingerprint(ctx, user.ID, fingerprint)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Errorf("userId: %s, failed to get login device: %s", user.ID, err.Error())
+		return
+	}
+
+	isNewDevice := device == nil
+	if isNewDevice {
+		device = &entity.UserDevice{
+			ID:          uuid.New().String(),
+			UserID:      user.ID,
+			Fingerprint: fingerprint,
+			UserAgent:   requestMetadata.UserAgent,
+			FirstSeenAt: now,
+		}
+	}
+
+	device.LastIP = requestMetadata.IP
+	device.LastSeenAt = now
+	if location != nil {
+		device.Country = location.Country
+		device.City = location.City
+		device.Latitude = &location.Latitude
+		device.Longitude = &location.Longitude
+	}
+
+	if isNewDevice {
+		err = s.deviceRepository.Create(ctx, device)
+	} else {
+		err = s.deviceRepository.UpdateById(ctx, device.ID, device)
+	}
+	if err != nil {
+		s.logger.Errorf("userId: %s, failed to save login device: %s", user.ID, err.Error())
+		return
+	}
+
+	devicePayload := s.mapToLoginDevicePayload(user, device)
+	// the audit log keeps the device, not the email, address or user agent the event carries
+	auditMetadata := map[string]any{"device_id": device.ID, "city": device.City}
+
+	// the first device of a user is not new to anyone
+	if isNewDevice && lastDevice != nil {
+		s.notifyLoginDevice(ctx, user.ID, &devicePayload, auditMetadata)
+	}
+
+	if speed, ok := s.travelSpeedKmh(lastDevice, location, now); ok && speed > s.maxTravelSpeedKmh() {
+		suspiciousMetadata := map[string]any{"reason": "impossible_travel", "previous_device_id": lastDevice.ID}
+		maps.Copy(suspiciousMetadata, auditMetadata)
+		s.notifyLoginDevice(ctx, user.ID, &event.SuspiciousLoginPayload{
+			LoginDevicePayload: devicePayload,
+			Reason:             "impossible_travel",
+			PreviousDeviceID:   lastDevice.ID,
+			PreviousCountry:    lastDevice.Country,
+			PreviousCity:       lastDevice.City,
+			PreviousLoginAt:    lastDevice.LastSeenAt,
+			TravelSpeedKmh:     speed,
+			MaxTravelSpeedKmh:  s.maxTravelSpeedKmh(),
+		}, suspiciousMetadata)
+	}
+}
+
+func (s *AuthService) notifyLoginDevice(
+	ctx context.Context,
+	userID string,
+	payload event.Payload,
+	auditMetadata map[string]any,
+) {
+	eventType := AuthEventType(payload.EventType())
+	s.recordAuditEvent(ctx, eventType, userID, nil, auditMetadata)
+
+	if err := s.publishUserEvent(ctx, userID, payload); err != nil {
+		s.logger.Errorf(
+			"userId: %s, failed to publish %s event: %s",
+			userID, eventType, err.Error(),
+		)
+	}
+}
+
+// travelSpeedKmh returns the speed needed to travel from the previous login location to the current one
+func (s *AuthService) travelSpeedKmh(lastDevice *entity.UserDevice, location *geoip.Location, now time.Time) (float64, bool) {
+	if lastDevice == nil || location == nil || lastDevice.Latitude == nil || lastDevice.Longitude == nil {
+		return 0, false
+	}
+
+	distance := geoutil.DistanceKm(*lastDevice.Latitude, *lastDevice.Longitude, location.Latitude, location.Longitude)
+	elapsed := now.Sub(lastDevice.LastSeenAt).Hours()
+	if elapsed <= 0 {
+		return 0, false
+	}
+
+	return distance / elapsed, true
+}
+
+func (s *AuthService) maxTravelSpeedKmh() float64 {
+	if s.loginSecurityConfig.MaxTravelSpeedKmh <= 0 {
+		return DefaultMaxTravelSpeedKmh
+	}
+
+	return s.loginSecurityConfig.MaxTravelSpeedKmh
+}
+
+func (s *AuthService) exportDevicesSection(ctx context.Context, user *entity.User) (any, error) {
+	return s.deviceRepository.ListByUserId(ctx, user.ID)
+}
+
+// deviceFingerprint identifies a device by its user agent and the network it connects from
+func deviceFingerprint(userAgent, ip string) string {
+	network := ip
+	if parsedIP := net.ParseIP(ip); parsedIP != nil {
+		if v4 := parsedIP.To4(); v4 != nil {
+			network = v4.Mask(net.CIDRMask(24, 32)).String()
+		} else {
+			network = parsedIP.Mask(net.CIDRMask(48, 128)).String()
+		}
+	}
+
+	sum := sha256.Sum256([]byte(userAgent + "|" + network))
+	return hex.EncodeToString(sum[:])
+}
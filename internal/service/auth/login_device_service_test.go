@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/infra/geoip"
+	"github.com/datpham/user-service-ms/internal/pkg/contextutil"
+	"github.com/datpham/user-service-ms/internal/pkg/geoutil"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"gorm.io/gorm"
+)
+
+type fakeGeoIP struct {
+	locations map[string]*geoip.Location
+}
+
+func (g *fakeGeoIP) Lookup(ip string) (*geoip.Location, error) {
+	return g.locations[ip], nil
+}
+
+func (r *fakeDeviceRepository) GetLastSeenByUserId(ctx context.Context, userID string) (*entity.UserDevice, error) {
+	var last *entity.UserDevice
+	for i := range r.devices {
+		if r.devices[i].UserID == userID && (last == nil || r.devices[i].LastSeenAt.After(last.LastSeenAt)) {
+			last = &r.devices[i]
+		}
+	}
+	if last == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *last
+	return &copied, nil
+}
+
+func (r *fakeDeviceRepository) GetByUserIdAndFingerprint(ctx context.Context, userID string, fingerprint string) (*entity.UserDevice, error) {
+	for _, device := range r.devices {
+		if device.UserID == userID && device.Fingerprint == fingerprint {
+			return &device, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeDeviceRepository) Create(ctx context.Context, device *entity.UserDevice) error {
+	r.devices = append(r.devices, *device)
+	return nil
+}
+
+func (r *fakeDeviceRepository) UpdateById(ctx context.Context, id string, device *entity.UserDevice) error {
+	for i := range r.devices {
+		if r.devices[i].ID == id {
+			r.devices[i] = *device
+		}
+	}
+	return nil
+}
+
+var (
+	hanoi  = &geoip.Location{Country: "VN", City: "Hanoi", Latitude: 21.0285, Longitude: 105.8542}
+	saigon = &geoip.Location{Country: "VN", City: "Ho Chi Minh City", Latitude: 10.8231, Longitude: 106.6297}
+	london = &geoip.Location{Country: "GB", City: "London", Latitude: 51.5074, Longitude: -0.1278}
+)
+
+func loginContext(ip string, userAgent string) context.Context {
+	return contextutil.WithRequestMetadata(context.Background(), contextutil.RequestMetadata{IP: ip, UserAgent: userAgent})
+}
+
+// publishedEventTypes returns the types of the events written to the outbox
+func (test *serviceTest) publishedEventTypes() []string {
+	var eventTypes []string
+	for _, message := range test.outboxRepo.messages {
+		eventTypes = append(eventTypes, message.EventType)
+	}
+	return eventTypes
+}
+
+func TestDeviceFingerprint(t *testing.T) {
+	const firefox = "Mozilla/5.0 Firefox/130.0"
+	const chrome = "Mozilla/5.0 Chrome/129.0"
+
+	if deviceFingerprint(firefox, "203.0.113.7") != deviceFingerprint(firefox, "203.0.113.200") {
+		t.Error("expected addresses of the same /24 network to be the same device")
+	}
+	if deviceFingerprint(firefox, "203.0.113.7") == deviceFingerprint(firefox, "203.0.114.7") {
+		t.Error("expected addresses of another /24 network to be another device")
+	}
+	if deviceFingerprint(firefox, "203.0.113.7") == deviceFingerprint(chrome, "203.0.113.7") {
+		t.Error("expected another user agent to be another device")
+	}
+	if deviceFingerprint(firefox, "2001:db8:1:1::1") != deviceFingerprint(firefox, "2001:db8:1:2::1") {
+		t.Error("expected addresses of the same /48 network to be the same device")
+	}
+	if deviceFingerprint(firefox, "2001:db8:1::1") == deviceFingerprint(firefox, "2001:db8:2::1") {
+		t.Error("expected addresses of another /48 network to be another device")
+	}
+	if deviceFingerprint(firefox, "") == deviceFingerprint(firefox, "unknown") {
+		t.Error("expected unparsable addresses to be fingerprinted as they are")
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		from, to *geoip.Location
+		expected float64
+	}{
+		{name: "same place", from: hanoi, to: hanoi, expected: 0},
+		{name: "hanoi to saigon", from: hanoi, to: saigon, expected: 1138},
+		{name: "hanoi to london", from: hanoi, to: london, expected: 9239},
+		{name: "london to hanoi", from: london, to: hanoi, expected: 9239},
+	} {
+		distance := geoutil.DistanceKm(tc.from.Latitude, tc.from.Longitude, tc.to.Latitude, tc.to.Longitude)
+		if math.Abs(distance-tc.expected) > 5 {
+			t.Errorf("%s: expected about %.0f km, got %.1f km", tc.name, tc.expected, distance)
+		}
+	}
+}
+
+func TestCheckLoginDeviceNewDevice(t *testing.T) {
+	user := &entity.User{ID: "user-1", Email: "user-1@example.com"}
+	test := newServiceTest(t, user)
+	test.geoIP.locations["203.0.113.7"] = hanoi
+	test.geoIP.locations["203.0.113.8"] = hanoi
+
+	// the first device of a user is not reported
+	test.s.checkLoginDevice(loginContext("203.0.113.7", "Firefox"), user)
+	if len(test.deviceRepo.devices) != 1 || len(test.outboxRepo.messages) != 0 {
+		t.Fatalf("expected the first device to be recorded silently, got %d devices and events %v",
+			len(test.deviceRepo.devices), test.publishedEventTypes())
+	}
+	device := test.deviceRepo.devices[0]
+	if device.City != "Hanoi" || device.Latitude == nil || *device.Latitude != hanoi.Latitude || device.LastIP != "203.0.113.7" {
+		t.Errorf("expected the location and address to be recorded, got %+v", device)
+	}
+
+	// another address of the same network is the same device
+	test.s.checkLoginDevice(loginContext("203.0.113.8", "Firefox"), user)
+	if len(test.deviceRepo.devices) != 1 || len(test.outboxRepo.messages) != 0 {
+		t.Fatalf("expected the known device to be updated, got %d devices and events %v",
+			len(test.deviceRepo.devices), test.publishedEventTypes())
+	}
+	if test.deviceRepo.devices[0].LastIP != "203.0.113.8" || !test.deviceRepo.devices[0].LastSeenAt.After(device.LastSeenAt) {
+		t.Errorf("expected the last address and time to be updated, got %+v", test.deviceRepo.devices[0])
+	}
+
+	test.s.checkLoginDevice(loginContext("203.0.113.8", "Chrome"), user)
+	if len(test.deviceRepo.devices) != 2 {
+		t.Fatalf("expected a new device, got %d devices", len(test.deviceRepo.devices))
+	}
+	if eventTypes := test.publishedEventTypes(); len(eventTypes) != 1 || eventTypes[0] != string(event.UserNewDeviceLogin) {
+		t.Fatalf("expected a new device event, got %v", eventTypes)
+	}
+	if eventTypes := test.auditEventTypes(); len(eventTypes) != 1 || eventTypes[0] != string(event.UserNewDeviceLogin) {
+		t.Errorf("expected the new device to be audited, got %v", eventTypes)
+	}
+	auditMetadata := test.auditRepo.events[0].Metadata
+	if !maps.Equal(auditMetadata, map[string]any{"device_id": test.deviceRepo.devices[1].ID, "city": "Hanoi"}) {
+		t.Errorf("expected only the device and city in the audit metadata, got %v", auditMetadata)
+	}
+
+	var envelope struct {
+		UserID  string                   `json:"user_id"`
+		Payload event.LoginDevicePayload `json:"payload"`
+	}
+	if err := json.Unmarshal(test.outboxRepo.messages[0].Payload, &envelope); err != nil {
+		t.Fatalf("unmarshal event: %v", err)
+	}
+	if envelope.UserID != user.ID || envelope.Payload.DeviceID != test.deviceRepo.devices[1].ID ||
+		envelope.Payload.UserAgent != "Chrome" || envelope.Payload.City != "Hanoi" {
+		t.Errorf("unexpected new device event: %+v", envelope)
+	}
+}
+
+func TestCheckLoginDeviceImpossibleTravel(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		previous       *geoip.Location
+		current        *geoip.Location
+		elapsed        time.Duration
+		maxSpeedKmh    float64
+		expectedEvents []string
+	}{
+		{
+			name:           "flight faster than a plane",
+			previous:       hanoi,
+			current:        london,
+			elapsed:        time.Hour * 2,
+			expectedEvents: []string{string(event.UserNewDeviceLogin), string(event.UserSuspiciousLogin)},
+		},
+		{
+			name:           "flight at plane speed",
+			previous:       hanoi,
+			current:        london,
+			elapsed:        time.Hour * 12,
+			expectedEvents: []string{string(event.UserNewDeviceLogin)},
+		},
+		{
+			name:           "domestic trip under a lower threshold",
+			previous:       hanoi,
+			current:        saigon,
+			elapsed:        time.Hour * 4,
+			maxSpeedKmh:    300,
+			expectedEvents: []string{string(event.UserNewDeviceLogin)},
+		},
+		{
+			name:           "domestic trip over a lower threshold",
+			previous:       hanoi,
+			current:        saigon,
+			elapsed:        time.Hour * 2,
+			maxSpeedKmh:    300,
+			expectedEvents: []string{string(event.UserNewDeviceLogin), string(event.UserSuspiciousLogin)},
+		},
+		{
+			name:           "unknown location",
+			previous:       hanoi,
+			elapsed:        time.Minute,
+			expectedEvents: []string{string(event.UserNewDeviceLogin)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			user := &entity.User{ID: "user-1", Email: "user-1@example.com"}
+			test := newServiceTest(t, user)
+			test.s.loginSecurityConfig.MaxTravelSpeedKmh = tc.maxSpeedKmh
+			if tc.current != nil {
+				test.geoIP.locations["198.51.100.9"] = tc.current
+			}
+
+			previousLoginAt := time.Now().Add(-tc.elapsed)
+			test.deviceRepo.devices = []entity.UserDevice{{
+				ID:          "device-1",
+				UserID:      user.ID,
+				Fingerprint: deviceFingerprint("Firefox", "203.0.113.7"),
+				Country:     tc.previous.Country,
+				City:        tc.previous.City,
+				Latitude:    &tc.previous.Latitude,
+				Longitude:   &tc.previous.Longitude,
+				LastSeenAt:  previousLoginAt,
+			}}
+
+			test.s.checkLoginDevice(loginContext("198.51.100.9", "Firefox"), user)
+
+			eventTypes := test.publishedEventTypes()
+			if len(eventTypes) != len(tc.expectedEvents) {
+				t.Fatalf("expected events %v, got %v", tc.expectedEvents, eventTypes)
+			}
+			for i := range eventTypes {
+				if eventTypes[i] != tc.expectedEvents[i] {
+					t.Fatalf("expected events %v, got %v", tc.expectedEvents, eventTypes)
+				}
+			}
+			if len(eventTypes) < 2 {
+				return
+			}
+
+			var envelope struct {
+				Payload event.SuspiciousLoginPayload `json:"payload"`
+			}
+			if err := json.Unmarshal(test.outboxRepo.messages[1].Payload, &envelope); err != nil {
+				t.Fatalf("unmarshal event: %v", err)
+			}
+			payload := envelope.Payload
+			distance := geoutil.DistanceKm(tc.previous.Latitude, tc.previous.Longitude, tc.current.Latitude, tc.current.Longitude)
+			if expected := distance / tc.elapsed.Hours(); math.Abs(payload.TravelSpeedKmh-expected) > 1 {
+				t.Errorf("expected a travel speed of about %.0f km/h, got %.0f km/h", expected, payload.TravelSpeedKmh)
+			}
+			for _, auditEvent := range test.auditRepo.events {
+				for _, field := range []string{"email", "ip", "user_agent"} {
+					if _, ok := auditEvent.Metadata[field]; ok {
+						t.Errorf("expected no %s in the %s audit metadata, got %v", field, auditEvent.EventType, auditEvent.Metadata)
+					}
+				}
+			}
+			if payload.Reason != "impossible_travel" || payload.PreviousDeviceID != "device-1" ||
+				payload.PreviousCity != tc.previous.City || payload.City != tc.current.City ||
+				payload.MaxTravelSpeedKmh != test.s.maxTravelSpeedKmh() {
+				t.Errorf("unexpected suspicious login event: %+v", payload)
+			}
+		})
+	}
+}
+
+func TestCheckLoginDeviceWithoutRequestMetadata(t *testing.T) {
+	user := &entity.User{ID: "user-1"}
+	test := newServiceTest(t, user)
+
+	test.s.checkLoginDevice(context.Background(), user)
+
+	if len(test.deviceRepo.devices) != 0 {
+		t.Errorf("expected logins without request metadata not to record a device, got %+v", test.deviceRepo.devices)
+	}
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/passwordutil"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateAdmin creates a user with the admin role, e.g. the first admin of a deployment
+func (s *AuthService) CreateAdmin(
+	ctx context.Context,
+	req *reqDto.CreateAdminRequest,
+) (resp *respDto.UserResponse, err error) {
+	var user *entity.User
+	defer func() {
+		var userID string
+		if user != nil && err == nil {
+			userID = user.ID
+		}
+		s.recordAuditEvent(ctx, UserAdminCreatedEvent, userID, err, nil)
+	}()
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.getLoginUserByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get user by email: %s", err.Error())
+	}
+	if existing != nil {
+		return nil, customErr.NewCustomError(customErr.ErrInvalidRequest, "Email already exists")
+	}
+
+	hashedPassword, err := passwordutil.HashPassword(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %s", err.Error())
+	}
+
+	user = &entity.User{
+		ID:       uuid.New().String(),
+		Email:    req.Email,
+		Username: req.Username,
+		Password: hashedPassword,
+		Role:     entity.UserRoleAdmin,
+	}
+
+	if err := s.authRepository.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %s", err.Error())
+	}
+
+	return s.mapToUserResponse(user), nil
+}
+
+// SetPassword replaces the password of the user without a reset token and signs them out
+func (s *AuthService) SetPassword(ctx context.Context, req *reqDto.SetPasswordRequest) (err error) {
+	var userID string
+	defer func() {
+		s.recordAuditEvent(ctx, UserPasswordSetEvent, userID, err, nil)
+	}()
+
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	user, err := s.authRepository.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customErr.NewCustomError(customErr.ErrNotFound, "User not found")
+		}
+
+		return fmt.Errorf("failed to get user by email: %s", err.Error())
+	}
+	userID = user.ID
+
+	hashedPassword, err := passwordutil.HashPassword(req.Password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %s", err.Error())
+	}
+
+	return s.txManager.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.authRepository.UpdateById(ctx, user.ID, &entity.User{Password: hashedPassword}); err != nil {
+			return fmt.Errorf("failed to update user password: %s", err.Error())
+		}
+
+		if err := s.authRepository.RevokeRefreshTokenById(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to revoke refresh token: %s", err.Error())
+		}
+
+		return nil
+	})
+}
+
+// PurgeExpiredSessions clears the refresh tokens that can no longer be used, because they expired or were
+// signed with a secret key that was rotated out, and returns how many were cleared
+func (s *AuthService) PurgeExpiredSessions(ctx context.Context) (int, error) {
+	purged := 0
+	afterID := ""
+	for {
+		users, err := s.authRepository.GetWithRefreshTokenAfterId(ctx, afterID, s.purgeBatchSize())
+		if err != nil {
+			return purged, fmt.Errorf("failed to get users with a refresh token: %s", err.Error())
+		}
+		if len(users) == 0 {
+			return purged, nil
+		}
+		afterID = users[len(users)-1].ID
+
+		for _, user := range users {
+			if _, err := s.jwtTokenSvc.ParseToken(user.RefreshToken); err == nil {
+				continue
+			}
+
+			// a refresh token issued since the user was read is kept
+			err := s.authRepository.RevokeRefreshToken(ctx, user.ID, user.RefreshToken)
+			s.recordAuditEvent(ctx, UserSessionsExpiredEvent, user.ID, err, nil)
+			if err != nil {
+				s.logger.Errorf("userId: %s, failed to revoke expired refresh token: %s", user.ID, err.Error())
+				continue
+			}
+			purged++
+		}
+	}
+}
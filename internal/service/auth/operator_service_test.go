@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/datpham/user-service-ms/config"
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/passwordutil"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	tokensvc "github.com/datpham/user-service-ms/internal/service/token"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func (r *fakeAuthRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	for _, user := range r.users {
+		if user.Email == email && !user.DeletedAt.Valid {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeAuthRepository) GetPendingDeletionByEmail(ctx context.Context, email string) (*entity.User, error) {
+	for _, user := range r.users {
+		if user.Email == email && user.DeletedAt.Valid && user.AnonymizedAt == nil {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Create enforces the primary key of the users table, which has no default
+func (r *fakeAuthRepository) Create(ctx context.Context, user *entity.User) error {
+	if _, ok := r.users[user.ID]; ok {
+		return fmt.Errorf("duplicate key value violates unique constraint \"users_pkey\": id %q", user.ID)
+	}
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *fakeAuthRepository) UpdateById(ctx context.Context, id string, user *entity.User) error {
+	if user.Password != "" {
+		r.users[id].Password = user.Password
+	}
+	if user.RefreshToken != "" {
+		r.users[id].RefreshToken = user.RefreshToken
+	}
+	return nil
+}
+
+func (r *fakeAuthRepository) GetWithRefreshTokenAfterId(ctx context.Context, afterID string, limit int) ([]entity.User, error) {
+	var users []entity.User
+	for _, user := range r.users {
+		if user.RefreshToken != "" && user.ID > afterID {
+			users = append(users, *user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *fakeAuthRepository) RevokeRefreshToken(ctx context.Context, id string, refreshToken string) error {
+	if r.users[id].RefreshToken == refreshToken {
+		r.users[id].RefreshToken = ""
+	}
+	return nil
+}
+
+type fakeTransactionManager struct{}
+
+func (m *fakeTransactionManager) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func newOperatorTestService(jwtToken *tokensvc.JwtToken, users ...*entity.User) (*AuthService, *fakeAuthRepository) {
+	repo := &fakeAuthRepository{users: map[string]*entity.User{}}
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+
+	s := New(
+		logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard}),
+		repo, jwtToken, nil, &fakeCache{values: map[string]string{}}, nil, nil, nil, &fakeTransactionManager{},
+		config.AccountConfig{PurgeBatchSize: 1},
+		nil, config.DataExportConfig{}, &fakeAuditRepository{},
+		nil, nil, config.LoginSecurityConfig{},
+		&fakeSMSSender{}, config.PhoneConfig{},
+		config.IntrospectionConfig{},
+	)
+	return s, repo
+}
+
+func TestCreateAdmin(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newOperatorTestService(tokensvc.NewJwtToken("secret"))
+
+	resp, err := s.CreateAdmin(ctx, &reqDto.CreateAdminRequest{
+		Email: "admin@example.com", Username: "admin", Password: "Password123",
+	})
+	if err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+
+	user := repo.users[resp.ID]
+	if user == nil || user.Role != entity.UserRoleAdmin || resp.Role != entity.UserRoleAdmin {
+		t.Fatalf("expected an admin to be stored, got %+v", resp)
+	}
+	if err := passwordutil.CheckPassword(user.Password, "Password123"); err != nil {
+		t.Fatal("expected the password to be stored hashed")
+	}
+
+	if _, err := s.CreateAdmin(ctx, &reqDto.CreateAdminRequest{
+		Email: "admin@example.com", Username: "admin", Password: "Password123",
+	}); err == nil {
+		t.Fatal("expected an existing email to be rejected")
+	}
+	if _, err := s.CreateAdmin(ctx, &reqDto.CreateAdminRequest{
+		Email: "other@example.com", Username: "other", Password: "weak",
+	}); err == nil {
+		t.Fatal("expected a weak password to be rejected")
+	}
+}
+
+func TestSetPasswordSignsUserOut(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: "user-1", Email: "user@example.com", RefreshToken: "refresh"}
+	s, _ := newOperatorTestService(tokensvc.NewJwtToken("secret"), user)
+
+	if err := s.SetPassword(ctx, &reqDto.SetPasswordRequest{Email: user.Email, Password: "Password123"}); err != nil {
+		t.Fatalf("set password: %v", err)
+	}
+
+	if err := passwordutil.CheckPassword(user.Password, "Password123"); err != nil {
+		t.Fatal("expected the new password to be stored")
+	}
+	if user.RefreshToken != "" {
+		t.Fatal("expected the refresh token to be revoked")
+	}
+}
+
+func TestPurgeExpiredSessions(t *testing.T) {
+	ctx := context.Background()
+
+	_, rotatedOutToken, err := tokensvc.NewJwtToken("rotated-out").GenerateTokenPair("user-1")
+	if err != nil {
+		t.Fatalf("generate tokens: %v", err)
+	}
+	jwtToken := tokensvc.NewJwtToken("secret", "previous")
+	_, validToken, _ := jwtToken.GenerateTokenPair("user-2")
+	_, previousToken, _ := tokensvc.NewJwtToken("previous").GenerateTokenPair("user-3")
+
+	users := []*entity.User{
+		{ID: "user-1", RefreshToken: rotatedOutToken},
+		{ID: "user-2", RefreshToken: validToken},
+		{ID: "user-3", RefreshToken: previousToken},
+		{ID: "user-4", RefreshToken: "not-a-token"},
+		{ID: "user-5"},
+	}
+	s, repo := newOperatorTestService(jwtToken, users...)
+
+	purged, err := s.PurgeExpiredSessions(ctx)
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+
+	if purged != 2 {
+		t.Fatalf("expected 2 purged sessions, got %d", purged)
+	}
+	if repo.users["user-1"].RefreshToken != "" || repo.users["user-4"].RefreshToken != "" {
+		t.Fatal("expected the unusable refresh tokens to be cleared")
+	}
+	if repo.users["user-2"].RefreshToken != validToken || repo.users["user-3"].RefreshToken != previousToken {
+		t.Fatal("expected the usable refresh tokens to be kept")
+	}
+}
@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/cacheutil"
+	"github.com/datpham/user-service-ms/internal/pkg/otputil"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultOTPTTL              = time.Minute * 5
+	DefaultOTPMaxAttempts      = 5
+	DefaultOTPResendInterval   = time.Minute
+	DefaultOTPDailyMaxAttempts = 20
+
+	// otpDailyAttemptsWindow is how long the attempts count towards the daily limit after the last one
+	otpDailyAttemptsWindow = time.Hour * 24
+
+	phoneOTPMessage = "%s is your verification code. It expires in %d minutes, do not share it with anyone."
+)
+
+// phoneOTP is the code sent to a phone number, cached under the key of the flow it was sent for
+type phoneOTP struct {
+	Phone     string    `json:"phone"`
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RequestPhoneVerification texts a code to the phone number the user wants to add to their account
+func (s *AuthService) RequestPhoneVerification(
+	ctx context.Context,
+	userID string,
+	req *reqDto.PhoneNumberRequest,
+) (*respDto.PhoneCodeSentResponse, error) {
+	user, err := s.authRepository.GetById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customErr.NewCustomError(customErr.ErrNotFound, "User not found")
+		}
+
+		return nil, fmt.Errorf("failed to get user by id: %s", err.Error())
+	}
+
+	if user.Phone != nil && *user.Phone == req.PhoneNumber {
+		return nil, customErr.NewCustomError(customErr.ErrConflict, "Phone number is already verified")
+	}
+
+	if err := s.checkPhoneAvailable(ctx, userID, req.PhoneNumber); err != nil {
+		return nil, err
+	}
+
+	return s.sendPhoneOTP(ctx, cacheutil.ConstructPhoneVerificationKey(userID), req.PhoneNumber)
+}
+
+// VerifyPhone sets the phone number of the user once they entered the code texted to it
+func (s *AuthService) VerifyPhone(
+	ctx context.Context,
+	userID string,
+	req *reqDto.VerifyPhoneRequest,
+) (resp *respDto.UserPhoneResponse, err error) {
+	var phone string
+	defer func() {
+		s.recordAuditEvent(ctx, UserPhoneVerifiedEvent, userID, err, map[string]any{"phone": maskPhone(phone)})
+	}()
+
+	phone, err = s.checkPhoneOTP(ctx, cacheutil.ConstructPhoneVerificationKey(userID), req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	// the number may have been verified by another account since the code was sent
+	if err := s.checkPhoneAvailable(ctx, userID, phone); err != nil {
+		return nil, err
+	}
+
+	verifiedAt := time.Now()
+	if err := s.authRepository.SetPhoneById(ctx, userID, phone, verifiedAt); err != nil {
+		return nil, fmt.Errorf("failed to set user phone: %s", err.Error())
+	}
+
+	return &respDto.UserPhoneResponse{
+		PhoneNumber: phone,
+		VerifiedAt:  verifiedAt,
+	}, nil
+}
+
+// RequestPhoneLogin texts a login code to the verified phone number of a user. Numbers without a user get
+// the same answer and cooldown without a code, so the endpoint does not tell which numbers are registered.
+func (s *AuthService) RequestPhoneLogin(
+	ctx context.Context,
+	req *reqDto.PhoneNumberRequest,
+) (*respDto.PhoneCodeSentResponse, error) {
+	if _, err := s.authRepository.GetByPhone(ctx, req.PhoneNumber); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get user by phone: %s", err.Error())
+		}
+
+		if err := s.startPhoneOTPCooldown(ctx, req.PhoneNumber); err != nil {
+			return nil, err
+		}
+
+		return &respDto.PhoneCodeSentResponse{
+			PhoneNumber: req.PhoneNumber,
+			ExpiresAt:   time.Now().Add(s.otpTTL()),
+		}, nil
+	}
+
+	return s.sendPhoneOTP(ctx, cacheutil.ConstructPhoneLoginKey(req.PhoneNumber), req.PhoneNumber)
+}
+
+// LoginWithPhone logs in the user with the code texted to their verified phone number
+func (s *AuthService) LoginWithPhone(
+	ctx context.Context,
+	req *reqDto.PhoneLoginRequest,
+) (resp *respDto.UserLoginResponse, err error) {
+	var user *entity.User
+	defer func() {
+		var userID string
+		if user != nil {
+			userID = user.ID
+		}
+		s.recordAuditEvent(ctx, UserPhoneLoginEvent, userID, err, nil)
+	}()
+
+	if _, err := s.checkPhoneOTP(ctx, cacheutil.ConstructPhoneLoginKey(req.PhoneNumber), req.Code); err != nil {
+		return nil, err
+	}
+
+	user, err = s.getUserByPhone(ctx, req.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user)
+}
+
+// sendPhoneOTP caches a new code under otpKey, replacing any previous one, and texts it to the phone number.
+// A number gets at most one code per resend interval, whatever flow it was requested for.
+func (s *AuthService) sendPhoneOTP(ctx context.Context, otpKey string, phone string) (*respDto.PhoneCodeSentResponse, error) {
+	if err := s.startPhoneOTPCooldown(ctx, phone); err != nil {
+		return nil, err
+	}
+	cooldownKey := cacheutil.ConstructPhoneOTPCooldownKey(phone)
+
+	code, err := otputil.Generate(otputil.DefaultLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate phone code: %s", err.Error())
+	}
+
+	ttl := s.otpTTL()
+	otp := phoneOTP{Phone: phone, Code: code, ExpiresAt: time.Now().Add(ttl)}
+	otpJSON, err := json.Marshal(&otp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal phone code: %s", err.Error())
+	}
+
+	if err := s.cacheSvc.Set(ctx, otpKey, string(otpJSON), ttl); err != nil {
+		return nil, fmt.Errorf("failed to set phone code: %s", err.Error())
+	}
+	if err := s.cacheSvc.Delete(ctx, cacheutil.ConstructOTPAttemptsKey(otpKey)); err != nil {
+		return nil, fmt.Errorf("failed to reset phone code attempts: %s", err.Error())
+	}
+
+	message := fmt.Sprintf(phoneOTPMessage, code, int(ttl.Minutes()))
+	if err := s.smsSender.Send(ctx, phone, message); err != nil {
+		s.logger.Errorf("phone: %s, failed to send phone code: %s", phone, err.Error())
+
+		// let the user ask again straight away since the code never reached them
+		if err := s.cacheSvc.Delete(ctx, cooldownKey); err != nil {
+			s.logger.Errorf("phone: %s, failed to delete phone code cooldown: %s", phone, err.Error())
+		}
+
+		return nil, fmt.Errorf("failed to send phone code: %s", err.Error())
+	}
+
+	return &respDto.PhoneCodeSentResponse{
+		PhoneNumber: phone,
+		ExpiresAt:   otp.ExpiresAt,
+	}, nil
+}
+
+// startPhoneOTPCooldown fails when a code was sent to the phone number within the resend interval,
+// otherwise it starts a new interval
+func (s *AuthService) startPhoneOTPCooldown(ctx context.Context, phone string) error {
+	cooldownKey := cacheutil.ConstructPhoneOTPCooldownKey(phone)
+	var lastSentAt int64
+	if err := s.cacheSvc.Get(ctx, cooldownKey, &lastSentAt); err == nil {
+		return customErr.NewCustomError(customErr.ErrTooManyRequests, "A code was sent recently, please wait before requesting another one")
+	} else if err != redis.Nil {
+		return fmt.Errorf("failed to get phone code cooldown: %s", err.Error())
+	}
+
+	if err := s.cacheSvc.Set(ctx, cooldownKey, time.Now().Unix(), s.otpResendInterval()); err != nil {
+		return fmt.Errorf("failed to set phone code cooldown: %s", err.Error())
+	}
+
+	return nil
+}
+
+// checkPhoneOTP returns the phone number the code under otpKey was sent to if it matches.
+// Every attempt counts towards the limit of the code and the daily limit of otpKey, which new codes do
+// not reset; the code is revoked once either is reached or the code is used.
+func (s *AuthService) checkPhoneOTP(ctx context.Context, otpKey string, code string) (string, error) {
+	var otp phoneOTP
+	if err := s.cacheSvc.Get(ctx, otpKey, &otp); err != nil {
+		if err == redis.Nil {
+			return "", customErr.NewCustomError(customErr.ErrInvalidRequest, "Invalid or expired code")
+		}
+
+		return "", fmt.Errorf("failed to get phone code: %s", err.Error())
+	}
+
+	dailyAttempts, err := s.cacheSvc.IncrWithExpiration(ctx, cacheutil.ConstructOTPDailyAttemptsKey(otpKey), otpDailyAttemptsWindow)
+	if err != nil {
+		return "", fmt.Errorf("failed to count daily phone code attempts: %s", err.Error())
+	}
+
+	if dailyAttempts > int64(s.otpDailyMaxAttempts()) {
+		s.revokePhoneOTP(ctx, otpKey)
+		return "", customErr.NewCustomError(customErr.ErrTooManyRequests, "Too many codes tried today, please try again tomorrow")
+	}
+
+	attemptsKey := cacheutil.ConstructOTPAttemptsKey(otpKey)
+	attempts, err := s.cacheSvc.IncrWithExpiration(ctx, attemptsKey, time.Until(otp.ExpiresAt))
+	if err != nil {
+		return "", fmt.Errorf("failed to count phone code attempts: %s", err.Error())
+	}
+
+	if attempts > int64(s.otpMaxAttempts()) {
+		s.revokePhoneOTP(ctx, otpKey)
+		return "", customErr.NewCustomError(customErr.ErrTooManyRequests, "Too many incorrect codes, please request a new one")
+	}
+
+	if !otputil.Equal(code, otp.Code) {
+		return "", customErr.NewCustomError(customErr.ErrInvalidRequest, "Invalid or expired code")
+	}
+
+	s.revokePhoneOTP(ctx, otpKey)
+	return otp.Phone, nil
+}
+
+func (s *AuthService) revokePhoneOTP(ctx context.Context, otpKey string) {
+	for _, key := range []string{otpKey, cacheutil.ConstructOTPAttemptsKey(otpKey)} {
+		if err := s.cacheSvc.Delete(ctx, key); err != nil {
+			s.logger.Errorf("key: %s, failed to delete phone code: %s", key, err.Error())
+		}
+	}
+}
+
+func (s *AuthService) checkPhoneAvailable(ctx context.Context, userID string, phone string) error {
+	owner, err := s.authRepository.GetByPhone(ctx, phone)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to get user by phone: %s", err.Error())
+	}
+
+	if owner != nil && owner.ID != userID {
+		return customErr.NewCustomError(customErr.ErrConflict, "Phone number is already in use")
+	}
+
+	return nil
+}
+
+func (s *AuthService) getUserByPhone(ctx context.Context, phone string) (*entity.User, error) {
+	user, err := s.authRepository.GetByPhone(ctx, phone)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customErr.NewCustomError(customErr.ErrNotFound, "User not found")
+		}
+
+		return nil, fmt.Errorf("failed to get user by phone: %s", err.Error())
+	}
+
+	return user, nil
+}
+
+func (s *AuthService) otpTTL() time.Duration {
+	if s.phoneConfig.OTPTTL <= 0 {
+		return DefaultOTPTTL
+	}
+
+	return s.phoneConfig.OTPTTL
+}
+
+func (s *AuthService) otpMaxAttempts() int {
+	if s.phoneConfig.OTPMaxAttempts <= 0 {
+		return DefaultOTPMaxAttempts
+	}
+
+	return s.phoneConfig.OTPMaxAttempts
+}
+
+func (s *AuthService) otpResendInterval() time.Duration {
+	if s.phoneConfig.OTPResendInterval <= 0 {
+		return DefaultOTPResendInterval
+	}
+
+	return s.phoneConfig.OTPResendInterval
+}
+
+func (s *AuthService) otpDailyMaxAttempts() int {
+	if s.phoneConfig.OTPDailyMaxAttempts <= 0 {
+		return DefaultOTPDailyMaxAttempts
+	}
+
+	return s.phoneConfig.OTPDailyMaxAttempts
+}
+
+// maskPhone keeps the last two digits of a phone number, enough to tell numbers apart in the audit log
+func maskPhone(phone string) string {
+	if len(phone) <= 2 {
+		return phone
+	}
+
+	return strings.Repeat("*", len(phone)-2) + phone[len(phone)-2:]
+}
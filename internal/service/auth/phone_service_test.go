@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/cacheutil"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/repository/audit"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type fakeAuthRepository struct {
+	IAuthRepository
+	users map[string]*entity.User
+	// anonymizeErrs fails the anonymization of the users by ID
+	anonymizeErrs map[string]error
+}
+
+func (r *fakeAuthRepository) GetById(ctx context.Context, id string) (*entity.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeAuthRepository) GetByPhone(ctx context.Context, phone string) (*entity.User, error) {
+	for _, user := range r.users {
+		if user.Phone != nil && *user.Phone == phone {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeAuthRepository) RevokeRefreshTokenById(ctx context.Context, id string) error {
+	r.users[id].RefreshToken = ""
+	return nil
+}
+
+func (r *fakeAuthRepository) SetPhoneById(ctx context.Context, id string, phone string, verifiedAt time.Time) error {
+	r.users[id].Phone = &phone
+	r.users[id].PhoneVerifiedAt = &verifiedAt
+	return nil
+}
+
+// fakeCache stores values as JSON the same way the redis cache does
+type fakeCache struct {
+	values map[string]string
+}
+
+func (c *fakeCache) Get(ctx context.Context, key string, obj any) error {
+	value, ok := c.values[key]
+	if !ok {
+		return redis.Nil
+	}
+	return json.Unmarshal([]byte(value), obj)
+}
+
+func (c *fakeCache) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	if str, ok := value.(string); ok {
+		c.values[key] = str
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.values[key] = string(data)
+	return nil
+}
+
+func (c *fakeCache) Delete(ctx context.Context, key string) error {
+	delete(c.values, key)
+	return nil
+}
+
+func (c *fakeCache) IncrWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	var count int64
+	if value, ok := c.values[key]; ok {
+		if err := json.Unmarshal([]byte(value), &count); err != nil {
+			return 0, err
+		}
+	}
+	count++
+	data, _ := json.Marshal(count)
+	c.values[key] = string(data)
+	return count, nil
+}
+
+type fakeAuditRepository struct {
+	IAuditRepository
+	events  []*entity.AuditEvent
+	filters []audit.AuditFilter
+}
+
+func (r *fakeAuditRepository) Append(ctx context.Context, event *entity.AuditEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+type sentSMS struct {
+	to   string
+	body string
+}
+
+type fakeSMSSender struct {
+	sent []sentSMS
+	err  error
+}
+
+func (s *fakeSMSSender) Send(ctx context.Context, to string, body string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, sentSMS{to: to, body: body})
+	return nil
+}
+
+var smsCodePattern = regexp.MustCompile(`^\d{6}`)
+
+func newPhoneTestService(users ...*entity.User) (*AuthService, *fakeSMSSender, *fakeAuditRepository) {
+	repo := &fakeAuthRepository{users: map[string]*entity.User{}}
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+	cache := &fakeCache{values: map[string]string{}}
+	sender := &fakeSMSSender{}
+	auditRepo := &fakeAuditRepository{}
+
+	s := New(
+		logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard}),
+		repo, nil, nil, cache, nil, nil, nil, nil, config.AccountConfig{},
+		nil, config.DataExportConfig{}, auditRepo,
+		nil, nil, config.LoginSecurityConfig{},
+		sender, config.PhoneConfig{OTPMaxAttempts: 3, OTPDailyMaxAttempts: 5},
+		config.IntrospectionConfig{},
+	)
+	return s, sender, auditRepo
+}
+
+func lastSMSCode(t *testing.T, sender *fakeSMSSender) string {
+	t.Helper()
+	if len(sender.sent) == 0 {
+		t.Fatal("no sms sent")
+	}
+	code := smsCodePattern.FindString(sender.sent[len(sender.sent)-1].body)
+	if code == "" {
+		t.Fatalf("no code in sms %q", sender.sent[len(sender.sent)-1].body)
+	}
+	return code
+}
+
+func assertErrorCode(t *testing.T, err error, code customErr.ErrorCode) {
+	t.Helper()
+	var customError *customErr.CustomError
+	if !errors.As(err, &customError) || customError.Code != code {
+		t.Fatalf("expected %s error, got %v", code, err)
+	}
+}
+
+func TestVerifyPhone(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: "user-1"}
+	s, sender, auditRepo := newPhoneTestService(user)
+
+	resp, err := s.RequestPhoneVerification(ctx, user.ID, &reqDto.PhoneNumberRequest{PhoneNumber: "+84901234567"})
+	if err != nil {
+		t.Fatalf("request verification: %v", err)
+	}
+	if resp.PhoneNumber != "+84901234567" || sender.sent[0].to != "+84901234567" {
+		t.Fatalf("unexpected response %+v, sent %+v", resp, sender.sent)
+	}
+
+	phoneResp, err := s.VerifyPhone(ctx, user.ID, &reqDto.VerifyPhoneRequest{Code: lastSMSCode(t, sender)})
+	if err != nil {
+		t.Fatalf("verify phone: %v", err)
+	}
+	if phoneResp.PhoneNumber != "+84901234567" || user.Phone == nil || *user.Phone != "+84901234567" {
+		t.Fatalf("phone not set, response %+v", phoneResp)
+	}
+	if len(auditRepo.events) != 1 || auditRepo.events[0].EventType != string(UserPhoneVerifiedEvent) {
+		t.Fatalf("unexpected audit events %+v", auditRepo.events)
+	}
+	if phone := auditRepo.events[0].Metadata["phone"]; phone != "**********67" {
+		t.Errorf("expected the audited phone number to be masked, got %v", phone)
+	}
+
+	// the code is single use
+	_, err = s.VerifyPhone(ctx, user.ID, &reqDto.VerifyPhoneRequest{Code: lastSMSCode(t, sender)})
+	assertErrorCode(t, err, customErr.ErrInvalidRequest)
+}
+
+func TestVerifyPhoneAttemptLimit(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: "user-1"}
+	s, sender, _ := newPhoneTestService(user)
+
+	if _, err := s.RequestPhoneVerification(ctx, user.ID, &reqDto.PhoneNumberRequest{PhoneNumber: "+84901234567"}); err != nil {
+		t.Fatalf("request verification: %v", err)
+	}
+	code := lastSMSCode(t, sender)
+	wrongCode := "000000"
+	if code == wrongCode {
+		wrongCode = "111111"
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := s.VerifyPhone(ctx, user.ID, &reqDto.VerifyPhoneRequest{Code: wrongCode})
+		assertErrorCode(t, err, customErr.ErrInvalidRequest)
+	}
+
+	_, err := s.VerifyPhone(ctx, user.ID, &reqDto.VerifyPhoneRequest{Code: code})
+	assertErrorCode(t, err, customErr.ErrTooManyRequests)
+
+	// the code was revoked once the limit was reached
+	_, err = s.VerifyPhone(ctx, user.ID, &reqDto.VerifyPhoneRequest{Code: code})
+	assertErrorCode(t, err, customErr.ErrInvalidRequest)
+}
+
+func TestRequestPhoneVerificationCooldown(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: "user-1"}
+	s, sender, _ := newPhoneTestService(user)
+	req := &reqDto.PhoneNumberRequest{PhoneNumber: "+84901234567"}
+
+	sender.err = errors.New("gateway down")
+	if _, err := s.RequestPhoneVerification(ctx, user.ID, req); err == nil {
+		t.Fatal("expected send failure")
+	}
+
+	// a failed send does not start the cooldown
+	sender.err = nil
+	if _, err := s.RequestPhoneVerification(ctx, user.ID, req); err != nil {
+		t.Fatalf("request verification: %v", err)
+	}
+
+	_, err := s.RequestPhoneVerification(ctx, user.ID, req)
+	assertErrorCode(t, err, customErr.ErrTooManyRequests)
+}
+
+func TestRequestPhoneVerificationConflict(t *testing.T) {
+	phone := "+84901234567"
+	owner := &entity.User{ID: "user-1", Phone: &phone}
+	user := &entity.User{ID: "user-2"}
+	s, sender, _ := newPhoneTestService(owner, user)
+
+	_, err := s.RequestPhoneVerification(context.Background(), user.ID, &reqDto.PhoneNumberRequest{PhoneNumber: phone})
+	assertErrorCode(t, err, customErr.ErrConflict)
+	if len(sender.sent) != 0 {
+		t.Fatalf("expected no sms, got %+v", sender.sent)
+	}
+}
+
+func TestRequestPhoneLoginUnknownNumber(t *testing.T) {
+	ctx := context.Background()
+	s, sender, _ := newPhoneTestService(&entity.User{ID: "user-1"})
+	req := &reqDto.PhoneNumberRequest{PhoneNumber: "+84901234567"}
+
+	resp, err := s.RequestPhoneLogin(ctx, req)
+	if err != nil {
+		t.Fatalf("expected unknown numbers to be answered like known ones, got %v", err)
+	}
+	if resp.PhoneNumber != req.PhoneNumber || !resp.ExpiresAt.After(time.Now()) {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if len(sender.sent) != 0 {
+		t.Fatalf("expected no sms, got %+v", sender.sent)
+	}
+
+	// the cooldown applies the same way as for a registered number
+	_, err = s.RequestPhoneLogin(ctx, req)
+	assertErrorCode(t, err, customErr.ErrTooManyRequests)
+}
+
+func TestLoginWithPhoneDailyAttemptLimit(t *testing.T) {
+	ctx := context.Background()
+	phone := "+84901234567"
+	s, sender, _ := newPhoneTestService(&entity.User{ID: "user-1", Phone: &phone})
+	cache := s.cacheSvc.(*fakeCache)
+	req := &reqDto.PhoneNumberRequest{PhoneNumber: phone}
+
+	requestCode := func() string {
+		t.Helper()
+		delete(cache.values, cacheutil.ConstructPhoneOTPCooldownKey(phone))
+		if _, err := s.RequestPhoneLogin(ctx, req); err != nil {
+			t.Fatalf("request login: %v", err)
+		}
+		return lastSMSCode(t, sender)
+	}
+	wrongCode := func(code string) string {
+		if code == "000000" {
+			return "111111"
+		}
+		return "000000"
+	}
+
+	// a new code resets the attempts at the code but not the daily attempts
+	code := requestCode()
+	for i := 0; i < 3; i++ {
+		_, err := s.LoginWithPhone(ctx, &reqDto.PhoneLoginRequest{PhoneNumber: phone, Code: wrongCode(code)})
+		assertErrorCode(t, err, customErr.ErrInvalidRequest)
+	}
+	code = requestCode()
+	for i := 0; i < 2; i++ {
+		_, err := s.LoginWithPhone(ctx, &reqDto.PhoneLoginRequest{PhoneNumber: phone, Code: wrongCode(code)})
+		assertErrorCode(t, err, customErr.ErrInvalidRequest)
+	}
+
+	code = requestCode()
+	_, err := s.LoginWithPhone(ctx, &reqDto.PhoneLoginRequest{PhoneNumber: phone, Code: code})
+	assertErrorCode(t, err, customErr.ErrTooManyRequests)
+
+	// the code was revoked once the limit was reached
+	_, err = s.LoginWithPhone(ctx, &reqDto.PhoneLoginRequest{PhoneNumber: phone, Code: code})
+	assertErrorCode(t, err, customErr.ErrInvalidRequest)
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/cacheutil"
+	tokensvc "github.com/datpham/user-service-ms/internal/service/token"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultIntrospectionCacheTTL = time.Second * 30
+
+	// token types of introspection responses, as registered for RFC 7662 token_type_hint
+	IntrospectionTokenTypeAccess  = "access_token"
+	IntrospectionTokenTypeRefresh = "refresh_token"
+)
+
+// IntrospectToken reports whether a token is active and who it was issued to, for other services.
+// A token is active when its signature and expiry are valid, it was not revoked and its user is
+// neither suspended nor deleted. A refresh token is only active until it is rotated.
+func (s *AuthService) IntrospectToken(
+	ctx context.Context,
+	req *reqDto.IntrospectTokenRequest,
+) (*respDto.TokenIntrospectionResponse, error) {
+	return s.introspectToken(ctx, req.Token)
+}
+
+// ValidateAccessToken introspects a token that must be an access token
+func (s *AuthService) ValidateAccessToken(
+	ctx context.Context,
+	accessToken string,
+) (*respDto.TokenIntrospectionResponse, error) {
+	resp, err := s.introspectToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.TokenType == IntrospectionTokenTypeRefresh {
+		return &respDto.TokenIntrospectionResponse{Active: false}, nil
+	}
+
+	return resp, nil
+}
+
+// ValidateToken returns the user ID of an active access token, it authenticates the requests to this service
+// with the checks of introspection: logged out tokens and the tokens of suspended or deleted users are rejected
+func (s *AuthService) ValidateToken(ctx context.Context, accessToken string) (string, error) {
+	resp, err := s.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		return "", err
+	}
+	if !resp.Active {
+		return "", errors.New("inactive access token")
+	}
+
+	return resp.Subject, nil
+}
+
+// Logout revokes the access token and the refresh token of the user it was issued to
+func (s *AuthService) Logout(ctx context.Context, accessToken string) (err error) {
+	var userID string
+	defer func() {
+		s.recordAuditEvent(ctx, UserLogoutEvent, userID, err, nil)
+	}()
+
+	claims, err := s.jwtTokenSvc.ParseToken(accessToken)
+	if err != nil || claims.TokenType == tokensvc.TokenTypeRefresh {
+		return customErr.NewCustomError(customErr.ErrUnauthorized, "Invalid access token")
+	}
+	userID = claims.UserID
+
+	if ttl := time.Until(claims.ExpiresAt); ttl > 0 {
+		denylistKey := cacheutil.ConstructTokenDenylistKey(claims.TokenID)
+		if err := s.cacheSvc.Set(ctx, denylistKey, time.Now().Unix(), ttl); err != nil {
+			return fmt.Errorf("failed to revoke access token: %s", err.Error())
+		}
+	}
+
+	if err := s.cacheSvc.Delete(ctx, cacheutil.ConstructTokenIntrospectionKey(accessToken)); err != nil {
+		return fmt.Errorf("failed to delete cached introspection: %s", err.Error())
+	}
+
+	if err := s.authRepository.RevokeRefreshTokenById(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %s", err.Error())
+	}
+
+	return nil
+}
+
+// introspectToken serves results of validly signed tokens from the cache for a short time,
+// so a revocation or suspension reaches other services within the cache ttl
+func (s *AuthService) introspectToken(ctx context.Context, token string) (*respDto.TokenIntrospectionResponse, error) {
+	claims, err := s.jwtTokenSvc.ParseToken(token)
+	if err != nil {
+		return &respDto.TokenIntrospectionResponse{Active: false}, nil
+	}
+
+	cacheKey := cacheutil.ConstructTokenIntrospectionKey(token)
+	var cached respDto.TokenIntrospectionResponse
+	if err := s.cacheSvc.Get(ctx, cacheKey, &cached); err == nil {
+		return &cached, nil
+	} else if err != redis.Nil {
+		s.logger.Errorf("userId: %s, failed to get cached introspection: %s", claims.UserID, err.Error())
+	}
+
+	resp, err := s.introspectClaims(ctx, token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := min(s.introspectionCacheTTL(), time.Until(claims.ExpiresAt))
+	if ttl > 0 {
+		respJSON, err := json.Marshal(resp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal introspection: %s", err.Error())
+		}
+
+		if err := s.cacheSvc.Set(ctx, cacheKey, string(respJSON), ttl); err != nil {
+			s.logger.Errorf("userId: %s, failed to cache introspection: %s", claims.UserID, err.Error())
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *AuthService) introspectClaims(
+	ctx context.Context,
+	token string,
+	claims *tokensvc.TokenClaims,
+) (*respDto.TokenIntrospectionResponse, error) {
+	var revokedAt int64
+	err := s.cacheSvc.Get(ctx, cacheutil.ConstructTokenDenylistKey(claims.TokenID), &revokedAt)
+	if err == nil {
+		return &respDto.TokenIntrospectionResponse{Active: false}, nil
+	}
+	if err != redis.Nil {
+		return nil, fmt.Errorf("failed to get token denylist: %s", err.Error())
+	}
+
+	user, err := s.authRepository.GetById(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &respDto.TokenIntrospectionResponse{Active: false}, nil
+		}
+
+		return nil, fmt.Errorf("failed to get user by id: %s", err.Error())
+	}
+
+	if user.SuspendedAt != nil {
+		return &respDto.TokenIntrospectionResponse{Active: false}, nil
+	}
+
+	if claims.TokenType == tokensvc.TokenTypeRefresh && user.RefreshToken != token {
+		return &respDto.TokenIntrospectionResponse{Active: false}, nil
+	}
+
+	return s.mapToTokenIntrospectionResponse(claims, user), nil
+}
+
+func (s *AuthService) introspectionCacheTTL() time.Duration {
+	if s.introspectionConfig.CacheTTL <= 0 {
+		return DefaultIntrospectionCacheTTL
+	}
+
+	return s.introspectionConfig.CacheTTL
+}
+
+func introspectionTokenType(tokenType string) string {
+	switch tokenType {
+	case tokensvc.TokenTypeAccess:
+		return IntrospectionTokenTypeAccess
+	case tokensvc.TokenTypeRefresh:
+		return IntrospectionTokenTypeRefresh
+	default:
+		return ""
+	}
+}
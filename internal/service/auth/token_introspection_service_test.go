@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/cacheutil"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	tokensvc "github.com/datpham/user-service-ms/internal/service/token"
+	"github.com/sirupsen/logrus"
+)
+
+func newIntrospectionTestService(t *testing.T, user *entity.User) (*AuthService, *fakeCache, string, string) {
+	t.Helper()
+
+	jwtToken := tokensvc.NewJwtToken("secret")
+	accessToken, refreshToken, err := jwtToken.GenerateTokenPair(user.ID)
+	if err != nil {
+		t.Fatalf("generate tokens: %v", err)
+	}
+	user.RefreshToken = refreshToken
+
+	repo := &fakeAuthRepository{users: map[string]*entity.User{user.ID: user}}
+	cache := &fakeCache{values: map[string]string{}}
+
+	s := New(
+		logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard}),
+		repo, jwtToken, nil, cache, nil, nil, nil, nil, config.AccountConfig{},
+		nil, config.DataExportConfig{}, &fakeAuditRepository{},
+		nil, nil, config.LoginSecurityConfig{},
+		&fakeSMSSender{}, config.PhoneConfig{},
+		config.IntrospectionConfig{},
+	)
+	return s, cache, accessToken, refreshToken
+}
+
+func TestIntrospectToken(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: "user-1", Email: "user@example.com", Role: entity.UserRoleAdmin}
+	s, cache, accessToken, refreshToken := newIntrospectionTestService(t, user)
+
+	resp, err := s.IntrospectToken(ctx, &reqDto.IntrospectTokenRequest{Token: accessToken})
+	if err != nil {
+		t.Fatalf("introspect: %v", err)
+	}
+	if !resp.Active || resp.Subject != "user-1" || resp.TokenType != IntrospectionTokenTypeAccess ||
+		resp.Email != "user@example.com" || resp.Role != entity.UserRoleAdmin || resp.TokenID == "" {
+		t.Fatalf("unexpected introspection %+v", resp)
+	}
+	if time.Until(time.Unix(resp.ExpiresAt, 0)) <= 0 || resp.IssuedAt == 0 {
+		t.Fatalf("unexpected token times %+v", resp)
+	}
+	if _, ok := cache.values[cacheutil.ConstructTokenIntrospectionKey(accessToken)]; !ok {
+		t.Fatal("expected introspection to be cached")
+	}
+
+	resp, err = s.IntrospectToken(ctx, &reqDto.IntrospectTokenRequest{Token: refreshToken})
+	if err != nil {
+		t.Fatalf("introspect refresh token: %v", err)
+	}
+	if !resp.Active || resp.TokenType != IntrospectionTokenTypeRefresh {
+		t.Fatalf("unexpected refresh token introspection %+v", resp)
+	}
+
+	for _, token := range []string{"not-a-token", accessToken + "x"} {
+		resp, err = s.IntrospectToken(ctx, &reqDto.IntrospectTokenRequest{Token: token})
+		if err != nil {
+			t.Fatalf("introspect: %v", err)
+		}
+		if resp.Active || resp.Subject != "" {
+			t.Fatalf("expected inactive token, got %+v", resp)
+		}
+	}
+}
+
+func TestIntrospectTokenSuspendedUser(t *testing.T) {
+	ctx := context.Background()
+	suspendedAt := time.Now()
+	user := &entity.User{ID: "user-1", SuspendedAt: &suspendedAt}
+	s, _, accessToken, _ := newIntrospectionTestService(t, user)
+
+	resp, err := s.IntrospectToken(ctx, &reqDto.IntrospectTokenRequest{Token: accessToken})
+	if err != nil {
+		t.Fatalf("introspect: %v", err)
+	}
+	if resp.Active {
+		t.Fatalf("expected inactive token for suspended user, got %+v", resp)
+	}
+}
+
+func TestValidateAccessTokenRejectsRefreshToken(t *testing.T) {
+	s, _, _, refreshToken := newIntrospectionTestService(t, &entity.User{ID: "user-1"})
+
+	resp, err := s.ValidateAccessToken(context.Background(), refreshToken)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if resp.Active {
+		t.Fatalf("expected refresh token to be rejected, got %+v", resp)
+	}
+}
+
+func TestLogoutRevokesTokens(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: "user-1"}
+	s, _, accessToken, refreshToken := newIntrospectionTestService(t, user)
+
+	// cache an active result that logging out must invalidate
+	if resp, err := s.ValidateAccessToken(ctx, accessToken); err != nil || !resp.Active {
+		t.Fatalf("expected active token before logout, got %+v, %v", resp, err)
+	}
+
+	if err := s.Logout(ctx, accessToken); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+
+	for _, token := range []string{accessToken, refreshToken} {
+		resp, err := s.IntrospectToken(ctx, &reqDto.IntrospectTokenRequest{Token: token})
+		if err != nil {
+			t.Fatalf("introspect: %v", err)
+		}
+		if resp.Active {
+			t.Fatalf("expected revoked token, got %+v", resp)
+		}
+	}
+
+	err := s.Logout(ctx, "not-a-token")
+	assertErrorCode(t, err, customErr.ErrUnauthorized)
+}
+
+func TestValidateTokenChecksRevocationAndUser(t *testing.T) {
+	ctx := context.Background()
+	deletedAt := time.Now()
+
+	tests := map[string]struct {
+		prepare func(s *AuthService, repo *fakeAuthRepository, accessToken string)
+		refresh bool
+		active  bool
+	}{
+		"active": {
+			active: true,
+		},
+		"refresh token": {
+			refresh: true,
+		},
+		"logged out": {
+			prepare: func(s *AuthService, repo *fakeAuthRepository, accessToken string) {
+				if err := s.Logout(ctx, accessToken); err != nil {
+					t.Fatalf("logout: %v", err)
+				}
+			},
+		},
+		"suspended user": {
+			prepare: func(s *AuthService, repo *fakeAuthRepository, accessToken string) {
+				repo.users["user-1"].SuspendedAt = &deletedAt
+			},
+		},
+		"deleted user": {
+			prepare: func(s *AuthService, repo *fakeAuthRepository, accessToken string) {
+				delete(repo.users, "user-1")
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, _, accessToken, refreshToken := newIntrospectionTestService(t, &entity.User{ID: "user-1"})
+			if tt.prepare != nil {
+				tt.prepare(s, s.authRepository.(*fakeAuthRepository), accessToken)
+			}
+
+			token := accessToken
+			if tt.refresh {
+				token = refreshToken
+			}
+
+			userID, err := s.ValidateToken(ctx, token)
+			if tt.active && (err != nil || userID != "user-1") {
+				t.Fatalf("expected user-1, got %q, %v", userID, err)
+			}
+			if !tt.active && err == nil {
+				t.Fatalf("expected the token to be rejected, got %q", userID)
+			}
+		})
+	}
+}
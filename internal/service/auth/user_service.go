@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"gorm.io/gorm"
+)
+
+// GetUser returns the profile of a user for other services, deleted users are not found
+func (s *AuthService) GetUser(ctx context.Context, userID string) (*respDto.UserResponse, error) {
+	user, err := s.authRepository.GetById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customErr.NewCustomError(customErr.ErrNotFound, "User not found")
+		}
+
+		return nil, fmt.Errorf("failed to get user by id: %s", err.Error())
+	}
+
+	return s.mapToUserResponse(user), nil
+}
+
+// BatchGetUsers returns the profiles of the users in the order of the requested IDs,
+// the IDs of unknown and deleted users are listed separately
+func (s *AuthService) BatchGetUsers(
+	ctx context.Context,
+	req *reqDto.BatchGetUsersRequest,
+) (*respDto.BatchUsersResponse, error) {
+	users, err := s.authRepository.GetByIds(ctx, req.UserIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users by ids: %s", err.Error())
+	}
+
+	usersByID := make(map[string]*respDto.UserResponse, len(users))
+	for i := range users {
+		usersByID[users[i].ID] = s.mapToUserResponse(&users[i])
+	}
+
+	resp := &respDto.BatchUsersResponse{
+		Users:       make([]*respDto.UserResponse, 0, len(users)),
+		NotFoundIDs: []string{},
+	}
+	seen := make(map[string]bool, len(req.UserIDs))
+	for _, id := range req.UserIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if user, ok := usersByID[id]; ok {
+			resp.Users = append(resp.Users, user)
+		} else {
+			resp.NotFoundIDs = append(resp.NotFoundIDs, id)
+		}
+	}
+
+	return resp, nil
+}
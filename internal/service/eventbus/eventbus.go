@@ -0,0 +1,79 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+const (
+	DriverRabbitMQ = "rabbitmq"
+	DriverKafka    = "kafka"
+	DriverNats     = "nats"
+	DriverMemory   = "memory"
+)
+
+// ErrPoisonMessage marks messages that can never be processed, handlers wrap it to skip the retries
+// and move the message to the dead letter destination of the broker right away
+var ErrPoisonMessage = errors.New("poison message")
+
+// Message is a message received from the broker
+type Message struct {
+	ID string
+	// Topic is the routing key the message was published with
+	Topic   string
+	Body    []byte
+	Headers map[string]string
+	// Attempt counts deliveries of the message to this service, starting at 1
+	Attempt int
+}
+
+// Handler processes a message, the message is retried when it returns an error
+type Handler func(ctx context.Context, message Message) error
+
+// EventPublisher publishes events to the broker under a routing key such as user.account.deleted
+type EventPublisher interface {
+	// Publish returns once the broker durably accepted the message, an error means it may not have been published
+	Publish(ctx context.Context, topic string, body []byte) error
+}
+
+// EventSubscriber delivers events published by other services to the registered handlers
+type EventSubscriber interface {
+	// Subscribe registers the handler for a topic pattern where * matches one word and # zero or more.
+	// Handlers must be registered before Start, the first matching registration wins.
+	Subscribe(topic string, handler Handler)
+	// Start delivers messages until ctx is cancelled and the in-flight messages were handled
+	Start(ctx context.Context) error
+}
+
+// EventBus is a broker connection used to both publish and subscribe
+type EventBus interface {
+	EventPublisher
+	EventSubscriber
+	Close() error
+}
+
+// MatchTopic matches a topic against a pattern where * is one word and # is zero or more
+func MatchTopic(pattern, topic string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+func matchWords(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchWords(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && matchWords(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && matchWords(pattern[1:], words[1:])
+	}
+}
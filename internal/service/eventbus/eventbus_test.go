@@ -0,0 +1,60 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"mail.bounced", "mail.bounced", true},
+		{"mail.bounced", "mail.complained", false},
+		{"mail.*", "mail.bounced", true},
+		{"mail.*", "mail.bounced.hard", false},
+		{"billing.#", "billing", true},
+		{"billing.#", "billing.account.closed", true},
+		{"#.closed", "billing.account.closed", true},
+		{"#", "anything.at.all", true},
+		{"*.account.*", "billing.account.closed", true},
+		{"*.account.*", "billing.user.closed", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchTopic(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestRouterDispatch(t *testing.T) {
+	var router Router
+	var handled []string
+	router.Subscribe("mail.bounced", func(ctx context.Context, message Message) error {
+		handled = append(handled, "bounced")
+		return nil
+	})
+	router.Subscribe("mail.#", func(ctx context.Context, message Message) error {
+		panic("handler bug")
+	})
+
+	ctx := context.Background()
+	if pattern, err := router.Dispatch(ctx, Message{Topic: "mail.bounced"}); err != nil || pattern != "mail.bounced" {
+		t.Errorf("unexpected dispatch result %q, %v", pattern, err)
+	}
+	if len(handled) != 1 {
+		t.Errorf("expected the first matching handler to run once, got %v", handled)
+	}
+
+	if _, err := router.Dispatch(ctx, Message{Topic: "mail.complained"}); err == nil || errors.Is(err, ErrPoisonMessage) {
+		t.Errorf("expected a retryable error from a panicking handler, got %v", err)
+	}
+
+	if pattern, err := router.Dispatch(ctx, Message{Topic: "billing.account_closed"}); !errors.Is(err, ErrPoisonMessage) || pattern != UnhandledTopic {
+		t.Errorf("expected unhandled messages to be poison, got %q, %v", pattern, err)
+	}
+}
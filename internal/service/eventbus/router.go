@@ -0,0 +1,52 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+)
+
+// UnhandledTopic is the label reported for messages no handler was registered for
+const UnhandledTopic = "unhandled"
+
+type route struct {
+	pattern string
+	handler Handler
+}
+
+// Router dispatches messages to the handler registered for their topic, brokers without
+// server-side routing embed it to implement Subscribe
+type Router struct {
+	routes []route
+}
+
+func (r *Router) Subscribe(topic string, handler Handler) {
+	r.routes = append(r.routes, route{pattern: topic, handler: handler})
+}
+
+// Patterns returns the registered topic patterns in registration order
+func (r *Router) Patterns() []string {
+	patterns := make([]string, 0, len(r.routes))
+	for _, route := range r.routes {
+		patterns = append(patterns, route.pattern)
+	}
+
+	return patterns
+}
+
+// Dispatch runs the handler of the message and returns the pattern it matched. Messages without a
+// handler are poison, a panicking handler is reported as an error so the message is retried.
+func (r *Router) Dispatch(ctx context.Context, message Message) (pattern string, err error) {
+	for _, route := range r.routes {
+		if MatchTopic(route.pattern, message.Topic) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					err = fmt.Errorf("handler panicked: %v", recovered)
+				}
+			}()
+
+			return route.pattern, route.handler(ctx, message)
+		}
+	}
+
+	return UnhandledTopic, fmt.Errorf("%w: no handler for topic %s", ErrPoisonMessage, message.Topic)
+}
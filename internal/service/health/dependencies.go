@@ -0,0 +1,8 @@
+package health
+
+import "context"
+
+// IPinger is a dependency the service needs to serve requests, e.g. database.Database or cache.Cache
+type IPinger interface {
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,135 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+)
+
+const (
+	DefaultCheckTimeout  = time.Second * 2
+	DefaultCheckInterval = time.Second * 10
+
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	// the reasons a dependency is down, the errors themselves are only logged
+	ReasonTimeout     = "timeout"
+	ReasonUnavailable = "unavailable"
+)
+
+// HealthService reports whether the process is alive and whether its dependencies are reachable.
+// Dependencies are checked concurrently, each within its own timeout, so one hanging dependency
+// does not hide the state of the others.
+type HealthService struct {
+	logger       *logger.Logger
+	dependencies map[string]IPinger
+	config       config.HealthConfig
+}
+
+func New(logger *logger.Logger, cfg config.HealthConfig, dependencies map[string]IPinger) *HealthService {
+	if cfg.CheckTimeout <= 0 {
+		cfg.CheckTimeout = DefaultCheckTimeout
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = DefaultCheckInterval
+	}
+
+	return &HealthService{
+		logger:       logger,
+		dependencies: dependencies,
+		config:       cfg,
+	}
+}
+
+// CheckInterval is how often the readiness of long lived listeners, e.g. the gRPC health service, is refreshed
+func (s *HealthService) CheckInterval() time.Duration {
+	return s.config.CheckInterval
+}
+
+// Liveness reports the process is up, it never checks dependencies so a broken dependency does not get
+// the process restarted
+func (s *HealthService) Liveness() *respDto.HealthResponse {
+	return &respDto.HealthResponse{Status: StatusUp}
+}
+
+// Readiness checks every dependency and is up only when all of them are
+func (s *HealthService) Readiness(ctx context.Context) *respDto.HealthResponse {
+	resp := &respDto.HealthResponse{
+		Status: StatusUp,
+		Checks: make(map[string]*respDto.DependencyHealthResponse, len(s.dependencies)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, dependency := range s.dependencies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			check := s.check(ctx, name, dependency)
+
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Checks[name] = check
+			if check.Status != StatusUp {
+				resp.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+
+	return resp
+}
+
+func (s *HealthService) check(ctx context.Context, name string, dependency IPinger) *respDto.DependencyHealthResponse {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout(name))
+	defer cancel()
+
+	start := time.Now()
+	err := ping(ctx, dependency)
+	check := &respDto.DependencyHealthResponse{
+		Status:     StatusUp,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		s.logger.Warnf("health check of %s failed: %s", name, err.Error())
+		check.Status = StatusDown
+		check.Reason = ReasonUnavailable
+		if errors.Is(err, context.DeadlineExceeded) {
+			check.Reason = ReasonTimeout
+		}
+	}
+
+	return check
+}
+
+// ping returns once the context is done even if the dependency ignores it
+func ping(ctx context.Context, dependency IPinger) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- dependency.Ping(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *HealthService) timeout(name string) time.Duration {
+	if timeout, ok := s.config.Timeouts[name]; ok && timeout > 0 {
+		return timeout
+	}
+
+	return s.config.CheckTimeout
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/sirupsen/logrus"
+)
+
+type fakePinger struct {
+	err   error
+	delay time.Duration
+}
+
+func (p *fakePinger) Ping(ctx context.Context) error {
+	if p.delay > 0 {
+		// ignores the context like a driver without cancellation support
+		time.Sleep(p.delay)
+	}
+	return p.err
+}
+
+func newTestService(cfg config.HealthConfig, dependencies map[string]IPinger) *HealthService {
+	return New(logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard}), cfg, dependencies)
+}
+
+func TestReadinessAllUp(t *testing.T) {
+	s := newTestService(config.HealthConfig{}, map[string]IPinger{
+		"database": &fakePinger{},
+		"cache":    &fakePinger{},
+	})
+
+	resp := s.Readiness(context.Background())
+	if resp.Status != StatusUp || len(resp.Checks) != 2 {
+		t.Fatalf("unexpected readiness %+v", resp)
+	}
+	for name, check := range resp.Checks {
+		if check.Status != StatusUp || check.Reason != "" {
+			t.Fatalf("unexpected check of %s: %+v", name, check)
+		}
+	}
+}
+
+func TestReadinessReportsFailedDependency(t *testing.T) {
+	s := newTestService(config.HealthConfig{}, map[string]IPinger{
+		"database": &fakePinger{},
+		"cache":    &fakePinger{err: errors.New("connection refused")},
+	})
+
+	resp := s.Readiness(context.Background())
+	if resp.Status != StatusDown {
+		t.Fatalf("expected down, got %+v", resp)
+	}
+	if resp.Checks["database"].Status != StatusUp {
+		t.Fatalf("database should be up, got %+v", resp.Checks["database"])
+	}
+	if check := resp.Checks["cache"]; check.Status != StatusDown || check.Reason != ReasonUnavailable {
+		t.Fatalf("unexpected cache check %+v", check)
+	}
+}
+
+func TestReadinessTimesOutPerDependency(t *testing.T) {
+	s := newTestService(
+		config.HealthConfig{
+			CheckTimeout: time.Second,
+			Timeouts:     map[string]time.Duration{"rabbitmq": time.Millisecond * 20},
+		},
+		map[string]IPinger{
+			"database": &fakePinger{delay: time.Millisecond * 50},
+			"rabbitmq": &fakePinger{delay: time.Second * 5},
+		},
+	)
+
+	start := time.Now()
+	resp := s.Readiness(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Fatalf("readiness waited for the hanging dependency, took %s", elapsed)
+	}
+	if resp.Checks["database"].Status != StatusUp {
+		t.Fatalf("database should be up within its timeout, got %+v", resp.Checks["database"])
+	}
+	if check := resp.Checks["rabbitmq"]; check.Status != StatusDown || check.Reason != ReasonTimeout {
+		t.Fatalf("unexpected rabbitmq check %+v", check)
+	}
+}
+
+func TestLivenessIgnoresDependencies(t *testing.T) {
+	s := newTestService(config.HealthConfig{}, map[string]IPinger{
+		"database": &fakePinger{err: errors.New("down")},
+	})
+
+	if resp := s.Liveness(); resp.Status != StatusUp || resp.Checks != nil {
+		t.Fatalf("unexpected liveness %+v", resp)
+	}
+}
@@ -0,0 +1,15 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+)
+
+type IUserRepository interface {
+	GetById(ctx context.Context, id string) (*entity.User, error)
+}
+
+type IMailer interface {
+	Send(ctx context.Context, message *Message) error
+}
@@ -0,0 +1,177 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/datpham/user-service-ms/config"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/metrics"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLocale  = "en"
+	DefaultAppName = "User Service"
+
+	// there is no verification email, the service has no email verification flow to send one for
+	TemplatePasswordReset   = "password_reset"
+	TemplateNewDeviceLogin  = "new_device_login"
+	TemplateSuspiciousLogin = "suspicious_login"
+	TemplateDataExportReady = "data_export_ready"
+
+	notificationOutcomeSent    = "sent"
+	notificationOutcomeSkipped = "skipped"
+	notificationOutcomeFailed  = "failed"
+)
+
+// Message is an email ready to be sent
+type Message struct {
+	From string
+	To   []string
+	Content
+}
+
+// Notification asks for the template to be sent to the recipient, in the locale of the user
+type Notification struct {
+	Template  string
+	UserID    string
+	Recipient string
+	// Payload is exposed to the template as .Payload
+	Payload any
+}
+
+// TemplateData is the data every email template is rendered with
+type TemplateData struct {
+	AppName string
+	Locale  string
+	Email   string
+	Payload any
+}
+
+// NotifierService renders transactional emails from the templates of the user's locale and sends them
+type NotifierService struct {
+	logger         *logger.Logger
+	renderer       *Renderer
+	mailer         IMailer
+	userRepository IUserRepository
+	config         config.NotifierConfig
+}
+
+func New(
+	logger *logger.Logger,
+	renderer *Renderer,
+	mailer IMailer,
+	userRepository IUserRepository,
+	cfg config.NotifierConfig,
+) *NotifierService {
+	if cfg.AppName == "" {
+		cfg.AppName = DefaultAppName
+	}
+
+	return &NotifierService{
+		logger:         logger,
+		renderer:       renderer,
+		mailer:         mailer,
+		userRepository: userRepository,
+		config:         cfg,
+	}
+}
+
+// Notify sends the notification unless its template is disabled or the user's email is known to bounce
+func (s *NotifierService) Notify(ctx context.Context, notification *Notification) error {
+	if s.config.Templates[notification.Template].Disabled {
+		metrics.NotificationEmailsTotal.WithLabelValues(notification.Template, notificationOutcomeSkipped).Inc()
+		return nil
+	}
+
+	if notification.Recipient == "" {
+		return customErr.NewCustomError(customErr.ErrInvalidRequest, "Notification has no recipient")
+	}
+
+	var locale string
+	if notification.UserID != "" {
+		user, err := s.userRepository.GetById(ctx, notification.UserID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get user: %s", err.Error())
+		}
+
+		if user != nil {
+			if user.EmailUndeliverableAt != nil {
+				s.logger.Infof("userId: %s, not sending %s email to an undeliverable address", user.ID, notification.Template)
+				metrics.NotificationEmailsTotal.WithLabelValues(notification.Template, notificationOutcomeSkipped).Inc()
+				return nil
+			}
+			locale = user.Locale
+		}
+	}
+
+	content, err := s.render(notification.Template, locale, notification.Recipient, notification.Payload)
+	if err != nil {
+		return err
+	}
+
+	from := s.config.Templates[notification.Template].From
+	if from == "" {
+		from = s.config.From
+	}
+
+	if err := s.mailer.Send(ctx, &Message{From: from, To: []string{notification.Recipient}, Content: *content}); err != nil {
+		metrics.NotificationEmailsTotal.WithLabelValues(notification.Template, notificationOutcomeFailed).Inc()
+		return fmt.Errorf("failed to send %s email: %s", notification.Template, err.Error())
+	}
+
+	metrics.NotificationEmailsTotal.WithLabelValues(notification.Template, notificationOutcomeSent).Inc()
+	return nil
+}
+
+// ListTemplates returns the email templates and the locales they can be previewed in
+func (s *NotifierService) ListTemplates() []*respDto.EmailTemplateResponse {
+	templates := s.renderer.Templates()
+	resp := make([]*respDto.EmailTemplateResponse, 0, len(templates))
+	for _, name := range templates {
+		resp = append(resp, &respDto.EmailTemplateResponse{
+			Name:     name,
+			Locales:  s.renderer.Locales(),
+			Disabled: s.config.Templates[name].Disabled,
+		})
+	}
+
+	return resp
+}
+
+// PreviewTemplate renders the template with sample data
+func (s *NotifierService) PreviewTemplate(name string, locale string) (*respDto.EmailPreviewResponse, error) {
+	sample, ok := samples[name]
+	if !ok {
+		return nil, customErr.NewCustomError(customErr.ErrNotFound, "Email template not found")
+	}
+
+	locale = s.renderer.ResolveLocale(locale)
+	content, err := s.render(name, locale, sampleRecipient, sample)
+	if err != nil {
+		return nil, err
+	}
+
+	return &respDto.EmailPreviewResponse{
+		Template: name,
+		Locale:   locale,
+		Subject:  content.Subject,
+		Text:     content.Text,
+		HTML:     content.HTML,
+	}, nil
+}
+
+func (s *NotifierService) render(name string, locale string, recipient string, payload any) (*Content, error) {
+	locale = s.renderer.ResolveLocale(locale)
+
+	return s.renderer.Render(name, locale, &TemplateData{
+		AppName: s.config.AppName,
+		Locale:  locale,
+		Email:   recipient,
+		Payload: payload,
+	})
+}
@@ -0,0 +1,218 @@
+package notifier
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type fakeUserRepository struct {
+	users map[string]*entity.User
+}
+
+func (r *fakeUserRepository) GetById(ctx context.Context, id string) (*entity.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+type fakeMailer struct {
+	sent []*Message
+}
+
+func (m *fakeMailer) Send(ctx context.Context, message *Message) error {
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+func newTestRenderer(t *testing.T) *Renderer {
+	t.Helper()
+
+	renderer, err := NewRenderer(TemplateFS(""), DefaultLocale)
+	if err != nil {
+		t.Fatalf("failed to load templates: %v", err)
+	}
+	return renderer
+}
+
+func newTestNotifier(t *testing.T, cfg config.NotifierConfig, users ...*entity.User) (*NotifierService, *fakeMailer) {
+	t.Helper()
+
+	repository := &fakeUserRepository{users: map[string]*entity.User{}}
+	for _, user := range users {
+		repository.users[user.ID] = user
+	}
+	mailer := &fakeMailer{}
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+
+	return New(testLogger, newTestRenderer(t), mailer, repository, cfg), mailer
+}
+
+func TestEmbeddedTemplatesRenderInEveryLocale(t *testing.T) {
+	svc, _ := newTestNotifier(t, config.NotifierConfig{})
+
+	templates := svc.ListTemplates()
+	if len(templates) != len(samples) {
+		t.Fatalf("expected a sample for each of the %d templates, got %d samples", len(templates), len(samples))
+	}
+
+	for _, template := range templates {
+		for _, locale := range template.Locales {
+			preview, err := svc.PreviewTemplate(template.Name, locale)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, template.Name, err)
+			}
+			if preview.Locale != locale {
+				t.Errorf("%s/%s: rendered in %s", locale, template.Name, preview.Locale)
+			}
+			if preview.Subject == "" || strings.Contains(preview.Subject, "\n") {
+				t.Errorf("%s/%s: unexpected subject %q", locale, template.Name, preview.Subject)
+			}
+			for _, body := range []string{preview.Text, preview.HTML} {
+				if strings.Contains(body, "<no value>") || !strings.Contains(body, DefaultAppName) {
+					t.Errorf("%s/%s: body was not rendered with the sample data:\n%s", locale, template.Name, body)
+				}
+			}
+			if !strings.Contains(preview.HTML, `<html lang="`+locale+`">`) {
+				t.Errorf("%s/%s: html body is not wrapped in the layout", locale, template.Name)
+			}
+		}
+	}
+}
+
+func TestRendererFallsBackToClosestLocale(t *testing.T) {
+	renderer := newTestRenderer(t)
+
+	for requested, want := range map[string]string{
+		"vi":    "vi",
+		"vi-VN": "vi",
+		"vi_VN": "vi",
+		"fr-FR": "en",
+		"":      "en",
+	} {
+		if got := renderer.ResolveLocale(requested); got != want {
+			t.Errorf("locale %q resolved to %q, want %q", requested, got, want)
+		}
+	}
+}
+
+func TestRendererEscapesHTML(t *testing.T) {
+	renderer := newTestRenderer(t)
+
+	content, err := renderer.Render(TemplateNewDeviceLogin, "en", &TemplateData{
+		AppName: "Test",
+		Locale:  "en",
+		Payload: &event.LoginDevicePayload{UserAgent: "<script>alert(1)</script>", IP: "203.0.113.1"},
+	})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	if strings.Contains(content.HTML, "<script>") {
+		t.Error("html body contains an unescaped user agent")
+	}
+	if !strings.Contains(content.Text, "<script>alert(1)</script>") {
+		t.Error("text body escaped the user agent")
+	}
+}
+
+func TestNewRendererRejectsTemplateWithoutSubject(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.html":     {Data: []byte(`{{define "layout"}}{{template "content" .}}{{end}}`)},
+		"en/welcome.txt":  {Data: []byte(`{{define "subject"}}Welcome{{end}}Hello`)},
+		"en/welcome.html": {Data: []byte(`{{define "content"}}Hello{{end}}{{template "layout" .}}`)},
+		"de/welcome.txt":  {Data: []byte(`Hallo`)},
+		"de/welcome.html": {Data: []byte(`{{define "content"}}Hallo{{end}}{{template "layout" .}}`)},
+	}
+
+	_, err := NewRenderer(fsys, "en")
+	if err == nil || !strings.Contains(err.Error(), "does not define a subject") {
+		t.Fatalf("expected a missing subject error, got %v", err)
+	}
+}
+
+func TestNotifySendsInUserLocale(t *testing.T) {
+	cfg := config.NotifierConfig{
+		From: "User Service <no-reply@example.com>",
+		Templates: map[string]config.NotifierTemplateConfig{
+			TemplateSuspiciousLogin: {From: "Security <security@example.com>"},
+		},
+	}
+	svc, mailer := newTestNotifier(t, cfg, &entity.User{ID: "user-1", Locale: "vi-VN"})
+
+	ctx := context.Background()
+	err := svc.Notify(ctx, &Notification{
+		Template:  TemplatePasswordReset,
+		UserID:    "user-1",
+		Recipient: "jane@example.com",
+		Payload:   &event.PasswordResetRequestedPayload{Email: "jane@example.com", ResetPasswordToken: 123456},
+	})
+	if err != nil {
+		t.Fatalf("notify failed: %v", err)
+	}
+	err = svc.Notify(ctx, &Notification{
+		Template:  TemplateSuspiciousLogin,
+		UserID:    "unknown-user",
+		Recipient: "john@example.com",
+		Payload:   samples[TemplateSuspiciousLogin],
+	})
+	if err != nil {
+		t.Fatalf("notify failed: %v", err)
+	}
+
+	if len(mailer.sent) != 2 {
+		t.Fatalf("expected 2 emails, got %d", len(mailer.sent))
+	}
+
+	reset := mailer.sent[0]
+	if reset.From != cfg.From || reset.To[0] != "jane@example.com" {
+		t.Errorf("unexpected password reset envelope: %s -> %v", reset.From, reset.To)
+	}
+	if !strings.HasPrefix(reset.Subject, "Đặt lại mật khẩu") || !strings.Contains(reset.Text, "123456") {
+		t.Errorf("password reset email was not rendered in vietnamese: %s\n%s", reset.Subject, reset.Text)
+	}
+
+	suspicious := mailer.sent[1]
+	if suspicious.From != "Security <security@example.com>" {
+		t.Errorf("template sender was not used: %s", suspicious.From)
+	}
+	if !strings.HasPrefix(suspicious.Subject, "Suspicious sign-in") {
+		t.Errorf("email of an unknown user was not rendered in the default locale: %s", suspicious.Subject)
+	}
+}
+
+func TestNotifySkipsDisabledTemplatesAndUndeliverableEmails(t *testing.T) {
+	cfg := config.NotifierConfig{
+		Templates: map[string]config.NotifierTemplateConfig{
+			TemplateNewDeviceLogin: {Disabled: true},
+		},
+	}
+	bouncedAt := time.Now()
+	svc, mailer := newTestNotifier(t, cfg, &entity.User{ID: "user-1", EmailUndeliverableAt: &bouncedAt})
+
+	ctx := context.Background()
+	for _, notification := range []*Notification{
+		{Template: TemplateNewDeviceLogin, Recipient: "jane@example.com", Payload: samples[TemplateNewDeviceLogin]},
+		{Template: TemplatePasswordReset, UserID: "user-1", Recipient: "jane@example.com", Payload: samples[TemplatePasswordReset]},
+	} {
+		if err := svc.Notify(ctx, notification); err != nil {
+			t.Fatalf("notify %s failed: %v", notification.Template, err)
+		}
+	}
+
+	if len(mailer.sent) != 0 {
+		t.Errorf("expected no emails, got %d", len(mailer.sent))
+	}
+}
@@ -0,0 +1,228 @@
+package notifier
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	// layoutFile wraps the html body of every template, in any locale
+	layoutFile = "layout.html"
+	// partialPrefix marks the html files of a locale that are parsed with each of its templates, e.g. the footer
+	partialPrefix = "_"
+)
+
+// all: keeps the partials, files starting with an underscore are left out of the embed otherwise
+//
+//go:embed all:templates
+var embeddedTemplates embed.FS
+
+// TemplateFS returns the templates of dir, or the templates built into the binary when dir is empty.
+//
+// The root holds layout.html and one directory per locale, e.g. "en" or "pt-BR". Every email of a locale
+// is a <name>.txt text template, which defines the "subject" template and renders the plain text body,
+// and a <name>.html template rendering the html body through the "layout" template.
+func TemplateFS(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+
+	templates, _ := fs.Sub(embeddedTemplates, "templates")
+	return templates
+}
+
+// Content is a rendered email
+type Content struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type localizedTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Renderer renders the emails of every locale found in its templates, falling back to the default locale
+type Renderer struct {
+	defaultLocale string
+	// templates holds the templates of each locale by template name
+	templates map[string]map[string]*localizedTemplate
+}
+
+// NewRenderer parses every template up front so a broken one fails at startup instead of when it is sent
+func NewRenderer(fsys fs.FS, defaultLocale string) (*Renderer, error) {
+	layout, err := fs.ReadFile(fsys, layoutFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email layout: %w", err)
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email templates: %w", err)
+	}
+
+	r := &Renderer{
+		defaultLocale: defaultLocale,
+		templates:     map[string]map[string]*localizedTemplate{},
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		templates, err := parseLocale(fsys, entry.Name(), string(layout))
+		if err != nil {
+			return nil, err
+		}
+		r.templates[entry.Name()] = templates
+	}
+
+	if _, ok := r.templates[defaultLocale]; !ok {
+		return nil, fmt.Errorf("no email templates for the default locale %q", defaultLocale)
+	}
+
+	return r, nil
+}
+
+func parseLocale(fsys fs.FS, locale string, layout string) (map[string]*localizedTemplate, error) {
+	files, err := fs.ReadDir(fsys, locale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s email templates: %w", locale, err)
+	}
+
+	base := htmltemplate.New(layoutFile)
+	if _, err := base.Parse(layout); err != nil {
+		return nil, fmt.Errorf("failed to parse email layout: %w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		name := file.Name()
+		switch {
+		case file.IsDir():
+		case strings.HasPrefix(name, partialPrefix) && path.Ext(name) == ".html":
+			if _, err := base.ParseFS(fsys, path.Join(locale, name)); err != nil {
+				return nil, fmt.Errorf("failed to parse email template %s/%s: %w", locale, name, err)
+			}
+		case path.Ext(name) == ".txt":
+			names = append(names, strings.TrimSuffix(name, ".txt"))
+		}
+	}
+
+	templates := map[string]*localizedTemplate{}
+	for _, name := range names {
+		textFile := path.Join(locale, name+".txt")
+		text, err := texttemplate.New(name+".txt").ParseFS(fsys, textFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", textFile, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("email template %s does not define a subject", textFile)
+		}
+
+		htmlFile := path.Join(locale, name+".html")
+		html, err := base.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("failed to clone email layout: %w", err)
+		}
+		// the root template is named after the file so executing the clone renders the page
+		if html, err = html.New(name+".html").ParseFS(fsys, htmlFile); err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", htmlFile, err)
+		}
+
+		templates[name] = &localizedTemplate{text: text, html: html}
+	}
+
+	return templates, nil
+}
+
+// Render renders the template in the closest locale available: the locale itself,
+// then its language without the region, then the default locale
+func (r *Renderer) Render(name string, locale string, data any) (*Content, error) {
+	template, ok := r.lookup(name, locale)
+	if !ok {
+		return nil, fmt.Errorf("email template %q does not exist", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := template.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := template.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text body: %w", name, err)
+	}
+	if err := template.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s html body: %w", name, err)
+	}
+
+	return &Content{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    strings.TrimSpace(html.String()) + "\n",
+	}, nil
+}
+
+// ResolveLocale returns the locale Render uses for the requested one
+func (r *Renderer) ResolveLocale(locale string) string {
+	for _, candidate := range r.candidates(locale) {
+		if _, ok := r.templates[candidate]; ok {
+			return candidate
+		}
+	}
+
+	return r.defaultLocale
+}
+
+// Locales returns the locales that have templates, sorted
+func (r *Renderer) Locales() []string {
+	locales := make([]string, 0, len(r.templates))
+	for locale := range r.templates {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+// Templates returns the template names of the default locale, sorted
+func (r *Renderer) Templates() []string {
+	names := make([]string, 0, len(r.templates[r.defaultLocale]))
+	for name := range r.templates[r.defaultLocale] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (r *Renderer) lookup(name string, locale string) (*localizedTemplate, bool) {
+	for _, candidate := range r.candidates(locale) {
+		if template, ok := r.templates[candidate][name]; ok {
+			return template, true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *Renderer) candidates(locale string) []string {
+	locale = strings.ReplaceAll(locale, "_", "-")
+	candidates := make([]string, 0, 3)
+	if locale != "" {
+		candidates = append(candidates, locale)
+		if language, _, ok := strings.Cut(locale, "-"); ok {
+			candidates = append(candidates, language)
+		}
+	}
+
+	return append(candidates, r.defaultLocale)
+}
@@ -0,0 +1,42 @@
+package notifier
+
+import (
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/event"
+)
+
+const sampleRecipient = "jane.doe@example.com"
+
+var sampleDevice = event.LoginDevicePayload{
+	Email:     sampleRecipient,
+	DeviceID:  "5f0c3a52-2d2c-4c36-9a53-0f4bd7c1e9a1",
+	UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 Safari/605.1.15",
+	IP:        "203.0.113.24",
+	Country:   "Vietnam",
+	City:      "Ho Chi Minh City",
+}
+
+// samples are the payloads the preview endpoint renders each template with
+var samples = map[string]any{
+	TemplatePasswordReset: &event.PasswordResetRequestedPayload{
+		Email:              sampleRecipient,
+		ResetPasswordToken: 482913,
+	},
+	TemplateNewDeviceLogin: &sampleDevice,
+	TemplateSuspiciousLogin: &event.SuspiciousLoginPayload{
+		LoginDevicePayload: sampleDevice,
+		Reason:             "impossible_travel",
+		PreviousDeviceID:   "0b6f6c4e-8f0e-4d7a-a3f5-3d1c2f9e7b10",
+		PreviousCountry:    "Germany",
+		PreviousCity:       "Berlin",
+		PreviousLoginAt:    time.Date(2025, time.January, 1, 8, 0, 0, 0, time.UTC),
+		TravelSpeedKmh:     4200,
+		MaxTravelSpeedKmh:  1000,
+	},
+	TemplateDataExportReady: &event.DataExportReadyPayload{
+		Email:       sampleRecipient,
+		ExportID:    "c2a1f7de-93a4-4f0a-9d0e-6f6a0e3b8b42",
+		DownloadURL: "https://example.com/api/v1/exports/c2a1f7de-93a4-4f0a-9d0e-6f6a0e3b8b42/download?expires=1735689600&signature=sample",
+	},
+}
@@ -0,0 +1,13 @@
+package sms
+
+import "context"
+
+const (
+	DriverLog  = "log"
+	DriverHTTP = "http"
+)
+
+// SMSSender sends a text message to an E.164 phone number
+type SMSSender interface {
+	Send(ctx context.Context, to string, body string) error
+}
@@ -1,33 +1,51 @@
 package tokensvc
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 )
 
 const (
 	AccessTokenDuration  = time.Hour * 24
 	RefreshTokenDuration = time.Hour * 24 * 30
+
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
 )
 
 type JwtToken struct {
 	secretKey string
+	// previousSecretKeys still verify the tokens signed before a key rotation, new tokens use secretKey
+	previousSecretKeys []string
+}
+
+// TokenClaims are the claims of a token issued by JwtToken
+type TokenClaims struct {
+	UserID    string
+	TokenID   string
+	TokenType string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
 }
 
-func NewJwtToken(secretKey string) *JwtToken {
+func NewJwtToken(secretKey string, previousSecretKeys ...string) *JwtToken {
 	return &JwtToken{
-		secretKey: secretKey,
+		secretKey:          secretKey,
+		previousSecretKeys: previousSecretKeys,
 	}
 }
 
 func (t *JwtToken) GenerateTokenPair(userId string) (string, string, error) {
-	accessToken, err := t.generateToken(userId, AccessTokenDuration)
+	accessToken, err := t.generateToken(userId, TokenTypeAccess, AccessTokenDuration)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken, err := t.generateToken(userId, RefreshTokenDuration)
+	refreshToken, err := t.generateToken(userId, TokenTypeRefresh, RefreshTokenDuration)
 	if err != nil {
 		return "", "", err
 	}
@@ -35,11 +53,67 @@ func (t *JwtToken) GenerateTokenPair(userId string) (string, string, error) {
 	return accessToken, refreshToken, nil
 }
 
-func (t *JwtToken) generateToken(userId string, duration time.Duration) (string, error) {
+func (t *JwtToken) generateToken(userId string, tokenType string, duration time.Duration) (string, error) {
+	now := time.Now()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": userId,
-		"exp":     time.Now().Add(duration).Unix(),
+		"jti":     uuid.New().String(),
+		"typ":     tokenType,
+		"iat":     now.Unix(),
+		"exp":     now.Add(duration).Unix(),
 	})
 
 	return token.SignedString([]byte(t.secretKey))
 }
+
+// ParseToken verifies the token signature and expiry and returns its claims, the previous secret keys
+// are tried in order when the signature does not match the current one
+func (t *JwtToken) ParseToken(tokenString string) (*TokenClaims, error) {
+	token, err := t.parse(tokenString, t.secretKey)
+	for _, secretKey := range t.previousSecretKeys {
+		if !isSignatureInvalid(err) {
+			break
+		}
+		token, err = t.parse(tokenString, secretKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	userId, _ := claims["user_id"].(string)
+	tokenID, _ := claims["jti"].(string)
+	tokenType, _ := claims["typ"].(string)
+	iat, hasIat := claims["iat"].(float64)
+	exp, hasExp := claims["exp"].(float64)
+	if userId == "" || tokenID == "" || tokenType == "" || !hasIat || !hasExp {
+		return nil, errors.New("invalid token claims")
+	}
+
+	return &TokenClaims{
+		UserID:    userId,
+		TokenID:   tokenID,
+		TokenType: tokenType,
+		IssuedAt:  time.Unix(int64(iat), 0),
+		ExpiresAt: time.Unix(int64(exp), 0),
+	}, nil
+}
+
+func (t *JwtToken) parse(tokenString string, secretKey string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return []byte(secretKey), nil
+	})
+}
+
+func isSignatureInvalid(err error) bool {
+	var validationErr *jwt.ValidationError
+	return errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0
+}
@@ -0,0 +1,51 @@
+package tokensvc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestParseTokenAcceptsPreviousSecretKeys(t *testing.T) {
+	_, oldRefreshToken, err := NewJwtToken("old-secret").GenerateTokenPair("user-1")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	rotated := NewJwtToken("new-secret", "old-secret")
+	claims, err := rotated.ParseToken(oldRefreshToken)
+	if err != nil {
+		t.Fatalf("token signed with the previous secret key was rejected: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.TokenType != TokenTypeRefresh {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	accessToken, _, err := rotated.GenerateTokenPair("user-1")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	if _, err := NewJwtToken("old-secret").ParseToken(accessToken); err == nil {
+		t.Fatal("new tokens must be signed with the current secret key")
+	}
+
+	if _, err := NewJwtToken("new-secret").ParseToken(oldRefreshToken); err == nil {
+		t.Fatal("token signed with a dropped secret key was accepted")
+	}
+}
+
+func TestParseTokenRejectsTokensWithoutIdOrType(t *testing.T) {
+	// the claims of the tokens issued before they carried an ID, type and issue time
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "user-1",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	if _, err := NewJwtToken("secret").ParseToken(token); err == nil {
+		t.Fatal("expected a token without an ID and type to be rejected")
+	}
+}
@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/pkg/httpclient"
+	"github.com/datpham/user-service-ms/internal/repository/common"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/datpham/user-service-ms/internal/repository/webhook"
+)
+
+type ISubscriptionRepository interface {
+	common.IGenericRepository[entity.WebhookSubscription]
+	List(ctx context.Context) ([]entity.WebhookSubscription, error)
+	ListEnabledByEventType(ctx context.Context, eventType string) ([]entity.WebhookSubscription, error)
+	UpdateFieldsById(ctx context.Context, id string, fields map[string]any) error
+	ResetFailuresById(ctx context.Context, id string) error
+	RecordFailureById(ctx context.Context, id string, disableAfter int, now time.Time) (bool, error)
+}
+
+type IDeliveryRepository interface {
+	common.IGenericRepository[entity.WebhookDelivery]
+	CreateBatch(ctx context.Context, deliveries []entity.WebhookDelivery) error
+	DeleteByUserId(ctx context.Context, userID string) error
+	ClaimPending(ctx context.Context, now time.Time, leaseExpiresAt time.Time, limit int) ([]entity.WebhookDelivery, error)
+	MarkSucceeded(ctx context.Context, id string, attempts int, statusCode int, deliveredAt time.Time) error
+	MarkAttemptFailed(
+		ctx context.Context,
+		id string,
+		attempts int,
+		status entity.WebhookDeliveryStatus,
+		nextAttemptAt time.Time,
+		statusCode int,
+		lastError string,
+	) error
+	ResetForReplay(ctx context.Context, id string, now time.Time) (bool, error)
+	List(ctx context.Context, filter webhook.DeliveryFilter) ([]entity.WebhookDelivery, error)
+}
+
+type IHTTPClient interface {
+	Post(ctx context.Context, url string, opts *httpclient.RequestOptions) (*httpclient.Response, error)
+}
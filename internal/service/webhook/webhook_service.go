@@ -0,0 +1,519 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	respDto "github.com/datpham/user-service-ms/internal/dto/response"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/event"
+	"github.com/datpham/user-service-ms/internal/pkg/httpclient"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/metrics"
+	"github.com/datpham/user-service-ms/internal/pkg/webhookutil"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/datpham/user-service-ms/internal/repository/webhook"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultBatchSize            = 20
+	DefaultMaxAttempts          = 8
+	DefaultRetryBackoff         = time.Second * 30
+	DefaultDisableAfterFailures = 20
+	DefaultDeliveryLimit        = 50
+	DefaultRequestTimeout       = time.Second * 10
+	DefaultClaimLease           = time.Minute * 5
+	MaxRetryBackoff             = time.Hour * 6
+
+	UserAgent = "user-service-webhooks"
+
+	// responseErrorLimit caps how much of a failed response body is kept in the delivery log
+	responseErrorLimit = 512
+
+	deliveryOutcomeSucceeded = "succeeded"
+	deliveryOutcomeRetried   = "retried"
+	deliveryOutcomeFailed    = "failed"
+)
+
+// partnerEventTypes are the event types that may be sent to partners. The other catalog events carry
+// secrets or PII meant for the user alone, e.g. the password reset token or the email and IP of a login.
+var partnerEventTypes = map[event.Type]bool{
+	event.UserDeleted: true,
+}
+
+// WebhookService manages the webhook subscriptions of partners and delivers the events they subscribed to.
+//
+// Deliveries are created in the transaction that publishes the event and sent by DeliverPending: every
+// request carries the delivery ID, the event type, a timestamp and an HMAC-SHA256 signature of the timestamp
+// and body made with the subscription secret. Failed deliveries are retried with exponential backoff up to
+// MaxAttempts, and a subscription whose deliveries failed DisableAfterFailures times in a row is disabled.
+// Deliveries are claimed with a lease and sent outside of any transaction, each outcome is recorded on its own.
+type WebhookService struct {
+	logger                 *logger.Logger
+	subscriptionRepository ISubscriptionRepository
+	deliveryRepository     IDeliveryRepository
+	httpClient             IHTTPClient
+	config                 config.WebhookConfig
+}
+
+func New(
+	logger *logger.Logger,
+	subscriptionRepository ISubscriptionRepository,
+	deliveryRepository IDeliveryRepository,
+	httpClient IHTTPClient,
+	cfg config.WebhookConfig,
+) *WebhookService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+	if cfg.DisableAfterFailures <= 0 {
+		cfg.DisableAfterFailures = DefaultDisableAfterFailures
+	}
+	if cfg.ClaimLease <= 0 {
+		cfg.ClaimLease = DefaultClaimLease
+	}
+
+	return &WebhookService{
+		logger:                 logger,
+		subscriptionRepository: subscriptionRepository,
+		deliveryRepository:     deliveryRepository,
+		httpClient:             httpClient,
+		config:                 cfg,
+	}
+}
+
+func (s *WebhookService) CreateSubscription(
+	ctx context.Context,
+	req *reqDto.CreateWebhookSubscriptionRequest,
+) (*respDto.WebhookSubscriptionResponse, error) {
+	if err := validateEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = webhookutil.GenerateSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription := &entity.WebhookSubscription{
+		ID:         uuid.New().String(),
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+		Enabled:    true,
+	}
+	if err := s.subscriptionRepository.Create(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %s", err.Error())
+	}
+
+	resp := mapToSubscriptionResponse(subscription)
+	resp.Secret = secret
+
+	return resp, nil
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]*respDto.WebhookSubscriptionResponse, error) {
+	subscriptions, err := s.subscriptionRepository.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %s", err.Error())
+	}
+
+	resp := make([]*respDto.WebhookSubscriptionResponse, 0, len(subscriptions))
+	for i := range subscriptions {
+		resp = append(resp, mapToSubscriptionResponse(&subscriptions[i]))
+	}
+
+	return resp, nil
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, id string) (*respDto.WebhookSubscriptionResponse, error) {
+	subscription, err := s.getSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapToSubscriptionResponse(subscription), nil
+}
+
+// UpdateSubscription changes the URL or event types, re-enabling a subscription resets its failure count
+func (s *WebhookService) UpdateSubscription(
+	ctx context.Context,
+	id string,
+	req *reqDto.UpdateWebhookSubscriptionRequest,
+) (*respDto.WebhookSubscriptionResponse, error) {
+	if _, err := s.getSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]any{}
+	if req.URL != nil {
+		fields["url"] = *req.URL
+	}
+	if req.EventTypes != nil {
+		if err := validateEventTypes(req.EventTypes); err != nil {
+			return nil, err
+		}
+
+		eventTypes, err := json.Marshal(req.EventTypes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal event types: %s", err.Error())
+		}
+		fields["event_types"] = string(eventTypes)
+	}
+	if req.Enabled != nil {
+		fields["enabled"] = *req.Enabled
+		if *req.Enabled {
+			fields["consecutive_failures"] = 0
+			fields["disabled_at"] = nil
+		} else {
+			fields["disabled_at"] = time.Now()
+		}
+	}
+
+	if len(fields) > 0 {
+		if err := s.subscriptionRepository.UpdateFieldsById(ctx, id, fields); err != nil {
+			return nil, fmt.Errorf("failed to update webhook subscription: %s", err.Error())
+		}
+	}
+
+	return s.GetSubscription(ctx, id)
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id string) error {
+	if _, err := s.getSubscription(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.subscriptionRepository.DeleteById(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %s", err.Error())
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first
+func (s *WebhookService) ListDeliveries(
+	ctx context.Context,
+	subscriptionID string,
+	req *reqDto.WebhookDeliveryFilterRequest,
+) ([]*respDto.WebhookDeliveryResponse, error) {
+	if _, err := s.getSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultDeliveryLimit
+	}
+
+	deliveries, err := s.deliveryRepository.List(ctx, webhook.DeliveryFilter{
+		SubscriptionID: subscriptionID,
+		Status:         entity.WebhookDeliveryStatus(req.Status),
+		Limit:          limit,
+		Offset:         req.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %s", err.Error())
+	}
+
+	resp := make([]*respDto.WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		resp = append(resp, mapToDeliveryResponse(&deliveries[i]))
+	}
+
+	return resp, nil
+}
+
+// ReplayDelivery sends a failed delivery again with a fresh attempt budget
+func (s *WebhookService) ReplayDelivery(ctx context.Context, id string) (*respDto.WebhookDeliveryResponse, error) {
+	delivery, err := s.deliveryRepository.GetById(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customErr.NewCustomError(customErr.ErrNotFound, "Webhook delivery not found")
+		}
+
+		return nil, fmt.Errorf("failed to get webhook delivery: %s", err.Error())
+	}
+
+	subscription, err := s.getSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !subscription.Enabled {
+		return nil, customErr.NewCustomError(customErr.ErrConflict, "Webhook subscription is disabled, enable it before replaying deliveries")
+	}
+
+	replayed, err := s.deliveryRepository.ResetForReplay(ctx, id, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay webhook delivery: %s", err.Error())
+	}
+	if !replayed {
+		return nil, customErr.NewCustomError(customErr.ErrConflict, "Only failed webhook deliveries can be replayed")
+	}
+
+	if delivery, err = s.deliveryRepository.GetById(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %s", err.Error())
+	}
+
+	return mapToDeliveryResponse(delivery), nil
+}
+
+// Enqueue creates a delivery of the event for every enabled subscription to its type,
+// joining the transaction carried by ctx so deliveries only exist for committed events
+func (s *WebhookService) Enqueue(
+	ctx context.Context,
+	userID string,
+	eventType string,
+	eventID string,
+	payload []byte,
+) error {
+	if !partnerEventTypes[event.Type(eventType)] {
+		return nil
+	}
+
+	subscriptions, err := s.subscriptionRepository.ListEnabledByEventType(ctx, eventType)
+	if err != nil {
+		return fmt.Errorf("failed to list webhook subscriptions: %s", err.Error())
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	deliveries := make([]entity.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, entity.WebhookDelivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: subscription.ID,
+			UserID:         userID,
+			EventID:        eventID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         entity.WebhookDeliveryStatusPending,
+			NextAttemptAt:  now,
+		})
+	}
+
+	if err := s.deliveryRepository.CreateBatch(ctx, deliveries); err != nil {
+		return fmt.Errorf("failed to create webhook deliveries: %s", err.Error())
+	}
+
+	return nil
+}
+
+// DeleteUserDeliveries deletes the deliveries of the events about a user, whose payloads carry their PII
+func (s *WebhookService) DeleteUserDeliveries(ctx context.Context, userID string) error {
+	if err := s.deliveryRepository.DeleteByUserId(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %s", err.Error())
+	}
+
+	return nil
+}
+
+// DeliverPending sends the deliveries that are due and returns how many were attempted. A delivery whose
+// outcome could not be recorded stays claimed until its lease expires and is sent again then.
+func (s *WebhookService) DeliverPending(ctx context.Context) (int, error) {
+	now := time.Now()
+	deliveries, err := s.deliveryRepository.ClaimPending(ctx, now, now.Add(s.config.ClaimLease), s.config.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %s", err.Error())
+	}
+
+	attempted := 0
+	subscriptions := map[string]*entity.WebhookSubscription{}
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			if subscription, err = s.subscriptionRepository.GetById(ctx, delivery.SubscriptionID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				s.logger.Errorf("deliveryId: %s, failed to get webhook subscription: %s", delivery.ID, err.Error())
+				continue
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		attempted++
+		if err := s.deliver(ctx, subscription, delivery); err != nil {
+			s.logger.Errorf("deliveryId: %s, failed to record webhook delivery: %s", delivery.ID, err.Error())
+		}
+	}
+
+	return attempted, nil
+}
+
+// deliver sends the delivery and records the outcome, only failing to record it is returned as an error
+func (s *WebhookService) deliver(ctx context.Context, subscription *entity.WebhookSubscription, delivery *entity.WebhookDelivery) error {
+	if subscription == nil || !subscription.Enabled {
+		metrics.WebhookDeliveriesTotal.WithLabelValues(delivery.EventType, deliveryOutcomeFailed).Inc()
+		return s.deliveryRepository.MarkAttemptFailed(
+			ctx, delivery.ID, delivery.Attempts, entity.WebhookDeliveryStatusFailed, delivery.NextAttemptAt,
+			0, "webhook subscription is disabled or deleted",
+		)
+	}
+	// deliveries enqueued before their event type was withheld from partners are never sent
+	if !partnerEventTypes[event.Type(delivery.EventType)] {
+		metrics.WebhookDeliveriesTotal.WithLabelValues(delivery.EventType, deliveryOutcomeFailed).Inc()
+		return s.deliveryRepository.MarkAttemptFailed(
+			ctx, delivery.ID, delivery.Attempts, entity.WebhookDeliveryStatusFailed, delivery.NextAttemptAt,
+			0, "event type is not delivered to webhooks",
+		)
+	}
+
+	attempts := delivery.Attempts + 1
+	statusCode, sendErr := s.send(ctx, subscription, delivery)
+	if sendErr == nil {
+		metrics.WebhookDeliveriesTotal.WithLabelValues(delivery.EventType, deliveryOutcomeSucceeded).Inc()
+		if err := s.subscriptionRepository.ResetFailuresById(ctx, subscription.ID); err != nil {
+			return fmt.Errorf("failed to reset webhook subscription failures: %s", err.Error())
+		}
+
+		return s.deliveryRepository.MarkSucceeded(ctx, delivery.ID, attempts, statusCode, time.Now())
+	}
+
+	disabled, err := s.subscriptionRepository.RecordFailureById(ctx, subscription.ID, s.config.DisableAfterFailures, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record webhook subscription failure: %s", err.Error())
+	}
+	if disabled {
+		subscription.Enabled = false
+		s.logger.Warnf(
+			"subscriptionId: %s, url: %s, disabling webhook subscription after %d consecutive failures",
+			subscription.ID, subscription.URL, s.config.DisableAfterFailures,
+		)
+	}
+
+	status := entity.WebhookDeliveryStatusPending
+	outcome := deliveryOutcomeRetried
+	if attempts >= s.config.MaxAttempts || disabled {
+		status = entity.WebhookDeliveryStatusFailed
+		outcome = deliveryOutcomeFailed
+		s.logger.Errorf(
+			"deliveryId: %s, subscriptionId: %s, giving up webhook delivery after %d attempts: %s",
+			delivery.ID, subscription.ID, attempts, sendErr.Error(),
+		)
+	}
+	metrics.WebhookDeliveriesTotal.WithLabelValues(delivery.EventType, outcome).Inc()
+
+	return s.deliveryRepository.MarkAttemptFailed(
+		ctx, delivery.ID, attempts, status, time.Now().Add(s.retryBackoff(attempts)), statusCode, sendErr.Error(),
+	)
+}
+
+func (s *WebhookService) send(ctx context.Context, subscription *entity.WebhookSubscription, delivery *entity.WebhookDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	resp, err := s.httpClient.Post(ctx, subscription.URL, &httpclient.RequestOptions{
+		Headers: map[string]string{
+			"User-Agent":                 UserAgent,
+			webhookutil.HeaderDeliveryID: delivery.ID,
+			webhookutil.HeaderEventType:  delivery.EventType,
+			webhookutil.HeaderTimestamp:  strconv.FormatInt(timestamp, 10),
+			webhookutil.HeaderSignature:  webhookutil.Sign(subscription.Secret, timestamp, delivery.Payload),
+		},
+		// the payload is compact json, the client sends it unchanged so the signature matches the body
+		Body: json.RawMessage(delivery.Payload),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if !resp.IsSuccess() {
+		body := string(resp.Body)
+		if len(body) > responseErrorLimit {
+			body = body[:responseErrorLimit] + "..."
+		}
+
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d: %s", resp.StatusCode, body)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (s *WebhookService) retryBackoff(attempts int) time.Duration {
+	backoff := s.config.RetryBackoff
+	for i := 1; i < attempts && backoff < MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, MaxRetryBackoff)
+}
+
+func (s *WebhookService) getSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	subscription, err := s.subscriptionRepository.GetById(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customErr.NewCustomError(customErr.ErrNotFound, "Webhook subscription not found")
+		}
+
+		return nil, fmt.Errorf("failed to get webhook subscription: %s", err.Error())
+	}
+
+	return subscription, nil
+}
+
+func validateEventTypes(eventTypes []string) error {
+	for _, eventType := range eventTypes {
+		if _, err := event.Lookup(event.Type(eventType)); err != nil {
+			return customErr.NewCustomError(customErr.ErrInvalidRequest, fmt.Sprintf("Unknown event type %q", eventType))
+		}
+		if !partnerEventTypes[event.Type(eventType)] {
+			return customErr.NewCustomError(
+				customErr.ErrInvalidRequest, fmt.Sprintf("Event type %q is not delivered to webhooks", eventType),
+			)
+		}
+	}
+
+	return nil
+}
+
+func mapToSubscriptionResponse(subscription *entity.WebhookSubscription) *respDto.WebhookSubscriptionResponse {
+	return &respDto.WebhookSubscriptionResponse{
+		ID:                  subscription.ID,
+		URL:                 subscription.URL,
+		EventTypes:          subscription.EventTypes,
+		Enabled:             subscription.Enabled,
+		ConsecutiveFailures: subscription.ConsecutiveFailures,
+		DisabledAt:          subscription.DisabledAt,
+		CreatedAt:           subscription.CreatedAt,
+		UpdatedAt:           subscription.UpdatedAt,
+	}
+}
+
+func mapToDeliveryResponse(delivery *entity.WebhookDelivery) *respDto.WebhookDeliveryResponse {
+	resp := &respDto.WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == entity.WebhookDeliveryStatusPending {
+		resp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+
+	return resp
+}
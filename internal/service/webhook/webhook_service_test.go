@@ -0,0 +1,487 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	reqDto "github.com/datpham/user-service-ms/internal/dto/request"
+	customErr "github.com/datpham/user-service-ms/internal/errors"
+	"github.com/datpham/user-service-ms/internal/pkg/httpclient"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/webhookutil"
+	"github.com/datpham/user-service-ms/internal/repository/common"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/datpham/user-service-ms/internal/repository/webhook"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type fakeSubscriptionRepository struct {
+	common.IGenericRepository[entity.WebhookSubscription]
+	subscriptions map[string]*entity.WebhookSubscription
+}
+
+func (r *fakeSubscriptionRepository) GetById(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *subscription
+	return &copied, nil
+}
+
+func (r *fakeSubscriptionRepository) Create(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	r.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) List(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	var subscriptions []entity.WebhookSubscription
+	for _, subscription := range r.subscriptions {
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return subscriptions, nil
+}
+
+func (r *fakeSubscriptionRepository) ListEnabledByEventType(ctx context.Context, eventType string) ([]entity.WebhookSubscription, error) {
+	var subscriptions []entity.WebhookSubscription
+	for _, subscription := range r.subscriptions {
+		if subscription.Enabled && slices.Contains(subscription.EventTypes, eventType) {
+			subscriptions = append(subscriptions, *subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (r *fakeSubscriptionRepository) UpdateFieldsById(ctx context.Context, id string, fields map[string]any) error {
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) ResetFailuresById(ctx context.Context, id string) error {
+	r.subscriptions[id].ConsecutiveFailures = 0
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) RecordFailureById(ctx context.Context, id string, disableAfter int, now time.Time) (bool, error) {
+	subscription := r.subscriptions[id]
+	subscription.ConsecutiveFailures++
+	if subscription.Enabled && subscription.ConsecutiveFailures >= disableAfter {
+		subscription.Enabled = false
+		subscription.DisabledAt = &now
+		return true, nil
+	}
+	return false, nil
+}
+
+type fakeDeliveryRepository struct {
+	common.IGenericRepository[entity.WebhookDelivery]
+	deliveries map[string]*entity.WebhookDelivery
+	// markErrs fails recording the outcome of the deliveries with these IDs
+	markErrs map[string]error
+}
+
+func (r *fakeDeliveryRepository) GetById(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *delivery
+	return &copied, nil
+}
+
+func (r *fakeDeliveryRepository) CreateBatch(ctx context.Context, deliveries []entity.WebhookDelivery) error {
+	for i := range deliveries {
+		delivery := deliveries[i]
+		r.deliveries[delivery.ID] = &delivery
+	}
+	return nil
+}
+
+func (r *fakeDeliveryRepository) DeleteByUserId(ctx context.Context, userID string) error {
+	for id, delivery := range r.deliveries {
+		if delivery.UserID == userID {
+			delete(r.deliveries, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakeDeliveryRepository) ClaimPending(
+	ctx context.Context,
+	now time.Time,
+	leaseExpiresAt time.Time,
+	limit int,
+) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == entity.WebhookDeliveryStatusPending && !delivery.NextAttemptAt.After(now) && len(deliveries) < limit {
+			delivery.NextAttemptAt = leaseExpiresAt
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *fakeDeliveryRepository) MarkSucceeded(ctx context.Context, id string, attempts int, statusCode int, deliveredAt time.Time) error {
+	if err := r.markErrs[id]; err != nil {
+		return err
+	}
+	delivery := r.deliveries[id]
+	delivery.Status = entity.WebhookDeliveryStatusSucceeded
+	delivery.Attempts = attempts
+	delivery.LastStatusCode = statusCode
+	delivery.DeliveredAt = &deliveredAt
+	return nil
+}
+
+func (r *fakeDeliveryRepository) MarkAttemptFailed(
+	ctx context.Context,
+	id string,
+	attempts int,
+	status entity.WebhookDeliveryStatus,
+	nextAttemptAt time.Time,
+	statusCode int,
+	lastError string,
+) error {
+	if err := r.markErrs[id]; err != nil {
+		return err
+	}
+	delivery := r.deliveries[id]
+	delivery.Status = status
+	delivery.Attempts = attempts
+	delivery.NextAttemptAt = nextAttemptAt
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = lastError
+	return nil
+}
+
+func (r *fakeDeliveryRepository) ResetForReplay(ctx context.Context, id string, now time.Time) (bool, error) {
+	delivery := r.deliveries[id]
+	if delivery.Status != entity.WebhookDeliveryStatusFailed {
+		return false, nil
+	}
+	delivery.Status = entity.WebhookDeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	return true, nil
+}
+
+func (r *fakeDeliveryRepository) List(ctx context.Context, filter webhook.DeliveryFilter) ([]entity.WebhookDelivery, error) {
+	return nil, nil
+}
+
+// receiver records the requests of a test endpoint that responds with the queued status codes, then 200
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newTestService(t *testing.T, cfg config.WebhookConfig, statuses ...int) (*WebhookService, *fakeSubscriptionRepository, *fakeDeliveryRepository, *receiver, string) {
+	t.Helper()
+
+	rc := &receiver{statuses: statuses}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	subscriptions := &fakeSubscriptionRepository{subscriptions: map[string]*entity.WebhookSubscription{}}
+	deliveries := &fakeDeliveryRepository{deliveries: map[string]*entity.WebhookDelivery{}, markErrs: map[string]error{}}
+	testLogger := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+	svc := New(testLogger, subscriptions, deliveries, httpclient.NewClient(time.Second), cfg)
+
+	return svc, subscriptions, deliveries, rc, server.URL
+}
+
+func createSubscription(t *testing.T, svc *WebhookService, url string) string {
+	t.Helper()
+
+	resp, err := svc.CreateSubscription(context.Background(), &reqDto.CreateWebhookSubscriptionRequest{
+		URL:        url,
+		EventTypes: []string{"user_deleted"},
+		Secret:     "test-secret",
+	})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+	return resp.ID
+}
+
+func onlyDelivery(t *testing.T, deliveries *fakeDeliveryRepository) *entity.WebhookDelivery {
+	t.Helper()
+
+	if len(deliveries.deliveries) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(deliveries.deliveries))
+	}
+	for _, delivery := range deliveries.deliveries {
+		return delivery
+	}
+	return nil
+}
+
+// makeDue moves the next attempt of every pending delivery to the past so DeliverPending picks it up
+func makeDue(deliveries *fakeDeliveryRepository) {
+	for _, delivery := range deliveries.deliveries {
+		delivery.NextAttemptAt = time.Now().Add(-time.Second)
+	}
+}
+
+func TestCreateSubscriptionRejectsUnknownEventTypes(t *testing.T) {
+	svc, _, _, _, url := newTestService(t, config.WebhookConfig{})
+
+	_, err := svc.CreateSubscription(context.Background(), &reqDto.CreateWebhookSubscriptionRequest{
+		URL:        url,
+		EventTypes: []string{"user_unknown"},
+	})
+
+	var serviceErr *customErr.CustomError
+	if !errors.As(err, &serviceErr) || serviceErr.Code != customErr.ErrInvalidRequest {
+		t.Fatalf("expected an invalid request error, got %v", err)
+	}
+}
+
+func TestCreateSubscriptionRejectsUserOnlyEventTypes(t *testing.T) {
+	svc, _, _, _, url := newTestService(t, config.WebhookConfig{})
+
+	for _, eventType := range []string{
+		"user_reset_password", "user_data_export_ready", "user_new_device_login", "user_suspicious_login",
+	} {
+		_, err := svc.CreateSubscription(context.Background(), &reqDto.CreateWebhookSubscriptionRequest{
+			URL:        url,
+			EventTypes: []string{"user_deleted", eventType},
+		})
+
+		var serviceErr *customErr.CustomError
+		if !errors.As(err, &serviceErr) || serviceErr.Code != customErr.ErrInvalidRequest {
+			t.Fatalf("%s: expected an invalid request error, got %v", eventType, err)
+		}
+	}
+}
+
+func TestEnqueueSkipsResetPasswordEvents(t *testing.T) {
+	svc, subscriptions, deliveries, rc, url := newTestService(t, config.WebhookConfig{})
+	// e.g. a subscription created before the event type was withheld from partners
+	subscriptions.subscriptions["sub-1"] = &entity.WebhookSubscription{
+		ID: "sub-1", URL: url, EventTypes: []string{"user_reset_password"}, Secret: "test-secret", Enabled: true,
+	}
+
+	ctx := context.Background()
+	payload := []byte(`{"event_type":"user_reset_password","payload":{"reset_password_token":12345}}`)
+	if err := svc.Enqueue(ctx, "user-1", "user_reset_password", "evt-1", payload); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if len(deliveries.deliveries) != 0 {
+		t.Fatalf("expected no delivery of a reset password event, got %d", len(deliveries.deliveries))
+	}
+
+	// one enqueued before is failed without being sent
+	deliveries.deliveries["del-1"] = &entity.WebhookDelivery{
+		ID: "del-1", SubscriptionID: "sub-1", EventType: "user_reset_password", Payload: payload,
+		Status: entity.WebhookDeliveryStatusPending, NextAttemptAt: time.Now().Add(-time.Second),
+	}
+	if _, err := svc.DeliverPending(ctx); err != nil {
+		t.Fatalf("deliver failed: %v", err)
+	}
+	if len(rc.requests) != 0 || deliveries.deliveries["del-1"].Status != entity.WebhookDeliveryStatusFailed {
+		t.Fatalf("expected the delivery to fail unsent, got %s after %d requests", deliveries.deliveries["del-1"].Status, len(rc.requests))
+	}
+}
+
+func TestDeliverPendingSignsPayload(t *testing.T) {
+	svc, _, deliveries, rc, url := newTestService(t, config.WebhookConfig{})
+	createSubscription(t, svc, url)
+
+	payload := []byte(`{"event_id":"evt-1","event_type":"user_deleted"}`)
+	ctx := context.Background()
+	if err := svc.Enqueue(ctx, "user-1", "user_deleted", "evt-1", payload); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if err := svc.Enqueue(ctx, "user-1", "user_new_device_login", "evt-2", payload); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	if attempted, err := svc.DeliverPending(ctx); err != nil || attempted != 1 {
+		t.Fatalf("expected 1 attempted delivery, got %d: %v", attempted, err)
+	}
+
+	delivery := onlyDelivery(t, deliveries)
+	if delivery.Status != entity.WebhookDeliveryStatusSucceeded || delivery.Attempts != 1 {
+		t.Errorf("unexpected delivery state: %s after %d attempts", delivery.Status, delivery.Attempts)
+	}
+
+	request := rc.requests[0]
+	if got := request.Header.Get(webhookutil.HeaderDeliveryID); got != delivery.ID {
+		t.Errorf("unexpected delivery id header: %s", got)
+	}
+	if got := request.Header.Get(webhookutil.HeaderEventType); got != "user_deleted" {
+		t.Errorf("unexpected event header: %s", got)
+	}
+	if string(rc.bodies[0]) != string(payload) {
+		t.Errorf("unexpected body: %s", rc.bodies[0])
+	}
+	if err := webhookutil.Verify(
+		"test-secret", request.Header.Get(webhookutil.HeaderTimestamp), rc.bodies[0],
+		request.Header.Get(webhookutil.HeaderSignature), time.Minute,
+	); err != nil {
+		t.Errorf("signature did not verify: %v", err)
+	}
+}
+
+func TestDeliverPendingRetriesWithBackoffUntilMaxAttempts(t *testing.T) {
+	cfg := config.WebhookConfig{MaxAttempts: 3, RetryBackoff: time.Minute}
+	svc, _, deliveries, _, url := newTestService(t, cfg, 500, 503, 502)
+	createSubscription(t, svc, url)
+
+	ctx := context.Background()
+	if err := svc.Enqueue(ctx, "user-1", "user_deleted", "evt-1", []byte(`{}`)); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	for attempt, backoff := range []time.Duration{time.Minute, time.Minute * 2} {
+		before := time.Now()
+		if _, err := svc.DeliverPending(ctx); err != nil {
+			t.Fatalf("deliver failed: %v", err)
+		}
+
+		delivery := onlyDelivery(t, deliveries)
+		if delivery.Status != entity.WebhookDeliveryStatusPending || delivery.Attempts != attempt+1 {
+			t.Fatalf("unexpected delivery state: %s after %d attempts", delivery.Status, delivery.Attempts)
+		}
+		if delay := delivery.NextAttemptAt.Sub(before); delay < backoff || delay > backoff+time.Second {
+			t.Errorf("attempt %d: expected a backoff of %s, got %s", attempt+1, backoff, delay)
+		}
+		makeDue(deliveries)
+	}
+
+	if _, err := svc.DeliverPending(ctx); err != nil {
+		t.Fatalf("deliver failed: %v", err)
+	}
+	delivery := onlyDelivery(t, deliveries)
+	if delivery.Status != entity.WebhookDeliveryStatusFailed || delivery.LastStatusCode != 502 {
+		t.Errorf("expected a failed delivery with status 502, got %s with %d", delivery.Status, delivery.LastStatusCode)
+	}
+}
+
+func TestDeliverPendingRecordsEachDeliveryOnItsOwn(t *testing.T) {
+	svc, _, deliveries, rc, url := newTestService(t, config.WebhookConfig{})
+	createSubscription(t, svc, url)
+
+	ctx := context.Background()
+	for _, eventID := range []string{"evt-1", "evt-2", "evt-3"} {
+		if err := svc.Enqueue(ctx, "user-1", "user_deleted", eventID, []byte(`{}`)); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+	}
+	var unrecorded *entity.WebhookDelivery
+	for _, delivery := range deliveries.deliveries {
+		if delivery.EventID == "evt-2" {
+			unrecorded = delivery
+			deliveries.markErrs[delivery.ID] = errors.New("connection reset")
+		}
+	}
+
+	before := time.Now()
+	if attempted, err := svc.DeliverPending(ctx); err != nil || attempted != 3 {
+		t.Fatalf("expected 3 attempted deliveries, got %d: %v", attempted, err)
+	}
+
+	for _, delivery := range deliveries.deliveries {
+		if delivery != unrecorded && delivery.Status != entity.WebhookDeliveryStatusSucceeded {
+			t.Errorf("expected %s to be recorded as succeeded, got %s", delivery.EventID, delivery.Status)
+		}
+	}
+	if unrecorded.Status != entity.WebhookDeliveryStatusPending || unrecorded.NextAttemptAt.Sub(before) < DefaultClaimLease {
+		t.Errorf("expected the unrecorded delivery to stay claimed for the lease, got %s due at %s", unrecorded.Status, unrecorded.NextAttemptAt)
+	}
+
+	if attempted, err := svc.DeliverPending(ctx); err != nil || attempted != 0 {
+		t.Errorf("expected the claimed delivery not to be sent again before its lease expires, got %d: %v", attempted, err)
+	}
+	if len(rc.requests) != 3 {
+		t.Errorf("expected 3 requests, got %d", len(rc.requests))
+	}
+}
+
+func TestDeliverPendingDisablesFailingSubscription(t *testing.T) {
+	cfg := config.WebhookConfig{MaxAttempts: 5, DisableAfterFailures: 2}
+	svc, subscriptions, deliveries, _, url := newTestService(t, cfg, 500, 500)
+	subscriptionID := createSubscription(t, svc, url)
+
+	ctx := context.Background()
+	if err := svc.Enqueue(ctx, "user-1", "user_deleted", "evt-1", []byte(`{}`)); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := svc.DeliverPending(ctx); err != nil {
+			t.Fatalf("deliver failed: %v", err)
+		}
+		makeDue(deliveries)
+	}
+
+	if subscriptions.subscriptions[subscriptionID].Enabled {
+		t.Fatal("subscription was not disabled")
+	}
+	if delivery := onlyDelivery(t, deliveries); delivery.Status != entity.WebhookDeliveryStatusFailed {
+		t.Errorf("expected the delivery to fail once its subscription is disabled, got %s", delivery.Status)
+	}
+	if err := svc.Enqueue(ctx, "user-1", "user_deleted", "evt-2", []byte(`{}`)); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if len(deliveries.deliveries) != 1 {
+		t.Error("a delivery was created for a disabled subscription")
+	}
+}
+
+func TestReplayDeliveryResendsFailedDelivery(t *testing.T) {
+	svc, _, deliveries, rc, url := newTestService(t, config.WebhookConfig{MaxAttempts: 1}, 500)
+	createSubscription(t, svc, url)
+
+	ctx := context.Background()
+	if err := svc.Enqueue(ctx, "user-1", "user_deleted", "evt-1", []byte(`{}`)); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if _, err := svc.DeliverPending(ctx); err != nil {
+		t.Fatalf("deliver failed: %v", err)
+	}
+	delivery := onlyDelivery(t, deliveries)
+
+	if _, err := svc.ReplayDelivery(ctx, delivery.ID); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if _, err := svc.DeliverPending(ctx); err != nil {
+		t.Fatalf("deliver failed: %v", err)
+	}
+
+	if delivery.Status != entity.WebhookDeliveryStatusSucceeded || len(rc.requests) != 2 {
+		t.Errorf("expected the replayed delivery to succeed, got %s after %d requests", delivery.Status, len(rc.requests))
+	}
+
+	_, err := svc.ReplayDelivery(ctx, delivery.ID)
+	var serviceErr *customErr.CustomError
+	if !errors.As(err, &serviceErr) || serviceErr.Code != customErr.ErrConflict {
+		t.Errorf("expected a conflict replaying a succeeded delivery, got %v", err)
+	}
+}
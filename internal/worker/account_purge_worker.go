@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+)
+
+const (
+	DefaultPurgeInterval = time.Hour
+)
+
+type IAccountPurger interface {
+	PurgeDeletedAccounts(ctx context.Context) (int, error)
+}
+
+// AccountPurgeWorker periodically anonymizes accounts whose deletion grace period has expired
+type AccountPurgeWorker struct {
+	logger   *logger.Logger
+	purger   IAccountPurger
+	interval time.Duration
+}
+
+func NewAccountPurgeWorker(logger *logger.Logger, purger IAccountPurger, interval time.Duration) *AccountPurgeWorker {
+	if interval <= 0 {
+		interval = DefaultPurgeInterval
+	}
+
+	return &AccountPurgeWorker{
+		logger:   logger,
+		purger:   purger,
+		interval: interval,
+	}
+}
+
+// Start runs the purge loop until the context is cancelled
+func (w *AccountPurgeWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *AccountPurgeWorker) runOnce(ctx context.Context) {
+	purged, err := w.purger.PurgeDeletedAccounts(ctx)
+	if err != nil {
+		w.logger.Errorf("failed to purge deleted accounts: %s", err.Error())
+		return
+	}
+
+	if purged > 0 {
+		w.logger.Infof("Purged %d deleted accounts", purged)
+	}
+}
@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+)
+
+const (
+	DefaultDataExportInterval = time.Second * 5
+)
+
+type IDataExportProcessor interface {
+	ProcessDataExports(ctx context.Context) (int, error)
+	ExpireDataExports(ctx context.Context) (int, error)
+}
+
+// DataExportWorker periodically writes the archives of the requested data exports and deletes the
+// archives whose retention period ended
+type DataExportWorker struct {
+	logger    *logger.Logger
+	processor IDataExportProcessor
+	interval  time.Duration
+}
+
+func NewDataExportWorker(logger *logger.Logger, processor IDataExportProcessor, interval time.Duration) *DataExportWorker {
+	if interval <= 0 {
+		interval = DefaultDataExportInterval
+	}
+
+	return &DataExportWorker{
+		logger:    logger,
+		processor: processor,
+		interval:  interval,
+	}
+}
+
+// Start runs the export loop until the context is cancelled
+func (w *DataExportWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *DataExportWorker) runOnce(ctx context.Context) {
+	ready, err := w.processor.ProcessDataExports(ctx)
+	if err != nil {
+		w.logger.Errorf("failed to process data exports: %s", err.Error())
+	} else if ready > 0 {
+		w.logger.Infof("Processed %d data exports", ready)
+	}
+
+	expired, err := w.processor.ExpireDataExports(ctx)
+	if err != nil {
+		w.logger.Errorf("failed to expire data exports: %s", err.Error())
+	} else if expired > 0 {
+		w.logger.Infof("Expired %d data exports", expired)
+	}
+}
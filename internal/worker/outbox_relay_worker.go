@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/metrics"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/datpham/user-service-ms/internal/service/eventbus"
+)
+
+const (
+	DefaultOutboxRelayInterval = time.Second
+	DefaultOutboxBatchSize     = 100
+	DefaultOutboxMaxAttempts   = 10
+	DefaultOutboxRetryBackoff  = time.Second * 5
+	DefaultOutboxClaimLease    = time.Minute
+	MaxOutboxRetryBackoff      = time.Hour
+)
+
+type IOutboxRepository interface {
+	ClaimPending(ctx context.Context, now time.Time, leaseExpiresAt time.Time, limit int) ([]entity.OutboxMessage, error)
+	MarkSent(ctx context.Context, id string, sentAt time.Time) error
+	MarkAttemptFailed(
+		ctx context.Context,
+		id string,
+		attempts int,
+		status entity.OutboxStatus,
+		nextAttemptAt time.Time,
+		lastError string,
+	) error
+	GetPendingStats(ctx context.Context) (int64, *time.Time, error)
+}
+
+// OutboxRelayWorker publishes pending outbox messages to the event bus and marks them sent once
+// the broker confirmed it stored them, retrying failed messages with exponential backoff until
+// MaxAttempts is reached. Messages are claimed with a lease and marked one by one, so a failure to mark
+// one message does not undo the others; a message whose mark failed is published again after the lease.
+type OutboxRelayWorker struct {
+	logger           *logger.Logger
+	outboxRepository IOutboxRepository
+	publisher        eventbus.EventPublisher
+	config           config.OutboxConfig
+}
+
+func NewOutboxRelayWorker(
+	logger *logger.Logger,
+	outboxRepository IOutboxRepository,
+	publisher eventbus.EventPublisher,
+	cfg config.OutboxConfig,
+) *OutboxRelayWorker {
+	if cfg.RelayInterval <= 0 {
+		cfg.RelayInterval = DefaultOutboxRelayInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultOutboxBatchSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultOutboxMaxAttempts
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultOutboxRetryBackoff
+	}
+	if cfg.ClaimLease <= 0 {
+		cfg.ClaimLease = DefaultOutboxClaimLease
+	}
+
+	return &OutboxRelayWorker{
+		logger:           logger,
+		outboxRepository: outboxRepository,
+		publisher:        publisher,
+		config:           cfg,
+	}
+}
+
+// Start runs the relay loop until the context is cancelled
+func (w *OutboxRelayWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.config.RelayInterval)
+	defer ticker.Stop()
+
+	for {
+		w.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *OutboxRelayWorker) runOnce(ctx context.Context) {
+	w.relayBatch(ctx)
+	w.updateLagMetrics(ctx)
+}
+
+func (w *OutboxRelayWorker) relayBatch(ctx context.Context) {
+	now := time.Now()
+	messages, err := w.outboxRepository.ClaimPending(ctx, now, now.Add(w.config.ClaimLease), w.config.BatchSize)
+	if err != nil {
+		w.logger.Errorf("failed to claim outbox messages: %s", err.Error())
+		return
+	}
+
+	for i := range messages {
+		if err := w.relayMessage(ctx, &messages[i]); err != nil {
+			w.logger.Errorf("outboxId: %s, failed to mark outbox message: %s", messages[i].ID, err.Error())
+		}
+	}
+}
+
+func (w *OutboxRelayWorker) relayMessage(ctx context.Context, message *entity.OutboxMessage) error {
+	publishErr := w.publisher.Publish(ctx, message.RoutingKey, message.Payload)
+	if publishErr == nil {
+		metrics.OutboxPublishedTotal.WithLabelValues(message.EventType).Inc()
+		return w.outboxRepository.MarkSent(ctx, message.ID, time.Now())
+	}
+
+	metrics.OutboxPublishFailuresTotal.WithLabelValues(message.EventType).Inc()
+
+	attempts := message.Attempts + 1
+	status := entity.OutboxStatusPending
+	if attempts >= w.config.MaxAttempts {
+		status = entity.OutboxStatusFailed
+		w.logger.Errorf(
+			"outboxId: %s, idempotencyKey: %s, giving up publishing after %d attempts: %s",
+			message.ID, message.IdempotencyKey, attempts, publishErr.Error(),
+		)
+	}
+
+	return w.outboxRepository.MarkAttemptFailed(
+		ctx, message.ID, attempts, status, time.Now().Add(w.retryBackoff(attempts)), publishErr.Error(),
+	)
+}
+
+func (w *OutboxRelayWorker) retryBackoff(attempts int) time.Duration {
+	backoff := w.config.RetryBackoff
+	for i := 1; i < attempts && backoff < MaxOutboxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, MaxOutboxRetryBackoff)
+}
+
+func (w *OutboxRelayWorker) updateLagMetrics(ctx context.Context) {
+	pending, oldest, err := w.outboxRepository.GetPendingStats(ctx)
+	if err != nil {
+		w.logger.Errorf("failed to get outbox stats: %s", err.Error())
+		return
+	}
+
+	metrics.OutboxPendingMessages.Set(float64(pending))
+	if oldest == nil {
+		metrics.OutboxLagSeconds.Set(0)
+		return
+	}
+
+	metrics.OutboxLagSeconds.Set(time.Since(*oldest).Seconds())
+}
@@ -0,0 +1,302 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+	"github.com/datpham/user-service-ms/internal/pkg/metrics"
+	"github.com/datpham/user-service-ms/internal/repository/entity"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeOutboxRepository struct {
+	messages     map[string]*entity.OutboxMessage
+	markSentErrs map[string]error
+	claimLeases  []time.Time
+}
+
+func newFakeOutboxRepository(messages ...entity.OutboxMessage) *fakeOutboxRepository {
+	repo := &fakeOutboxRepository{
+		messages:     map[string]*entity.OutboxMessage{},
+		markSentErrs: map[string]error{},
+	}
+	for i := range messages {
+		repo.messages[messages[i].ID] = &messages[i]
+	}
+
+	return repo
+}
+
+func (r *fakeOutboxRepository) ClaimPending(
+	_ context.Context,
+	now time.Time,
+	leaseExpiresAt time.Time,
+	limit int,
+) ([]entity.OutboxMessage, error) {
+	r.claimLeases = append(r.claimLeases, leaseExpiresAt)
+
+	var due []*entity.OutboxMessage
+	for _, message := range r.messages {
+		if message.Status == entity.OutboxStatusPending && !message.NextAttemptAt.After(now) {
+			due = append(due, message)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].CreatedAt.Before(due[j].CreatedAt) })
+
+	var claimed []entity.OutboxMessage
+	for _, message := range due[:min(limit, len(due))] {
+		message.NextAttemptAt = leaseExpiresAt
+		claimed = append(claimed, *message)
+	}
+
+	return claimed, nil
+}
+
+func (r *fakeOutboxRepository) MarkSent(_ context.Context, id string, sentAt time.Time) error {
+	if err := r.markSentErrs[id]; err != nil {
+		return err
+	}
+
+	r.messages[id].Status = entity.OutboxStatusSent
+	r.messages[id].SentAt = &sentAt
+	return nil
+}
+
+func (r *fakeOutboxRepository) MarkAttemptFailed(
+	_ context.Context,
+	id string,
+	attempts int,
+	status entity.OutboxStatus,
+	nextAttemptAt time.Time,
+	lastError string,
+) error {
+	message := r.messages[id]
+	message.Attempts = attempts
+	message.Status = status
+	message.NextAttemptAt = nextAttemptAt
+	message.LastError = lastError
+	return nil
+}
+
+func (r *fakeOutboxRepository) GetPendingStats(context.Context) (int64, *time.Time, error) {
+	var (
+		count  int64
+		oldest *time.Time
+	)
+	for _, message := range r.messages {
+		if message.Status != entity.OutboxStatusPending {
+			continue
+		}
+		count++
+		if oldest == nil || message.CreatedAt.Before(*oldest) {
+			oldest = &message.CreatedAt
+		}
+	}
+
+	return count, oldest, nil
+}
+
+type fakePublisher struct {
+	published []string
+	err       error
+}
+
+func (p *fakePublisher) Publish(_ context.Context, topic string, body []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	p.published = append(p.published, topic+":"+string(body))
+	return nil
+}
+
+func newOutboxMessage(id string, createdAt time.Time) entity.OutboxMessage {
+	return entity.OutboxMessage{
+		ID:            id,
+		EventType:     "user_created",
+		RoutingKey:    "user.account.created",
+		Payload:       []byte(id),
+		Status:        entity.OutboxStatusPending,
+		NextAttemptAt: createdAt,
+		CreatedAt:     createdAt,
+	}
+}
+
+func newTestOutboxRelayWorker(
+	repo *fakeOutboxRepository,
+	publisher *fakePublisher,
+	cfg config.OutboxConfig,
+) *OutboxRelayWorker {
+	log := logger.New(logger.LoggerConfig{Level: logrus.PanicLevel, Output: io.Discard})
+	return NewOutboxRelayWorker(log, repo, publisher, cfg)
+}
+
+func TestOutboxRelayWorkerPublishesClaimedMessagesInOrder(t *testing.T) {
+	now := time.Now()
+	repo := newFakeOutboxRepository(
+		newOutboxMessage("second", now.Add(-time.Minute)),
+		newOutboxMessage("first", now.Add(-time.Hour)),
+		newOutboxMessage("third", now.Add(-time.Second)),
+		newOutboxMessage("not-due", now.Add(time.Hour)),
+	)
+	publisher := &fakePublisher{}
+	worker := newTestOutboxRelayWorker(repo, publisher, config.OutboxConfig{BatchSize: 2})
+
+	worker.runOnce(context.Background())
+
+	if len(publisher.published) != 2 ||
+		publisher.published[0] != "user.account.created:first" ||
+		publisher.published[1] != "user.account.created:second" {
+		t.Fatalf("expected the two oldest messages to be published in order, got %v", publisher.published)
+	}
+	if lease := repo.claimLeases[0]; lease.Sub(now) < DefaultOutboxClaimLease {
+		t.Errorf("expected the messages to be leased for %s, got %s", DefaultOutboxClaimLease, lease.Sub(now))
+	}
+	for id, status := range map[string]entity.OutboxStatus{
+		"first":   entity.OutboxStatusSent,
+		"second":  entity.OutboxStatusSent,
+		"third":   entity.OutboxStatusPending,
+		"not-due": entity.OutboxStatusPending,
+	} {
+		if repo.messages[id].Status != status {
+			t.Errorf("expected %s to be %s, got %s", id, status, repo.messages[id].Status)
+		}
+	}
+
+	worker.runOnce(context.Background())
+
+	if len(publisher.published) != 3 || publisher.published[2] != "user.account.created:third" {
+		t.Fatalf("expected the next run to publish the remaining due message, got %v", publisher.published)
+	}
+}
+
+func TestOutboxRelayWorkerMarksMessagesIndependently(t *testing.T) {
+	now := time.Now()
+	repo := newFakeOutboxRepository(
+		newOutboxMessage("first", now.Add(-time.Hour)),
+		newOutboxMessage("second", now.Add(-time.Minute)),
+		newOutboxMessage("third", now.Add(-time.Second)),
+	)
+	repo.markSentErrs["second"] = errors.New("connection reset")
+	publisher := &fakePublisher{}
+	worker := newTestOutboxRelayWorker(repo, publisher, config.OutboxConfig{})
+
+	worker.runOnce(context.Background())
+
+	if len(publisher.published) != 3 {
+		t.Fatalf("expected every message to be published, got %v", publisher.published)
+	}
+	if repo.messages["first"].Status != entity.OutboxStatusSent || repo.messages["third"].Status != entity.OutboxStatusSent {
+		t.Error("expected the messages around the failed mark to stay sent")
+	}
+
+	second := repo.messages["second"]
+	if second.Status != entity.OutboxStatusPending || !second.NextAttemptAt.After(now) {
+		t.Errorf("expected the unmarked message to stay leased, got %s due at %s", second.Status, second.NextAttemptAt)
+	}
+
+	worker.runOnce(context.Background())
+
+	if len(publisher.published) != 3 {
+		t.Errorf("expected the leased message not to be published again before its lease expires, got %v", publisher.published)
+	}
+}
+
+func TestOutboxRelayWorkerRetriesWithBackoffThenFails(t *testing.T) {
+	now := time.Now()
+	repo := newFakeOutboxRepository(newOutboxMessage("message", now.Add(-time.Hour)))
+	publisher := &fakePublisher{err: errors.New("broker unavailable")}
+	worker := newTestOutboxRelayWorker(repo, publisher, config.OutboxConfig{
+		MaxAttempts:  3,
+		RetryBackoff: time.Second,
+	})
+	message := repo.messages["message"]
+
+	for attempt, backoff := range []time.Duration{time.Second, 2 * time.Second} {
+		before := time.Now()
+		worker.runOnce(context.Background())
+
+		if message.Status != entity.OutboxStatusPending || message.Attempts != attempt+1 {
+			t.Fatalf("attempt %d: expected a pending message, got %s after %d attempts", attempt+1, message.Status, message.Attempts)
+		}
+		if message.LastError != "broker unavailable" {
+			t.Errorf("attempt %d: expected the publish error to be recorded, got %q", attempt+1, message.LastError)
+		}
+		if wait := message.NextAttemptAt.Sub(before); wait < backoff || wait > backoff+time.Second {
+			t.Errorf("attempt %d: expected a retry in %s, got %s", attempt+1, backoff, wait)
+		}
+
+		message.NextAttemptAt = now
+	}
+
+	worker.runOnce(context.Background())
+
+	if message.Status != entity.OutboxStatusFailed || message.Attempts != 3 {
+		t.Fatalf("expected the message to fail after 3 attempts, got %s after %d attempts", message.Status, message.Attempts)
+	}
+
+	worker.runOnce(context.Background())
+
+	if message.Attempts != 3 {
+		t.Errorf("expected a failed message not to be retried, got %d attempts", message.Attempts)
+	}
+}
+
+func TestOutboxRetryBackoff(t *testing.T) {
+	worker := newTestOutboxRelayWorker(newFakeOutboxRepository(), &fakePublisher{}, config.OutboxConfig{
+		RetryBackoff: time.Second,
+	})
+
+	for attempts, expected := range map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		5:  16 * time.Second,
+		12: 2048 * time.Second,
+		13: MaxOutboxRetryBackoff,
+		40: MaxOutboxRetryBackoff,
+	} {
+		if backoff := worker.retryBackoff(attempts); backoff != expected {
+			t.Errorf("expected a %s backoff after %d attempts, got %s", expected, attempts, backoff)
+		}
+	}
+}
+
+func TestOutboxRelayWorkerUpdatesLagMetrics(t *testing.T) {
+	now := time.Now()
+	repo := newFakeOutboxRepository(
+		newOutboxMessage("sent", now.Add(-time.Hour)),
+		newOutboxMessage("pending", now.Add(-10*time.Minute)),
+		newOutboxMessage("later", now.Add(time.Hour)),
+	)
+	repo.markSentErrs["pending"] = errors.New("connection reset")
+	repo.messages["sent"].Status = entity.OutboxStatusSent
+	worker := newTestOutboxRelayWorker(repo, &fakePublisher{}, config.OutboxConfig{})
+
+	worker.runOnce(context.Background())
+
+	if pending := testutil.ToFloat64(metrics.OutboxPendingMessages); pending != 2 {
+		t.Errorf("expected 2 pending messages, got %v", pending)
+	}
+	if lag := testutil.ToFloat64(metrics.OutboxLagSeconds); lag < 600 || lag > 660 {
+		t.Errorf("expected the lag of the oldest pending message, got %vs", lag)
+	}
+
+	delete(repo.markSentErrs, "pending")
+	repo.messages["pending"].NextAttemptAt = now
+	repo.messages["later"].NextAttemptAt = now
+	worker.runOnce(context.Background())
+
+	if pending := testutil.ToFloat64(metrics.OutboxPendingMessages); pending != 0 {
+		t.Errorf("expected no pending messages, got %v", pending)
+	}
+	if lag := testutil.ToFloat64(metrics.OutboxLagSeconds); lag != 0 {
+		t.Errorf("expected no lag, got %vs", lag)
+	}
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/datpham/user-service-ms/config"
+	"github.com/datpham/user-service-ms/internal/pkg/logger"
+)
+
+const (
+	DefaultWebhookDeliveryInterval = time.Second * 5
+)
+
+type IWebhookDeliverer interface {
+	DeliverPending(ctx context.Context) (int, error)
+}
+
+// WebhookDeliveryWorker periodically sends the webhook deliveries that are due
+type WebhookDeliveryWorker struct {
+	logger    *logger.Logger
+	deliverer IWebhookDeliverer
+	interval  time.Duration
+}
+
+func NewWebhookDeliveryWorker(logger *logger.Logger, deliverer IWebhookDeliverer, cfg config.WebhookConfig) *WebhookDeliveryWorker {
+	interval := cfg.DeliveryInterval
+	if interval <= 0 {
+		interval = DefaultWebhookDeliveryInterval
+	}
+
+	return &WebhookDeliveryWorker{
+		logger:    logger,
+		deliverer: deliverer,
+		interval:  interval,
+	}
+}
+
+// Start runs the delivery loop until the context is cancelled
+func (w *WebhookDeliveryWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *WebhookDeliveryWorker) runOnce(ctx context.Context) {
+	attempted, err := w.deliverer.DeliverPending(ctx)
+	if err != nil {
+		w.logger.Errorf("failed to deliver webhooks: %s", err.Error())
+		return
+	}
+
+	if attempted > 0 {
+		w.logger.Debugf("Attempted %d webhook deliveries", attempted)
+	}
+}